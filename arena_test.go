@@ -0,0 +1,118 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadAllArena(t *testing.T) {
+	input := fixture("id,name,note\n", 20000, "%[1]d,name %[1]d,\n", "%[1]d,\"quoted \"\"%[1]d\"\"\",\"multi\nline\"\n", "%d,,é\n", "%d,x,y\n\n")
+
+	forModes(func(mode SIMDMode, chunkSize int) {
+		r := NewReader(strings.NewReader(input))
+		r.SIMD, r.ChunkSize = mode, chunkSize
+		want, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("TestReadAllArena(%d, %d): %v", mode, chunkSize, err)
+		}
+
+		r = NewReader(strings.NewReader(input))
+		r.SIMD, r.ChunkSize = mode, chunkSize
+		a, err := r.ReadAllArena()
+		if err != nil {
+			t.Fatalf("TestReadAllArena(%d, %d): %v", mode, chunkSize, err)
+		}
+		if a.Len() != len(want) {
+			t.Fatalf("TestReadAllArena(%d, %d): got: %d records want: %d", mode, chunkSize, a.Len(), len(want))
+		}
+		var record []string
+		for i := range want {
+			record = a.AppendRecord(record[:0], i)
+			if !reflect.DeepEqual(record, want[i]) || a.NumFields(i) != len(want[i]) || a.Field(i, 1) != want[i][1] {
+				t.Errorf("TestReadAllArena(%d, %d): got: %q for record %d want: %q", mode, chunkSize, record, i, want[i])
+				break
+			}
+		}
+		if got := a.Record(a.Len() - 1); !reflect.DeepEqual(got, want[len(want)-1]) {
+			t.Errorf("TestReadAllArena(%d, %d): got: %q want: %q", mode, chunkSize, got, want[len(want)-1])
+		}
+	})
+
+	r := NewReader(strings.NewReader("a,b\n1,\"2\n"))
+	if _, err := r.ReadAllArena(); err == nil {
+		t.Errorf("TestReadAllArena: got: nil want: a parse error")
+	}
+	if a, err := NewReader(strings.NewReader("")).ReadAllArena(); err != nil || a.Len() != 0 {
+		t.Errorf("TestReadAllArena: got: %v want: no records", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("TestReadAllArena: got: no panic want: a panic for a field out of range")
+		}
+	}()
+	a, _ := NewReader(strings.NewReader("a,b\nc,d\n")).ReadAllArena()
+	a.Field(0, 2)
+}
+
+// BenchmarkReadAllArena compares ReadAllArena to ReadAll, reporting the heap
+// that the records returned hold on to as retained-B besides the allocations
+// made while reading them
+func BenchmarkReadAllArena(b *testing.B) {
+	buf, err := ioutil.ReadFile("testdata/nyc-taxi-data-100K.csv")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, arena := range []bool{false, true} {
+		b.Run(fmt.Sprintf("arena=%v", arena), func(b *testing.B) {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+
+			var kept interface{}
+			b.SetBytes(int64(len(buf)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				kept = nil
+				r := NewReader(bytes.NewReader(buf))
+				if arena {
+					kept, err = r.ReadAllArena()
+				} else {
+					kept, err = r.ReadAll()
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)), "retained-B")
+			runtime.KeepAlive(kept)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+// Stage1Backend is an experimental, pluggable implementation of stage 1,
+// which computes the bit masks of the structural characters in a chunk of
+// input. It is meant for offloading this embarrassingly parallel scan, say
+// to a GPU for very large inputs, whereas stage 2 keeps running on the CPU.
+// Only the interface is provided: this package has no implementation other
+// than the built-in one. Preprocess is called for every chunk in order, from
+// a single goroutine.
+type Stage1Backend interface {
+	// Preprocess fills masks[:n] with three masks for every 64-byte block
+	// of buf and returns n; masks has room for them all. The masks are
+	// those of the delimiters, separators and quotes, in which bit i
+	// corresponds to byte i of the block:
+	//   - delimiters are newlines plus carriage returns that precede a
+	//     newline outside quotes; a partial last block gets a delimiter
+	//     right beyond the end of buf
+	//   - separators within quotes are cleared
+	//   - quotes are cleared for escaped quotes ("" within quotes)
+	// It appends the offset of every block with fields that require
+	// unescaping quotes or replacing \r\n to postProc, which it returns.
+	// quoted reports whether buf starts within quotes; Preprocess returns
+	// whether it ends within quotes.
+	Preprocess(buf []byte, comma byte, quoted bool, masks, postProc []uint64) (n int, _ []uint64, _ bool)
+}
+
+// preprocess runs stage 1 on a chunk, with masks preallocated for its size
+func (r *Reader) preprocess(buf []byte, comma byte, quoted uint64, masks, postProc []uint64) ([]uint64, []uint64, uint64) {
+	if r.Stage1 == nil {
+		if k := r.simdKernel(); k != nil && k.stage1 != nil {
+			return stage1PreprocessBufferWith(k.stage1, buf, uint64(comma), quoted, &masks, &postProc)
+		}
+		return stage1PreprocessBufferEx(buf, uint64(comma), quoted, &masks, &postProc)
+	}
+	n, postProc, inQuotes := r.Stage1.Preprocess(buf, comma, quoted != 0, masks, postProc[:0])
+	if inQuotes {
+		return masks[:n], postProc, ^uint64(0)
+	}
+	return masks[:n], postProc, 0
+}
@@ -0,0 +1,60 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// testBackend runs the built-in stage 1 behind the Stage1Backend interface
+type testBackend struct {
+	calls int
+}
+
+func (b *testBackend) Preprocess(buf []byte, comma byte, quoted bool, masks, postProc []uint64) (int, []uint64, bool) {
+	b.calls++
+	q := uint64(0)
+	if quoted {
+		q = ^q
+	}
+	filled, postProc, q := stage1PreprocessBufferEx(buf, uint64(comma), q, &masks, &postProc)
+	return len(filled), postProc, q != 0
+}
+
+func TestStage1Backend(t *testing.T) {
+	if !SupportedCPU() {
+		t.SkipNow()
+	}
+	corpus := selfTestCorpus()
+	input := corpus[len(corpus)-1]
+	want, err := encodingCsv(input, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	backend := &testBackend{}
+	r := NewReader(bytes.NewReader(input))
+	r.Stage1 = backend
+	if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("TestStage1Backend: got: %v want: records of encoding/csv", err)
+	}
+	if backend.calls < 2 {
+		t.Errorf("TestStage1Backend: got: %d calls want: one per chunk", backend.calls)
+	}
+}
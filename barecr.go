@@ -0,0 +1,95 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io"
+)
+
+// bareCRs has the carriage returns of the input outside quotes that are not
+// followed by a newline read as newlines (see BareCR). Offsets are unaffected, so the
+// input can still be seeked.
+func (r *Reader) bareCRs() {
+	if !r.BareCR {
+		return
+	}
+	if r.inMemory {
+		if bytes.IndexByte(r.data, '\r') >= 0 {
+			r.data = append([]byte(nil), r.data...)
+			replaceBareCRs(r.data, false, true)
+			r.r = bytes.NewReader(r.data)
+		}
+	} else if _, ok := r.r.(*bareCRFilter); !ok {
+		r.r = &bareCRFilter{in: r.r}
+	}
+}
+
+// replaceBareCRs replaces the carriage returns of buf outside quotes that are
+// followed by another byte than a newline by newlines, as well as one that
+// ends buf if final is set. quoted reports whether buf starts within quotes;
+// replaceBareCRs returns whether it ends within quotes.
+func replaceBareCRs(buf []byte, quoted, final bool) bool {
+	for i := 0; ; i++ {
+		j := bytes.IndexAny(buf[i:], "\r\"")
+		if j < 0 {
+			return quoted
+		}
+		if i += j; buf[i] == '"' {
+			quoted = !quoted
+		} else if !quoted && (i+1 < len(buf) && buf[i+1] != '\n' || i+1 == len(buf) && final) {
+			buf[i] = '\n'
+		}
+	}
+}
+
+// bareCRFilter replaces bare carriage returns by newlines, holding back a
+// carriage return that ends the bytes read until the byte that follows is read
+type bareCRFilter struct {
+	in     io.Reader
+	buf    []byte // bytes read but not yet returned
+	quoted bool   // whether the bytes read so far end within quotes
+	err    error
+}
+
+func (f *bareCRFilter) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		ready := len(f.buf)
+		if f.err == nil && ready > 0 && f.buf[ready-1] == '\r' {
+			ready--
+		}
+		if ready > 0 {
+			n := copy(p, f.buf[:ready])
+			f.buf = f.buf[:copy(f.buf, f.buf[n:])]
+			return n, nil
+		}
+		if f.err != nil {
+			return 0, f.err
+		}
+
+		held := len(f.buf)
+		if free := cap(f.buf) - held; free < len(p) {
+			f.buf = append(make([]byte, 0, held+len(p)), f.buf...)
+		}
+		n, err := f.in.Read(f.buf[held : held+len(p)])
+		f.buf, f.err = f.buf[:held+n], err
+		f.quoted = replaceBareCRs(f.buf, f.quoted, err != nil)
+	}
+}
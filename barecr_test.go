@@ -0,0 +1,79 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBareCR(t *testing.T) {
+	input := fixture("", 3000, "%d,a\r", "%d,\"b\rc\r\nd\"\r\n", "%d,\r\r") + "end,\"\r\"\r"
+	want := append(fixtureRecords(3000, []string{"a"}, []string{"b\rc\nd"}, []string{""}), []string{"end", "\r"})
+
+	forModes(func(mode SIMDMode, chunkSize int) {
+		for _, oneByte := range []bool{false, true} {
+			var in io.Reader = strings.NewReader(input)
+			if oneByte {
+				in = iotest.OneByteReader(in)
+			}
+			r := NewReader(in)
+			r.SIMD, r.ChunkSize, r.BareCR, r.KeepRaw = mode, chunkSize, true, true
+			var records [][]string
+			line := 1
+			for {
+				record, err := r.ReadRecord()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("TestBareCR(%d, %d, %v): %v", mode, chunkSize, oneByte, err)
+				}
+				if record.Line != line {
+					t.Errorf("TestBareCR(%d, %d, %v): got: line %d for %q want: %d", mode, chunkSize, oneByte, record.Line, record.Fields, line)
+					break
+				}
+				line += strings.Count(string(record.Raw), "\n") + 1
+				if len(records)%3 == 2 {
+					line++ // the empty line that follows
+				}
+				records = append(records, record.Fields)
+			}
+			if !reflect.DeepEqual(records, want) {
+				t.Errorf("TestBareCR(%d, %d, %v): got: %d records want: %d", mode, chunkSize, oneByte, len(records), len(want))
+			}
+		}
+	})
+
+	b := []byte(input)
+	records, err := ReadBytes(b, func(r *Reader) { r.BareCR = true })
+	if err != nil || !reflect.DeepEqual(records, want) || string(b) != input {
+		t.Errorf("TestBareCR: got: %d records (%v) want: %d, with the input unmodified", len(records), err, len(want))
+	}
+
+	r := NewReader(strings.NewReader("a\rb\r"))
+	if records, err := r.ReadAll(); err != nil || len(records) != 1 {
+		t.Errorf("TestBareCR: got: %q (%v) want: a single record", records, err)
+	}
+
+	f := &bareCRFilter{in: strings.NewReader("a\rb")}
+	if n, err := f.Read(nil); n != 0 || err != nil {
+		t.Errorf("TestBareCR: got: %d, %v want: 0, <nil> for an empty read", n, err)
+	}
+}
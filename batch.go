@@ -0,0 +1,145 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"context"
+	"io"
+	"math"
+)
+
+// ReadBatch reads up to n records at once, which saves the overhead of a
+// call to Read for every record without holding all of them like ReadAll.
+// The records are handed out from the blocks that the parsing stages deliver
+// as they are, so a batch never spans two blocks and may hold fewer than n
+// records even before the input ends. Once no records remain, ReadBatch
+// returns the error that ended the input, which is io.EOF at the end of the
+// input. The records are not affected by ReuseRecord. Like Read, it may be
+// called from multiple goroutines concurrently.
+func (r *Reader) ReadBatch(n int) ([][]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if n <= 0 {
+		return nil, nil
+	}
+	return r.readBatch(n)
+}
+
+// readBatch reads up to n records of the current block (see ReadBatch)
+func (r *Reader) readBatch(n int) ([][]string, error) {
+	if !r.simd() {
+		r.fill(n) // encoding/csv delivers one record at a time
+	}
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+	start := r.currrecord - 1
+	end := start + n
+	if end > len(r.records) {
+		end = len(r.records)
+	}
+	r.recordNumber += end - r.currrecord
+	r.currrecord, r.lastPos = end, r.positions[end-1]
+	return r.records[start:end:end], nil
+}
+
+// A RecordBatch holds a block of records as delivered by ReadAllStream
+type RecordBatch struct {
+	Records [][]string
+	Line    int   // line on which the first record starts
+	Err     error // error that ended the input, if any, without records
+
+	r     *Reader
+	chunk []byte // chunk buffer the fields point into, if releasable
+}
+
+// Release hands the memory that the fields of b point into back to r, for
+// reuse by the parsing stages, which saves allocations when streaming large
+// inputs. Releasing is optional; once released, neither b nor any of its
+// records and fields may be used any longer, nor may b be released twice.
+func (b *RecordBatch) Release() {
+	if b.chunk != nil {
+		b.r.releaseChunk(b.chunk)
+	}
+	b.Records, b.chunk = nil, nil
+}
+
+// ReadAllStream reads all remaining records of r a block at a time, as
+// delivered by the parsing stages, so the records can be processed while
+// parsing continues. The batches are delivered in the order of the input. An
+// error other than io.EOF that ends the input is delivered as a last batch
+// that holds only Err, after which the channel is closed; at the end of the
+// input the channel is closed right away. To stop early, cancel ctx: this
+// tears down the parsing stages and closes the channel, which need not be
+// drained then (a last batch with the error of ctx may still be received).
+// Once ReadAllStream has been called, r must no longer be read from directly.
+func (r *Reader) ReadAllStream(ctx context.Context) <-chan RecordBatch {
+	batches := make(chan RecordBatch) // the parsing stages run ahead regardless
+	go func() {
+		defer close(batches)
+		for {
+			batch := r.readStreamBatch(ctx)
+			if batch.Err == io.EOF {
+				return
+			}
+			select {
+			case batches <- batch:
+				if batch.Err == nil {
+					continue
+				}
+			case <-ctx.Done():
+				r.Lock()
+				r.cancelled(ctx)
+				r.Unlock()
+			}
+			return
+		}
+	}()
+	return batches
+}
+
+// readStreamBatch reads the remaining records of the current block, or
+// encoding/csv's worth of a block (see ReadAllStream)
+func (r *Reader) readStreamBatch(ctx context.Context) RecordBatch {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.cancelled(ctx); err != nil {
+		return RecordBatch{Err: err}
+	}
+	defer r.watch(ctx)()
+
+	n := math.MaxInt
+	if !r.simd() {
+		n = csvBlockSize
+	}
+	// only a block that is handed out whole may be released
+	whole := r.currrecord >= len(r.records)
+	records, err := r.readBatch(n)
+	if err := r.cancelled(ctx); err != nil {
+		return RecordBatch{Err: err}
+	}
+	if err != nil {
+		return RecordBatch{Err: err}
+	}
+	batch := RecordBatch{Records: records, Line: r.positions[r.currrecord-len(records)].line}
+	if whole && r.chunk != nil && r.trailer == nil {
+		batch.r, batch.chunk, r.chunk = r, r.chunk, nil
+	}
+	return batch
+}
@@ -0,0 +1,168 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadBatch(t *testing.T) {
+	var data bytes.Buffer
+	for i := 0; i < 50000; i++ {
+		fmt.Fprintf(&data, "%d,\"field\n%d\",%d\n", i, i, i*i)
+	}
+	want, err := NewReader(bytes.NewReader(data.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		for _, n := range []int{1, 7, 1000, 1 << 20} {
+			r := NewReader(bytes.NewReader(data.Bytes()))
+			r.kernel = kernel
+			var got [][]string
+			for {
+				batch, err := r.ReadBatch(n)
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("TestReadBatch(%d): got: %v want: nil", n, err)
+				}
+				if len(batch) == 0 || len(batch) > n {
+					t.Fatalf("TestReadBatch(%d): got: %d records want: 1 to %d", n, len(batch), n)
+				}
+				got = append(got, batch...)
+				if number, line := r.RecordNumber(), r.LineNumber(); number != len(got) || line != 2*len(got)-1 {
+					t.Fatalf("TestReadBatch(%d): got: record %d on line %d want: record %d on line %d", n, number, line, len(got), 2*len(got)-1)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("TestReadBatch(%d): got: %d records want: %d", n, len(got), len(want))
+			}
+		}
+	}
+}
+
+func TestReadAllStream(t *testing.T) {
+	var data bytes.Buffer
+	for i := 0; i < 50000; i++ {
+		fmt.Fprintf(&data, "%d,\"field\n%d\",%d\n", i, i, i*i)
+	}
+	want, err := NewReader(bytes.NewReader(data.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		r := NewReader(bytes.NewReader(data.Bytes()))
+		r.kernel = kernel
+		var got [][]string
+		for batch := range r.ReadAllStream(context.Background()) {
+			if batch.Err != nil {
+				t.Fatalf("TestReadAllStream: got: %v want: nil", batch.Err)
+			}
+			if batch.Line != 2*len(got)+1 {
+				t.Fatalf("TestReadAllStream: got: line %d want: %d", batch.Line, 2*len(got)+1)
+			}
+			got = append(got, batch.Records...)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("TestReadAllStream: got: %d records want: %d", len(got), len(want))
+		}
+	}
+}
+
+func TestReadAllStreamError(t *testing.T) {
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		r := NewReader(strings.NewReader("a,b\nc,d\"\ne,f\n"))
+		r.kernel = kernel
+		var batches []RecordBatch
+		for batch := range r.ReadAllStream(context.Background()) {
+			batches = append(batches, batch)
+		}
+		last := batches[len(batches)-1]
+		if _, ok := last.Err.(*csv.ParseError); !ok || len(last.Records) != 0 {
+			t.Errorf("TestReadAllStreamError: got: %d records, %v want: %T", len(last.Records), last.Err, &csv.ParseError{})
+		}
+	}
+}
+
+func TestReadAllStreamCancel(t *testing.T) {
+	input := selfTestCorpus()
+	big := input[len(input)-1]
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		baseline := runtime.NumGoroutine()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		r := NewReader(bytes.NewReader(big))
+		r.kernel = kernel
+		r.ChunkSize = 1 << 10
+		batches := r.ReadAllStream(ctx)
+		if batch := <-batches; batch.Err != nil || len(batch.Records) == 0 {
+			t.Fatalf("TestReadAllStreamCancel: got: %d records, %v want: records", len(batch.Records), batch.Err)
+		}
+		cancel()
+		waitGoroutines(t, baseline)
+
+		if _, err := r.Read(); err != context.Canceled {
+			t.Errorf("TestReadAllStreamCancel: got: %v want: %v", err, context.Canceled)
+		}
+	}
+}
+
+func TestRecordBatchRelease(t *testing.T) {
+	var data bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&data, "%d,\"fi\"\"eld\",%d\n", i, i*i)
+	}
+	want, err := NewReader(bytes.NewReader(data.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	r := NewReader(io.MultiReader(bytes.NewReader(data.Bytes()))) // not in memory
+	r.ChunkSize, r.Memory = 1<<12, &MemoryProfile{}
+	r.MaxPendingBlocks = 4 // for released chunks to be read into again
+	var got [][]string
+	n := 0
+	for batch := range r.ReadAllStream(context.Background()) {
+		if batch.Err != nil {
+			t.Fatalf("TestRecordBatchRelease: got: %v want: nil", batch.Err)
+		}
+		if n++; n%2 == 0 {
+			got = append(got, copyRecords(batch.Records)...)
+			batch.Release()
+		} else {
+			got = append(got, batch.Records...) // must not be overwritten
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TestRecordBatchRelease: got: %d records want: %d", len(got), len(want))
+	}
+	if r.Memory.ChunkBuffers.Pooled == 0 {
+		t.Errorf("TestRecordBatchRelease: got: no pooled chunk buffers want: some")
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ChunkBoundary is a range of a blob that holds whole records, as returned
+// by SplitBlob, along with the line on which it starts
+type ChunkBoundary struct {
+	Start uint64
+	End   uint64
+	Line  int
+}
+
+var errZeroChunkSize = errors.New("simdcsv: zero chunk size")
+
+var errBlobQuoted = errors.New("simdcsv: blob ends within a quoted field")
+
+// SplitBlob splits blob into ranges of about chunkSize bytes that end at
+// record boundaries, so that the ranges can be parsed independently, for
+// instance in parallel. The blob is cut into pieces of chunkSize bytes, and
+// the first newline outside quotes of every piece but the first ends a range,
+// so ranges are as much longer as the records that span the pieces. Quotes
+// are assumed to delimit quoted fields only (as opposed to LazyQuotes).
+//
+// Whether a newline is quoted depends on all of the blob that precedes it.
+// The blob is therefore scanned in parallel for the first newline of every
+// piece of chunkSize bytes that follows either an even or an odd number of
+// quotes, after which the quote state that is propagated from the start of
+// the blob decides between the two.
+func SplitBlob(blob []byte, chunkSize uint64) ([]ChunkBoundary, error) {
+	if chunkSize == 0 {
+		return nil, errZeroChunkSize
+	}
+	pieces := scanPieces(uint64(len(blob)), chunkSize, func(start, end uint64) blobPiece {
+		return scanPiece(blob[start:end])
+	})
+	return pieceBoundaries(pieces, chunkSize, uint64(len(blob)))
+}
+
+// scanPieces scans the pieces of chunkSize bytes of an input of size bytes
+// in parallel
+func scanPieces(size, chunkSize uint64, scan func(start, end uint64) blobPiece) []blobPiece {
+	pieces := make([]blobPiece, (size+chunkSize-1)/chunkSize)
+	var wg sync.WaitGroup
+	next := make(chan int, len(pieces))
+	for i := range pieces {
+		next <- i
+	}
+	close(next)
+	for w := 0; w < runtime.GOMAXPROCS(0) && w < len(pieces); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				start := uint64(i) * chunkSize
+				end := start + chunkSize
+				if end > size {
+					end = size
+				}
+				pieces[i] = scan(start, end)
+			}
+		}()
+	}
+	wg.Wait()
+	return pieces
+}
+
+// pieceBoundaries returns the ranges that end at the first newline outside
+// quotes of every piece but the first, of an input of size bytes
+func pieceBoundaries(pieces []blobPiece, chunkSize, size uint64) ([]ChunkBoundary, error) {
+	var boundaries []ChunkBoundary
+	quoted, line := false, 1
+	current := ChunkBoundary{Line: 1}
+	for i, piece := range pieces {
+		if i > 0 {
+			if nl := piece.newline[boolIndex(quoted)]; nl >= 0 {
+				end := uint64(i)*chunkSize + uint64(nl) + 1
+				current.End = end
+				boundaries = append(boundaries, current)
+				current = ChunkBoundary{Start: end, Line: line + piece.lines[boolIndex(quoted)]}
+			}
+		}
+		quoted = quoted != piece.odd
+		line += piece.total
+	}
+	if quoted {
+		return nil, errBlobQuoted
+	}
+	if current.Start < size {
+		current.End = size
+		boundaries = append(boundaries, current)
+	}
+	return boundaries, nil
+}
+
+// blobPiece is a piece of a blob as scanned by SplitBlob
+type blobPiece struct {
+	newline [2]int // offset of the first newline outside quotes when starting outside (or within) quotes, or -1
+	lines   [2]int // number of newlines up to and including newline
+	odd     bool   // whether the piece holds an odd number of quotes
+	total   int    // number of newlines in the piece
+}
+
+// scanPiece finds the first newline outside quotes in piece for either
+// quote state at its start
+func scanPiece(piece []byte) (p blobPiece) {
+	p.newline = [2]int{-1, -1}
+	quoted, lines := false, 0
+	for i := 0; i < len(piece) && (p.newline[0] < 0 || p.newline[1] < 0); i++ {
+		switch piece[i] {
+		case '"':
+			quoted = !quoted
+		case '\n':
+			lines++
+			// when starting within quotes, the quote state is the opposite
+			if s := boolIndex(quoted); p.newline[s] < 0 {
+				p.newline[s], p.lines[s] = i, lines
+			}
+		}
+	}
+	p.odd = bytes.Count(piece, []byte{'"'})&1 == 1
+	p.total = bytes.Count(piece, []byte{'\n'})
+	return
+}
+
+// then returns the piece that p, of n bytes, and q, which follows it, make up
+func (p blobPiece) then(q blobPiece, n int) blobPiece {
+	for s := range p.newline {
+		// q starts in the opposite quote state if p holds an odd number of quotes
+		if t := s ^ boolIndex(p.odd); p.newline[s] < 0 && q.newline[t] >= 0 {
+			p.newline[s], p.lines[s] = n+q.newline[t], p.total+q.lines[t]
+		}
+	}
+	p.odd = p.odd != q.odd
+	p.total += q.total
+	return p
+}
+
+func boolIndex(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
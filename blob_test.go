@@ -0,0 +1,79 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitBlob(t *testing.T) {
+	var data bytes.Buffer
+	for i := 0; i < 5000; i++ {
+		// quoted newlines that pieces are bound to start after
+		fmt.Fprintf(&data, "%d,\"multi\nline %s\",\"\"\"%d\"\"\"\n", i, strings.Repeat("a,\n", i%20), i)
+	}
+	blob := data.Bytes()
+
+	rCsv := csv.NewReader(bytes.NewReader(blob))
+	want, err := rCsv.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []uint64{1, 64, 1000, 65536, uint64(len(blob)) * 2} {
+		boundaries, err := SplitBlob(blob, size)
+		if err != nil {
+			t.Fatalf("TestSplitBlob(%d): got: %v want: nil", size, err)
+		}
+		var got [][]string
+		start := uint64(0)
+		for _, b := range boundaries {
+			if b.Start != start || b.End <= b.Start || blob[b.End-1] != '\n' {
+				t.Fatalf("TestSplitBlob(%d): got: %+v want: range from %d up to a newline", size, b, start)
+			}
+			start = b.End
+
+			rCsv := csv.NewReader(bytes.NewReader(blob[b.Start:b.End]))
+			records, err := rCsv.ReadAll()
+			if err != nil {
+				t.Fatalf("TestSplitBlob(%d): got: %v want: nil", size, err)
+			}
+			if line := strings.Count(string(blob[:b.Start]), "\n") + 1; b.Line != line {
+				t.Errorf("TestSplitBlob(%d): got: line %d want: %d", size, b.Line, line)
+			}
+			got = append(got, records...)
+		}
+		if start != uint64(len(blob)) || !reflect.DeepEqual(got, want) {
+			t.Errorf("TestSplitBlob(%d): got: %d records up to %d want: %d records up to %d", size, len(got), start, len(want), len(blob))
+		}
+	}
+
+	if boundaries, err := SplitBlob(nil, 64); err != nil || len(boundaries) != 0 {
+		t.Errorf("TestSplitBlob: got: %v, %v want: none", boundaries, err)
+	}
+	if _, err := SplitBlob(blob, 0); err != errZeroChunkSize {
+		t.Errorf("TestSplitBlob: got: %v want: %v", err, errZeroChunkSize)
+	}
+	if _, err := SplitBlob([]byte("a,\"b\nc\n"), 2); err != errBlobQuoted {
+		t.Errorf("TestSplitBlob: got: %v want: %v", err, errBlobQuoted)
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// blockReader decompresses independent blocks of compressed input
+// concurrently, returning the decompressed blocks in order
+type blockReader struct {
+	blocks chan chan decodedBlock // in the order of the input
+	buf    []byte                 // rest of the current block
+	err    error
+}
+
+type decodedBlock struct {
+	data []byte
+	err  error
+}
+
+// newBlockReader returns a blockReader for the blocks that next returns in
+// turn, which are decompressed by decode from multiple goroutines
+func newBlockReader(next func() ([]byte, error), decode func([]byte) ([]byte, error)) *blockReader {
+	workers := runtime.GOMAXPROCS(0)
+	br := &blockReader{blocks: make(chan chan decodedBlock, workers)}
+	go func() {
+		defer close(br.blocks)
+		for {
+			block, err := next()
+			result := make(chan decodedBlock, 1)
+			br.blocks <- result
+			if err != nil {
+				result <- decodedBlock{nil, err}
+				return
+			}
+			go func() {
+				data, err := decode(block)
+				result <- decodedBlock{data, err}
+			}()
+		}
+	}()
+	return br
+}
+
+// Read fills p as far as the input goes, so that the chunk buffers of stage 1
+// are filled with decompressed blocks
+func (br *blockReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if len(br.buf) == 0 {
+			if br.err != nil {
+				break
+			}
+			result, ok := <-br.blocks
+			if !ok {
+				br.err = io.EOF
+				break
+			}
+			decoded := <-result
+			br.buf, br.err = decoded.data, decoded.err
+		}
+		m := copy(p[n:], br.buf)
+		br.buf, n = br.buf[m:], n+m
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, br.err
+}
+
+// bgzfBlockSize returns the size of the BGZF block that header starts,
+// which is a gzip member that records its size in an extra field, as
+// written by bgzip
+func bgzfBlockSize(header []byte) (int, bool) {
+	const fextra = 1 << 2
+	if len(header) < 18 || !bytes.HasPrefix(header, gzipMagic) || header[3]&fextra == 0 {
+		return 0, false
+	}
+	xlen := int(binary.LittleEndian.Uint16(header[10:]))
+	for extra := header[12:]; len(extra) >= 4 && xlen >= 4; {
+		slen := int(binary.LittleEndian.Uint16(extra[2:]))
+		if extra[0] == 'B' && extra[1] == 'C' && slen == 2 && len(extra) >= 6 {
+			return int(binary.LittleEndian.Uint16(extra[4:])) + 1, true
+		}
+		if 4+slen > len(extra) {
+			break
+		}
+		extra, xlen = extra[4+slen:], xlen-4-slen
+	}
+	return 0, false
+}
+
+var errBGZF = errors.New("simdcsv: invalid BGZF block")
+
+// newBGZFReader returns a reader that decompresses the BGZF blocks of in
+// concurrently
+func newBGZFReader(in *bufio.Reader) io.Reader {
+	next := func() ([]byte, error) {
+		header, err := in.Peek(18)
+		if len(header) == 0 && err != nil {
+			return nil, err
+		}
+		size, ok := bgzfBlockSize(header)
+		if !ok {
+			return nil, errBGZF
+		}
+		block := make([]byte, size)
+		if _, err := io.ReadFull(in, block); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return block, nil
+	}
+	decode := func(block []byte) ([]byte, error) {
+		zr, err := gzip.NewReader(bytes.NewReader(block))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(zr)
+	}
+	return newBlockReader(next, decode)
+}
+
+// maxParallelFrame is the largest content size of zstd frames that are
+// decompressed concurrently, as every frame is decompressed as a whole
+const maxParallelFrame = 16 << 20
+
+// zstdFrameContentSize returns the content size declared by the header of
+// the zstd frame that header starts, if any
+func zstdFrameContentSize(header []byte) (uint64, bool) {
+	if len(header) < 5 || !bytes.HasPrefix(header, zstdMagic) {
+		return 0, false
+	}
+	fhd := header[4]
+	singleSegment := fhd&(1<<5) != 0
+	offset := 5
+	if !singleSegment {
+		offset++ // window descriptor
+	}
+	offset += [4]int{0, 1, 2, 4}[fhd&3] // dictionary id
+	size := [4]int{0, 2, 4, 8}[fhd>>6]
+	if size == 0 && singleSegment {
+		size = 1
+	}
+	if size == 0 || len(header) < offset+size {
+		return 0, false
+	}
+	var fcs [8]byte
+	copy(fcs[:], header[offset:offset+size])
+	n := binary.LittleEndian.Uint64(fcs[:])
+	if size == 2 {
+		n += 256
+	}
+	return n, true
+}
+
+// readZstdFrame reads the zstd frame, or skippable frame, at the start of
+// in, delimiting it by the headers of its blocks
+func readZstdFrame(in io.Reader) ([]byte, error) {
+	frame := make([]byte, 5, 1<<16)
+	if _, err := io.ReadFull(in, frame); err != nil {
+		return nil, err
+	}
+	read := func(n int) error {
+		frame = append(frame, make([]byte, n)...)
+		_, err := io.ReadFull(in, frame[len(frame)-n:])
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	if binary.LittleEndian.Uint32(frame)&0xfffffff0 == 0x184d2a50 {
+		// skippable frame, such as a seek table
+		if err := read(3); err != nil {
+			return nil, err
+		}
+		return frame, read(int(binary.LittleEndian.Uint32(frame[4:])))
+	}
+	if !bytes.HasPrefix(frame, zstdMagic) {
+		return nil, errors.New("simdcsv: invalid zstd frame")
+	}
+	fhd := frame[4]
+	singleSegment := fhd&(1<<5) != 0
+	header := [4]int{0, 1, 2, 4}[fhd&3] // dictionary id
+	if !singleSegment {
+		header++ // window descriptor
+	}
+	if fcs := [4]int{0, 2, 4, 8}[fhd>>6]; fcs == 0 && singleSegment {
+		header++
+	} else {
+		header += fcs
+	}
+	if err := read(header); err != nil {
+		return nil, err
+	}
+
+	for last := false; !last; {
+		if err := read(3); err != nil {
+			return nil, err
+		}
+		bh := frame[len(frame)-3:]
+		h := uint32(bh[0]) | uint32(bh[1])<<8 | uint32(bh[2])<<16
+		size := int(h >> 3)
+		if h>>1&3 == 1 { // RLE block
+			size = 1
+		}
+		if err := read(size); err != nil {
+			return nil, err
+		}
+		last = h&1 != 0
+	}
+	if fhd&(1<<2) != 0 {
+		return frame, read(4) // content checksum
+	}
+	return frame, nil
+}
+
+// newZstdFrameReader returns a reader that decompresses the zstd frames of
+// in concurrently, such as those of seekable zstd, where every frame is
+// decompressed as a whole
+func newZstdFrameReader(in *bufio.Reader) (io.Reader, error) {
+	d, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	if err != nil {
+		return nil, err
+	}
+	next := func() ([]byte, error) {
+		return readZstdFrame(in)
+	}
+	decode := func(frame []byte) ([]byte, error) {
+		if binary.LittleEndian.Uint32(frame)&0xfffffff0 == 0x184d2a50 {
+			return nil, nil // skippable frame, such as a seek table
+		}
+		return d.DecodeAll(frame, nil)
+	}
+	return newBlockReader(next, decode), nil
+}
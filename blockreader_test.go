@@ -0,0 +1,102 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// bgzf compresses data into BGZF blocks of up to size bytes, followed by the
+// empty block that marks the end
+func bgzf(data []byte, size int) []byte {
+	var out bytes.Buffer
+	for {
+		n := size
+		if n > len(data) {
+			n = len(data)
+		}
+		var deflated bytes.Buffer
+		fw, _ := flate.NewWriter(&deflated, flate.BestSpeed)
+		fw.Write(data[:n])
+		fw.Close()
+
+		header := []byte{0x1f, 0x8b, 8, 4, 0, 0, 0, 0, 0, 0xff, 6, 0, 'B', 'C', 2, 0, 0, 0}
+		binary.LittleEndian.PutUint16(header[16:], uint16(len(header)+deflated.Len()+8-1))
+		out.Write(header)
+		out.Write(deflated.Bytes())
+		binary.Write(&out, binary.LittleEndian, crc32.ChecksumIEEE(data[:n]))
+		binary.Write(&out, binary.LittleEndian, uint32(n))
+		if n == 0 {
+			return out.Bytes()
+		}
+		data = data[n:]
+	}
+}
+
+func TestParallelDecompression(t *testing.T) {
+	var input bytes.Buffer
+	input.WriteString("id,name\n")
+	for i := 0; input.Len() < 4<<20; i++ {
+		fmt.Fprintf(&input, "%d,\"name %d\"\n", i, i)
+	}
+	expected, err := encodingCsv(input.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// frames of seekable zstd, followed by a (fake) seek table
+	zw, _ := zstd.NewWriter(nil)
+	var zst []byte
+	for data := input.Bytes(); len(data) > 0; {
+		n := 100000
+		if n > len(data) {
+			n = len(data)
+		}
+		zst = zw.EncodeAll(data[:n], zst)
+		data = data[n:]
+	}
+	zst = append(zst, 0x5e, 0x2a, 0x4d, 0x18, 4, 0, 0, 0, 1, 2, 3, 4)
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{{"bgzf", bgzf(input.Bytes(), 60000)}, {"zstd", zst}} {
+		in, err := decompress(bufio.NewReader(bytes.NewReader(tc.data)))
+		if _, ok := in.(*blockReader); err != nil || !ok {
+			t.Fatalf("TestParallelDecompression(%s): got: %T, %v want: parallel decompression", tc.name, in, err)
+		}
+		records, err := NewReader(in).ReadAll()
+		if err != nil || !reflect.DeepEqual(records, expected) {
+			t.Errorf("TestParallelDecompression(%s): got: %d records, %v want: %d", tc.name, len(records), err, len(expected))
+		}
+
+		// truncated input
+		in, _ = decompress(bytes.NewReader(tc.data[:len(tc.data)/2]))
+		if _, err := NewReader(in).ReadAll(); err == nil {
+			t.Errorf("TestParallelDecompression(%s): got: nil want: error for truncated input", tc.name)
+		}
+	}
+}
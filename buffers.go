@@ -0,0 +1,93 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// chunkAlign is the alignment (and minimum trailing padding) of chunk buffers
+const chunkAlign = 64
+
+// Chunk buffers that are no longer referenced by any record
+var chunkPool sync.Pool
+
+// allocChunk returns a zeroed buffer of length size that starts at a 64-byte
+// aligned address and is followed by at least 64 bytes of padding, so that
+// a full 64-byte load at any aligned offset stays within the allocation.
+func allocChunk(size int) []byte {
+	raw := make([]byte, size+2*chunkAlign)
+	offset := int(-uintptr(unsafe.Pointer(&raw[0])) & (chunkAlign - 1))
+	return raw[offset : offset+size : offset+size+chunkAlign]
+}
+
+// getChunk returns an aligned chunk buffer of length size, reusing a
+// pooled buffer when one of sufficient capacity is available
+func getChunk(size int) []byte {
+	buf, _ := takeChunk(size)
+	return buf
+}
+
+// takeChunk is like getChunk, and reports whether the buffer was pooled
+func takeChunk(size int) (buf []byte, pooled bool) {
+	if p, ok := chunkPool.Get().(*[]byte); ok {
+		if cap(*p) >= size+chunkAlign {
+			return (*p)[:size], true
+		}
+	}
+	return allocChunk(size), false
+}
+
+// Mask slices of stage 1 that stage 2 is done with
+var masksPool sync.Pool
+
+type maskBuffers struct {
+	masks, postProc []uint64
+}
+
+// takeMasks returns a slice of length size for the masks of a chunk, along
+// with an empty slice of capacity postProcSize for the offsets of the blocks
+// to post-process, reusing pooled slices when available
+func takeMasks(size, postProcSize int) (masks, postProc []uint64) {
+	if p, ok := masksPool.Get().(*maskBuffers); ok {
+		if cap(p.masks) >= size && cap(p.postProc) >= postProcSize {
+			return p.masks[:size], p.postProc[:0]
+		}
+	}
+	return make([]uint64, size), make([]uint64, 0, postProcSize)
+}
+
+// putMasks hands the masks of a chunk back for reuse, once stage 2 is done
+// with them
+func putMasks(masks, postProc []uint64) {
+	if cap(masks) > 0 {
+		masksPool.Put(&maskBuffers{masks[:cap(masks)], postProc})
+	}
+}
+
+// putChunk hands a chunk buffer back for reuse.
+//
+// NB Since records point directly into the chunk buffers, only buffers that
+// are guaranteed to be unreferenced may be released.
+func putChunk(buf []byte) {
+	if cap(buf) < chunkAlign {
+		return
+	}
+	buf = buf[:cap(buf)-chunkAlign]
+	chunkPool.Put(&buf)
+}
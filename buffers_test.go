@@ -0,0 +1,40 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestChunkAlignment(t *testing.T) {
+	for _, size := range []int{1, 63, 64, 1000, 320000} {
+		for i := 0; i < 4; i++ {
+			buf := getChunk(size)
+			if len(buf) != size {
+				t.Errorf("TestChunkAlignment: got: %d want: %d", len(buf), size)
+			}
+			if cap(buf)-len(buf) < chunkAlign {
+				t.Errorf("TestChunkAlignment: insufficient padding: %d", cap(buf)-len(buf))
+			}
+			if uintptr(unsafe.Pointer(&buf[0]))&(chunkAlign-1) != 0 {
+				t.Errorf("TestChunkAlignment: buffer not aligned: %p", &buf[0])
+			}
+			putChunk(buf)
+		}
+	}
+}
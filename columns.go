@@ -0,0 +1,176 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// A ColumnType selects the conversion of a column (see StreamColumns)
+type ColumnType int
+
+const (
+	StringColumn ColumnType = iota
+	Int64Column
+	Float64Column
+	BoolColumn
+)
+
+// A Column holds the fields of a column of a Batch, of which only the slice
+// of its Type is set
+type Column struct {
+	Type    ColumnType
+	Strings []string
+	Ints    []int64
+	Floats  []float64
+	Bools   []bool
+}
+
+// A Batch holds a block of records by column
+type Batch struct {
+	Columns []Column
+	Lines   []int // line on which every record starts
+}
+
+// Len returns the number of records in b
+func (b *Batch) Len() int {
+	return len(b.Lines)
+}
+
+// StreamColumns converts all remaining records of r into Batches with a
+// Column of the given type for every element of types, and delivers them in
+// the order of the records, like Stream. Conversion is done by the parsing
+// workers a block at a time. Fields beyond the columns are ignored (see
+// SelectColumns), whereas missing and empty fields convert to the zero value.
+// If skipHeader is set, the first record is left out.
+func StreamColumns(r *Reader, types []ColumnType, skipHeader bool) (<-chan *Batch, <-chan error) {
+	r.Lock()
+	skipHeader = skipHeader && r.recordNumber == 0
+	r.Unlock()
+
+	decodeBlock := func(records [][]string, positions []recordPos) (interface{}, error) {
+		if skipHeader && len(positions) > 0 && positions[0].offset == r.dataOffset {
+			records, positions = records[1:], positions[1:]
+		}
+		batch, err := newBatch(types, records, positions)
+		if err != nil {
+			return nil, err
+		}
+		return []*Batch{batch}, nil
+	}
+	return streamBlocks[*Batch](r, decodeBlock)
+}
+
+// newBatch converts records into columns of the given types
+func newBatch(types []ColumnType, records [][]string, positions []recordPos) (*Batch, error) {
+	b := &Batch{Columns: make([]Column, len(types)), Lines: make([]int, len(records))}
+	for i := range records {
+		b.Lines[i] = positions[i].line
+	}
+	for c, typ := range types {
+		col := &b.Columns[c]
+		col.Type = typ
+		var err error
+		switch typ {
+		case StringColumn:
+			col.Strings = make([]string, len(records))
+			for i, record := range records {
+				if c < len(record) {
+					col.Strings[i] = record[c]
+				}
+			}
+		case Int64Column:
+			col.Ints = make([]int64, len(records))
+			for i, record := range records {
+				if c < len(record) && record[c] != "" {
+					if col.Ints[i], err = parseInt64(record[c]); err != nil {
+						return nil, fmt.Errorf("record on line %d: field %d: %w", b.Lines[i], c+1, err)
+					}
+				}
+			}
+		case Float64Column:
+			col.Floats = make([]float64, len(records))
+			for i, record := range records {
+				if c < len(record) && record[c] != "" {
+					if col.Floats[i], err = strconv.ParseFloat(record[c], 64); err != nil {
+						return nil, fmt.Errorf("record on line %d: field %d: %w", b.Lines[i], c+1, err)
+					}
+				}
+			}
+		case BoolColumn:
+			col.Bools = make([]bool, len(records))
+			for i, record := range records {
+				if c < len(record) && record[c] != "" {
+					if col.Bools[i], err = strconv.ParseBool(record[c]); err != nil {
+						return nil, fmt.Errorf("record on line %d: field %d: %w", b.Lines[i], c+1, err)
+					}
+				}
+			}
+		default:
+			return nil, fmt.Errorf("simdcsv: invalid column type %d", typ)
+		}
+	}
+	return b, nil
+}
+
+// parseInt64 parses a decimal integer like strconv.ParseInt, converting eight
+// digits at a time for numbers of up to 18 digits, which cannot overflow
+func parseInt64(s string) (int64, error) {
+	digits := s
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 || len(digits) > 18 {
+		return strconv.ParseInt(s, 10, 64)
+	}
+
+	n := uint64(0)
+	for ; len(digits) >= 8; digits = digits[8:] {
+		chunk := binary.LittleEndian.Uint64(stringBytes(digits[:8]))
+		if !eightDigits(chunk) {
+			return strconv.ParseInt(s, 10, 64)
+		}
+		n = n*100000000 + parseEightDigits(chunk)
+	}
+	for i := 0; i < len(digits); i++ {
+		d := digits[i] - '0'
+		if d > 9 {
+			return strconv.ParseInt(s, 10, 64)
+		}
+		n = n*10 + uint64(d)
+	}
+	if s[0] == '-' {
+		return -int64(n), nil
+	}
+	return int64(n), nil
+}
+
+// eightDigits reports whether all bytes of chunk are ASCII digits
+func eightDigits(chunk uint64) bool {
+	return (chunk&0xf0f0f0f0f0f0f0f0)|((chunk+0x0606060606060606)&0xf0f0f0f0f0f0f0f0)>>4 == 0x3333333333333333
+}
+
+// parseEightDigits converts the eight ASCII digits of chunk, with the most
+// significant digit in the lowest byte, by combining pairs of digits, then
+// pairs of pairs and finally the two halves
+func parseEightDigits(chunk uint64) uint64 {
+	chunk = (chunk & 0x0f0f0f0f0f0f0f0f) * 2561 >> 8
+	chunk = (chunk & 0x00ff00ff00ff00ff) * 6553601 >> 16
+	return (chunk & 0x0000ffff0000ffff) * 42949672960001 >> 32
+}
@@ -0,0 +1,75 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseInt64(t *testing.T) {
+	for _, s := range []string{"0", "7", "-7", "+12", "12345678", "-123456789", "123456789012345678", "-999999999999999999",
+		"9223372036854775807", "-9223372036854775808", "9223372036854775808", "", "-", "1234567a", "12345678a", "1 2", "０"} {
+		want, wantErr := strconv.ParseInt(s, 10, 64)
+		got, err := parseInt64(s)
+		if got != want || (err == nil) != (wantErr == nil) {
+			t.Errorf("TestParseInt64(%q): got: %d, %v want: %d, %v", s, got, err, want, wantErr)
+		}
+	}
+}
+
+func TestStreamColumns(t *testing.T) {
+	var input bytes.Buffer
+	input.WriteString("id,price,name,active\n")
+	const total = 100000
+	for i := 0; i < total; i++ {
+		fmt.Fprintf(&input, "%d,%d.25,\"name %d\",%t\n", int64(i)*1000003-50000000000, i, i, i%2 == 0)
+	}
+
+	types := []ColumnType{Int64Column, Float64Column, StringColumn, BoolColumn}
+	for _, threshold := range []int{-1, 0} {
+		r := NewReader(bytes.NewReader(input.Bytes()))
+		r.FallbackThreshold = threshold
+		batches, errs := StreamColumns(r, types, true)
+
+		i := 0
+		for b := range batches {
+			for j := 0; j < b.Len(); j, i = j+1, i+1 {
+				if b.Columns[0].Ints[j] != int64(i)*1000003-50000000000 || b.Columns[1].Floats[j] != float64(i)+0.25 ||
+					b.Columns[2].Strings[j] != fmt.Sprint("name ", i) || b.Columns[3].Bools[j] != (i%2 == 0) || b.Lines[j] != i+2 {
+					t.Fatalf("TestStreamColumns: got: record %d of batch wrong want: record %d", j, i)
+				}
+			}
+		}
+		if err := <-errs; err != nil || i != total {
+			t.Errorf("TestStreamColumns: got: %d records, %v want: %d", i, err, total)
+		}
+	}
+
+	// without skipping the header, its conversion fails
+	r := NewReader(strings.NewReader("id\n1\n"))
+	batches, errs := StreamColumns(r, []ColumnType{Int64Column}, false)
+	for range batches {
+		t.Errorf("TestStreamColumns: unexpected batch")
+	}
+	if err := <-errs; err == nil || !strings.HasPrefix(err.Error(), "record on line 1: field 1:") {
+		t.Errorf("TestStreamColumns: got: %v want: error on line 1", err)
+	}
+}
@@ -0,0 +1,331 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// validCommentPrefix reports whether the CommentPrefix of r can be told
+// apart from records
+func (r *Reader) validCommentPrefix() bool {
+	p := r.CommentPrefix
+	return !strings.ContainsAny(p, "\r\n") && !strings.HasPrefix(p, `"`) && !strings.HasPrefix(p, r.delimiter())
+}
+
+// commentPrefixes returns the prefixes of comment lines, as set by Comment
+// and CommentPrefix
+func (r *Reader) commentPrefixes() (prefixes []string) {
+	if r.Comment != 0 {
+		prefixes = append(prefixes, string(r.Comment))
+	}
+	if r.CommentPrefix != "" {
+		prefixes = append(prefixes, r.CommentPrefix)
+	}
+	return
+}
+
+// commentFilter returns in with the lines that start with CommentPrefix
+// blanked, so encoding/csv skips them while keeping count of the lines
+func (r *Reader) commentFilter(in io.Reader) io.Reader {
+	if r.CommentPrefix == "" {
+		return in
+	}
+	return &commentFilter{in: in, prefix: r.CommentPrefix, lineStart: true}
+}
+
+// commentFilter drops the contents of comment lines, tracking quotes so that
+// newlines within quoted fields do not start a line
+type commentFilter struct {
+	in     io.Reader
+	prefix string
+
+	lineStart bool   // whether the next byte starts a line
+	matched   int    // number of bytes of the line matching prefix so far
+	comment   bool   // whether within a comment line
+	quoted    bool   // whether within a quoted field
+	pending   []byte // bytes that are yet to be returned
+	buf       []byte
+
+	passed  int64         // number of bytes returned (or pending) so far
+	removed int64         // number of bytes removed so far
+	shifts  []offsetShift // offsets at which bytes were removed
+}
+
+// offsetShift records the number of bytes removed from the input preceding
+// the filtered offset at
+type offsetShift struct {
+	at, removed int64
+}
+
+func (f *commentFilter) Read(p []byte) (n int, err error) {
+	for len(f.pending) == 0 && err == nil {
+		if cap(f.buf) < len(p) {
+			f.buf = make([]byte, len(p))
+		}
+		var m int
+		m, err = f.in.Read(f.buf[:len(p)])
+		f.filter(f.buf[:m])
+	}
+	n = copy(p, f.pending)
+	f.pending = f.pending[n:]
+	if len(f.pending) > 0 {
+		err = nil // report the error once the pending bytes are read
+	}
+	if err == io.EOF && f.matched > 0 {
+		// input ends within what might have become a comment
+		f.flush()
+		err = nil
+	}
+	return
+}
+
+func (f *commentFilter) filter(buf []byte) {
+	f.pending = f.pending[:0]
+	for _, c := range buf {
+		switch {
+		case f.comment:
+			if c == '\n' {
+				f.pending = append(f.pending, c)
+				f.passed++
+				f.comment, f.lineStart = false, true
+			} else {
+				f.remove(1)
+			}
+		case f.lineStart || f.matched > 0:
+			if c == f.prefix[f.matched] {
+				if f.matched++; f.matched == len(f.prefix) {
+					f.comment, f.matched = true, 0
+					f.remove(len(f.prefix))
+				}
+				f.lineStart = false
+				continue
+			}
+			f.lineStart = false
+			f.flush()
+			f.pass(c)
+		default:
+			f.pass(c)
+		}
+	}
+}
+
+// flush returns the bytes that turned out not to start a comment
+func (f *commentFilter) flush() {
+	matched := f.matched
+	f.matched = 0
+	for i := 0; i < matched; i++ {
+		f.pass(f.prefix[i])
+	}
+}
+
+// pass returns a byte that is not part of a comment
+func (f *commentFilter) pass(c byte) {
+	f.pending = append(f.pending, c)
+	f.passed++
+	if c == '"' {
+		f.quoted = !f.quoted
+	} else if c == '\n' && !f.quoted {
+		f.lineStart = true
+	}
+}
+
+// remove accounts for n bytes of a comment being removed
+func (f *commentFilter) remove(n int) {
+	f.removed += int64(n)
+	if last := len(f.shifts) - 1; last >= 0 && f.shifts[last].at == f.passed {
+		f.shifts[last].removed = f.removed
+	} else {
+		f.shifts = append(f.shifts, offsetShift{f.passed, f.removed})
+	}
+}
+
+// inputOffset maps an offset of the filtered input to an offset of the
+// input, which requires offsets to be mapped in increasing order
+func (f *commentFilter) inputOffset(offset int64) int64 {
+	if f == nil {
+		return offset
+	}
+	i := 0
+	for i < len(f.shifts) && f.shifts[i].at <= offset {
+		i++
+	}
+	if i == 0 {
+		return offset
+	}
+	// earlier shifts are no longer needed
+	f.shifts = f.shifts[i-1:]
+	return offset + f.shifts[0].removed
+}
+
+// commentQuotes keeps the quotes of comment lines from toggling the quoted
+// state of stage 1, which processes a copy of the chunk with these quotes
+// blanked. The rows of comment lines are dropped after stage 2 (see
+// filterOutComments).
+type commentQuotes struct {
+	prefixes []string
+	longest  int
+
+	lineStart bool   // whether the next chunk starts a line
+	comment   bool   // whether the next chunk continues a comment line
+	partial   []byte // start of the line that may yet become a comment prefix
+	scratch   []byte // copy of the chunk processed by stage 1
+}
+
+// newCommentQuotes returns the commentQuotes for prefixes, or nil if there
+// are none
+func newCommentQuotes(prefixes []string) *commentQuotes {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	c := &commentQuotes{prefixes: prefixes, lineStart: true}
+	for _, p := range prefixes {
+		if len(p) > c.longest {
+			c.longest = len(p)
+		}
+	}
+	return c
+}
+
+// blank returns a copy of buf in which the quotes of comment lines are
+// replaced, or buf itself if there are none, given whether buf starts within
+// quotes. The copy is only valid until the next call.
+func (c *commentQuotes) blank(buf []byte, quoted bool) []byte {
+	out, i := buf, 0
+	if quoted {
+		c.lineStart, c.partial = false, c.partial[:0]
+	}
+	if !c.comment && bytes.IndexByte(buf, '"') < 0 {
+		// without quotes, only the last line can carry over
+		if quoted {
+			return buf
+		}
+		if j := bytes.LastIndexByte(buf, '\n'); j >= 0 {
+			i, c.lineStart, c.partial = j+1, true, c.partial[:0]
+		}
+	}
+
+	for i < len(buf) {
+		if c.comment {
+			end := bytes.IndexByte(buf[i:], '\n')
+			if end < 0 {
+				end = len(buf)
+			} else {
+				end += i
+				c.comment, c.lineStart = false, true
+			}
+			for j := bytes.IndexByte(buf[i:end], '"'); j >= 0; j = bytes.IndexByte(buf[i:end], '"') {
+				if len(out) > 0 && &out[0] == &buf[0] {
+					out = c.copy(buf)
+				}
+				out[i+j], i = escapedPlaceholder, i+j+1
+			}
+			i = end + 1
+			continue
+		}
+		if c.lineStart && !quoted {
+			n := c.longest - len(c.partial)
+			if n > len(buf)-i {
+				n = len(buf) - i
+			}
+			start := buf[i : i+n]
+			if len(c.partial) > 0 {
+				start = append(c.partial, start...)
+			}
+			c.lineStart, c.partial = false, c.partial[:0]
+			if hasCommentPrefix(start, c.prefixes) {
+				c.comment = true
+				continue
+			}
+			if i+n == len(buf) && partialCommentPrefix(start, c.prefixes) {
+				// the line continues in the next chunk
+				c.lineStart, c.partial = true, append(c.partial, start...)
+				break
+			}
+		}
+		j := bytes.IndexAny(buf[i:], "\"\n")
+		if j < 0 {
+			break
+		}
+		if i += j; buf[i] == '"' {
+			quoted = !quoted
+		} else if !quoted {
+			c.lineStart = true
+		}
+		i++
+	}
+	return out
+}
+
+// resync continues from the last line of a chunk as rescanned instead (see
+// lazyQuotes), which is nil if the chunk ends within quotes
+func (c *commentQuotes) resync(line []byte, comment bool) {
+	c.comment, c.lineStart, c.partial = comment, false, c.partial[:0]
+	switch {
+	case comment || line == nil:
+	case len(line) == 0:
+		c.lineStart = true
+	case partialCommentPrefix(line, c.prefixes):
+		c.lineStart, c.partial = true, append(c.partial, line...)
+	}
+}
+
+// copy returns buf as copied to the scratch buffer
+func (c *commentQuotes) copy(buf []byte) []byte {
+	if cap(c.scratch) < len(buf)+chunkAlign {
+		c.scratch = allocChunk(len(buf))
+	}
+	scratch := c.scratch[:len(buf)]
+	copy(scratch, buf)
+	return scratch
+}
+
+// hasCommentPrefix reports whether line starts with any of prefixes
+func hasCommentPrefix(line []byte, prefixes []string) bool {
+	for _, p := range prefixes {
+		if bytes.HasPrefix(line, stringBytes(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// partialCommentPrefix reports whether line is the start of any of prefixes,
+// so that it may yet become a comment line as it continues
+func partialCommentPrefix(line []byte, prefixes []string) bool {
+	for _, p := range prefixes {
+		if len(line) < len(p) && strings.HasPrefix(p, string(line)) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterOutComments removes the records that stem from comment lines
+// (see Comment and CommentPrefix), as marked by recordPositions
+func filterOutComments(records *[][]string, positions *[]recordPos) {
+	n := 0
+	for i, pos := range *positions {
+		if !pos.comment {
+			(*records)[n], (*positions)[n] = (*records)[i], pos
+			n++
+		}
+	}
+	*records, *positions = (*records)[:n], (*positions)[:n]
+}
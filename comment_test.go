@@ -0,0 +1,86 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCommentPrefix(t *testing.T) {
+	small := []byte("// title, with comma\na,b\n/x,1\n\"q\n// not a comment\",2\n//\r\n-- c,d\n//last")
+	smallWant := [][]string{{"a", "b"}, {"/x", "1"}, {"q\n// not a comment", "2"}, {"-- c", "d"}}
+	smallLines := []int{2, 3, 4, 7}
+
+	var big, bigData bytes.Buffer
+	var bigLines []int
+	for i := 0; big.Len() < 1<<20; i++ {
+		if i%5 == 0 {
+			fmt.Fprintf(&big, "// comment %d, with \"\"quotes\"\"\n", i)
+		}
+		fmt.Fprintf(&big, "%d,\"value \"\"%d\"\"\",/%d\n", i, i, i)
+		fmt.Fprintf(&bigData, "%d,\"value \"\"%d\"\"\",/%d\n", i, i, i)
+		bigLines = append(bigLines, i+i/5+2)
+	}
+	bigWant, err := encodingCsv(bigData.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input []byte
+		want  [][]string
+		lines []int
+	}{
+		{"small", small, smallWant, smallLines},
+		{"big", big.Bytes(), bigWant, bigLines},
+	} {
+		for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+			for _, inMemory := range []bool{false, true} {
+				r := NewReader(bytes.NewReader(tc.input))
+				if inMemory {
+					r = newBytesReader(tc.input)
+				}
+				r.kernel, r.CommentPrefix, r.FieldsPerRecord = kernel, "//", -1
+
+				var got [][]string
+				for {
+					record, err := r.ReadRecord()
+					if err != nil {
+						break
+					}
+					if record.Line != tc.lines[len(got)] {
+						t.Fatalf("TestCommentPrefix(%s): got: line %d want: %d", tc.name, record.Line, tc.lines[len(got)])
+					}
+					got = append(got, record.Fields)
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Fatalf("TestCommentPrefix(%s): got: %q want: %q", tc.name, got[:2], tc.want[:2])
+				}
+			}
+		}
+	}
+
+	r := NewReader(bytes.NewReader(small))
+	r.CommentPrefix = ",/"
+	if _, err := r.ReadAll(); err != errInvalidDelim {
+		t.Errorf("TestCommentPrefix: got: %v want: %v", err, errInvalidDelim)
+	}
+}
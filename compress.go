@@ -0,0 +1,82 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Magic bytes at the start of compressed input
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+)
+
+// NewReaderAuto returns a new Reader that reads from r, decompressing the
+// input if it starts with the magic bytes of gzip, zstd or bzip2. Other
+// input is read as is. Decompressed input does not support Rewind or
+// SeekRecord.
+//
+// Input that consists of independent blocks is decompressed in parallel,
+// which is the case for the BGZF blocks written by bgzip and for zstd
+// frames that declare a content size of up to 16 MiB, as written for
+// seekable zstd.
+func NewReaderAuto(r io.Reader) (*Reader, error) {
+	in, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(in), nil
+}
+
+// decompress returns r wrapped by the decompressor that its magic bytes
+// call for, if any
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(18) // long enough for the headers of blocks
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		if _, ok := bgzfBlockSize(magic); ok {
+			return newBGZFReader(br), nil
+		}
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		if size, ok := zstdFrameContentSize(magic); ok && size <= maxParallelFrame {
+			return newZstdFrameReader(br)
+		}
+		// decode synchronously, so that the decoder holds no goroutines
+		// that would need to be closed
+		d, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2.NewReader(br), nil
+	}
+	return br, nil
+}
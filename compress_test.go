@@ -0,0 +1,76 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNewReaderAuto(t *testing.T) {
+	var input bytes.Buffer
+	input.WriteString("id,name\n")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&input, "%d,\"name %d\"\n", i, i)
+	}
+	expected, err := encodingCsv(input.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(input.Bytes())
+	gw.Close()
+
+	zw, _ := zstd.NewWriter(nil)
+	zst := zw.EncodeAll(input.Bytes(), nil)
+
+	// compressed by bzip2 (for which there is no encoder in the standard library)
+	bz2, err := os.ReadFile("testdata/records.csv.bz2")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{{"plain", input.Bytes()}, {"gzip", gz.Bytes()}, {"zstd", zst}, {"bzip2", bz2}, {"short", []byte("a")}} {
+		want := expected
+		if tc.name == "short" {
+			want = [][]string{{"a"}}
+		}
+		r, err := NewReaderAuto(bytes.NewReader(tc.data))
+		if err != nil {
+			t.Fatalf("TestNewReaderAuto(%s): %v", tc.name, err)
+		}
+		records, err := r.ReadAll()
+		if err != nil || !reflect.DeepEqual(records, want) {
+			t.Errorf("TestNewReaderAuto(%s): got: %d records, %v want: %d", tc.name, len(records), err, len(want))
+		}
+	}
+
+	if _, err := NewReaderAuto(bytes.NewReader(gz.Bytes()[:5])); err == nil {
+		t.Errorf("TestNewReaderAuto: got: nil want: error for a truncated gzip header")
+	}
+}
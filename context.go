@@ -0,0 +1,108 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import "context"
+
+// ReadContext is like Read, but gives up once ctx is done, in which case it
+// tears down the parsing stages and returns the error of ctx (as does every
+// subsequent call). A read from the underlying io.Reader that is in progress
+// is not interrupted, though its result is discarded.
+func (r *Reader) ReadContext(ctx context.Context) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.cancelled(ctx); err != nil {
+		return nil, err
+	}
+	defer r.watch(ctx)()
+
+	err := r.next()
+	if err := r.cancelled(ctx); err != nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.records[r.currrecord-1], nil
+}
+
+// ReadAllContext is like ReadAll, but gives up once ctx is done, as
+// described for ReadContext.
+func (r *Reader) ReadAllContext(ctx context.Context) ([][]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.cancelled(ctx); err != nil {
+		return nil, err
+	}
+	defer r.watch(ctx)()
+
+	records, err := r.readAll(ctx)
+	if err := r.cancelled(ctx); err != nil {
+		return nil, err
+	}
+	return records, err
+}
+
+// watch starts the parsing stages, unless these have been started before,
+// and tears them down as soon as ctx is done, until the returned function
+// is called.
+func (r *Reader) watch(ctx context.Context) (unwatch func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	if r.simd() && r.slots == nil && r.readErr == nil {
+		// obtain the first chunk on a goroutine of its own, so a read that
+		// blocks can be abandoned
+		if r.slots = r.startStreaming(); r.slots != nil {
+			go r.streamRecords(r.slots)
+		}
+	}
+
+	slots, done := r.slots, make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			if slots != nil {
+				// release the workers, and the consumer waiting on them
+				slots.stop()
+				slots.close()
+			}
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// cancelled ends reading with the error of ctx once it is done
+func (r *Reader) cancelled(ctx context.Context) error {
+	err := ctx.Err()
+	if err != nil {
+		r.abort(err)
+	}
+	return err
+}
+
+// abort tears down the parsing stages, if any, and ends reading with err
+func (r *Reader) abort(err error) {
+	if r.slots != nil {
+		r.slots.stop()
+		r.slots.close()
+	}
+	r.records, r.positions, r.currrecord, r.readErr = nil, nil, 0, err
+}
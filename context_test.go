@@ -0,0 +1,95 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitGoroutines waits for the number of goroutines to drop to n
+func waitGoroutines(t *testing.T, n int) {
+	for start := time.Now(); runtime.NumGoroutine() > n; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("waitGoroutines: %d goroutines remain, want %d", runtime.NumGoroutine(), n)
+		}
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	input := selfTestCorpus()
+	big := input[len(input)-1]
+	want, err := encodingCsv(big, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		baseline := runtime.NumGoroutine()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		r := NewReader(bytes.NewReader(big))
+		r.kernel = kernel
+		for i := 0; i < 10; i++ {
+			record, err := r.ReadContext(ctx)
+			if err != nil || !reflect.DeepEqual(record, want[i]) {
+				t.Fatalf("TestReadContext: got: %q, %v want: %q", record, err, want[i])
+			}
+		}
+		cancel()
+		for i := 0; i < 2; i++ {
+			if _, err := r.ReadContext(ctx); err != context.Canceled {
+				t.Errorf("TestReadContext: got: %v want: %v", err, context.Canceled)
+			}
+		}
+		if _, err := r.Read(); err != context.Canceled {
+			t.Errorf("TestReadContext: got: %v want: %v", err, context.Canceled)
+		}
+		waitGoroutines(t, baseline)
+
+		records, err := NewReader(bytes.NewReader(big)).ReadAllContext(context.Background())
+		if err != nil || !reflect.DeepEqual(records, want) {
+			t.Errorf("TestReadContext: got: %d records, %v want: %d", len(records), err, len(want))
+		}
+		if _, err := NewReader(bytes.NewReader(big)).ReadAllContext(ctx); err != context.Canceled {
+			t.Errorf("TestReadContext: got: %v want: %v", err, context.Canceled)
+		}
+	}
+}
+
+func TestReadContextBlocked(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	pr, pw := io.Pipe()
+	go pw.Write([]byte("a,b\nc,d\n"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r := NewReader(pr)
+	r.FallbackThreshold = -1
+	if records, err := r.ReadAllContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("TestReadContextBlocked: got: %q, %v want: %v", records, err, context.DeadlineExceeded)
+	}
+
+	pw.Close() // unblocks the pending read
+	waitGoroutines(t, baseline)
+}
@@ -0,0 +1,128 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package convert
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/minio/simdcsv"
+)
+
+// ToJSONLines converts the records of r, the first of which is the header,
+// into JSON Lines written to w: a JSON object for every record, with the
+// names in the header as keys, in order. Fields beyond the header are left
+// out. The objects are marshaled by the parsing workers (see
+// simdcsv.StreamHeader).
+func ToJSONLines(w io.Writer, r *simdcsv.Reader) error {
+	var once sync.Once
+	var keys [][]byte // quoted names of the header, followed by a colon
+
+	lines, errs := simdcsv.StreamHeader(r, func(header, record []string) ([]byte, error) {
+		once.Do(func() {
+			for _, name := range header {
+				keys = append(keys, append(appendJSONString(nil, name), ':'))
+			}
+		})
+		size := 3
+		for i, field := range record {
+			if i < len(keys) {
+				size += len(keys[i]) + len(field) + 3
+			}
+		}
+		line := append(make([]byte, 0, size), '{')
+		for i, field := range record {
+			if i >= len(keys) {
+				break
+			}
+			if i > 0 {
+				line = append(line, ',')
+			}
+			line = append(line, keys[i]...)
+			line = appendJSONString(line, field)
+		}
+		return append(line, '}', '\n'), nil
+	})
+
+	bw := bufio.NewWriterSize(w, 1<<16)
+	var err error
+	for line := range lines {
+		if err == nil {
+			_, err = bw.Write(line)
+		}
+	}
+	if parseErr := <-errs; parseErr != nil {
+		return parseErr
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString appends s as a JSON string, escaping it like
+// encoding/json, except for HTML characters
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		rn, size := utf8.DecodeRuneInString(s[i:])
+		if rn == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if rn == '\u2028' || rn == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[rn&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
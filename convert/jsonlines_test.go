@@ -0,0 +1,85 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/minio/simdcsv"
+)
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"", "plain", `q"uote\`, "new\nline\r\t", "\x01\x1f", "bad\xffutf8", "sep ara tor", "ünïcode"} {
+		want, _ := json.Marshal(s)
+		if got := appendJSONString(nil, s); !bytes.Equal(got, want) {
+			t.Errorf("TestAppendJSONString: got: %s want: %s", got, want)
+		}
+	}
+}
+
+func TestToJSONLines(t *testing.T) {
+	var input bytes.Buffer
+	input.WriteString("id,\"na\"\"me\",note\n")
+	const total = 100000
+	for i := 0; i < total; i++ {
+		fmt.Fprintf(&input, "%d,\"name \"\"%d\"\"\",\"line\tbreak\\%d\"\n", i, i, i)
+	}
+
+	var out bytes.Buffer
+	if err := ToJSONLines(&out, simdcsv.NewReader(bytes.NewReader(input.Bytes()))); err != nil {
+		t.Fatalf("TestToJSONLines: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != total {
+		t.Fatalf("TestToJSONLines: got: %d lines want: %d", len(lines), total)
+	}
+	for i, line := range lines {
+		want := fmt.Sprintf(`{"id":"%d","na\"me":"name \"%d\"","note":"line\tbreak\\%d"}`, i, i, i)
+		if line != want {
+			t.Fatalf("TestToJSONLines: got: %s want: %s", line, want)
+		}
+	}
+}
+
+func TestToJSONLinesQuotedNewlines(t *testing.T) {
+	// rows with quoted newlines, which chunks are bound to start within
+	var input bytes.Buffer
+	input.WriteString("id,note\n")
+	const total = 100000
+	for i := 0; i < total; i++ {
+		fmt.Fprintf(&input, "%d,\"line\nbreak\\%d\"\n", i, i)
+	}
+
+	var out bytes.Buffer
+	if err := ToJSONLines(&out, simdcsv.NewReader(bytes.NewReader(input.Bytes()))); err != nil {
+		t.Fatalf("TestToJSONLinesQuotedNewlines: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != total {
+		t.Fatalf("TestToJSONLinesQuotedNewlines: got: %d lines want: %d", len(lines), total)
+	}
+	for i, line := range lines {
+		want := fmt.Sprintf(`{"id":"%d","note":"line\nbreak\\%d"}`, i, i)
+		if line != want {
+			t.Fatalf("TestToJSONLinesQuotedNewlines: got: %s want: %s", line, want)
+		}
+	}
+}
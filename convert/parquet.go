@@ -0,0 +1,322 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package convert converts CSV into other formats, keeping the conversion
+// within the parallel parsing of simdcsv.
+package convert
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+
+	"github.com/minio/simdcsv"
+)
+
+// A Field is a column of a Parquet file
+type Field struct {
+	Name string
+	Type simdcsv.ColumnType
+}
+
+// A Schema lists the columns of a Parquet file, which are the columns of the
+// records in order (see simdcsv.Reader.SelectColumns)
+type Schema []Field
+
+// inferSample is the number of records that types are inferred from
+const inferSample = 1000
+
+var errNoHeader = errors.New("convert: no header")
+
+// InferSchema infers a Schema from the header and a sample of records, where
+// columns of integers become Int64Column, of numbers Float64Column, of
+// booleans BoolColumn and of anything else StringColumn. Empty fields fit
+// any type.
+func InferSchema(header []string, sample [][]string) Schema {
+	schema := make(Schema, len(header))
+	for c, name := range header {
+		ints, floats, bools := true, true, true
+		for _, record := range sample {
+			if c >= len(record) || record[c] == "" {
+				continue
+			}
+			field := record[c]
+			if _, err := strconv.ParseInt(field, 10, 64); err != nil {
+				ints = false
+			}
+			if _, err := strconv.ParseFloat(field, 64); err != nil {
+				floats = false
+			}
+			if _, err := strconv.ParseBool(field); err != nil {
+				bools = false
+			}
+		}
+		typ := simdcsv.StringColumn
+		switch {
+		case ints:
+			typ = simdcsv.Int64Column
+		case floats:
+			typ = simdcsv.Float64Column
+		case bools:
+			typ = simdcsv.BoolColumn
+		}
+		schema[c] = Field{name, typ}
+	}
+	return schema
+}
+
+// ToParquet converts the records of r, the first of which is the header,
+// into a Parquet file written to w, with a row group for every block of
+// records. The fields are converted by the parsing workers (see
+// simdcsv.StreamColumns), and the row groups are encoded in parallel as
+// well. If schema is nil, it is inferred from the header and the records
+// that follow (see InferSchema), which are peeked at; that starts parsing,
+// so the conversion of the fields falls to the consumer instead.
+//
+// Columns are required and encoded plainly, without compression.
+func ToParquet(w io.Writer, r *simdcsv.Reader, schema Schema) error {
+	if schema == nil {
+		records, err := r.Peek(inferSample + 1)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(records) == 0 {
+			return errNoHeader
+		}
+		schema = InferSchema(records[0], records[1:])
+	}
+	types := make([]simdcsv.ColumnType, len(schema))
+	for c, field := range schema {
+		types[c] = field.Type
+	}
+	batches, errs := simdcsv.StreamColumns(r, types, true)
+
+	// row groups are encoded concurrently, but written in order
+	workers := runtime.GOMAXPROCS(0)
+	encoded := make(chan *rowGroup, workers)
+	go func() {
+		defer close(encoded)
+		sem := make(chan struct{}, workers)
+		for b := range batches {
+			rg := &rowGroup{rows: b.Len(), done: make(chan struct{})}
+			sem <- struct{}{}
+			go func(b *simdcsv.Batch) {
+				rg.encode(b)
+				close(rg.done)
+				<-sem
+			}(b)
+			encoded <- rg
+		}
+	}()
+
+	pw := &parquetWriter{w: w}
+	pw.write([]byte(parquetMagic))
+	for rg := range encoded {
+		<-rg.done
+		pw.writeRowGroup(rg, schema)
+	}
+	if err := <-errs; err != nil {
+		return err
+	}
+	pw.writeFooter(schema)
+	return pw.err
+}
+
+const parquetMagic = "PAR1"
+
+// Parquet enums
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	convertedUTF8  = 0
+	repetitionReq  = 0
+	pageTypeData   = 0
+	codecNone      = 0
+	parquetVersion = 1
+)
+
+// physicalType returns the Parquet type that columns of type typ are stored as
+func physicalType(typ simdcsv.ColumnType) int32 {
+	switch typ {
+	case simdcsv.Int64Column:
+		return typeInt64
+	case simdcsv.Float64Column:
+		return typeDouble
+	case simdcsv.BoolColumn:
+		return typeBoolean
+	}
+	return typeByteArray
+}
+
+// rowGroup holds the column chunks of a block of records, each consisting of
+// a single data page
+type rowGroup struct {
+	rows   int
+	pages  [][]byte // header and data of the page of every column
+	header []int    // size of the header of every page
+	done   chan struct{}
+}
+
+// encode encodes the columns of b as plain data pages
+func (rg *rowGroup) encode(b *simdcsv.Batch) {
+	for _, col := range b.Columns {
+		var data []byte
+		switch col.Type {
+		case simdcsv.Int64Column:
+			data = make([]byte, 0, 8*len(col.Ints))
+			for _, v := range col.Ints {
+				data = binary.LittleEndian.AppendUint64(data, uint64(v))
+			}
+		case simdcsv.Float64Column:
+			data = make([]byte, 0, 8*len(col.Floats))
+			for _, v := range col.Floats {
+				data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+			}
+		case simdcsv.BoolColumn:
+			data = make([]byte, (len(col.Bools)+7)/8)
+			for i, v := range col.Bools {
+				if v {
+					data[i/8] |= 1 << (i % 8)
+				}
+			}
+		default:
+			size := 0
+			for _, s := range col.Strings {
+				size += 4 + len(s)
+			}
+			data = make([]byte, 0, size)
+			for _, s := range col.Strings {
+				data = binary.LittleEndian.AppendUint32(data, uint32(len(s)))
+				data = append(data, s...)
+			}
+		}
+
+		var h compactWriter
+		h.beginStruct(0) // PageHeader
+		h.i32(1, pageTypeData)
+		h.i32(2, int32(len(data)))
+		h.i32(3, int32(len(data)))
+		h.beginStruct(5) // DataPageHeader
+		h.i32(1, int32(rg.rows))
+		h.i32(2, encodingPlain)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.endStruct()
+		h.endStruct()
+
+		rg.header = append(rg.header, len(h.buf))
+		rg.pages = append(rg.pages, append(h.buf, data...))
+	}
+}
+
+// parquetWriter writes a Parquet file, keeping track of the offsets of the
+// column chunks for the footer
+type parquetWriter struct {
+	w      io.Writer
+	offset int64
+	err    error
+
+	rows   int64
+	groups compactWriter // list of RowGroup
+	count  int           // number of row groups
+}
+
+func (pw *parquetWriter) write(b []byte) {
+	if pw.err != nil {
+		return
+	}
+	var n int
+	n, pw.err = pw.w.Write(b)
+	pw.offset += int64(n)
+}
+
+// writeRowGroup writes the pages of rg and adds its RowGroup to the footer
+func (pw *parquetWriter) writeRowGroup(rg *rowGroup, schema Schema) {
+	if rg.rows == 0 {
+		return
+	}
+	g := &pw.groups
+	g.beginStruct(0) // RowGroup
+	g.list(1, compactStruct, len(rg.pages))
+	size := int64(0)
+	for c, page := range rg.pages {
+		offset := pw.offset
+		pw.write(page)
+		size += int64(len(page))
+
+		g.beginStruct(0) // ColumnChunk
+		g.i64(2, offset)
+		g.beginStruct(3) // ColumnMetaData
+		g.i32(1, physicalType(schema[c].Type))
+		g.list(2, compactI32, 2)
+		g.listI32(encodingPlain)
+		g.listI32(encodingRLE)
+		g.list(3, compactBinary, 1)
+		g.listString(schema[c].Name)
+		g.i32(4, codecNone)
+		g.i64(5, int64(rg.rows))
+		g.i64(6, int64(len(page)))
+		g.i64(7, int64(len(page)))
+		g.i64(9, offset)
+		g.endStruct()
+		g.endStruct()
+	}
+	g.i64(2, size)
+	g.i64(3, int64(rg.rows))
+	g.endStruct()
+	pw.rows += int64(rg.rows)
+	pw.count++
+}
+
+// writeFooter writes the FileMetaData
+func (pw *parquetWriter) writeFooter(schema Schema) {
+	var m compactWriter
+	m.beginStruct(0) // FileMetaData
+	m.i32(1, parquetVersion)
+	m.list(2, compactStruct, len(schema)+1)
+	m.beginStruct(0) // root SchemaElement
+	m.string(4, "schema")
+	m.i32(5, int32(len(schema)))
+	m.endStruct()
+	for _, field := range schema {
+		m.beginStruct(0) // SchemaElement
+		m.i32(1, physicalType(field.Type))
+		m.i32(3, repetitionReq)
+		m.string(4, field.Name)
+		if field.Type == simdcsv.StringColumn {
+			m.i32(6, convertedUTF8)
+		}
+		m.endStruct()
+	}
+	m.i64(3, pw.rows)
+	m.list(4, compactStruct, pw.count)
+	m.buf = append(m.buf, pw.groups.buf...)
+	m.string(6, "simdcsv")
+	m.endStruct()
+
+	pw.write(m.buf)
+	pw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(m.buf))))
+	pw.write([]byte(parquetMagic))
+}
@@ -0,0 +1,140 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package convert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/minio/simdcsv"
+)
+
+// compactReader decodes Thrift structs of the compact protocol into maps by
+// field id, as far as needed for verifying the metadata
+type compactReader struct {
+	buf []byte
+}
+
+func (r *compactReader) varint() uint64 {
+	u, n := binary.Uvarint(r.buf)
+	r.buf = r.buf[n:]
+	return u
+}
+
+func (r *compactReader) zigzag() int64 {
+	u := r.varint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *compactReader) value(typ byte) interface{} {
+	switch typ {
+	case compactI32, compactI64:
+		return r.zigzag()
+	case compactBinary:
+		n := r.varint()
+		s := string(r.buf[:n])
+		r.buf = r.buf[n:]
+		return s
+	case compactList:
+		h := r.buf[0]
+		r.buf = r.buf[1:]
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.varint())
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = r.value(h & 0xf)
+		}
+		return list
+	case compactStruct:
+		return r.structure()
+	}
+	panic(fmt.Sprintf("unexpected type %d", typ))
+}
+
+func (r *compactReader) structure() map[int16]interface{} {
+	fields, id := map[int16]interface{}{}, int16(0)
+	for {
+		h := r.buf[0]
+		r.buf = r.buf[1:]
+		if h == 0 {
+			return fields
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(h & 0xf)
+	}
+}
+
+func TestToParquet(t *testing.T) {
+	var input bytes.Buffer
+	input.WriteString("id,price,name,active\n")
+	const total = 200000
+	for i := 0; i < total; i++ {
+		fmt.Fprintf(&input, "%d,%d.5,\"name, %d\",%t\n", i-100, i, i, i%3 == 0)
+	}
+
+	for _, schema := range []Schema{nil, {{"id", simdcsv.Int64Column}, {"price", simdcsv.StringColumn}}} {
+		var out bytes.Buffer
+		if err := ToParquet(&out, simdcsv.NewReader(bytes.NewReader(input.Bytes())), schema); err != nil {
+			t.Fatalf("TestToParquet: %v", err)
+		}
+		file := out.Bytes()
+		if !bytes.HasPrefix(file, []byte(parquetMagic)) || !bytes.HasSuffix(file, []byte(parquetMagic)) {
+			t.Fatalf("TestToParquet: got: no magic bytes want: %q", parquetMagic)
+		}
+		size := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+		meta := (&compactReader{file[len(file)-8-size : len(file)-8]}).structure()
+
+		wantSchema := schema
+		if schema == nil {
+			wantSchema = Schema{{"id", simdcsv.Int64Column}, {"price", simdcsv.Float64Column}, {"name", simdcsv.StringColumn}, {"active", simdcsv.BoolColumn}}
+		}
+		var names []string
+		for _, element := range meta[2].([]interface{})[1:] {
+			names = append(names, element.(map[int16]interface{})[4].(string))
+		}
+		if want := []string{"id", "price", "name", "active"}[:len(wantSchema)]; meta[3] != int64(total) || !reflect.DeepEqual(names, want) {
+			t.Fatalf("TestToParquet: got: %v rows of %q want: %d rows of %q", meta[3], names, total, want)
+		}
+
+		// the column of ids holds every id in order
+		id := int64(-100)
+		for _, group := range meta[4].([]interface{}) {
+			chunk := group.(map[int16]interface{})[1].([]interface{})[0].(map[int16]interface{})
+			offset := chunk[3].(map[int16]interface{})[9].(int64)
+			page := &compactReader{file[offset:]}
+			header := page.structure()
+			data := page.buf[:header[3].(int64)]
+			for ; len(data) > 0; data, id = data[8:], id+1 {
+				if v := int64(binary.LittleEndian.Uint64(data)); v != id {
+					t.Fatalf("TestToParquet: got: %d want: %d", v, id)
+				}
+			}
+		}
+		if id != total-100 {
+			t.Errorf("TestToParquet: got: %d ids want: %d", id+100, total)
+		}
+	}
+}
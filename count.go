@@ -0,0 +1,92 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"errors"
+	"io"
+	"math/bits"
+)
+
+var errStrayQuote = errors.New("simdcsv: quote that neither opens nor closes a field")
+
+// CountRecords returns the number of records of the input, as ReadAll would
+// return them with the default settings, for quickly sizing up huge inputs.
+// Only stage 1 runs, off whose masks the rows are counted, so no fields are
+// parsed; in particular, their number is not checked. Input with stray
+// quotes, which ReadAll would reject, is rejected likewise.
+func CountRecords(r io.Reader) (int, error) {
+	rd := NewReader(r)
+	if !rd.simd() {
+		count := 0
+		for {
+			if _, err := rd.Read(); err == io.EOF {
+				return count, nil
+			} else if err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+
+	chunk := allocChunk(defaultChunkSize)
+	masks := make([]uint64, ((defaultChunkSize>>6)+2)*3)
+	postProc := make([]uint64, 0, ((defaultChunkSize>>6)+1)*2)
+	lazy := newLazyQuotes(",", 0)
+
+	count := 0
+	quoted := uint64(0)
+	ended := uint64(1) // whether the last byte so far ended a row, as at the start
+	for {
+		n, err := io.ReadFull(r, chunk)
+		if err == io.EOF {
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return count, err
+		}
+		buf, last := chunk[:n], err != nil
+
+		postProc = postProc[:0]
+		m, _, _ := rd.preprocess(buf, ',', quoted, masks, postProc)
+		if !lazy.wellFormed(buf, m, quoted, last) {
+			return count, errStrayQuote
+		}
+
+		for b := 0; b*64 < len(buf) && b*3+2 < len(m); b++ {
+			delimiters, top := m[b*3], uint(63)
+			if rem := len(buf) - b*64; rem < 64 {
+				delimiters &= 1<<rem - 1 // ignore the delimiter added beyond the end
+				top = uint(rem - 1)
+			}
+			inQuotes := prefixXor(m[b*3+2]) ^ quoted
+			quoted = uint64(int64(inQuotes) >> 63)
+
+			// rows end at the delimiters outside quotes that do not follow
+			// another one, as those end empty rows (or are CRLFs)
+			ends := delimiters &^ inQuotes
+			count += bits.OnesCount64(ends &^ (ends<<1 | ended))
+			ended = ends >> top & 1
+		}
+		if last {
+			break
+		}
+	}
+	if ended == 0 {
+		count++ // the last row lacks a terminator
+	}
+	return count, nil
+}
@@ -0,0 +1,65 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCountRecords(t *testing.T) {
+	var data bytes.Buffer
+	for i := 0; data.Len() < 3*defaultChunkSize; i++ {
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&data, "%d,\"multi\r\nline\n%s\"\r\n", i, strings.Repeat("x", i%100))
+		case 1:
+			fmt.Fprintf(&data, "%d,\"\"\"quoted\"\"\",%s\n\n", i, strings.Repeat("y", i%200))
+		default:
+			fmt.Fprintf(&data, "%d,plain\n", i)
+		}
+	}
+	data.WriteString("unterminated,row")
+
+	inputs := map[string][]byte{"generated": data.Bytes(), "empty": nil, "newlines": []byte("\n\r\n\n")}
+	for _, file := range []string{"parking-citations-100K.csv", "nyc-taxi-data-100K.csv"} {
+		buf, err := ioutil.ReadFile("testdata/" + file)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		inputs[file] = buf
+	}
+
+	for name, input := range inputs {
+		r := NewReader(bytes.NewReader(input))
+		r.FieldsPerRecord = -1
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("TestCountRecords(%s): %v", name, err)
+		}
+		if count, err := CountRecords(bytes.NewReader(input)); err != nil || count != len(records) {
+			t.Errorf("TestCountRecords(%s): got: %d, %v want: %d", name, count, err, len(records))
+		}
+	}
+
+	if _, err := CountRecords(strings.NewReader("a,b\"c\n")); err != errStrayQuote {
+		t.Errorf("TestCountRecords: got: %v want: %v", err, errStrayQuote)
+	}
+}
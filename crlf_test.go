@@ -0,0 +1,45 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreserveCRLF(t *testing.T) {
+	plain := "%d,\"a\nb\"\r\n"
+	input := fixture("", 2000, "%d,\"a\r\nb\"\r\n", "%d,\"\"\"a\"\"\r\n\nb\r\r\n\"\n", plain, plain, plain)
+	want := fixtureRecords(2000, []string{"a\r\nb"}, []string{"\"a\"\r\n\nb\r\r\n"}, []string{"a\nb"}, []string{"a\nb"}, []string{"a\nb"})
+
+	forModes(func(mode SIMDMode, chunkSize int) {
+		r := NewReader(strings.NewReader(input))
+		r.SIMD, r.ChunkSize, r.PreserveCRLF = mode, chunkSize, true
+		records, err := r.ReadAll()
+		if err != nil || !reflect.DeepEqual(records, want) {
+			t.Errorf("TestPreserveCRLF(%d, %d): got: %d records (%v) want: %d", mode, chunkSize, len(records), err, len(want))
+		}
+
+		r = NewReader(strings.NewReader(input))
+		r.SIMD, r.ChunkSize = mode, chunkSize
+		records, err = r.ReadAll()
+		if err != nil || records[0][1] != "a\nb" || records[1][1] != "\"a\"\n\nb\r\n" {
+			t.Errorf("TestPreserveCRLF(%d, %d): got: %d records (%v) want: normalized", mode, chunkSize, len(records), err)
+		}
+	}, 64, 4096, 1<<20)
+}
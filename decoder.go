@@ -0,0 +1,234 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Decoder unmarshals records into structs of type T. The exported fields
+// of T are matched by name to the columns of the header, which is the first
+// record (see ReadHeader). The name of a field is given by its csv tag
+// (`csv:"price"`), if any, or else by the field name; fields tagged
+// `csv:"-"` are left alone, as are fields without a matching column. Tag
+// options such as omitempty (see Encoder) are ignored.
+//
+// Fields may be strings, integers, floating point numbers, booleans,
+// time.Time values (see TimeLayout), implement encoding.TextUnmarshaler, or
+// be pointers to any of these. Empty fields leave the zero value, so that
+// pointers stay nil.
+type Decoder[T any] struct {
+	// TimeLayout is the layout of time.Time fields, time.RFC3339 if empty
+	TimeLayout string
+
+	r *Reader
+
+	once    sync.Once
+	columns []columnDecoder // resolved once the header is known
+	err     error
+}
+
+// columnDecoder sets a field of a struct from a column of the records
+type columnDecoder struct {
+	name   string
+	column int
+	index  []int // of the field (see reflect.Value.FieldByIndex)
+	set    func(v reflect.Value, s string) error
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// NewDecoder returns a Decoder that unmarshals the records read from r
+func NewDecoder[T any](r *Reader) *Decoder[T] {
+	return &Decoder[T]{r: r}
+}
+
+// Decode reads the next record and unmarshals it, reading the header first
+// if need be.
+func (d *Decoder[T]) Decode() (T, error) {
+	var v T
+	if _, err := d.r.ReadHeader(); err != nil {
+		return v, err
+	}
+	record, err := d.r.ReadRecord()
+	if err != nil {
+		return v, err
+	}
+	if err := d.decode(record.Fields, &v); err != nil {
+		return v, fmt.Errorf("record on line %d: %w", record.Line, err)
+	}
+	return v, nil
+}
+
+// Stream unmarshals all remaining records and delivers the values in the
+// order of the records, like StreamHeader, so unmarshaling is done by the
+// parsing workers. Once Stream has been called, neither d nor its Reader
+// must be read from directly.
+func (d *Decoder[T]) Stream() (<-chan T, <-chan error) {
+	return StreamHeader(d.r, func(_, record []string) (v T, err error) {
+		err = d.decode(record, &v)
+		return
+	})
+}
+
+// decode unmarshals record into v
+func (d *Decoder[T]) decode(record []string, v *T) error {
+	d.once.Do(d.resolve)
+	if d.err != nil {
+		return d.err
+	}
+	rv := reflect.ValueOf(v).Elem()
+	for _, c := range d.columns {
+		if c.column >= len(record) || record[c.column] == "" {
+			continue
+		}
+		if err := c.set(rv.FieldByIndex(c.index), record[c.column]); err != nil {
+			return fmt.Errorf("column %q: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// resolve matches the fields of T to the columns of the header, which has
+// been read by the time the first record is decoded
+func (d *Decoder[T]) resolve() {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		d.err = fmt.Errorf("simdcsv: cannot decode into %v", t)
+		return
+	}
+	columns := make(map[string]int)
+	for i, name := range d.r.header {
+		columns[name] = i
+	}
+
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		name, _, ok := fieldTag(f)
+		if !ok {
+			continue
+		}
+		column, ok := columns[d.r.NormalizeHeader.apply(name)]
+		if !ok {
+			continue
+		}
+		set, err := d.setter(f.Type)
+		if err != nil {
+			d.err = fmt.Errorf("simdcsv: field %s: %w", f.Name, err)
+			return
+		}
+		d.columns = append(d.columns, columnDecoder{name, column, f.Index, set})
+	}
+}
+
+// fieldTag returns the column name of a struct field, given by its csv tag,
+// if any, or else by its name, and whether the tag has the omitempty option.
+// It returns false for fields tagged `csv:"-"`.
+func fieldTag(f reflect.StructField) (name string, omitEmpty, ok bool) {
+	name = f.Name
+	tag, ok := f.Tag.Lookup("csv")
+	if !ok {
+		return name, false, true
+	}
+	if tag == "-" {
+		return "", false, false
+	}
+	tag, opts, _ := strings.Cut(tag, ",")
+	if tag != "" {
+		name = tag
+	}
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		omitEmpty = omitEmpty || opt == "omitempty"
+	}
+	return name, omitEmpty, true
+}
+
+// setter returns the function that sets a value of type t from a field
+func (d *Decoder[T]) setter(t reflect.Type) (func(v reflect.Value, s string) error, error) {
+	switch {
+	case t == timeType:
+		layout := d.TimeLayout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return func(v reflect.Value, s string) error {
+			tm, err := time.Parse(layout, s)
+			v.Set(reflect.ValueOf(tm))
+			return err
+		}, nil
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return func(v reflect.Value, s string) error {
+			return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		set, err := d.setter(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value, s string) error {
+			p := reflect.New(t.Elem())
+			v.Set(p)
+			return set(p.Elem(), s)
+		}, nil
+	case reflect.String:
+		return func(v reflect.Value, s string) error {
+			v.SetString(s)
+			return nil
+		}, nil
+	case reflect.Bool:
+		return func(v reflect.Value, s string) error {
+			b, err := strconv.ParseBool(s)
+			v.SetBool(b)
+			return err
+		}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v reflect.Value, s string) error {
+			i, err := strconv.ParseInt(s, 10, t.Bits())
+			v.SetInt(i)
+			return err
+		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(v reflect.Value, s string) error {
+			u, err := strconv.ParseUint(s, 10, t.Bits())
+			v.SetUint(u)
+			return err
+		}, nil
+	case reflect.Float32, reflect.Float64:
+		return func(v reflect.Value, s string) error {
+			f, err := strconv.ParseFloat(s, t.Bits())
+			v.SetFloat(f)
+			return err
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
@@ -0,0 +1,109 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecoder(t *testing.T) {
+	type trade struct {
+		ID      int       `csv:"id"`
+		Price   float64   `csv:"price"`
+		Volume  uint32    `csv:"volume"`
+		Settled bool      `csv:"settled"`
+		Date    time.Time `csv:"date"`
+		Note    *string   `csv:"note"`
+		Symbol  string
+		Ignored string `csv:"-"`
+	}
+
+	var input bytes.Buffer
+	input.WriteString("ID,Symbol,Price,Volume,Settled,Date,Note,Ignored\n")
+	const total = 50000
+	for i := 0; i < total; i++ {
+		note := ""
+		if i%2 == 1 {
+			note = fmt.Sprintf("\"note, %d\"", i)
+		}
+		fmt.Fprintf(&input, "%d,SYM%d,%d.5,%d,%t,2020-01-%02d,%s,x\n", i, i%10, i, i*3, i%3 == 0, i%28+1, note)
+	}
+	check := func(v trade, i int) error {
+		date := time.Date(2020, 1, i%28+1, 0, 0, 0, 0, time.UTC)
+		if v.ID != i || v.Price != float64(i)+0.5 || v.Volume != uint32(i*3) || v.Settled != (i%3 == 0) ||
+			!v.Date.Equal(date) || v.Symbol != fmt.Sprint("SYM", i%10) || v.Ignored != "" ||
+			(v.Note == nil) != (i%2 == 0) || v.Note != nil && *v.Note != fmt.Sprint("note, ", i) {
+			return fmt.Errorf("got: %+v want: record %d", v, i)
+		}
+		return nil
+	}
+
+	// decoding by the workers
+	r := NewReader(bytes.NewReader(input.Bytes()))
+	r.NormalizeHeader = LowerCase
+	d := NewDecoder[trade](r)
+	d.TimeLayout = "2006-01-02"
+	values, errs := d.Stream()
+	i := 0
+	for v := range values {
+		if err := check(v, i); err != nil {
+			t.Fatalf("TestDecoder: %v", err)
+		}
+		i++
+	}
+	if err := <-errs; err != nil || i != total {
+		t.Errorf("TestDecoder: got: %d records, %v want: %d", i, err, total)
+	}
+
+	// decoding one record at a time
+	r = NewReader(bytes.NewReader(input.Bytes()))
+	r.NormalizeHeader = LowerCase
+	d = NewDecoder[trade](r)
+	d.TimeLayout = "2006-01-02"
+	for i = 0; ; i++ {
+		v, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("TestDecoder: %v", err)
+		}
+		if err := check(v, i); err != nil {
+			t.Fatalf("TestDecoder: %v", err)
+		}
+	}
+	if i != total {
+		t.Errorf("TestDecoder: got: %d records want: %d", i, total)
+	}
+
+	// errors name the line and column
+	for _, threshold := range []int{-1, 0} {
+		r := NewReader(strings.NewReader("id,price\n1,2\n2,x\n"))
+		r.FallbackThreshold = threshold
+		values, errs := NewDecoder[trade](r).Stream()
+		for range values {
+		}
+		if err := <-errs; err == nil || !strings.HasPrefix(err.Error(), `record on line 3: column "price"`) {
+			t.Errorf("TestDecoder: got: %v want: error in column price on line 3", err)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"os"
+	"sort"
+)
+
+// dedups reports whether records with a repeated key are dropped (see
+// DedupColumns)
+func (r *Reader) dedups() bool {
+	return len(r.DedupColumns) > 0 || len(r.DedupNames) > 0
+}
+
+// dedupKey is the 128-bit hash of the key of a record
+type dedupKey [2]uint64
+
+// dedup hashes the keys of records and drops the records whose key was seen
+// before (see DedupColumns)
+type dedup struct {
+	header  bool  // whether the first record is a header, which is kept
+	columns []int // of the records that make up the key, or -1
+	seeds   [2]maphash.Seed
+	seen    dedupSet
+}
+
+// newDedup resolves the key columns, where the names are looked up in header
+func (r *Reader) newDedup(header []string) *dedup {
+	d := &dedup{
+		header:  len(r.DedupNames) > 0 || len(r.SelectNames) > 0 || len(r.WhereNames) > 0 || len(r.NormalizeNames) > 0 || r.NormalizeHeader != 0 || r.query != nil && r.query.header,
+		columns: append([]int(nil), r.DedupColumns...),
+		seeds:   [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+		seen:    dedupSet{keys: make(map[dedupKey]struct{}), spill: r.DedupSpill},
+	}
+	for _, name := range r.DedupNames {
+		col := -1
+		name = r.NormalizeHeader.apply(name)
+		for i, column := range header {
+			if r.NormalizeHeader.apply(column) == name {
+				col = i
+				break
+			}
+		}
+		d.columns = append(d.columns, col)
+	}
+	return d
+}
+
+// keys returns the keys of records, which is done by the workers
+func (d *dedup) keys(records [][]string) []dedupKey {
+	keys := make([]dedupKey, len(records))
+	for i, record := range records {
+		keys[i] = d.key(record)
+	}
+	return keys
+}
+
+// key hashes the key columns of record, each preceded by its length, where
+// columns that the record lacks are empty
+func (d *dedup) key(record []string) (k dedupKey) {
+	var n [binary.MaxVarintLen64]byte
+	for i, seed := range d.seeds {
+		var h maphash.Hash
+		h.SetSeed(seed)
+		for _, col := range d.columns {
+			field := ""
+			if col >= 0 && col < len(record) {
+				field = record[col]
+			}
+			h.Write(n[:binary.PutUvarint(n[:], uint64(len(field)))])
+			h.WriteString(field)
+		}
+		k[i] = h.Sum64()
+	}
+	return
+}
+
+// dropDuplicates removes the records of output with a key seen before, in the order of
+// the records, except for the header if the records start with it
+func (r *Reader) dropDuplicates(output *recordsOutput) error {
+	if r.dedup == nil || len(output.records) == 0 {
+		return nil
+	}
+	keys := output.keys
+	if len(keys) < len(output.records) {
+		keys = r.dedup.keys(output.records)
+	}
+	n := 0
+	for i, record := range output.records {
+		header := r.dedup.header && i < len(output.positions) && output.positions[i].offset == r.dataOffset
+		if !header {
+			dup, err := r.dedup.seen.add(keys[i])
+			if err != nil {
+				return err
+			}
+			if dup {
+				continue
+			}
+		}
+		output.records[n] = record
+		if i < len(output.positions) {
+			output.positions[n] = output.positions[i]
+		}
+		n++
+	}
+	output.records = output.records[:n]
+	if n < len(output.positions) {
+		output.positions = output.positions[:n]
+	}
+	return nil
+}
+
+// duplicate reports whether record, read from encoding/csv, repeats the key
+// of an earlier record
+func (r *Reader) duplicate(record []string, pos recordPos) (bool, error) {
+	if !r.dedups() {
+		return false, nil
+	}
+	if r.dedup == nil {
+		var header []string
+		if pos.offset == r.dataOffset {
+			header = record
+		}
+		r.dedup = r.newDedup(header)
+	}
+	if r.dedup.header && pos.offset == r.dataOffset {
+		return false, nil
+	}
+	return r.dedup.seen.add(r.dedup.key(record))
+}
+
+// dedupSet is the set of the keys seen, which are held in memory up to the
+// spill threshold and then written to sorted runs in temporary files, each
+// with a Bloom filter in memory
+type dedupSet struct {
+	keys  map[dedupKey]struct{}
+	spill int // number of keys held in memory at most, if positive
+	runs  []*dedupRun
+}
+
+// dedupRun is a sorted run of keys in a file
+type dedupRun struct {
+	f     *os.File
+	n     int
+	bloom []uint64 // bloomBits bits per key
+}
+
+const (
+	bloomBits   = 16 // per key, for a false positive rate of about 0.05%
+	bloomHashes = 11
+	keySize     = 16
+)
+
+// reset empties the set, as reading restarts
+func (s *dedupSet) reset() {
+	for _, run := range s.runs {
+		run.f.Close()
+	}
+	s.keys, s.runs = make(map[dedupKey]struct{}), nil
+}
+
+// add adds k to the set, reporting whether it was there already
+func (s *dedupSet) add(k dedupKey) (bool, error) {
+	if _, ok := s.keys[k]; ok {
+		return true, nil
+	}
+	for _, run := range s.runs {
+		if found, err := run.contains(k); found || err != nil {
+			return found, err
+		}
+	}
+	s.keys[k] = struct{}{}
+	if s.spill > 0 && len(s.keys) >= s.spill {
+		return false, s.spillKeys()
+	}
+	return false, nil
+}
+
+// spillKeys writes the keys in memory to a new run
+func (s *dedupSet) spillKeys() error {
+	keys := make([]dedupKey, 0, len(s.keys))
+	for k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+
+	f, err := os.CreateTemp("", "simdcsv-dedup-*")
+	if err != nil {
+		return err
+	}
+	os.Remove(f.Name()) // the file lives on as long as it is open, where the platform allows
+	run := &dedupRun{f: f, n: len(keys), bloom: make([]uint64, (len(keys)*bloomBits+63)/64)}
+	buf := make([]byte, 0, 64<<10)
+	for _, k := range keys {
+		run.setBloom(k)
+		buf = binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(buf, k[0]), k[1])
+		if len(buf) == cap(buf) {
+			if _, err := f.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+	if _, err := f.Write(buf); err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+	s.keys = make(map[dedupKey]struct{})
+	return nil
+}
+
+func (k dedupKey) less(o dedupKey) bool {
+	return k[0] < o[0] || k[0] == o[0] && k[1] < o[1]
+}
+
+// bloomIndexes calls f with the bits of k in the Bloom filter, which are
+// derived from the halves of the key as it is a hash already
+func (run *dedupRun) bloomIndexes(k dedupKey, f func(word int, bit uint64) bool) bool {
+	m := uint64(len(run.bloom) * 64)
+	for i := uint64(0); i < bloomHashes; i++ {
+		b := (k[0] + i*k[1]) % m
+		if !f(int(b/64), 1<<(b%64)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (run *dedupRun) setBloom(k dedupKey) {
+	run.bloomIndexes(k, func(word int, bit uint64) bool {
+		run.bloom[word] |= bit
+		return true
+	})
+}
+
+// contains reports whether the run holds k, searching the file only if the
+// Bloom filter cannot rule it out
+func (run *dedupRun) contains(k dedupKey) (bool, error) {
+	if !run.bloomIndexes(k, func(word int, bit uint64) bool { return run.bloom[word]&bit != 0 }) {
+		return false, nil
+	}
+	var buf [keySize]byte
+	var err error
+	i := sort.Search(run.n, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		_, err = run.f.ReadAt(buf[:], int64(i)*keySize)
+		return !(dedupKey{binary.BigEndian.Uint64(buf[:]), binary.BigEndian.Uint64(buf[8:])}).less(k)
+	})
+	if err != nil || i == run.n {
+		return false, err
+	}
+	if _, err = run.f.ReadAt(buf[:], int64(i)*keySize); err != nil {
+		return false, err
+	}
+	return dedupKey{binary.BigEndian.Uint64(buf[:]), binary.BigEndian.Uint64(buf[8:])} == k, nil
+}
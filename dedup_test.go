@@ -0,0 +1,90 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("event,user,time\n")
+	var rows [][]string
+	for i := 0; i < 5000; i++ {
+		rows = append(rows, []string{fmt.Sprint("e", i%1500), fmt.Sprint("u", i%7), fmt.Sprint(i)})
+		fmt.Fprintf(&input, "%s,\"%s\",%s\n", rows[i][0], rows[i][1], rows[i][2])
+	}
+	distinct := func(key func(row []string) string) (want [][]string) {
+		seen := map[string]bool{}
+		for _, row := range rows {
+			if k := key(row); !seen[k] {
+				seen[k] = true
+				want = append(want, row)
+			}
+		}
+		return
+	}
+	header := []string{"event", "user", "time"}
+
+	for _, tc := range []struct {
+		columns []int
+		names   []string
+		spill   int
+		want    [][]string
+	}{
+		{[]int{0}, nil, 0, append([][]string{header}, distinct(func(row []string) string { return row[0] })...)},
+		{nil, []string{"user"}, 0, append([][]string{header}, distinct(func(row []string) string { return row[1] })...)},
+		{[]int{1}, []string{"event"}, 0, append([][]string{header}, distinct(func(row []string) string { return row[1] + "," + row[0] })...)},
+		{nil, []string{"event", "missing"}, 100, append([][]string{header}, distinct(func(row []string) string { return row[0] })...)},
+		{[]int{0, 1}, nil, 1000, append([][]string{header}, distinct(func(row []string) string { return row[0] + "," + row[1] })...)},
+	} {
+		forModes(func(mode SIMDMode, chunkSize int) {
+			for _, readAll := range []bool{false, true} {
+				r := NewReader(strings.NewReader(input.String()))
+				r.SIMD, r.ChunkSize = mode, chunkSize
+				r.DedupColumns, r.DedupNames, r.DedupSpill = tc.columns, tc.names, tc.spill
+				got, err := readRecords(r, readAll)
+				if err != nil || !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("TestDedup(%v, %v, %d, %d, %d, %v): got: %d records (%v) want: %d", tc.columns, tc.names, tc.spill, mode, chunkSize, readAll, len(got), err, len(tc.want))
+				}
+			}
+		})
+	}
+
+	// duplicates are dropped before the values are decoded, and anew after Rewind
+	want := distinct(func(row []string) string { return row[1] })
+	r := NewReader(strings.NewReader(input.String()))
+	r.ChunkSize, r.DedupNames = 4096, []string{"user"}
+	for pass := 0; pass < 2; pass++ {
+		values, errs := StreamHeader(r, func(header, record []string) (string, error) {
+			return record[2], nil
+		})
+		var got []string
+		for v := range values {
+			got = append(got, v)
+		}
+		if err := <-errs; err != nil || len(got) != len(want) || got[len(got)-1] != want[len(want)-1][2] {
+			t.Errorf("TestDedup(StreamHeader, %d): got: %v (%v) want: %d values", pass, got, err, len(want))
+		}
+		if err := r.Rewind(); err != nil {
+			t.Fatalf("TestDedup: %v", err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// An Encoder marshals structs of type T into records written by a Writer,
+// preceded by a header with the names of the columns. As for a Decoder, the
+// exported fields of T are the columns, named by their csv tag, if any, or
+// else by their name; fields tagged `csv:"-"` are left out. Fields tagged
+// with the omitempty option (`csv:"price,omitempty"`) are written as empty
+// fields if they hold the zero value of their type.
+//
+// Fields may be strings, integers, floating point numbers, booleans,
+// time.Time values (see TimeLayout), implement encoding.TextMarshaler, or be
+// pointers to any of these, which are written as empty fields if nil.
+type Encoder[T any] struct {
+	// TimeLayout is the layout of time.Time fields, time.RFC3339 if empty
+	TimeLayout string
+
+	// FloatFormat and FloatPrecision are the format and precision of
+	// floating point numbers (see strconv.FormatFloat). If FloatFormat is
+	// zero, they are formatted as 'g' with the smallest precision that
+	// represents them exactly.
+	FloatFormat    byte
+	FloatPrecision int
+
+	// NoHeader, if true, leaves out the header
+	NoHeader bool
+
+	w *Writer
+
+	once    sync.Once
+	header  []string
+	columns []columnEncoder
+	record  []string
+	err     error
+	started bool // once the header is written
+}
+
+// columnEncoder formats a column of the records from a field of a struct
+type columnEncoder struct {
+	index     []int // of the field (see reflect.Value.FieldByIndex)
+	omitEmpty bool
+	format    func(v reflect.Value) (string, error)
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// NewEncoder returns an Encoder that writes the records marshaled from
+// values to w
+func NewEncoder[T any](w *Writer) *Encoder[T] {
+	return &Encoder[T]{w: w}
+}
+
+// Encode marshals v into a record and writes it, writing the header first
+// if need be. Writes are buffered, so the Writer must eventually be flushed.
+func (e *Encoder[T]) Encode(v T) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(&v).Elem() // addressable for pointer receivers
+	for i, c := range e.columns {
+		field := rv.FieldByIndex(c.index)
+		if c.omitEmpty && field.IsZero() {
+			e.record[i] = ""
+			continue
+		}
+		s, err := c.format(field)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", e.header[i], err)
+		}
+		e.record[i] = s
+	}
+	return e.w.Write(e.record)
+}
+
+// EncodeAll writes the records marshaled from values, like Encode, and then
+// flushes the Writer, returning any error from the flush.
+func (e *Encoder[T]) EncodeAll(values []T) error {
+	for _, v := range values {
+		if err := e.Encode(v); err != nil {
+			return err
+		}
+	}
+	return e.flush()
+}
+
+// Stream writes the records marshaled from the values received from values,
+// like Encode, until it is closed, and then flushes the Writer. values is
+// drained even if an error occurs, so that its sender is not blocked.
+func (e *Encoder[T]) Stream(values <-chan T) error {
+	var err error
+	for v := range values {
+		if err == nil {
+			err = e.Encode(v)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// start resolves the columns and writes the header, unless done before
+func (e *Encoder[T]) start() error {
+	e.once.Do(e.resolve)
+	if e.err != nil || e.started {
+		return e.err
+	}
+	e.started = true
+	if e.NoHeader {
+		return nil
+	}
+	return e.w.Write(e.header)
+}
+
+// flush writes the header if no record has been, and flushes the Writer
+func (e *Encoder[T]) flush() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// resolve finds the fields of T that are written as columns
+func (e *Encoder[T]) resolve() {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		e.err = fmt.Errorf("simdcsv: cannot encode %v", t)
+		return
+	}
+
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		name, omitEmpty, ok := fieldTag(f)
+		if !ok {
+			continue
+		}
+		format, err := e.formatter(f.Type)
+		if err != nil {
+			e.err = fmt.Errorf("simdcsv: field %s: %w", f.Name, err)
+			return
+		}
+		e.header = append(e.header, name)
+		e.columns = append(e.columns, columnEncoder{f.Index, omitEmpty, format})
+	}
+	e.record = make([]string, len(e.columns))
+}
+
+// formatter returns the function that formats a value of type t as a field
+func (e *Encoder[T]) formatter(t reflect.Type) (func(v reflect.Value) (string, error), error) {
+	switch {
+	case t == timeType:
+		layout := e.TimeLayout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return func(v reflect.Value) (string, error) {
+			return v.Interface().(time.Time).Format(layout), nil
+		}, nil
+	case t.Kind() == reflect.Pointer:
+		format, err := e.formatter(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (string, error) {
+			if v.IsNil() {
+				return "", nil
+			}
+			return format(v.Elem())
+		}, nil
+	case t.Implements(textMarshalerType):
+		return func(v reflect.Value) (string, error) {
+			text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+			return string(text), err
+		}, nil
+	case reflect.PointerTo(t).Implements(textMarshalerType):
+		return func(v reflect.Value) (string, error) {
+			text, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+			return string(text), err
+		}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return func(v reflect.Value) (string, error) {
+			return v.String(), nil
+		}, nil
+	case reflect.Bool:
+		return func(v reflect.Value) (string, error) {
+			return strconv.FormatBool(v.Bool()), nil
+		}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v reflect.Value) (string, error) {
+			return strconv.FormatInt(v.Int(), 10), nil
+		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(v reflect.Value) (string, error) {
+			return strconv.FormatUint(v.Uint(), 10), nil
+		}, nil
+	case reflect.Float32, reflect.Float64:
+		format, precision := e.FloatFormat, e.FloatPrecision
+		if format == 0 {
+			format, precision = 'g', -1
+		}
+		return func(v reflect.Value) (string, error) {
+			return strconv.FormatFloat(v.Float(), format, precision, t.Bits()), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
@@ -0,0 +1,106 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type upper string
+
+func (u *upper) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(string(*u))), nil
+}
+
+func TestEncoder(t *testing.T) {
+	type trade struct {
+		ID      int        `csv:"id"`
+		Price   float64    `csv:"price,omitempty"`
+		Settled bool       `csv:"settled"`
+		Date    time.Time  `csv:"date"`
+		Expiry  *time.Time `csv:"expiry"`
+		Note    *string    `csv:"note"`
+		Symbol  upper
+		Ignored string `csv:"-"`
+	}
+
+	date := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	note := "a, \"note\""
+	values := []trade{
+		{1, 2.5, true, date, &date, &note, "abc", "x"},
+		{2, 0, false, date, nil, nil, "", "y"},
+	}
+
+	var out bytes.Buffer
+	e := NewEncoder[trade](NewWriter(&out))
+	e.TimeLayout, e.FloatFormat, e.FloatPrecision = "2006-01-02", 'f', 2
+	if err := e.EncodeAll(values); err != nil {
+		t.Fatalf("TestEncoder: %v", err)
+	}
+	want := "id,price,settled,date,expiry,note,Symbol\n" +
+		"1,2.50,true,2020-01-02,2020-01-02,\"a, \"\"note\"\"\",ABC\n" +
+		"2,,false,2020-01-02,,,\n"
+	if out.String() != want {
+		t.Errorf("TestEncoder: got: %q want: %q", out.String(), want)
+	}
+
+	// streaming without a header
+	out.Reset()
+	e = NewEncoder[trade](NewWriter(&out))
+	e.TimeLayout, e.NoHeader = "2006-01-02", true
+	ch := make(chan trade)
+	go func() {
+		for _, v := range values {
+			ch <- v
+		}
+		close(ch)
+	}()
+	if err := e.Stream(ch); err != nil || out.String() != strings.SplitN(strings.ReplaceAll(want, "2.50", "2.5"), "\n", 2)[1] {
+		t.Errorf("TestEncoder: got: %q (%v)", out.String(), err)
+	}
+
+	// round trip through a Decoder
+	type row struct {
+		ID    int       `csv:"id"`
+		Price float64   `csv:"price,omitempty"`
+		Date  time.Time `csv:"date"`
+		Note  *string   `csv:"note"`
+	}
+	var rows []row
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, row{i, float64(i) / 3, date.Add(time.Duration(i) * time.Hour), &note})
+	}
+	out.Reset()
+	if err := NewEncoder[row](NewWriter(&out)).EncodeAll(rows); err != nil {
+		t.Fatalf("TestEncoder: %v", err)
+	}
+	d := NewDecoder[row](NewReader(bytes.NewReader(out.Bytes())))
+	for _, want := range rows {
+		if got, err := d.Decode(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("TestEncoder: got: %+v (%v) want: %+v", got, err, want)
+		}
+	}
+
+	type unsupported struct{ C chan int }
+	if err := NewEncoder[unsupported](NewWriter(&out)).Encode(unsupported{}); err == nil {
+		t.Errorf("TestEncoder: expected error for %T", unsupported{})
+	}
+}
@@ -0,0 +1,162 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// An Encoding is the character encoding of the input (see Reader.Encoding)
+type Encoding int
+
+const (
+	UTF8 Encoding = iota
+	UTF16LE
+	UTF16BE
+	Latin1
+	Windows1252
+)
+
+// transcode has the input transcoded to UTF-8 as it is read, unless it is
+// UTF-8 already. The offsets of the transcoded input no longer correspond to
+// the input, which therefore cannot be seeked.
+func (r *Reader) transcode() {
+	if r.Encoding == UTF8 || r.transcoding {
+		return
+	}
+	r.transcoding, r.ra = true, nil
+	if r.inMemory {
+		r.data, _ = io.ReadAll(&transcoder{in: bytes.NewReader(r.data), enc: r.Encoding})
+		r.r = bytes.NewReader(r.data)
+	} else {
+		r.r = &transcoder{in: r.r, enc: r.Encoding}
+	}
+}
+
+// windows1252 holds the characters for bytes 0x80 through 0x9f, where the
+// bytes that Windows-1252 leaves undefined map to the C1 controls
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// transcoder transcodes its input to UTF-8
+type transcoder struct {
+	in      io.Reader
+	enc     Encoding
+	buf     []byte
+	pending []byte // bytes of a character that continues in the input
+	out     []byte // transcoded bytes that are yet to be returned
+	started bool   // whether past the byte order mark, if any
+	err     error
+}
+
+// Read fills p as far as the input goes, so that the chunk buffers of stage 1
+// are filled with transcoded input
+func (t *transcoder) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if len(t.out) == 0 {
+			if t.err != nil {
+				break
+			}
+			t.fill(len(p) - n)
+			continue
+		}
+		m := copy(p[n:], t.out)
+		t.out, n = t.out[m:], n+m
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, t.err
+}
+
+// fill transcodes about size bytes of input
+func (t *transcoder) fill(size int) {
+	if size < 4096 {
+		size = 4096
+	}
+	if cap(t.buf) < size {
+		t.buf = make([]byte, size)
+	}
+	n, err := t.in.Read(t.buf[:size])
+	in := append(t.pending, t.buf[:n]...)
+	t.pending, t.out = nil, t.out[:0]
+	if err != nil {
+		t.err = err
+	}
+
+	switch t.enc {
+	case UTF16LE, UTF16BE:
+		for i := 0; i+1 < len(in); i += 2 {
+			u := uint16(in[i]) | uint16(in[i+1])<<8
+			if t.enc == UTF16BE {
+				u = u<<8 | u>>8
+			}
+			if !t.started {
+				t.started = true
+				if u == 0xfeff {
+					continue // byte order mark
+				}
+			}
+			if utf16.IsSurrogate(rune(u)) && u < 0xdc00 {
+				if i+3 >= len(in) {
+					if t.err == nil {
+						t.pending = append(t.pending, in[i:]...)
+						return
+					}
+				} else {
+					v := uint16(in[i+2]) | uint16(in[i+3])<<8
+					if t.enc == UTF16BE {
+						v = v<<8 | v>>8
+					}
+					if rn := utf16.DecodeRune(rune(u), rune(v)); rn != utf8.RuneError {
+						t.out = utf8.AppendRune(t.out, rn)
+						i += 2
+						continue
+					}
+				}
+			}
+			if u < utf8.RuneSelf {
+				t.out = append(t.out, byte(u))
+			} else {
+				t.out = utf8.AppendRune(t.out, rune(u)) // lone surrogates turn into U+FFFD
+			}
+		}
+		if len(in)%2 == 1 {
+			if t.err == nil {
+				t.pending = append(t.pending, in[len(in)-1])
+			} else {
+				t.out = utf8.AppendRune(t.out, utf8.RuneError)
+			}
+		}
+	default:
+		for _, c := range in {
+			switch {
+			case c < utf8.RuneSelf:
+				t.out = append(t.out, c)
+			case t.enc == Windows1252 && c < 0xa0:
+				t.out = utf8.AppendRune(t.out, windows1252[c-0x80])
+			default:
+				t.out = utf8.AppendRune(t.out, rune(c))
+			}
+		}
+	}
+}
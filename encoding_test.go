@@ -0,0 +1,85 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestEncoding(t *testing.T) {
+	var utf8Input, latin1 bytes.Buffer
+	for i := 0; utf8Input.Len() < 1<<20; i++ {
+		fmt.Fprintf(&utf8Input, "%d,\"café %d\",\"😀 “%d”\"\n", i, i, i)
+		fmt.Fprintf(&latin1, "%d,\"caf\xe9 %d\",\"\x80 \x93%d\x94\"\n", i, i, i)
+	}
+	expected, err := encodingCsv(utf8Input.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected1252, expectedLatin1 := make([][]string, len(expected)), make([][]string, len(expected))
+	for i, record := range expected {
+		expected1252[i] = []string{record[0], record[1], fmt.Sprintf("€ “%d”", i)}
+		expectedLatin1[i] = []string{record[0], record[1], fmt.Sprintf("\u0080 \u0093%d\u0094", i)}
+	}
+
+	units := utf16.Encode(append([]rune{0xfeff}, []rune(utf8Input.String())...))
+	le, be := make([]byte, 2*len(units)), make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(le[2*i:], u)
+		binary.BigEndian.PutUint16(be[2*i:], u)
+	}
+
+	for _, tc := range []struct {
+		enc  Encoding
+		data []byte
+		want [][]string
+	}{
+		{UTF16LE, le, expected},
+		{UTF16BE, be, expected},
+		{Windows1252, latin1.Bytes(), expected1252},
+		{Latin1, latin1.Bytes(), expectedLatin1},
+	} {
+		for _, inMemory := range []bool{false, true} {
+			r := NewReader(bytes.NewReader(tc.data))
+			if inMemory {
+				r = newBytesReader(tc.data)
+			}
+			r.Encoding = tc.enc
+			records, err := r.ReadAll()
+			if err != nil || !reflect.DeepEqual(records, tc.want) {
+				t.Errorf("TestEncoding(%d): got: %d records, %v want: %d", tc.enc, len(records), err, len(tc.want))
+			}
+			if !inMemory {
+				if err := r.Rewind(); err != errNotReaderAt {
+					t.Errorf("TestEncoding(%d): got: %v want: %v", tc.enc, err, errNotReaderAt)
+				}
+			}
+		}
+	}
+
+	// a lone surrogate and an odd trailing byte
+	r := NewReader(bytes.NewReader([]byte{'a', 0, 0x00, 0xd8, 'b', 0, '\n', 0, 'c'}))
+	r.Encoding = UTF16LE
+	if records, err := r.ReadAll(); err != nil || !reflect.DeepEqual(records, [][]string{{"a�b"}, {"�"}}) {
+		t.Errorf("TestEncoding: got: %q, %v want: replacement characters", records, err)
+	}
+}
@@ -0,0 +1,254 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// escapedPlaceholder replaces the characters that follow an escape in the
+// copy of a chunk processed by stage 1, so they are not taken as quotes,
+// separators or newlines
+const escapedPlaceholder = 0
+
+// csvEscapePlaceholder stands in for an escape along with the character that
+// follows it for encoding/csv, being of the same length
+const csvEscapePlaceholder = '\u0081'
+
+// validEscape reports whether Escape is 0 or can be told apart from the
+// other delimiters
+func (r *Reader) validEscape() bool {
+	e := r.Escape
+	if e == 0 {
+		return true
+	}
+	return e < utf8.RuneSelf && e != '"' && e != '\r' && e != '\n' && e != r.Comment &&
+		!strings.ContainsRune(r.delimiter(), e)
+}
+
+// escaper neutralizes the escaped characters of the chunks for stage 1
+type escaper struct {
+	escape  byte
+	pending bool   // whether the previous chunk ended with an escape
+	scratch []byte // copy of the chunk processed by stage 1
+}
+
+// newEscaper returns the escaper for escape, or nil if escape is 0
+func newEscaper(escape rune) *escaper {
+	if escape == 0 {
+		return nil
+	}
+	return &escaper{escape: byte(escape)}
+}
+
+// neutralize returns a copy of buf in which every character that follows an
+// escape is replaced, or buf itself if it has no escaped characters. The copy
+// is only valid until the next call.
+func (e *escaper) neutralize(buf []byte) []byte {
+	if len(buf) == 0 || !e.pending && bytes.IndexByte(buf, e.escape) < 0 {
+		return buf
+	}
+	if cap(e.scratch) < len(buf)+chunkAlign {
+		e.scratch = allocChunk(len(buf))
+	}
+	scratch := e.scratch[:len(buf)]
+	copy(scratch, buf)
+
+	i := 0
+	if e.pending {
+		scratch[0], i, e.pending = escapedPlaceholder, 1, false
+	}
+	for {
+		j := bytes.IndexByte(scratch[i:], e.escape)
+		if j < 0 {
+			break
+		}
+		if i += j + 1; i == len(scratch) {
+			e.pending = true
+			break
+		}
+		scratch[i] = escapedPlaceholder
+		i++
+	}
+	return scratch
+}
+
+// recountLines sets the lines of the records at positions, which start in buf
+// from offset start on line, where buf starts at input offset offset. Unlike
+// recordPositions, this counts escaped newlines.
+func recountLines(buf []byte, start int, line int, offset int64, positions []recordPos) {
+	for i := range positions {
+		next := int(positions[i].offset - offset)
+		line += bytes.Count(buf[start:next], []byte{'\n'})
+		positions[i].line, start = line, next
+	}
+}
+
+// unescaped returns the character that the escape of c stands for, which
+// follows MySQL: \0, \b, \n, \r, \t and \Z stand for NUL, backspace,
+// newline, carriage return, tab and Ctrl-Z, whereas other characters stand
+// for themselves
+func unescaped(c byte) byte {
+	switch c {
+	case '0':
+		return 0
+	case 'b':
+		return '\b'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'Z':
+		return 0x1a
+	}
+	return c
+}
+
+// unescapeField resolves the escapes of a field, leaving \N (which stands
+// for NULL in MySQL) as is
+func unescapeField(field string, escape byte) string {
+	i := strings.IndexByte(field, escape)
+	if i < 0 {
+		return field
+	}
+	var b strings.Builder
+	b.Grow(len(field))
+	for ; i >= 0; i = strings.IndexByte(field, escape) {
+		b.WriteString(field[:i])
+		if i+1 == len(field) {
+			b.WriteByte(escape) // nothing to escape
+			field = ""
+			break
+		}
+		if c := field[i+1]; c == 'N' {
+			b.WriteByte(escape)
+			b.WriteByte(c)
+		} else {
+			b.WriteByte(unescaped(c))
+		}
+		field = field[i+2:]
+	}
+	b.WriteString(field)
+	return b.String()
+}
+
+// escapeFilter returns in with every escape along with the character that
+// follows it replaced by csvEscapePlaceholder, if Escape is set
+func (r *Reader) escapeFilter(in io.Reader) io.Reader {
+	if r.Escape == 0 {
+		return in
+	}
+	return &escapeFilter{in: in, escape: byte(r.Escape)}
+}
+
+// escapeFilter hides escaped characters from encoding/csv, recording them to
+// be restored in the records (see csvPositions.restoreEscapes)
+type escapeFilter struct {
+	in      io.Reader
+	escape  byte
+	buf     []byte // bytes read but not yet returned
+	offset  int64  // number of bytes returned so far
+	escaped []escapedChar
+	err     error
+}
+
+// escapedChar records the character that follows an escape at offset
+type escapedChar struct {
+	offset int64
+	c      byte
+}
+
+func (f *escapeFilter) Read(p []byte) (int, error) {
+	for {
+		ready := len(f.buf)
+		if f.err == nil && ready > 0 && f.buf[ready-1] == f.escape {
+			ready-- // the character it escapes is yet to be read
+		}
+		if ready > 0 {
+			n := copy(p, f.buf[:ready])
+			f.buf = f.buf[:copy(f.buf, f.buf[n:])]
+			f.offset += int64(n)
+			return n, nil
+		}
+		if f.err != nil {
+			return 0, f.err
+		}
+
+		held := len(f.buf)
+		if free := cap(f.buf) - held; free < len(p) {
+			f.buf = append(make([]byte, 0, held+len(p)), f.buf...)
+		}
+		n, err := f.in.Read(f.buf[held : held+len(p)])
+		f.buf, f.err = f.buf[:held+n], err
+		// escapes are replaced along with what follows, except for one held back
+		for i := held - 1; i < len(f.buf); i++ {
+			if i < 0 {
+				continue
+			}
+			if f.buf[i] == f.escape && i+1 < len(f.buf) {
+				f.escaped = append(f.escaped, escapedChar{f.offset + int64(i), f.buf[i+1]})
+				utf8.EncodeRune(f.buf[i:], csvEscapePlaceholder)
+				i++
+			}
+		}
+	}
+}
+
+// restoreEscapes puts the escaped characters of the record at pos back in
+// place of the placeholders encoding/csv returns, unescaped in the fields
+func (p *csvPositions) restoreEscapes(record []string, pos *recordPos) {
+	if p.escapes == nil {
+		return
+	}
+	start := pos.offset - p.offset
+	escaped := p.escapes.escaped
+	for len(escaped) > 0 && escaped[0].offset < start {
+		escaped = escaped[1:] // within skipped lines or records
+	}
+	n := 0
+	for n < len(escaped) && escaped[n].offset < start+int64(len(pos.raw)) {
+		pos.raw[escaped[n].offset-start] = p.escapes.escape
+		pos.raw[escaped[n].offset-start+1] = escaped[n].c
+		n++
+	}
+	p.escapes.escaped = escaped[n:]
+
+	placeholder := string(csvEscapePlaceholder)
+	for i, field := range record {
+		if !strings.Contains(field, placeholder) {
+			continue
+		}
+		var b strings.Builder
+		for j := strings.Index(field, placeholder); j >= 0 && len(escaped) > 0; j = strings.Index(field, placeholder) {
+			b.WriteString(field[:j])
+			if c := escaped[0].c; c == 'N' {
+				b.WriteByte(p.escapes.escape)
+				b.WriteByte(c)
+			} else {
+				b.WriteByte(unescaped(c))
+			}
+			escaped, field = escaped[1:], field[j+len(placeholder):]
+		}
+		b.WriteString(field)
+		record[i] = b.String()
+	}
+}
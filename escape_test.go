@@ -0,0 +1,73 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	var data bytes.Buffer
+	var want [][]string
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&data, "%d,a\\,b,\"q\\\"uoted\",multi\\\nline,back\\\\slash,tab\\tx,\\N,%s\n", i, strings.Repeat("x", i%100))
+		want = append(want, []string{fmt.Sprint(i), "a,b", `q"uoted`, "multi\nline", `back\slash`, "tab\tx", `\N`, strings.Repeat("x", i%100)})
+	}
+
+	for _, size := range []int{0, 64, 1000, 50} {
+		for _, lazy := range []bool{false, true} {
+			input := data.Bytes()
+			if size == 50 {
+				input = input[:bytes.Index(input, []byte("\n2,"))+1] // small input
+			}
+			r := NewReader(bytes.NewReader(input))
+			r.Escape, r.ChunkSize, r.LazyQuotes = '\\', size, lazy
+			for i := 0; ; i++ {
+				record, err := r.ReadRecord()
+				if err == io.EOF {
+					if size != 50 && i != len(want) {
+						t.Errorf("TestEscape(%d, %v): got: %d records want: %d", size, lazy, i, len(want))
+					}
+					break
+				}
+				if err != nil || !reflect.DeepEqual(record.Fields, want[i]) || record.Line != 2*i+1 {
+					t.Fatalf("TestEscape(%d, %v): got: %q on line %d, %v want: %q on line %d", size, lazy, record.Fields, record.Line, err, want[i], 2*i+1)
+				}
+			}
+		}
+	}
+
+	r := NewReader(strings.NewReader("a\\,b,\"c\\\nd\",e\n"))
+	r.Escape = '\\'
+	if _, err := r.Read(); err != nil {
+		t.Fatalf("TestEscape: got: %v want: nil", err)
+	}
+	if line, column := r.FieldPos(2); line != 2 || column != 4 {
+		t.Errorf("TestEscape: got: field at %d:%d want: 2:4", line, column)
+	}
+
+	r = NewReader(strings.NewReader("a,b\n"))
+	r.Escape = ','
+	if _, err := r.Read(); err != errInvalidDelim {
+		t.Errorf("TestEscape: got: %v want: %v", err, errInvalidDelim)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"io"
+	"time"
+)
+
+// Faults injects failures into parsing, so that applications can test
+// their handling of errors and backpressure (see Reader.Faults). Every hook
+// is optional. The read hooks apply to input that is read from an
+// io.Reader, as opposed to in-memory input (see ReadBytes).
+type Faults struct {
+	// ShortRead returns the number of bytes, at most n, that a read of n
+	// bytes from the input is limited to.
+	ShortRead func(n int) int
+
+	// ReadDelay returns the delay before every read from the input.
+	ReadDelay func() time.Duration
+
+	// ReadError returns the error, if any, that the read at the given
+	// input offset fails with.
+	ReadError func(offset int64) error
+
+	// Fallback reports whether to hand the chunk with the given sequence
+	// to encoding/csv, as if the SIMD stages could not parse it. It is
+	// invoked concurrently by the parsing workers.
+	Fallback func(sequence int) bool
+}
+
+// reads reports whether any of the read hooks is set
+func (f *Faults) reads() bool {
+	return f != nil && (f.ShortRead != nil || f.ReadDelay != nil || f.ReadError != nil)
+}
+
+// fallback reports whether to force the fallback for a chunk
+func (f *Faults) fallback(sequence int) bool {
+	return f != nil && f.Fallback != nil && f.Fallback(sequence)
+}
+
+// faultReader injects the read faults into its input
+type faultReader struct {
+	in     io.Reader
+	faults *Faults
+	offset int64
+}
+
+func (fr *faultReader) Read(p []byte) (n int, err error) {
+	if fr.faults.ReadDelay != nil {
+		time.Sleep(fr.faults.ReadDelay())
+	}
+	if fr.faults.ReadError != nil {
+		if err := fr.faults.ReadError(fr.offset); err != nil {
+			return 0, err
+		}
+	}
+	if fr.faults.ShortRead != nil && len(p) > 0 {
+		if n := fr.faults.ShortRead(len(p)); n >= 0 && n < len(p) {
+			p = p[:n]
+		}
+	}
+	n, err = fr.in.Read(p)
+	fr.offset += int64(n)
+	return
+}
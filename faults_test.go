@@ -0,0 +1,169 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestFaults(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/worldcitiespop-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	want, err := encodingCsv(buf, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		var forced int32
+		r := NewReader(bytes.NewReader(buf))
+		r.Faults = &Faults{Fallback: func(sequence int) bool {
+			atomic.AddInt32(&forced, 1)
+			return true
+		}}
+		if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("TestFaults: got: %v want: records of encoding/csv", err)
+		}
+		if forced < 2 {
+			t.Errorf("TestFaults: got: %d forced fallbacks want: one per chunk", forced)
+		}
+	})
+
+	t.Run("short-reads", func(t *testing.T) {
+		input := buf[:bytes.IndexByte(buf[100000:], '\n')+100001]
+		reads := 0
+		r := NewReader(bytes.NewReader(input))
+		r.FallbackThreshold = -1
+		r.Faults = &Faults{
+			ShortRead: func(n int) int { return 4096 },
+			ReadDelay: func() time.Duration { reads++; return 0 },
+		}
+		if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want[:len(got)]) || len(got) == 0 {
+			t.Fatalf("TestFaults: got: %v want: records of encoding/csv", err)
+		}
+		if reads < 3 {
+			t.Errorf("TestFaults: got: %d reads want: short reads", reads)
+		}
+	})
+
+	injected := errors.New("injected")
+	readError := func(offset int64) error {
+		if offset >= 1<<20 {
+			return injected
+		}
+		return nil
+	}
+
+	t.Run("read-error", func(t *testing.T) {
+		r := NewReader(bytes.NewReader(buf))
+		r.Faults = &Faults{ReadError: readError}
+		if got, err := r.ReadAll(); err != injected {
+			t.Errorf("TestFaults: got: %d records, %v want: %v", len(got), err, injected)
+		}
+	})
+
+	for _, tc := range []struct {
+		name   string
+		faults *Faults
+	}{
+		{"read-error-records", &Faults{ReadError: readError}},
+		{"first-read-error", &Faults{
+			ShortRead: func(n int) int { return 4096 },
+			ReadError: func(offset int64) error {
+				if offset >= 4096 {
+					return injected
+				}
+				return nil
+			},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := &testLogger{}
+			r := NewReader(bytes.NewReader(buf))
+			r.Faults, r.Logger = tc.faults, logger
+			var got [][]string
+			for {
+				record, err := r.Read()
+				if err != nil {
+					if err != injected {
+						t.Fatalf("TestFaults: got: %v want: %v", err, injected)
+					}
+					break
+				}
+				got = append(got, record)
+			}
+			// the records read in full before the error come first
+			if len(got) == 0 || len(got) >= len(want) || !reflect.DeepEqual(got, want[:len(got)]) {
+				t.Errorf("TestFaults: got: %d records want: records of encoding/csv up to the read error", len(got))
+			}
+			if len(logger.errors) != 1 {
+				t.Errorf("TestFaults: got: %q want: the read error logged", logger.errors)
+			}
+		})
+	}
+}
+
+// testLogger collects the messages logged at the error level
+type testLogger struct {
+	errors []string
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) {}
+
+func (l *testLogger) Error(msg string, args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprint(append([]interface{}{msg}, args...)...))
+}
+
+func TestShortReads(t *testing.T) {
+	var data bytes.Buffer
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&data, "%d,\"multi\nline, %d\",%d\n", i, i, i*i)
+	}
+	want, err := encodingCsv(data.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		in   func() io.Reader
+	}{
+		{"one-byte", func() io.Reader { return iotest.OneByteReader(bytes.NewReader(data.Bytes())) }},
+		{"half", func() io.Reader { return iotest.HalfReader(bytes.NewReader(data.Bytes())) }},
+		{"data-eof", func() io.Reader { return iotest.DataErrReader(bytes.NewReader(data.Bytes())) }},
+		{"odd", func() io.Reader {
+			return &faultReader{in: bytes.NewReader(data.Bytes()), faults: &Faults{ShortRead: func(n int) int { return 1000 }}}
+		}},
+	} {
+		r := NewReader(tc.in())
+		r.ChunkSize = 4096
+		if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("TestShortReads(%s): got: %d records, %v want: %d records", tc.name, len(got), err, len(want))
+		}
+	}
+}
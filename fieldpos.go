@@ -0,0 +1,108 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FieldPos returns the line and column corresponding to the start of the
+// field with the given index in the record most recently returned by Read,
+// like encoding/csv.Reader.FieldPos. Numbering of lines and columns starts
+// at 1; columns are counted in bytes, not runes.
+//
+// If this is called with an out-of-bounds index, it panics.
+func (r *Reader) FieldPos(field int) (line, column int) {
+	r.Lock()
+	defer r.Unlock()
+
+	if field < 0 || r.lastPos.raw == nil {
+		panic("out of range index passed to FieldPos")
+	}
+	if r.proj != nil && field < len(r.proj.columns) && r.proj.columns[field] >= 0 {
+		field = r.proj.columns[field]
+	}
+	return r.fieldPos(r.lastPos.raw, r.lastPos.line, field)
+}
+
+// fieldPos locates the start of a field within the raw bytes of a record
+// that starts at the beginning of line, scanning the fields that precede
+// it the way encoding/csv parses them
+func (r *Reader) fieldPos(raw []byte, line, field int) (int, int) {
+	comma := []byte(r.delimiter())
+	lineStart, i := 0, 0
+
+	for f := 0; ; f++ {
+		if r.TrimLeadingSpace {
+			for i < len(raw) {
+				rn, size := utf8.DecodeRune(raw[i:])
+				if !unicode.IsSpace(rn) || rn == '\n' || rn == '\r' {
+					break
+				}
+				i += size
+			}
+		}
+		if f == field {
+			return line, i - lineStart + 1
+		}
+
+		if i < len(raw) && raw[i] == '"' {
+			// quoted field, which may span lines
+			for i++; i < len(raw); i++ {
+				if r.escapedAt(raw, i) {
+					if i++; i < len(raw) && raw[i] == '\n' {
+						line, lineStart = line+1, i+1
+					}
+				} else if raw[i] == '\n' {
+					line, lineStart = line+1, i+1
+				} else if raw[i] == '"' {
+					if i+1 < len(raw) && raw[i+1] == '"' {
+						i++ // escaped quote
+					} else if next := raw[i+1:]; !r.LazyQuotes || len(next) == 0 ||
+						bytes.HasPrefix(next, comma) || atTerminator(next) {
+						i++
+						break
+					}
+				}
+			}
+		}
+		for i < len(raw) && !atTerminator(raw[i:]) && !bytes.HasPrefix(raw[i:], comma) {
+			if r.escapedAt(raw, i) {
+				if i++; i < len(raw) && raw[i] == '\n' {
+					line, lineStart = line+1, i+1
+				}
+			}
+			i++
+		}
+		if !bytes.HasPrefix(raw[i:], comma) {
+			panic("out of range index passed to FieldPos")
+		}
+		i += len(comma)
+	}
+}
+
+// escapedAt reports whether raw has an escape at offset i
+func (r *Reader) escapedAt(raw []byte, i int) bool {
+	return r.Escape != 0 && raw[i] == byte(r.Escape)
+}
+
+// atTerminator reports whether b starts with a line terminator
+func atTerminator(b []byte) bool {
+	return len(b) > 0 && b[0] == '\n' || len(b) > 1 && b[0] == '\r' && b[1] == '\n'
+}
@@ -0,0 +1,88 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFieldPos(t *testing.T) {
+	corpus := selfTestCorpus()
+	corpus = append(corpus,
+		[]byte("a,\"b\nc\"\"\nd\",e\r\n\"f\",g\n"),
+		[]byte("a,  b,\t\"c\nd\",   e\n  f,g,h,i\n"),
+		[]byte("a;\"b\";c\n\n\nd;e;f\n"),
+		[]byte("a\"b,\"c\"d\",e\n"),
+		[]byte("a€\"b\nc\"€d\n"),
+	)
+
+	for _, input := range corpus {
+		for _, comma := range []rune{',', ';', '€'} {
+			for _, options := range []struct{ trim, lazy bool }{{}, {trim: true}, {lazy: true}} {
+				for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+					r := NewReader(bytes.NewReader(input))
+					r.Comma, r.TrimLeadingSpace, r.LazyQuotes = comma, options.trim, options.lazy
+					r.FieldsPerRecord, r.FallbackThreshold, r.kernel = -1, -1, kernel
+					cr := csv.NewReader(bytes.NewReader(input))
+					cr.Comma, cr.TrimLeadingSpace, cr.LazyQuotes = comma, options.trim, options.lazy
+					cr.FieldsPerRecord = -1
+					for n := 0; ; n++ {
+						want, err := cr.Read()
+						if err != nil {
+							break // only compare the records up to any error
+						}
+						record, err := r.Read()
+						if err != nil || strings.Join(record, "|") != strings.Join(want, "|") {
+							if err != nil && err != io.EOF {
+								break // differing error handling is covered elsewhere
+							}
+							t.Fatalf("TestFieldPos(%q, %q): record %d: got: %q, %v want: %q", input, comma, n, record, err, want)
+						}
+						for i := range want {
+							line, col := r.FieldPos(i)
+							wantLine, wantCol := cr.FieldPos(i)
+							if line != wantLine || col != wantCol {
+								t.Fatalf("TestFieldPos(%q, %q, %+v): record %d field %d: got: %d:%d want: %d:%d", input, comma, options, n, i, line, col, wantLine, wantCol)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFieldPosPanics(t *testing.T) {
+	r := NewReader(strings.NewReader("a,b\n"))
+	if _, err := r.Read(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, field := range []int{-1, 2} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("TestFieldPosPanics(%d): no panic", field)
+				}
+			}()
+			r.FieldPos(field)
+		}()
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// fixture returns header followed by n rows that cycle through the formats
+// of rows, each given the number of the row
+func fixture(header string, n int, rows ...string) string {
+	var b strings.Builder
+	b.WriteString(header)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, rows[i%len(rows)], i)
+	}
+	return b.String()
+}
+
+// fixtureRecords returns the records of n rows that cycle through the fields
+// of rows, each preceded by the number of the row
+func fixtureRecords(n int, rows ...[]string) (records [][]string) {
+	for i := 0; i < n; i++ {
+		records = append(records, append([]string{strconv.Itoa(i)}, rows[i%len(rows)]...))
+	}
+	return
+}
+
+// forModes calls f with the SIMD stages forced and disabled, each with the
+// given chunk sizes, or by default with one that splits a fixture into many
+// chunks and one that holds it whole
+func forModes(f func(mode SIMDMode, chunkSize int), chunkSizes ...int) {
+	if len(chunkSizes) == 0 {
+		chunkSizes = []int{4096, 1 << 20}
+	}
+	for _, mode := range []SIMDMode{SIMDForce, SIMDDisable} {
+		for _, chunkSize := range chunkSizes {
+			f(mode, chunkSize)
+		}
+	}
+}
+
+// readRecords reads all records of r, with ReadAll or by calling Read
+func readRecords(r *Reader, readAll bool) (records [][]string, err error) {
+	if readAll {
+		return r.ReadAll()
+	}
+	for {
+		var record []string
+		if record, err = r.Read(); err != nil {
+			break
+		}
+		records = append(records, record)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return
+}
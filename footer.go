@@ -0,0 +1,67 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import "errors"
+
+// errFooterWhere is returned when SkipFooter is combined with predicates,
+// which would have to tell the footer apart from the records they drop
+var errFooterWhere = errors.New("simdcsv: SkipFooter cannot be combined with WhereColumns or WhereNames")
+
+// holdFooter returns records, preceded by the records held back before,
+// except for the last SkipFooter ones, which are held back in turn until
+// more records follow, and are dropped at the end of the input
+func (r *Reader) holdFooter(records [][]string, positions []recordPos) ([][]string, []recordPos) {
+	if r.SkipFooter <= 0 {
+		return records, positions
+	}
+	if len(positions) != len(records) {
+		positions = make([]recordPos, len(records))
+	}
+	records = append(r.footer[:len(r.footer):len(r.footer)], records...)
+	positions = append(r.footerPositions[:len(r.footerPositions):len(r.footerPositions)], positions...)
+
+	n := len(records) - r.SkipFooter
+	if n < 0 {
+		n = 0
+	}
+	// the records held back outlive the chunk that their fields point into
+	r.footer = copyRecords(append([][]string(nil), records[n:]...))
+	r.footerPositions = append([]recordPos(nil), positions[n:]...)
+	for i := range r.footerPositions {
+		r.footerPositions[i].raw = append([]byte(nil), r.footerPositions[i].raw...)
+	}
+	return records[:n:n], positions[:n:n]
+}
+
+// csvReadFooter reads the next record from encoding/csv, holding back the
+// last SkipFooter records of the input
+func (r *Reader) csvReadFooter() ([]string, recordPos, error) {
+	if r.SkipFooter <= 0 {
+		return r.csvReadWhere()
+	}
+	for len(r.footer) <= r.SkipFooter {
+		record, pos, err := r.csvReadWhere()
+		if err != nil {
+			return nil, recordPos{}, err
+		}
+		r.footer, r.footerPositions = append(r.footer, record), append(r.footerPositions, pos)
+	}
+	record, pos := r.footer[0], r.footerPositions[0]
+	r.footer, r.footerPositions = r.footer[1:], r.footerPositions[1:]
+	return record, pos, nil
+}
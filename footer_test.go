@@ -0,0 +1,76 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSkipRowsFooter(t *testing.T) {
+	data := fixture("", 50000, "%[1]d,\"value \"\"%[1]d\"\"\"\n")
+	want, err := encodingCsv([]byte(data), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	preamble := "\"report\nof today\",x\n\n# generated\ncolumns,\"a\n\nb\"\n"
+	footer := "total,1\n\"end\nof\",report\n"
+	input := []byte(preamble + data + footer)
+
+	forModes(func(mode SIMDMode, chunkSize int) {
+		for _, inMemory := range []bool{false, true} {
+			r := NewReader(bytes.NewReader(input))
+			if inMemory {
+				r = newBytesReader(input)
+			}
+			r.SIMD, r.ChunkSize, r.Comment = mode, chunkSize, '#'
+			r.SkipRows, r.SkipFooter = 2, 2
+
+			first, err := r.ReadRecord()
+			if err != nil || !reflect.DeepEqual(first.Fields, want[0]) || first.Line != 8 {
+				t.Fatalf("TestSkipRowsFooter(%d, %v): got: %q on line %d, %v want: %q on line 8", mode, inMemory, first.Fields, first.Line, err, want[0])
+			}
+			got, err := r.ReadAll()
+			if err != nil || !reflect.DeepEqual(append([][]string{first.Fields}, got...), want) {
+				t.Fatalf("TestSkipRowsFooter(%d, %v): got: %d records, %v want: %d", mode, inMemory, len(got)+1, err, len(want))
+			}
+
+			if !inMemory {
+				if err := r.SeekRecord(len(want) - 1); err != nil {
+					t.Fatalf("TestSkipRowsFooter(%d): got: %v want: nil", mode, err)
+				}
+				if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want[len(want)-1:]) {
+					t.Errorf("TestSkipRowsFooter(%d): got: %q, %v after seeking want: %q", mode, got, err, want[len(want)-1:])
+				}
+			}
+		}
+	}, 4096)
+
+	// fewer records than the footer
+	r := NewReader(bytes.NewReader([]byte("a,b\n")))
+	r.SkipFooter = 2
+	if got, err := r.ReadAll(); err != nil || len(got) != 0 {
+		t.Errorf("TestSkipRowsFooter: got: %q, %v want: no records", got, err)
+	}
+
+	r = NewReader(bytes.NewReader(input))
+	r.SkipFooter, r.WhereColumns = 1, map[int]Predicate{0: Equal("0")}
+	if _, err := r.ReadAll(); err != errFooterWhere {
+		t.Errorf("TestSkipRowsFooter: got: %v want: %v", err, errFooterWhere)
+	}
+}
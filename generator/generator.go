@@ -0,0 +1,203 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package generator produces synthetic CSV data with controllable
+// properties, for benchmarking and fuzz-testing CSV pipelines against
+// realistic data shapes.
+package generator
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+)
+
+// Options controls the shape of the generated CSV. Densities are
+// probabilities between 0 and 1.
+type Options struct {
+	Seed    int64 // seed of the random source, equal seeds give equal output
+	Columns int   // number of fields per record (default 8)
+
+	MinFieldLength int // minimum length of a field (before quoting)
+	MaxFieldLength int // maximum length of a field (default 16)
+
+	QuoteDensity     float64 // fraction of fields that are quoted
+	EscapeDensity    float64 // fraction of quoted fields containing escaped quotes
+	SeparatorDensity float64 // fraction of quoted fields containing separators
+	NewlineDensity   float64 // fraction of quoted fields containing embedded newlines
+	CRLFDensity      float64 // fraction of records terminated by \r\n instead of \n
+
+	MalformedDensity float64 // fraction of records with an injected malformation
+}
+
+// Malformation identifies the kind of malformation injected into a record
+type Malformation int
+
+const (
+	// None denotes a well-formed record
+	None Malformation = iota
+	// BareQuote is a quote within an unquoted field
+	BareQuote
+	// ExtraneousQuote is a character following the closing quote of a field
+	ExtraneousQuote
+	// FieldCount is a record with a wrong number of fields
+	FieldCount
+)
+
+// Record is a single generated record
+type Record struct {
+	Raw          []byte       // the record as written, including its terminator
+	Fields       []string     // the fields as encoding/csv parses them, unless malformed
+	Malformation Malformation // malformation injected into the record, if any
+}
+
+// Generator produces random records according to its options
+type Generator struct {
+	opts Options
+	rnd  *rand.Rand
+}
+
+const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 ._-"
+
+// New returns a Generator for opts
+func New(opts Options) *Generator {
+	if opts.Columns <= 0 {
+		opts.Columns = 8
+	}
+	if opts.MaxFieldLength <= 0 {
+		opts.MaxFieldLength = 16
+	}
+	if opts.MinFieldLength > opts.MaxFieldLength {
+		opts.MinFieldLength = opts.MaxFieldLength
+	}
+	return &Generator{opts: opts, rnd: rand.New(rand.NewSource(opts.Seed))}
+}
+
+// Next generates the next record
+func (g *Generator) Next() Record {
+	var raw bytes.Buffer
+	rcd := Record{}
+	if g.chance(g.opts.MalformedDensity) {
+		rcd.Malformation = Malformation(1 + g.rnd.Intn(3))
+	}
+
+	columns := g.opts.Columns
+	if rcd.Malformation == FieldCount {
+		if columns == 1 || g.rnd.Intn(2) == 0 {
+			columns++
+		} else {
+			columns--
+		}
+	}
+	malformed := -1 // field receiving a quote malformation
+	if rcd.Malformation == BareQuote || rcd.Malformation == ExtraneousQuote {
+		malformed = g.rnd.Intn(columns)
+	}
+	crlf := g.chance(g.opts.CRLFDensity)
+
+	for c := 0; c < columns; c++ {
+		if c > 0 {
+			raw.WriteByte(',')
+		}
+		field := g.field(columns)
+		switch {
+		case c == malformed && rcd.Malformation == BareQuote:
+			i := g.rnd.Intn(len(field) + 1)
+			if i == 0 {
+				field += "x" // a leading quote would start a quoted field
+				i = len(field)
+			}
+			raw.WriteString(field[:i] + `"` + field[i:])
+		case c == malformed && rcd.Malformation == ExtraneousQuote:
+			raw.WriteString(`"` + field + `"x`)
+		case g.chance(g.opts.QuoteDensity):
+			field = g.quoted(field)
+			raw.WriteByte('"')
+			escaped := strings.ReplaceAll(field, `"`, `""`)
+			if crlf {
+				escaped = strings.ReplaceAll(escaped, "\n", "\r\n")
+			}
+			raw.WriteString(escaped)
+			raw.WriteByte('"')
+		default:
+			raw.WriteString(field)
+		}
+		rcd.Fields = append(rcd.Fields, field)
+	}
+	if crlf {
+		raw.WriteByte('\r')
+	}
+	raw.WriteByte('\n')
+
+	rcd.Raw = raw.Bytes()
+	if rcd.Malformation != None {
+		rcd.Fields = nil
+	}
+	return rcd
+}
+
+// Generate writes n records to w
+func (g *Generator) Generate(w io.Writer, n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := w.Write(g.Next().Raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bytes returns n records
+func (g *Generator) Bytes(n int) []byte {
+	var buf bytes.Buffer
+	g.Generate(&buf, n)
+	return buf.Bytes()
+}
+
+func (g *Generator) chance(p float64) bool {
+	return p > 0 && g.rnd.Float64() < p
+}
+
+// field returns a random unquoted field
+func (g *Generator) field(columns int) string {
+	n := g.opts.MinFieldLength + g.rnd.Intn(g.opts.MaxFieldLength-g.opts.MinFieldLength+1)
+	if n == 0 && columns == 1 {
+		n = 1 // a record with a single empty field is an empty line
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[g.rnd.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+// quoted adds the characters that require quoting to a field
+func (g *Generator) quoted(field string) string {
+	insert := func(s string) {
+		i := g.rnd.Intn(len(field) + 1)
+		field = field[:i] + s + field[i:]
+	}
+	if g.chance(g.opts.EscapeDensity) {
+		insert(`"`)
+	}
+	if g.chance(g.opts.SeparatorDensity) {
+		insert(",")
+	}
+	if g.chance(g.opts.NewlineDensity) {
+		insert("\n")
+	}
+	return field
+}
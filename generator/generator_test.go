@@ -0,0 +1,89 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestGenerator(t *testing.T) {
+
+	opts := Options{
+		Seed:             1,
+		Columns:          5,
+		MaxFieldLength:   12,
+		QuoteDensity:     0.5,
+		EscapeDensity:    0.3,
+		SeparatorDensity: 0.3,
+		NewlineDensity:   0.3,
+		CRLFDensity:      0.5,
+	}
+
+	g := New(opts)
+	var buf bytes.Buffer
+	var want [][]string
+	for i := 0; i < 1000; i++ {
+		rcd := g.Next()
+		buf.Write(rcd.Raw)
+		want = append(want, rcd.Fields)
+	}
+
+	got, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("TestGenerator: got: %v want: nil", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TestGenerator: mismatch between generated and parsed records")
+	}
+
+	if !bytes.Equal(New(opts).Bytes(100), New(opts).Bytes(100)) {
+		t.Errorf("TestGenerator: same seed gives different output")
+	}
+}
+
+func TestGeneratorMalformed(t *testing.T) {
+
+	errs := map[Malformation]error{
+		BareQuote:       csv.ErrBareQuote,
+		ExtraneousQuote: csv.ErrQuote,
+		FieldCount:      csv.ErrFieldCount,
+	}
+
+	g := New(Options{Seed: 2, Columns: 3, QuoteDensity: 0.5, MalformedDensity: 0.5})
+	for i := 0; i < 1000; i++ {
+		rcd := g.Next()
+		r := csv.NewReader(bytes.NewReader(rcd.Raw))
+		r.FieldsPerRecord = 3
+		record, err := r.Read()
+		if rcd.Malformation == None {
+			if err != nil || !reflect.DeepEqual(record, rcd.Fields) {
+				t.Fatalf("TestGeneratorMalformed: got: %q, %v want: %q", record, err, rcd.Fields)
+			}
+		} else if !errors.Is(err, errs[rcd.Malformation]) {
+			t.Fatalf("TestGeneratorMalformed: got: %v want: %v for %q", err, errs[rcd.Malformation], rcd.Raw)
+		}
+	}
+
+	if err := g.Generate(io.Discard, 10); err != nil {
+		t.Errorf("TestGeneratorMalformed: got: %v want: nil", err)
+	}
+}
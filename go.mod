@@ -1,5 +1,7 @@
 module github.com/minio/simdcsv
 
-go 1.15
+go 1.19
 
 require github.com/klauspost/cpuid/v2 v2.0.3
+
+require github.com/klauspost/compress v1.17.4
@@ -0,0 +1,60 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+// ReadHeader reads the first record of the input as the header, which names
+// the fields of the records that ReadMap returns. Once records have been
+// read, ReadHeader returns the header without reading. The header is
+// normalized by NormalizeHeader, if set.
+func (r *Reader) ReadHeader() ([]string, error) {
+	if r.RecordNumber() == 0 {
+		if _, err := r.Read(); err != nil {
+			return nil, err
+		}
+	}
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.header...), nil
+}
+
+// ReadMap reads the next record as a map from the names in the header to the
+// fields, reading the header first if need be (see ReadHeader). Fields beyond
+// the header are left out, and of columns with the same name the last one
+// is kept.
+func (r *Reader) ReadMap() (map[string]string, error) {
+	if r.RecordNumber() == 0 {
+		if _, err := r.ReadHeader(); err != nil {
+			return nil, err
+		}
+	}
+	record, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	header := r.header
+	r.Unlock()
+
+	m := make(map[string]string, len(header))
+	for i, field := range record {
+		if i < len(header) {
+			m[header[i]] = field
+		}
+	}
+	return m, nil
+}
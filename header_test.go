@@ -0,0 +1,62 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReadMap(t *testing.T) {
+	var data bytes.Buffer
+	data.WriteString("City Name,Population,Country\n")
+	for i := 0; data.Len() < 1<<20; i++ {
+		fmt.Fprintf(&data, "city %d,%d,\"country, %d\"\n", i, i*1000, i%100)
+	}
+
+	r := NewReader(bytes.NewReader(data.Bytes()))
+	r.NormalizeHeader = SnakeCase
+	header, err := r.ReadHeader()
+	if want := []string{"city_name", "population", "country"}; err != nil || !reflect.DeepEqual(header, want) {
+		t.Fatalf("TestReadMap: got: %q, %v want: %q", header, err, want)
+	}
+	for i := 0; ; i++ {
+		record, err := r.ReadMap()
+		if err == io.EOF {
+			break
+		}
+		want := map[string]string{"city_name": fmt.Sprint("city ", i), "population": fmt.Sprint(i * 1000), "country": fmt.Sprint("country, ", i%100)}
+		if err != nil || !reflect.DeepEqual(record, want) {
+			t.Fatalf("TestReadMap: got: %v, %v want: %v", record, err, want)
+		}
+	}
+	if header, err := r.ReadHeader(); err != nil || len(header) != 3 {
+		t.Errorf("TestReadMap: got: %q, %v after reading want: the header", header, err)
+	}
+
+	// the header is read implicitly
+	r = NewReader(bytes.NewReader([]byte("a,b\n1,2\n")))
+	if record, err := r.ReadMap(); err != nil || !reflect.DeepEqual(record, map[string]string{"a": "1", "b": "2"}) {
+		t.Errorf("TestReadMap: got: %v, %v want: map[a:1 b:2]", record, err)
+	}
+	if _, err := r.ReadMap(); err != io.EOF {
+		t.Errorf("TestReadMap: got: %v want: %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+// An Option configures a Reader before any records are read, for instance
+//
+//	func(r *simdcsv.Reader) { r.Comma = ';' }
+type Option func(r *Reader)
+
+// ReadBytes parses all records contained in b.
+//
+// The input is handed to the parsing stages without copying, so the
+// returned fields share memory with b; b must not be modified afterwards.
+func ReadBytes(b []byte, opts ...Option) ([][]string, error) {
+	return newBytesReader(b, opts...).ReadAll()
+}
+
+// ReadFile parses all records of the named file.
+func ReadFile(path string, opts ...Option) ([][]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ReadBytes(b, opts...)
+}
+
+// FilterCopy copies the records of src for which keep returns true to dst,
+// byte for byte as they appear in src (including their line terminators),
+// so the formatting is preserved exactly. Lines that hold no record, such
+// as empty lines and comments, are dropped. It returns the number of
+// records copied.
+func FilterCopy(dst io.Writer, src io.Reader, keep func(record []string) bool, opts ...Option) (int, error) {
+	r := NewReader(src)
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.KeepRaw = true
+
+	w := bufio.NewWriter(dst)
+	copied := 0
+	for {
+		record, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return copied, err
+		}
+		if keep(record.Fields) {
+			if _, err := w.Write(r.lastPos.raw); err != nil {
+				return copied, err
+			}
+			copied++
+		}
+	}
+	return copied, w.Flush()
+}
+
+// newBytesReader returns a Reader that slices chunks directly out of b
+func newBytesReader(b []byte, opts ...Option) *Reader {
+	r := NewReader(bytes.NewReader(b))
+	r.data, r.inMemory = b, true
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
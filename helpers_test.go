@@ -0,0 +1,127 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	for _, test := range []struct {
+		filename string
+		sep      rune
+	}{
+		{"testdata/parking-citations-100K.csv", ','},
+		{"testdata/worldcitiespop-100K.csv", ','},
+		{"testdata/nyc-taxi-data-100K.csv", ','},
+		{"testdata/part.tbl", '|'},
+	} {
+		buf, err := ioutil.ReadFile(test.filename)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		records, err := encodingCsv(buf, test.sep)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		h := sha256.New()
+		simdrecords, err := ReadFile(test.filename, func(r *Reader) {
+			r.Comma = test.sep
+			r.InputHash = h
+		})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if !reflect.DeepEqual(simdrecords, records) {
+			t.Errorf("TestReadFile(%s): got: %v want: %v", test.filename, len(simdrecords), len(records))
+		}
+		if want := sha256.Sum256(buf); !reflect.DeepEqual(h.Sum(nil), want[:]) {
+			t.Errorf("TestReadFile(%s): digest mismatch", test.filename)
+		}
+	}
+}
+
+func TestReadBytes(t *testing.T) {
+	for _, input := range []string{"", "a,b\nc,d\n", "a;b\n\"c\"\"\";d"} {
+		want, err := encodingCsv([]byte(input), ';')
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(want) == 0 {
+			want = nil
+		}
+		for _, threshold := range []int{-1, 0} {
+			got, err := ReadBytes([]byte(input), func(r *Reader) {
+				r.Comma = ';'
+				r.FallbackThreshold = threshold
+			})
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("TestReadBytes(%q): got: %q want: %q", input, got, want)
+			}
+		}
+	}
+	if records, err := ReadBytes(nil); records != nil || err != nil {
+		t.Errorf("TestReadBytes(nil): got: %v, %v", records, err)
+	}
+}
+
+func TestFilterCopy(t *testing.T) {
+	var big, bigWant bytes.Buffer
+	for i := 0; big.Len() < 1<<20; i++ {
+		line := fmt.Sprintf("%d,\"value \"\"%d\"\"\", x%d\n", i, i, i%7)
+		if i%3 == 0 {
+			line = line[:len(line)-1] + "\r\n"
+		}
+		big.WriteString(line)
+		if i%2 == 0 {
+			bigWant.WriteString(line)
+		}
+	}
+
+	keepEven := func(record []string) bool {
+		i, err := strconv.Atoi(record[0])
+		return err == nil && i%2 == 0
+	}
+
+	for _, tc := range []struct {
+		input, want string
+	}{
+		{"0,\"a\nb\"\r\n1,c\n\n# comment\n2,d", "0,\"a\nb\"\r\n2,d"},
+		{big.String(), bigWant.String()},
+	} {
+		for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+			var out bytes.Buffer
+			n, err := FilterCopy(&out, strings.NewReader(tc.input), keepEven, func(r *Reader) {
+				r.kernel, r.Comment = kernel, '#'
+			})
+			if err != nil || out.String() != tc.want {
+				t.Fatalf("TestFilterCopy: got: %d records, %v want: %d bytes", n, err, len(tc.want))
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// indexMagic starts every index written by an Indexer
+var indexMagic = []byte("simdcsv\x01")
+
+// defaultIndexEvery is the default number of records between index entries
+const defaultIndexEvery = 4096
+
+var errInvalidIndex = errors.New("simdcsv: invalid index")
+
+// An Indexer scans the records of the input once, writing a compact index
+// of the input offsets at which every so many records start. OpenIndexed
+// loads the index to seek records without reparsing the input that precedes
+// them.
+type Indexer struct {
+	// Every is the number of records between the entries of the index,
+	// which trades the size of the index for the number of records parsed
+	// in vain when seeking. It defaults to 4096.
+	Every int
+}
+
+// Index reads all records of r, writing the index to w, and returns the
+// number of records. The Reader that later uses the index must be configured
+// like r, so that it counts the same records.
+func (ix *Indexer) Index(w io.Writer, r *Reader) (int, error) {
+	every := ix.Every
+	if every <= 0 {
+		every = defaultIndexEvery
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write(indexMagic)
+	var buf [2 * binary.MaxVarintLen64]byte
+	bw.Write(binary.AppendUvarint(buf[:0], uint64(every)))
+
+	r.Lock()
+	defer r.Unlock()
+
+	// entries hold the deltas of the offset and line from the previous one
+	prev := checkpoint{line: 1}
+	n := 0
+	for ; ; n++ {
+		if err := r.next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return n, err
+		}
+		if n == 0 || n%every != 0 {
+			continue
+		}
+		cp := checkpoint{n, r.lastPos.offset, r.lastPos.line}
+		entry := binary.AppendUvarint(buf[:0], uint64(cp.offset-prev.offset))
+		entry = binary.AppendUvarint(entry, uint64(cp.line-prev.line))
+		if _, err := bw.Write(entry); err != nil {
+			return n, err
+		}
+		prev = cp
+	}
+	return n, bw.Flush()
+}
+
+// OpenIndexed returns a Reader for the input at ra, as indexed by an
+// Indexer, with which SeekRecord and ReadRange resume parsing from the
+// closest entry of the index preceding the record sought. The Reader must be
+// configured like the one that was indexed before reading from it.
+func OpenIndexed(ra io.ReaderAt, index io.Reader) (*Reader, error) {
+	checkpoints, err := readIndex(bufio.NewReader(index))
+	if err != nil {
+		return nil, err
+	}
+	r := NewReader(io.NewSectionReader(ra, 0, math.MaxInt64))
+	r.ra, r.index = ra, checkpoints
+	return r, nil
+}
+
+// readIndex reads the checkpoints of an index written by an Indexer
+func readIndex(in *bufio.Reader) ([]checkpoint, error) {
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(in, magic); err != nil || !bytes.Equal(magic, indexMagic) {
+		return nil, errInvalidIndex
+	}
+	every, err := binary.ReadUvarint(in)
+	if err != nil || every == 0 || every > math.MaxInt32 {
+		return nil, errInvalidIndex
+	}
+
+	var checkpoints []checkpoint
+	cp := checkpoint{line: 1}
+	for {
+		offset, err := binary.ReadUvarint(in)
+		if err == io.EOF {
+			return checkpoints, nil
+		}
+		line, lineErr := binary.ReadUvarint(in)
+		if err != nil || lineErr != nil || offset > math.MaxInt64-uint64(cp.offset) || line > math.MaxInt32 {
+			return nil, errInvalidIndex
+		}
+		cp.records += int(every)
+		cp.offset += int64(offset)
+		cp.line += int(line)
+		checkpoints = append(checkpoints, cp)
+	}
+}
+
+// ReadRange returns the count records that follow the first n records (see
+// SeekRecord), or fewer if the input ends before.
+func (r *Reader) ReadRange(n, count int) ([][]string, error) {
+	if err := r.SeekRecord(n); err != nil {
+		return nil, err
+	}
+	records := make([][]string, 0, count)
+	for len(records) < count {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return records, err
+		}
+		if r.ReuseRecord {
+			record = append([]string(nil), record...)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,99 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// offsetsReader records the lowest offset read from its input
+type offsetsReader struct {
+	io.ReaderAt
+	lowest int64
+}
+
+func (o *offsetsReader) ReadAt(p []byte, off int64) (int, error) {
+	for lowest := atomic.LoadInt64(&o.lowest); off < lowest; lowest = atomic.LoadInt64(&o.lowest) {
+		if atomic.CompareAndSwapInt64(&o.lowest, lowest, off) {
+			break
+		}
+	}
+	return o.ReaderAt.ReadAt(p, off)
+}
+
+func TestIndexer(t *testing.T) {
+	var data bytes.Buffer
+	var want [][]string
+	var lines []int
+	for i, line := 0, 1; i < 20000; i++ {
+		fmt.Fprintf(&data, "%d,\"quoted\n%s\",%d\n", i, strings.Repeat("x", i%100), i*i)
+		want = append(want, []string{fmt.Sprint(i), "quoted\n" + strings.Repeat("x", i%100), fmt.Sprint(i * i)})
+		lines = append(lines, line)
+		line += 2
+	}
+
+	var index bytes.Buffer
+	n, err := (&Indexer{Every: 1000}).Index(&index, NewReader(bytes.NewReader(data.Bytes())))
+	if err != nil || n != len(want) {
+		t.Fatalf("TestIndexer: got: %d records, %v want: %d records", n, err, len(want))
+	}
+	if index.Len() > 20*8 {
+		t.Errorf("TestIndexer: got: index of %d bytes want: at most %d", index.Len(), 20*8)
+	}
+
+	for _, n := range []int{0, 999, 1000, 12345, 19999} {
+		in := &offsetsReader{ReaderAt: bytes.NewReader(data.Bytes()), lowest: int64(data.Len())}
+		r, err := OpenIndexed(in, bytes.NewReader(index.Bytes()))
+		if err != nil {
+			t.Fatalf("TestIndexer: %v", err)
+		}
+		r.ChunkSize = 4096
+		if err := r.SeekRecord(n); err != nil {
+			t.Fatalf("TestIndexer(%d): got: %v want: nil", n, err)
+		}
+		record, err := r.ReadRecord()
+		if err != nil || !reflect.DeepEqual(record.Fields, want[n]) || record.Line != lines[n] {
+			t.Errorf("TestIndexer(%d): got: %q on line %d, %v want: %q on line %d", n, record.Fields, record.Line, err, want[n], lines[n])
+		}
+		if start := strings.Index(data.String(), fmt.Sprintf("\n%d,", n/1000*1000)) + 1; n >= 1000 && in.lowest < int64(start) {
+			t.Errorf("TestIndexer(%d): got: read from %d want: from %d", n, in.lowest, start)
+		}
+
+		records, err := r.ReadRange(n, 10)
+		if end := n + 10; end > len(want) {
+			want := want[n:]
+			if err != nil || !reflect.DeepEqual(records, want) {
+				t.Errorf("TestIndexer(%d): got: %d records, %v want: %d records", n, len(records), err, len(want))
+			}
+		} else if err != nil || !reflect.DeepEqual(records, want[n:end]) {
+			t.Errorf("TestIndexer(%d): got: %d records, %v want: %d records", n, len(records), err, 10)
+		}
+	}
+
+	if _, err := OpenIndexed(bytes.NewReader(data.Bytes()), strings.NewReader("not an index")); err != errInvalidIndex {
+		t.Errorf("TestIndexer: got: %v want: %v", err, errInvalidIndex)
+	}
+	if _, err := OpenIndexed(bytes.NewReader(data.Bytes()), bytes.NewReader(index.Bytes()[:index.Len()-1])); err != errInvalidIndex {
+		t.Errorf("TestIndexer: got: %v want: %v", err, errInvalidIndex)
+	}
+}
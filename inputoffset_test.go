@@ -0,0 +1,79 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestInputOffset(t *testing.T) {
+	corpus := selfTestCorpus()
+	corpus = append(corpus,
+		[]byte("a,\"b\nc\"\"\nd\",e\r\n\n\n\"f\",g\n"),
+		[]byte("# comment\na,b\n#\n# x,\"y\nc,d\r\n#"),
+		[]byte("#/ comment\na,b\n#/\n#/ x,\"y\nc,d\r\n\"#/ e\",f"),
+	)
+
+	for _, input := range corpus {
+		for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+			for _, prefix := range []string{"", "//"} {
+				if prefix != "" && !bytes.HasPrefix(input, []byte("#/")) {
+					continue
+				}
+				// CommentPrefix skips the same lines as Comment does for the
+				// input with the prefix replaced by a comment character
+				in := input
+				if prefix != "" {
+					in = bytes.ReplaceAll(input, []byte("#/"), []byte(prefix))
+				}
+				cr := csv.NewReader(bytes.NewReader(input))
+				cr.Comment, cr.FieldsPerRecord = '#', -1
+
+				r := NewReader(bytes.NewReader(in))
+				r.FieldsPerRecord, r.FallbackThreshold, r.kernel = -1, -1, kernel
+				if prefix != "" {
+					r.CommentPrefix = prefix
+				} else {
+					r.Comment = '#'
+				}
+				if offset := r.InputOffset(); offset != 0 {
+					t.Errorf("TestInputOffset: got: %d want: 0", offset)
+				}
+				for n := 0; ; n++ {
+					want, err := cr.Read()
+					if err != nil {
+						break
+					}
+					record, err := r.Read()
+					if err != nil && err != io.EOF {
+						break
+					}
+					if err != nil || strings.Join(record, "|") != strings.ReplaceAll(strings.Join(want, "|"), "#/", string(in[:2])) {
+						t.Fatalf("TestInputOffset(%q, %q): record %d: got: %q, %v want: %q", in, prefix, n, record, err, want)
+					}
+					if got, want := r.InputOffset(), cr.InputOffset(); got != want {
+						t.Fatalf("TestInputOffset(%q, %q): record %d: got: %d want: %d", in, prefix, n, got, want)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+//go:build go1.23
+
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"errors"
+	"io"
+	"iter"
+)
+
+var errIterationStopped = errors.New("simdcsv: iteration over records stopped")
+
+// Records returns an iterator over the remaining records of r, to be ranged
+// over like
+//
+//	for record, err := range r.Records() {
+//
+// An error that ends the input is yielded last, except for io.EOF. The
+// records are yielded as returned by Read, so ReuseRecord applies. Breaking
+// out of the loop early tears down the parsing stages, after which reading
+// from r fails.
+func (r *Reader) Records() iter.Seq2[[]string, error] {
+	return func(yield func([]string, error) bool) {
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				return
+			}
+			if !yield(record, err) {
+				if err == nil {
+					r.Lock()
+					r.abort(errIterationStopped)
+					r.Unlock()
+				}
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+//go:build go1.23
+
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	input := selfTestCorpus()
+	big := input[len(input)-1]
+	want, err := encodingCsv(big, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		r := NewReader(bytes.NewReader(big))
+		r.kernel = kernel
+		var got [][]string
+		for record, err := range r.Records() {
+			if err != nil {
+				t.Fatalf("TestRecords: got: %v want: nil", err)
+			}
+			got = append(got, record)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("TestRecords: got: %d records want: %d", len(got), len(want))
+		}
+	}
+}
+
+func TestRecordsError(t *testing.T) {
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		r := NewReader(strings.NewReader("a,b\nc,d\"\ne,f\n"))
+		r.kernel = kernel
+		var errs []error
+		for _, err := range r.Records() {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) != 1 {
+			t.Fatalf("TestRecordsError: got: %v want: 1 error", errs)
+		}
+		if _, ok := errs[0].(*csv.ParseError); !ok {
+			t.Errorf("TestRecordsError: got: %v want: %T", errs[0], &csv.ParseError{})
+		}
+	}
+}
+
+func TestRecordsBreak(t *testing.T) {
+	input := selfTestCorpus()
+	big := input[len(input)-1]
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		baseline := runtime.NumGoroutine()
+
+		r := NewReader(bytes.NewReader(big))
+		r.kernel = kernel
+		r.ChunkSize = 1 << 10
+		n := 0
+		for range r.Records() {
+			if n++; n == 10 {
+				break
+			}
+		}
+		waitGoroutines(t, baseline)
+
+		if _, err := r.Read(); err != errIterationStopped {
+			t.Errorf("TestRecordsBreak: got: %v want: %v", err, errIterationStopped)
+		}
+	}
+}
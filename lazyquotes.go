@@ -0,0 +1,229 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"math/bits"
+)
+
+// lazyQuotes keeps the SIMD stages going for LazyQuotes: the quotes of every
+// chunk are checked to be well-formed, in which case the chunk parses the
+// same regardless of LazyQuotes. A chunk with stray quotes is rescanned the
+// way encoding/csv parses it, to determine its rows and the quoted state at
+// its end, and handed to encoding/csv. Without LazyQuotes, such a chunk is
+// rescanned likewise, as encoding/csv ends its rows in the same places up to
+// the records that it rejects anyway.
+type lazyQuotes struct {
+	comma   []byte   // field delimiter
+	escape  byte     // escape character, if any
+	comment []string // prefixes of comment lines, if any
+	line    []byte   // last line as resolved, unless within quotes
+	within  bool     // whether line is a comment that continues
+	tail    []byte   // last bytes of the previous chunk
+	pending []byte   // bytes following a quote that closed the previous chunk
+}
+
+func newLazyQuotes(comma string, escape byte) *lazyQuotes {
+	return &lazyQuotes{comma: []byte(comma), escape: escape, tail: []byte{'\n'}}
+}
+
+// wellFormed reports whether all quotes of buf open a field or close one, as
+// marked by the stage 1 masks, given the quoted state at the start of buf
+func (l *lazyQuotes) wellFormed(buf []byte, masks []uint64, quoted uint64, last bool) bool {
+	if len(buf) == 0 {
+		return true
+	}
+	if l.pending != nil {
+		// check the quote along with the bytes that follow in buf
+		following := append(l.pending, buf[:l.context(buf)]...)
+		if l.pending = nil; !l.closes(following, 0, last) {
+			return false
+		}
+	}
+
+	for b := 0; b*64 < len(buf) && b*3+2 < len(masks); b++ {
+		quotes := masks[b*3+2]
+		if rem := len(buf) - b*64; rem < 64 {
+			quotes &= 1<<rem - 1
+		}
+		inQuotes := prefixXor(quotes) ^ quoted
+		quoted = uint64(int64(inQuotes) >> 63)
+
+		for q := quotes; q != 0; q &= q - 1 {
+			p := b*64 + bits.TrailingZeros64(q)
+			if inQuotes&(1<<(p&63)) != 0 {
+				// an opening quote starts a field, or escapes a quote
+				if !l.opens(buf, p) {
+					return false
+				}
+			} else if !l.closes(buf, p+1, last) {
+				return false
+			}
+		}
+	}
+	l.tail = append(l.tail[:0], buf[len(buf)-l.context(buf):]...)
+	return true
+}
+
+// context returns the number of bytes of buf that may be needed to tell
+// whether a quote at either of its ends is well-formed
+func (l *lazyQuotes) context(buf []byte) int {
+	if n := len(l.comma) + 1; n < len(buf) {
+		return n
+	}
+	return len(buf)
+}
+
+// opens reports whether an opening quote may be at offset i of buf
+func (l *lazyQuotes) opens(buf []byte, i int) bool {
+	preceding := buf[:i]
+	if i < len(l.comma) {
+		preceding = append(l.tail[:len(l.tail):len(l.tail)], preceding...)
+	}
+	if len(preceding) == 0 {
+		return true // the start of the input
+	}
+	switch preceding[len(preceding)-1] {
+	case '\n', '"':
+		return true
+	}
+	return bytes.HasSuffix(preceding, l.comma)
+}
+
+// closes reports whether a closing quote may precede offset i of buf. A
+// quote that closes a chunk other than the last is checked once the bytes
+// that follow are known.
+func (l *lazyQuotes) closes(buf []byte, i int, last bool) bool {
+	following := buf[i:]
+	if len(following) == 0 {
+		if !last {
+			l.pending = []byte{}
+		}
+		return true
+	}
+	switch following[0] {
+	case '\n', '"':
+		return true
+	case '\r':
+		if len(following) > 1 {
+			return following[1] == '\n'
+		}
+	default:
+		if len(following) >= len(l.comma) {
+			return bytes.HasPrefix(following, l.comma)
+		} else if !bytes.HasPrefix(l.comma, following) {
+			return false
+		}
+	}
+	if !last {
+		l.pending = append([]byte{}, following...)
+	}
+	return !last
+}
+
+// States of encoding/csv while parsing with LazyQuotes
+const (
+	lazyFieldStart = iota
+	lazyUnquoted
+	lazyQuoted
+	lazyQuotedQuote // a quote within a quoted field, which may close it
+)
+
+// resolve scans the row split from the previous chunk and chunk the way
+// encoding/csv parses them with LazyQuotes. It returns the header and trailer
+// of chunk (see stage1Streaming), along with the quoted state at its end.
+func (l *lazyQuotes) resolve(splitRow, chunk []byte, first, last bool) (header, trailer, quoted uint64) {
+	buf := make([]byte, 0, len(splitRow)+len(chunk))
+	buf = append(append(buf, splitRow...), chunk...)
+
+	state := lazyFieldStart
+	firstRow, lastRow := -1, 0
+	line, lineStart := 0, true
+	l.within = false
+	for i := 0; i < len(buf); i++ {
+		if lineStart && hasCommentPrefix(buf[i:], l.comment) {
+			// a comment line holds no fields up to its newline
+			j := bytes.IndexByte(buf[i:], '\n')
+			if j < 0 {
+				l.within = true
+				break
+			}
+			i += j
+		}
+		lineStart = false
+		switch c := buf[i]; {
+		case c == l.escape && l.escape != 0:
+			i++ // the escaped character is data
+			if state == lazyFieldStart {
+				state = lazyUnquoted
+			} else if state == lazyQuotedQuote {
+				state = lazyQuoted
+			}
+		case c == '\r' && state == lazyQuotedQuote && i+1 < len(buf) && buf[i+1] == '\n':
+			// a CRLF closing the field
+		case c == '\n' && state != lazyQuoted:
+			state, line, lineStart = lazyFieldStart, i+1, true
+			if row := i - len(splitRow); row >= 0 {
+				if firstRow < 0 {
+					firstRow = row
+					if row > 0 && chunk[row-1] == '\r' {
+						firstRow-- // the delimiter of a CRLF is its CR
+					}
+				}
+				lastRow = row + 1
+			}
+		case c == l.comma[0] && state != lazyQuoted && bytes.HasPrefix(buf[i:], l.comma):
+			state = lazyFieldStart
+			i += len(l.comma) - 1
+		case c == '"' && state == lazyFieldStart:
+			state = lazyQuoted
+		case c == '"' && state == lazyQuoted:
+			state = lazyQuotedQuote
+		case state == lazyQuotedQuote:
+			// an escaped quote, or else a literal one
+			state = lazyQuoted
+		case state == lazyFieldStart:
+			state = lazyUnquoted
+		}
+	}
+
+	switch {
+	case first:
+		header = 0
+	case firstRow < 0:
+		header = uint64(len(chunk))
+	default:
+		header = uint64(firstRow)
+	}
+	if !last && header < uint64(len(chunk)) {
+		trailer = uint64(len(chunk) - lastRow)
+	}
+
+	l.tail, l.pending = append(l.tail[:0], buf[len(buf)-l.context(buf):]...), nil
+	if state == lazyQuotedQuote && !last {
+		l.pending = []byte{}
+	}
+	l.line = buf[line:]
+	if state == lazyQuoted || state == lazyQuotedQuote {
+		l.line = nil
+	}
+	if state == lazyQuoted {
+		quoted = ^uint64(0)
+	}
+	return
+}
@@ -0,0 +1,156 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLazyQuotes(t *testing.T) {
+	var wellFormed, stray bytes.Buffer
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&wellFormed, "%d,\"quoted \"\"%d\"\"\",\"a,b\",%s\n", i, i, strings.Repeat("x", i%100))
+		switch i % 7 {
+		case 0:
+			fmt.Fprintf(&stray, "%d,bare\"quote,x\n", i)
+		case 1:
+			fmt.Fprintf(&stray, "%d,\"stray \"quote\",x\r\n", i)
+		case 2:
+			fmt.Fprintf(&stray, "%d,\"closed\"late\",\"a,b\"\n", i)
+		default:
+			fmt.Fprintf(&stray, "%d,\"quoted,%s\",x\n", i, strings.Repeat("y", i%150))
+		}
+	}
+
+	for _, input := range []struct {
+		name string
+		data []byte
+	}{{"well-formed", wellFormed.Bytes()}, {"stray", stray.Bytes()}} {
+		rCsv := csv.NewReader(bytes.NewReader(input.data))
+		rCsv.LazyQuotes = true
+		want, err := rCsv.ReadAll()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		for _, size := range []int{0, 64, 128, 1000, 4096} {
+			for _, inMemory := range []bool{false, true} {
+				r := NewReader(bytes.NewReader(input.data))
+				if inMemory {
+					r = newBytesReader(input.data)
+				}
+				r.LazyQuotes, r.ChunkSize = true, size
+				if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("TestLazyQuotes(%s, %d, %v): got: %d records, %v want: %d records", input.name, size, inMemory, len(got), err, len(want))
+				}
+			}
+		}
+	}
+}
+
+func TestLazyQuotesRowSpanningChunk(t *testing.T) {
+	// no row ends within the first chunk
+	for _, input := range []string{
+		"\"\"\",,\n\nabab,,\"a\"ba\"ba,\"\"b,abbab,,,a\n\"b\"\"\n\n\nabbab\n\"aaa,a,b\"ab\"b,,\n",
+		strings.Repeat("a", 100) + "\nb\n",
+		strings.Repeat("a", 64) + "\nb\n",
+	} {
+		rCsv := csv.NewReader(strings.NewReader(input))
+		rCsv.LazyQuotes, rCsv.FieldsPerRecord = true, -1
+		want, err := rCsv.ReadAll()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		r := NewReader(strings.NewReader(input))
+		r.LazyQuotes, r.FallbackThreshold, r.FieldsPerRecord, r.ChunkSize = true, -1, -1, 64
+		if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("TestLazyQuotesRowSpanningChunk(%q): got: %q, %v want: %q", input, got, err, want)
+		}
+	}
+}
+
+func TestLazyQuotesComments(t *testing.T) {
+	// stray quotes precede the line of the next chunk that starts like a
+	// comment while being within a quoted field
+	for _, input := range []string{
+		"aa\n//\n\"\"#//\"\"##,aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\n#\",aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa#\"#//\"\",\"\"#//\n",
+		"\n//\"\n\"\"\"\"\"\"//\"//\"\"\"\"\"\"\n#aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa,a//#a\"\"#\"\"\"\"\n",
+	} {
+		rCsv := csv.NewReader(strings.NewReader(input))
+		rCsv.LazyQuotes, rCsv.FieldsPerRecord, rCsv.Comment = true, -1, '#'
+		want, err := rCsv.ReadAll()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		r := NewReader(strings.NewReader(input))
+		r.LazyQuotes, r.FallbackThreshold, r.FieldsPerRecord, r.ChunkSize, r.Comment = true, -1, -1, 64, '#'
+		if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("TestLazyQuotesComments(%q): got: %q, %v want: %q", input, got, err, want)
+		}
+	}
+}
+
+func TestLazyQuotesVariableWidth(t *testing.T) {
+	// records of different widths within the row split between chunks, and
+	// a CRLF closing a quoted field split between chunks
+	for _, input := range []string{
+		",,\"x\"\"a,\"\"aaaaaaaaaaaaaaaaaaaaaaax\"\",\"\"aaaaaaaaaaaaaaaaaaaaaaa\"\r\n",
+		"a\"b\n\"" + strings.Repeat("c", 57) + "\"\r\n" + strings.Repeat("d,e\r\n", 30),
+		"a,b\"c\n" + strings.Repeat("d", 70) + "\ne,\"f\"\r\n",
+		strings.Repeat("a\"b,", 15) + "\r\nc\r\nd,\"e\"\r\n",
+		strings.Repeat("a\"b,c\nd\r\n", 50000) + "e,\"f\"\r\n",
+		"," + strings.Repeat("a", 62) + "\"\"a\naa",
+		strings.Repeat("b,c\n", 79984) + "," + strings.Repeat("a", 62) + "\"\"a\naa", // at the default chunk size
+	} {
+		rCsv := csv.NewReader(strings.NewReader(input))
+		rCsv.LazyQuotes, rCsv.FieldsPerRecord = true, -1
+		want, err := rCsv.ReadAll()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		for _, chunkSize := range []int{64, 0} {
+			r := NewReader(strings.NewReader(input))
+			r.LazyQuotes, r.FallbackThreshold, r.FieldsPerRecord, r.ChunkSize = true, -1, -1, chunkSize
+			var got [][]string
+			for {
+				record, err := r.Read()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Errorf("TestLazyQuotesVariableWidth(%.20q, %d): %v", input, chunkSize, err)
+					break
+				}
+				got = append(got, record)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("TestLazyQuotesVariableWidth(%.20q, %d): got: %q want: %q", input, chunkSize, got, want)
+			}
+			if offset := r.InputOffset(); offset != int64(len(input)) {
+				t.Errorf("TestLazyQuotesVariableWidth(%.20q, %d): got: offset %d want: %d", input, chunkSize, offset, len(input))
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors wrapped by the RecordError that ends reading once a record exceeds
+// a limit (see Reader.MaxRecordBytes, MaxFieldBytes and MaxFieldsPerRecord).
+var (
+	ErrRecordTooLarge = errors.New("simdcsv: record exceeds MaxRecordBytes")
+	ErrFieldTooLarge  = errors.New("simdcsv: field exceeds MaxFieldBytes")
+	ErrTooManyFields  = errors.New("simdcsv: record exceeds MaxFieldsPerRecord")
+)
+
+// limited reports whether any of the limits is set
+func (r *Reader) limited() bool {
+	return r.MaxRecordBytes > 0 || r.MaxFieldBytes > 0 || r.MaxFieldsPerRecord > 0
+}
+
+// checkLimits returns the error for the first of records that exceeds a limit
+func (r *Reader) checkLimits(records [][]string, positions []recordPos) error {
+	if !r.limited() {
+		return nil
+	}
+	for i, record := range records {
+		line := i + 1
+		var raw []byte
+		if i < len(positions) {
+			line, raw = positions[i].line, trimTerminator(positions[i].raw)
+		}
+		if r.MaxRecordBytes > 0 && len(raw) > r.MaxRecordBytes {
+			return recordTooLarge(line, r.MaxRecordBytes)
+		}
+		if r.MaxFieldsPerRecord > 0 && len(record) > r.MaxFieldsPerRecord {
+			return &RecordError{Line: line, Err: fmt.Errorf("%w: %d fields, at most %d", ErrTooManyFields, len(record), r.MaxFieldsPerRecord)}
+		}
+		if r.MaxFieldBytes > 0 {
+			for col, field := range record {
+				if len(field) > r.MaxFieldBytes {
+					return &RecordError{Line: line, Err: fmt.Errorf("field %d: %w: %d bytes, at most %d", col+1, ErrFieldTooLarge, len(field), r.MaxFieldBytes)}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// recordTooLarge returns the error for the record on line exceeding max bytes
+func recordTooLarge(line, max int) error {
+	return &RecordError{Line: line, Err: fmt.Errorf("%w: more than %d bytes", ErrRecordTooLarge, max)}
+}
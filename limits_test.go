@@ -0,0 +1,64 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	good := fixture("", 1000, "%[1]d,\"value %[1]d\"\n")
+	unterminated := good + "x,\"unterminated\n" + strings.Repeat("a,b,c\n", 1<<18)
+
+	for _, tc := range []struct {
+		name    string
+		input   string
+		config  func(r *Reader)
+		records int
+		err     error
+	}{
+		{"unterminated", unterminated, func(r *Reader) { r.MaxRecordBytes = 10000 }, 1000, ErrRecordTooLarge},
+		{"record", good + "y," + strings.Repeat("z", 200) + "\n", func(r *Reader) { r.MaxRecordBytes = 100 }, 1000, ErrRecordTooLarge},
+		{"field", good + "y,\"" + strings.Repeat("z", 200) + "\"\n", func(r *Reader) { r.MaxFieldBytes = 100 }, 1000, ErrFieldTooLarge},
+		{"fields", good + strings.Repeat("y,", 100) + "z\n", func(r *Reader) { r.MaxFieldsPerRecord, r.FieldsPerRecord = 10, -1 }, 1000, ErrTooManyFields},
+		{"within", good, func(r *Reader) { r.MaxRecordBytes, r.MaxFieldBytes, r.MaxFieldsPerRecord = 20, 10, 2 }, 1000, nil},
+	} {
+		forModes(func(mode SIMDMode, chunkSize int) {
+			r := NewReader(strings.NewReader(tc.input))
+			r.SIMD, r.ChunkSize = mode, chunkSize
+			tc.config(r)
+			records := 0
+			var err error
+			for {
+				if _, err = r.Read(); err != nil {
+					break
+				}
+				records++
+			}
+			var recordErr *RecordError
+			if tc.err != nil && (!errors.Is(err, tc.err) || !errors.As(err, &recordErr)) || tc.err == nil && err.Error() != "EOF" {
+				t.Errorf("TestLimits(%s, %d): got: %v want: %v", tc.name, mode, err, tc.err)
+			}
+			// a block of records that exceeds a limit is dropped as a whole
+			if mode == SIMDDisable && records != tc.records || records > tc.records {
+				t.Errorf("TestLimits(%s, %d): got: %d records want: %d", tc.name, mode, records, tc.records)
+			}
+		}, 4096)
+	}
+}
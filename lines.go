@@ -0,0 +1,331 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"hash"
+	"io"
+	"math/bits"
+	"strings"
+)
+
+// prefixXor returns the mask where every bit is the xor of itself and all
+// lower bits, which turns a mask of quotes into a mask of quoted regions
+func prefixXor(mask uint64) uint64 {
+	mask ^= mask << 1
+	mask ^= mask << 2
+	mask ^= mask << 4
+	mask ^= mask << 8
+	mask ^= mask << 16
+	mask ^= mask << 32
+	return mask
+}
+
+// recordPos holds the position of a record in the input
+type recordPos struct {
+	line    int      // line on which the record starts
+	offset  int64    // input offset at which the record starts
+	raw     []byte   // unmodified bytes of the record and its terminator
+	comment bool     // whether the record is a comment line (see Comment and CommentPrefix)
+	sum     []byte   // of the raw bytes, without the terminator (see RawHash)
+	nulls   NullMask // fields that are NULL, if any (see NullLiterals)
+}
+
+// hashRaw sums the raw bytes of the records at positions with h, with the
+// sums sharing a single allocation
+func hashRaw(h hash.Hash, positions []recordPos) {
+	sums := make([]byte, 0, len(positions)*h.Size())
+	for i := range positions {
+		h.Reset()
+		h.Write(trimTerminator(positions[i].raw))
+		sums = h.Sum(sums)
+		positions[i].sum = sums[len(sums)-h.Size() : len(sums) : len(sums)]
+	}
+}
+
+// recordPositions appends, for every row of buf that stage 2 turns into a
+// record, its position, counting from line for the row at offset start,
+// where buf starts at input offset offset.
+// Rows are delimited by the (unquoted) delimiter bits of the stage 1 masks,
+// whereas every newline counts towards the line, including quoted ones.
+// The raw bytes of the rows are slices of buf, so keeping them costs nothing.
+// Rows starting with any of the comment prefixes are marked as comments,
+// which leaves out quoted fields starting like one.
+func recordPositions(buf []byte, masks []uint64, start int, line int, offset int64, comments []string, positions []recordPos) []recordPos {
+
+	quoted := uint64(0)
+	rowStart, rowLine := start, line
+
+	appendRow := func(end, next int) {
+		if row := buf[rowStart:end]; !emptyRow(row) {
+			isComment := false
+			for _, comment := range comments {
+				isComment = isComment || bytes.HasPrefix(row, stringBytes(comment))
+			}
+			positions = append(positions, recordPos{rowLine, offset + int64(rowStart), buf[rowStart:next:next], isComment, nil, nil})
+		}
+	}
+
+	for b := 0; b*64 < len(buf) && b*3+2 < len(masks); b++ {
+		delimiters, quotes := masks[b*3], masks[b*3+2]
+		if rem := len(buf) - b*64; rem < 64 {
+			delimiters &= 1<<rem - 1 // ignore the delimiter added beyond the end
+		}
+
+		inQuotes := prefixXor(quotes) ^ quoted
+		quoted = uint64(int64(inQuotes) >> 63)
+
+		// delimiters within quotes can only be newlines, which are counted
+		// up to every row terminator in turn, so the next row starts on the
+		// right line
+		quotedNewlines := delimiters & inQuotes
+
+		for outside := delimiters &^ inQuotes; outside != 0; outside &= outside - 1 {
+			preceding := outside&-outside - 1
+			line += bits.OnesCount64(quotedNewlines & preceding)
+			quotedNewlines &^= preceding
+
+			pos := b*64 + bits.TrailingZeros64(outside)
+			next := pos + 1 // beyond the terminator
+			if buf[pos] == '\r' && next < len(buf) && buf[next] == '\n' {
+				next++
+			}
+			appendRow(pos, next)
+			if buf[pos] == '\n' {
+				line++
+			}
+			rowStart, rowLine = pos+1, line
+		}
+		line += bits.OnesCount64(quotedNewlines)
+	}
+	if rowStart < len(buf) {
+		appendRow(len(buf), len(buf))
+	}
+	return positions
+}
+
+// terminatorAt returns the line terminator at offset i of buf, if any
+func terminatorAt(buf []byte, i int) []byte {
+	if i >= len(buf) {
+		return nil
+	}
+	if buf[i] == '\r' && i+1 < len(buf) && buf[i+1] == '\n' {
+		return buf[i : i+2]
+	}
+	return buf[i : i+1]
+}
+
+// trimTerminator returns raw without its line terminator
+func trimTerminator(raw []byte) []byte {
+	if len(raw) > 0 && raw[len(raw)-1] == '\n' {
+		raw = raw[:len(raw)-1]
+		if len(raw) > 0 && raw[len(raw)-1] == '\r' {
+			raw = raw[:len(raw)-1]
+		}
+	}
+	return raw
+}
+
+// emptyRow reports whether stage 2 skips a row, which is the case for a row
+// consisting of a single empty field
+func emptyRow(row []byte) bool {
+	return len(row) == 0 || len(row) == 2 && row[0] == '"' && row[1] == '"'
+}
+
+// csvPositions tracks the positions of the records read by an encoding/csv
+// Reader
+type csvPositions struct {
+	rCsv    *csv.Reader
+	raw     *rawInput      // input retained for the raw bytes of the records
+	filter  *commentFilter // maps offsets past removed comments, if any
+	line    int            // line on which the input starts
+	offset  int64          // input offset at which the input starts
+	end     int64          // input offset at the end of the previous record
+	endLine int            // line at offset end
+
+	onError func(err *RecordError) Action // see Reader.OnError
+
+	placeholder string // stands in for commaString, if any (see separatorFilter)
+	commaString string
+	escapes     *escapeFilter // escaped characters to restore, if any
+
+	preserveCRLF bool   // see Reader.PreserveCRLF
+	rawQuotes    string // the delimiter, if quoted fields are returned raw (see Reader.RawQuotes)
+}
+
+func newCsvPositions(in io.Reader, line int, offset int64) *csvPositions {
+	p := &csvPositions{line: line, offset: offset, endLine: line, raw: &rawInput{in: in}}
+	p.filter, _ = in.(*commentFilter)
+	p.rCsv = csv.NewReader(p.raw)
+	return p
+}
+
+// next returns the position of the record last read
+func (p *csvPositions) next() recordPos {
+	l, _ := p.rCsv.FieldPos(0)
+	return p.nextAt(p.line + l - 1)
+}
+
+// nextAt returns the position of the record last read, which starts on line
+func (p *csvPositions) nextAt(line int) recordPos {
+	end := p.rCsv.InputOffset()
+	raw := p.raw.consume(p.end, end)
+	rawLine := p.endLine
+	p.end, p.endLine = end, p.endLine+bytes.Count(raw, []byte{'\n'})
+
+	// skip the empty lines and comments preceding the record
+	for ; rawLine < line; rawLine++ {
+		raw = raw[bytes.IndexByte(raw, '\n')+1:]
+	}
+	start := p.filter.inputOffset(end - int64(len(raw)))
+	if p.placeholder != "" {
+		raw = bytes.ReplaceAll(raw, []byte(p.placeholder), []byte(p.commaString))
+	}
+	return recordPos{line: line, offset: p.offset + start, raw: raw}
+}
+
+// read reads the next record along with its position, consulting onError
+// about problematic records
+func (p *csvPositions) read() ([]string, recordPos, error) {
+	for {
+		record, err := p.rCsv.Read()
+		if p.placeholder != "" {
+			restoreSeparators(record, p.placeholder, p.commaString)
+		}
+		if err == nil {
+			pos := p.next()
+			p.restoreEscapes(record, &pos)
+			if p.rawQuotes != "" {
+				rawQuotes(record, pos.raw, p.rawQuotes, p.rCsv.TrimLeadingSpace)
+			} else if p.preserveCRLF {
+				restoreCRLF(record, pos.raw)
+			}
+			return record, pos, nil
+		}
+		if errors.Is(err, ErrRecordTooLarge) {
+			return nil, recordPos{}, recordTooLarge(p.endLine, p.raw.limit)
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			parseErr = p.fileLines(parseErr)
+			err = parseErr
+		}
+		if p.onError == nil || parseErr == nil {
+			return nil, recordPos{}, err
+		}
+		pos := p.nextAt(parseErr.StartLine)
+		p.restoreEscapes(record, &pos)
+		if p.preserveCRLF {
+			restoreCRLF(record, pos.raw)
+		}
+		recordErr := &RecordError{Line: pos.line, Record: record, Err: err}
+		switch p.onError(recordErr) {
+		case Skip:
+		case Replace:
+			return recordErr.Record, pos, nil
+		default:
+			return nil, recordPos{}, err
+		}
+	}
+}
+
+// restoreCRLF puts back the carriage returns that encoding/csv drops from the
+// \r\n sequences within the quoted fields of record, the line breaks of
+// which occur in the same order in raw, the input of the record
+func restoreCRLF(record []string, raw []byte) {
+	raw = trimTerminator(raw)
+	if bytes.IndexByte(raw, '\r') < 0 {
+		return
+	}
+	for i, field := range record {
+		if !strings.Contains(field, "\n") {
+			continue
+		}
+		var b strings.Builder
+		for j := strings.IndexByte(field, '\n'); j >= 0; j = strings.IndexByte(field, '\n') {
+			k := bytes.IndexByte(raw, '\n')
+			if k < 0 {
+				return // cannot happen as long as raw holds the record
+			}
+			b.WriteString(field[:j])
+			if k > 0 && raw[k-1] == '\r' {
+				b.WriteByte('\r')
+			}
+			b.WriteByte('\n')
+			field, raw = field[j+1:], raw[k+1:]
+		}
+		b.WriteString(field)
+		record[i] = b.String()
+	}
+}
+
+// fileLines returns err with its lines counted from the start of the file
+// rather than from the start of the input of encoding/csv
+func (p *csvPositions) fileLines(err *csv.ParseError) *csv.ParseError {
+	fileErr := *err
+	fileErr.StartLine += p.line - 1
+	fileErr.Line += p.line - 1
+	return &fileErr
+}
+
+// readAll reads all records along with their positions
+func (p *csvPositions) readAll() (records [][]string, positions []recordPos, err error) {
+	for {
+		record, pos, err := p.read()
+		if err == io.EOF {
+			return records, positions, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, record)
+		positions = append(positions, pos)
+	}
+}
+
+// rawInput retains the input consumed by encoding/csv, so that the raw
+// bytes of its records can be recovered from the input offsets
+type rawInput struct {
+	in     io.Reader
+	buf    []byte
+	offset int64 // input offset of buf[0]
+	end    int64 // input offset at the end of the record last consumed
+	limit  int   // bytes beyond end, besides a read ahead, that fail reading (see MaxRecordBytes)
+}
+
+func (ri *rawInput) Read(p []byte) (n int, err error) {
+	if ri.limit > 0 && ri.offset+int64(len(ri.buf))-ri.end > int64(ri.limit+len(p)) {
+		return 0, ErrRecordTooLarge
+	}
+	n, err = ri.in.Read(p)
+	ri.buf = append(ri.buf, p[:n]...)
+	return
+}
+
+// consume returns a copy of the input between offsets start and end,
+// after which the input before end may be discarded
+func (ri *rawInput) consume(start, end int64) []byte {
+	raw := append([]byte(nil), ri.buf[start-ri.offset:end-ri.offset]...)
+	ri.end = end
+	if consumed := int(end - ri.offset); consumed > len(ri.buf)/2 {
+		ri.buf = ri.buf[:copy(ri.buf, ri.buf[consumed:])]
+		ri.offset = end
+	}
+	return raw
+}
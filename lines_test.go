@@ -0,0 +1,72 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestErrorLines checks that errors occurring beyond the first chunk report
+// the lines of the file, newlines within quotes included
+func TestErrorLines(t *testing.T) {
+	input := func(n, bad int, row string) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			switch {
+			case i == bad:
+				fmt.Fprintf(&b, row, i)
+			case i%7 == 0:
+				fmt.Fprintf(&b, "%d,\"a\nb\",c\n", i)
+			default:
+				fmt.Fprintf(&b, "%d,a,b\n", i)
+			}
+		}
+		return b.String()
+	}
+
+	for _, n := range []int{5, 1000, 3000} {
+		for _, bad := range []int{1, n / 2, n - 1} {
+			for _, row := range []string{"%d,\"x\ny\"\n", "%d,a\"x,y\n"} {
+				in := input(n, bad, row)
+				_, want := csv.NewReader(strings.NewReader(in)).ReadAll()
+				forModes(func(mode SIMDMode, chunkSize int) {
+					r := NewReader(strings.NewReader(in))
+					r.SIMD, r.ChunkSize = mode, chunkSize
+					if _, err := r.ReadAll(); !reflect.DeepEqual(err, want) {
+						t.Errorf("TestErrorLines(%d, %d, %q, %d, %d): got: %v want: %v", n, bad, row, mode, chunkSize, err, want)
+					}
+
+					var lines []int
+					r = NewReader(strings.NewReader(in))
+					r.SIMD, r.ChunkSize = mode, chunkSize
+					r.OnError = func(err *RecordError) Action {
+						lines = append(lines, err.Line)
+						return Skip
+					}
+					r.ReadAll()
+					if wantLines := []int{want.(*csv.ParseError).StartLine}; !reflect.DeepEqual(lines, wantLines) {
+						t.Errorf("TestErrorLines(%d, %d, %q, %d, %d): got: %v want: %v", n, bad, row, mode, chunkSize, lines, wantLines)
+					}
+				}, 64, 4096, 1<<20)
+			}
+		}
+	}
+}
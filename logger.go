@@ -0,0 +1,38 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+// Logger receives diagnostics (see Reader.Logger), as *slog.Logger does
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+func (r *Reader) logDebug(msg string, args ...interface{}) {
+	if r.Logger != nil {
+		r.Logger.Debug(msg, args...)
+	}
+}
+
+// readFailed ends the input with err, which the consumer receives after
+// the records read before
+func (r *Reader) readFailed(out *outputSlots, err error) {
+	if r.Logger != nil {
+		r.Logger.Error("simdcsv: reading input", "err", err)
+	}
+	out.fail(err)
+}
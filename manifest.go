@@ -0,0 +1,124 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync/atomic"
+)
+
+// Checksum is a checksum of a multiset of records, which is the lane-wise
+// sum of the SHA-256 digests of the records. So the checksum of the union
+// of records is the sum of the checksums, independently of their order.
+type Checksum [4]uint64
+
+// Add returns the checksum of the records of both c and o
+func (c Checksum) Add(o Checksum) Checksum {
+	for i := range c {
+		c[i] += o[i]
+	}
+	return c
+}
+
+// recordChecksum returns the checksum of a single record
+func recordChecksum(record []string) (c Checksum) {
+	h := sha256.New()
+	var n [binary.MaxVarintLen64]byte
+	for _, field := range record {
+		h.Write(n[:binary.PutUvarint(n[:], uint64(len(field)))])
+		io.WriteString(h, field)
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	for i := range c {
+		c[i] = binary.LittleEndian.Uint64(sum[i*8:])
+	}
+	return
+}
+
+// Manifest summarizes the records parsed from a range of the input (see
+// Reader.Manifest), such as a record-aligned shard that is parsed by one
+// of several workers. Manifests of the same range of input compare equal
+// (with ==) when they summarize the same records.
+type Manifest struct {
+	Offset  int64    // input offset at which the range starts, set prior to parsing a shard
+	Size    int64    // number of bytes in the range
+	Records int      // number of records
+	Sum     Checksum // checksum of the records
+}
+
+// add accounts for records
+func (m *Manifest) add(records [][]string) {
+	if m == nil {
+		return
+	}
+	for _, record := range records {
+		m.Sum = m.Sum.Add(recordChecksum(record))
+	}
+	m.Records += len(records)
+}
+
+// countBytes accounts for n bytes of the range, while parsing
+func (m *Manifest) countBytes(n int64) {
+	if m != nil {
+		atomic.AddInt64(&m.Size, n)
+	}
+}
+
+// MergeManifests returns the manifest of the union of shards, which must
+// partition a range of the input (in any order).
+func MergeManifests(shards ...Manifest) (Manifest, error) {
+	if len(shards) == 0 {
+		return Manifest{}, errors.New("simdcsv: no manifests to merge")
+	}
+	shards = append([]Manifest(nil), shards...)
+	sort.Slice(shards, func(i, j int) bool { return shards[i].Offset < shards[j].Offset })
+
+	merged := Manifest{Offset: shards[0].Offset}
+	for _, shard := range shards {
+		if end := merged.Offset + merged.Size; shard.Offset != end {
+			return Manifest{}, fmt.Errorf("simdcsv: shard at offset %d does not follow the range ending at %d", shard.Offset, end)
+		}
+		merged.Size += shard.Size
+		merged.Records += shard.Records
+		merged.Sum = merged.Sum.Add(shard.Sum)
+	}
+	return merged, nil
+}
+
+// VerifyManifest checks that the manifests of shards add up to the
+// manifest of a sequential parse of the same range of the input.
+func VerifyManifest(whole Manifest, shards ...Manifest) error {
+	merged, err := MergeManifests(shards...)
+	if err != nil {
+		return err
+	}
+	switch {
+	case merged.Offset != whole.Offset || merged.Size != whole.Size:
+		return fmt.Errorf("simdcsv: shards cover %d bytes at offset %d, want %d bytes at offset %d", merged.Size, merged.Offset, whole.Size, whole.Offset)
+	case merged.Records != whole.Records:
+		return fmt.Errorf("simdcsv: shards hold %d records, want %d", merged.Records, whole.Records)
+	case merged.Sum != whole.Sum:
+		return errors.New("simdcsv: checksum of shards differs")
+	}
+	return nil
+}
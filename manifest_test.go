@@ -0,0 +1,92 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < 1<<20; i++ {
+		fmt.Fprintf(&buf, "%d,\"quoted \"\"%d\"\"\",%x\n", i, i*i, i)
+	}
+	data := buf.Bytes()
+
+	// record-aligned shards at roughly a third and two thirds of the input
+	bounds := []int{0}
+	for _, at := range []int{len(data) / 3, len(data) * 2 / 3} {
+		bounds = append(bounds, at+bytes.IndexByte(data[at:], '\n')+1)
+	}
+	bounds = append(bounds, len(data))
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		whole := &Manifest{}
+		r := NewReader(bytes.NewReader(data))
+		r.kernel, r.Manifest = kernel, whole
+		if _, err := r.ReadAll(); err != nil {
+			t.Fatalf("TestManifest: %v", err)
+		}
+		if whole.Size != int64(len(data)) || whole.Records != bytes.Count(data, []byte{'\n'}) {
+			t.Fatalf("TestManifest: got: %d bytes, %d records", whole.Size, whole.Records)
+		}
+
+		shards := make([]Manifest, len(bounds)-1)
+		for i := len(shards) - 1; i >= 0; i-- {
+			shards[i].Offset = int64(bounds[i])
+			r := NewReader(io.NewSectionReader(bytes.NewReader(data), int64(bounds[i]), int64(bounds[i+1]-bounds[i])))
+			r.kernel, r.Manifest = kernel, &shards[i]
+			for {
+				if _, err := r.Read(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("TestManifest: %v", err)
+				}
+			}
+		}
+		if err := VerifyManifest(*whole, shards[2], shards[0], shards[1]); err != nil {
+			t.Errorf("TestManifest: %v", err)
+		}
+		if err := VerifyManifest(*whole, shards[0], shards[2]); err == nil {
+			t.Errorf("TestManifest: missing shard got verified")
+		}
+		tampered := shards[1]
+		tampered.Sum = tampered.Sum.Add(recordChecksum([]string{"x"}))
+		if err := VerifyManifest(*whole, shards[0], tampered, shards[2]); err == nil {
+			t.Errorf("TestManifest: tampered shard got verified")
+		}
+	}
+}
+
+func TestManifestPreamble(t *testing.T) {
+	input := []byte("report\n\na,b\nc,d\n")
+	want := Manifest{Size: int64(len(input)), Records: 2}
+	want.Sum = recordChecksum([]string{"a", "b"}).Add(recordChecksum([]string{"c", "d"}))
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		got := &Manifest{}
+		records, err := ReadBytes(input, func(r *Reader) {
+			r.kernel, r.Manifest, r.SkipLines = kernel, got, 2
+		})
+		if err != nil || len(records) != 2 || *got != want {
+			t.Errorf("TestManifestPreamble: got: %q, %v, %+v want: %+v", records, err, *got, want)
+		}
+	}
+}
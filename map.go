@@ -0,0 +1,55 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import "fmt"
+
+// Map adds fn to the transforms applied to every record, in the order in
+// which they were added, after FieldTransform. The record returned by fn
+// replaces the record passed, which fn may modify, and a nil record drops
+// it, whereas an error ends parsing. The transforms are invoked
+// concurrently by the parsing workers, so records are transformed in
+// parallel yet returned in order, and fn must be safe for concurrent use.
+func (r *Reader) Map(fn func(record []string) ([]string, error)) {
+	r.maps = append(r.maps, fn)
+}
+
+// mapper transforms a record (see Map)
+type mapper func(record []string) ([]string, error)
+
+// mapRecords applies the transforms of Map to records, dropping those for
+// which a transform returns nil
+func (r *Reader) mapRecords(records *[][]string, positions *[]recordPos) error {
+	kept := 0
+	for i, record := range *records {
+		for _, fn := range r.maps {
+			var err error
+			if record, err = fn(record); err != nil {
+				return fmt.Errorf("record on line %d: %w", (*positions)[i].line, err)
+			}
+			if record == nil {
+				break
+			}
+		}
+		if record != nil {
+			(*records)[kept], (*positions)[kept] = record, (*positions)[i]
+			kept++
+		}
+	}
+	*records, *positions = (*records)[:kept], (*positions)[:kept]
+	return nil
+}
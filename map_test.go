@@ -0,0 +1,79 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	input := fixture("id,name\n", 5000, "%[1]d,\"n\n%[1]d\"\n")
+	want := [][]string{{"ID", "NAME", "id"}}
+	for i := 0; i < 5000; i++ {
+		if i%4 != 0 {
+			want = append(want, []string{strconv.Itoa(i * 2), fmt.Sprintf("N\n%d", i), strconv.Itoa(i)})
+		}
+	}
+
+	forModes(func(mode SIMDMode, chunkSize int) {
+		r := NewReader(strings.NewReader(input))
+		r.SIMD, r.ChunkSize = mode, chunkSize
+		r.FieldTransform = func(col int, raw []byte) (string, error) { return strings.ToUpper(string(raw)), nil }
+		r.Map(func(record []string) ([]string, error) {
+			if i, err := strconv.Atoi(record[0]); err == nil {
+				if i%4 == 0 {
+					return nil, nil
+				}
+				return append(record, record[0]), nil
+			}
+			return append(record, "id"), nil
+		})
+		r.Map(func(record []string) ([]string, error) {
+			if i, err := strconv.Atoi(record[0]); err == nil {
+				record[0] = strconv.Itoa(i * 2)
+			}
+			return record, nil
+		})
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("TestMap(%d, %d): %v", mode, chunkSize, err)
+		}
+		if !reflect.DeepEqual(records, want) {
+			t.Errorf("TestMap(%d, %d): got: %d records want: %d", mode, chunkSize, len(records), len(want))
+		}
+	})
+
+	errOdd := errors.New("odd")
+	for _, mode := range []SIMDMode{SIMDForce, SIMDDisable} {
+		r := NewReader(strings.NewReader("1\n2\n3\n"))
+		r.SIMD = mode
+		r.Map(func(record []string) ([]string, error) {
+			if record[0] == "3" {
+				return nil, errOdd
+			}
+			return record, nil
+		})
+		if _, err := r.ReadAll(); !errors.Is(err, errOdd) || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("TestMap(%d): got: %v want: %v on line 3", mode, err, errOdd)
+		}
+	}
+}
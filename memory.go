@@ -0,0 +1,116 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// MemoryProfile reports the memory attributable to each stage of the
+// parsing pipeline (see Reader.Memory). Its counters are updated
+// atomically while parsing, so read them once all records have been read.
+type MemoryProfile struct {
+	ChunkBuffers StageMemory // input buffers, both allocated and taken from the pool
+	Masks        StageMemory // bit masks of stage 1, held until stage 2 is done with a chunk
+	SplitRows    StageMemory // rows split between chunks, copied for encoding/csv
+	Records      StageMemory // record and field slices, held until received by the consumer
+}
+
+// StageMemory holds the memory counters of a single pipeline stage
+type StageMemory struct {
+	Allocated int64 // bytes allocated in total
+	Pooled    int64 // bytes taken from a pool instead of allocated
+	Live      int64 // bytes currently in use
+	PeakLive  int64 // maximum of Live
+}
+
+// acquire accounts for n bytes put to use, which were allocated unless pooled
+func (s *StageMemory) acquire(n int, pooled bool) {
+	if pooled {
+		atomic.AddInt64(&s.Pooled, int64(n))
+	} else {
+		atomic.AddInt64(&s.Allocated, int64(n))
+	}
+	live := atomic.AddInt64(&s.Live, int64(n))
+	for {
+		peak := atomic.LoadInt64(&s.PeakLive)
+		if live <= peak || atomic.CompareAndSwapInt64(&s.PeakLive, peak, live) {
+			return
+		}
+	}
+}
+
+// release accounts for n bytes no longer in use
+func (s *StageMemory) release(n int) {
+	atomic.AddInt64(&s.Live, -int64(n))
+}
+
+func (p *MemoryProfile) acquireChunk(buf []byte, pooled bool) {
+	if p != nil {
+		p.ChunkBuffers.acquire(cap(buf), pooled)
+	}
+}
+
+func (p *MemoryProfile) releaseChunk(buf []byte) {
+	if p != nil {
+		p.ChunkBuffers.release(cap(buf))
+	}
+}
+
+// masksMemory returns the size of the masks of a chunk
+func masksMemory(c chunkInfo) int {
+	return (cap(c.masks) + cap(c.postProc)) * 8
+}
+
+func (p *MemoryProfile) acquireMasks(c chunkInfo) {
+	if p != nil {
+		p.Masks.acquire(masksMemory(c), false)
+		p.SplitRows.acquire(cap(c.splitRow), false)
+	}
+}
+
+// releaseMasks releases both the masks and the split row of a chunk
+func (p *MemoryProfile) releaseMasks(c chunkInfo) {
+	if p != nil {
+		p.Masks.release(masksMemory(c))
+		p.SplitRows.release(cap(c.splitRow))
+	}
+}
+
+// recordsMemory returns the size of the record and field slices of a
+// block, which excludes the contents of the fields (records of a block
+// share their backing array of fields, hence the lengths)
+func recordsMemory(output recordsOutput) int {
+	n := cap(output.records)*int(unsafe.Sizeof([]string(nil))) + cap(output.positions)*int(unsafe.Sizeof(recordPos{}))
+	for _, record := range output.records {
+		n += len(record) * int(unsafe.Sizeof(""))
+	}
+	return n
+}
+
+func (p *MemoryProfile) acquireRecords(output recordsOutput) {
+	if p != nil {
+		p.Records.acquire(recordsMemory(output), false)
+	}
+}
+
+func (p *MemoryProfile) releaseRecords(output recordsOutput) {
+	if p != nil {
+		p.Records.release(recordsMemory(output))
+	}
+}
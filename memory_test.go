@@ -0,0 +1,57 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMemoryProfile(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/parking-citations-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, inMemory := range []bool{false, true} {
+		var profile MemoryProfile
+		r := NewReader(bytes.NewReader(buf))
+		if inMemory {
+			r = newBytesReader(buf)
+		}
+		r.Memory = &profile
+		if _, err := r.ReadAll(); err != nil {
+			t.Fatalf("TestMemoryProfile: got: %v want: nil", err)
+		}
+
+		stages := map[string]StageMemory{"Masks": profile.Masks, "SplitRows": profile.SplitRows, "Records": profile.Records}
+		if !inMemory {
+			stages["ChunkBuffers"] = profile.ChunkBuffers
+		} else if profile.ChunkBuffers != (StageMemory{}) {
+			t.Errorf("TestMemoryProfile: got: %+v want: no chunk buffers for in-memory input", profile.ChunkBuffers)
+		}
+		for name, s := range stages {
+			if s.Allocated+s.Pooled == 0 || s.PeakLive == 0 || s.PeakLive > s.Allocated+s.Pooled {
+				t.Errorf("TestMemoryProfile(%s): got: %+v", name, s)
+			}
+		}
+		if profile.Masks.Live != 0 || profile.SplitRows.Live != 0 || profile.Records.Live != 0 {
+			t.Errorf("TestMemoryProfile: got: %+v want: no live memory left", profile)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import "strings"
+
+// ReadAllFile parses all records of the named file, which is memory-mapped
+// and parsed in place instead of being read. The fields are copied out of
+// the mapping in one go, which is released before ReadAllFile returns (see
+// MapFile to avoid even that copy).
+func ReadAllFile(path string, opts ...Option) ([][]string, error) {
+	records, unmap, err := MapFile(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	records = copyRecords(records)
+	return records, unmap()
+}
+
+// MapFile is like ReadAllFile, except that the fields share memory with the
+// mapping of the file rather than being copied, which saves the time and
+// memory for the copy.
+//
+// The fields are only valid until unmap is called: accessing them, or any
+// string derived from them without copying, faults afterwards. Likewise, the
+// file must neither be truncated nor modified while it is mapped.
+func MapFile(path string, opts ...Option) (records [][]string, unmap func() error, err error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if records, err = newBytesReader(data, opts...).ReadAll(); err != nil {
+		unmap()
+		return nil, nil, err
+	}
+	return records, unmap, nil
+}
+
+// copyRecords copies all fields of records into a single allocation
+func copyRecords(records [][]string) [][]string {
+	var b strings.Builder
+	n := 0
+	for _, record := range records {
+		for _, field := range record {
+			n += len(field)
+		}
+	}
+	b.Grow(n)
+	for _, record := range records {
+		for _, field := range record {
+			b.WriteString(field)
+		}
+	}
+	s := b.String()
+	for _, record := range records {
+		for i := range record {
+			record[i], s = s[:len(record[i])], s[len(record[i]):]
+		}
+	}
+	return records
+}
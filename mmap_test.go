@@ -0,0 +1,72 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadAllFile(t *testing.T) {
+	var data bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&data, "%d,\"quoted \"\"%d\"\"\",%s\n", i, i, strings.Repeat("x", i%100))
+	}
+	want, err := csv.NewReader(bytes.NewReader(data.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "records.csv")
+	if err := ioutil.WriteFile(path, data.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadAllFile(path)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("TestReadAllFile: got: %d records, %v want: %d records", len(got), err, len(want))
+	}
+
+	got, unmap, err := MapFile(path, func(r *Reader) { r.ChunkSize = 4096 })
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("TestReadAllFile: got: %d records, %v want: %d records", len(got), err, len(want))
+	}
+	if err == nil {
+		if err := unmap(); err != nil {
+			t.Errorf("TestReadAllFile: got: %v want: nil", err)
+		}
+	}
+
+	empty := filepath.Join(dir, "empty.csv")
+	if err := ioutil.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadAllFile(empty); err != nil || len(got) != 0 {
+		t.Errorf("TestReadAllFile: got: %d records, %v want: 0 records, nil", len(got), err)
+	}
+
+	if _, err := ReadAllFile(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Errorf("TestReadAllFile: got: nil want: error")
+	}
+}
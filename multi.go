@@ -0,0 +1,221 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// MultiReader returns a Reader of the records of the inputs in turn, such as
+// daily exports of the same table. The first record of every input is its
+// header: the Reader starts with the union of the headers, with the columns
+// in the order in which their names first appear, and the records of every
+// input are reordered to match, with empty fields for the columns that the
+// input lacks and without the fields beyond its header. Inputs whose header
+// matches the union are passed on as they are, whereas the others are
+// parsed, reordered and encoded anew.
+//
+// The headers are read once reading starts, with the Comma, Comment,
+// LazyQuotes and TrimLeadingSpace of the Reader, and names match once
+// normalized by its NormalizeHeader, so these may be set before reading.
+// The inputs are taken to be UTF-8.
+func MultiReader(inputs ...io.Reader) *Reader {
+	m := &multiInput{inputs: inputs}
+	m.r = NewReader(m)
+	return m.r
+}
+
+// multiInput is the input of a MultiReader: the union of the headers,
+// followed by the records of the inputs
+type multiInput struct {
+	r       *Reader
+	inputs  []io.Reader
+	sources []*multiSource // of the inputs left, once started
+	header  []string       // union of the headers
+	out     bytes.Buffer   // encoded input that is pending
+	w       *Writer        // encodes into out
+	started bool
+	err     error
+}
+
+// multiSource is an input of a MultiReader past its header
+type multiSource struct {
+	in      *bufio.Reader
+	columns []int   // of the union for the columns of the input, or nil if they match
+	records *Reader // of the input, if its columns are reordered
+	last    byte    // last byte passed on, if the input is passed on as it is
+}
+
+func (m *multiInput) Read(p []byte) (int, error) {
+	if !m.started {
+		m.started = true
+		m.err = m.start()
+	}
+	for {
+		if m.out.Len() > 0 {
+			return m.out.Read(p)
+		}
+		if m.err != nil {
+			return 0, m.err
+		}
+		if len(m.sources) == 0 {
+			m.err = io.EOF
+			continue
+		}
+
+		src := m.sources[0]
+		if src.columns == nil {
+			n, err := src.in.Read(p)
+			if n > 0 {
+				src.last = p[n-1]
+				return n, nil
+			}
+			if err == io.EOF {
+				if src.last != 0 && src.last != '\n' {
+					m.out.WriteByte('\n') // terminate the last record before the next input
+				}
+				m.sources = m.sources[1:]
+			} else if err != nil {
+				m.err = err
+			}
+			continue
+		}
+
+		// encode records until there is enough to return
+		for m.out.Len() < len(p) {
+			record, err := src.records.Read()
+			if err == io.EOF {
+				m.sources = m.sources[1:]
+				break
+			} else if err != nil {
+				m.err = err
+				break
+			}
+			reordered := make([]string, len(m.header))
+			for i, field := range record {
+				if i < len(src.columns) {
+					reordered[src.columns[i]] = field
+				}
+			}
+			if err = m.w.Write(reordered); err != nil {
+				m.err = err
+				break
+			}
+			m.w.Flush()
+		}
+	}
+}
+
+// start reads the headers of all inputs and encodes their union
+func (m *multiInput) start() error {
+	columns := make(map[string]int) // by normalized name and occurrence
+	for i, input := range m.inputs {
+		in := bufio.NewReaderSize(input, 64<<10)
+		header, err := m.readHeader(in)
+		if err != nil {
+			return fmt.Errorf("simdcsv: input %d: %w", i, err)
+		}
+		if header == nil {
+			continue // empty input
+		}
+
+		src := &multiSource{in: in, columns: make([]int, len(header))}
+		occurrences := make(map[string]int)
+		for c, name := range header {
+			normalized := m.r.NormalizeHeader.apply(name)
+			key := fmt.Sprint(normalized, "\x00", occurrences[normalized])
+			occurrences[normalized]++
+			col, ok := columns[key]
+			if !ok {
+				col = len(m.header)
+				columns[key] = col
+				m.header = append(m.header, name)
+			}
+			src.columns[c] = col
+		}
+		m.sources = append(m.sources, src)
+	}
+	m.inputs = nil
+
+	for _, src := range m.sources {
+		if src.matches(len(m.header)) {
+			src.columns = nil
+		} else {
+			src.records = m.newSourceReader(src.in)
+		}
+	}
+	m.w = NewWriter(&m.out)
+	m.w.Comma = m.r.Comma
+	if err := m.w.Write(m.header); err != nil {
+		return err
+	}
+	m.w.Flush()
+	return nil
+}
+
+// matches reports whether the columns of the input are those of a union of
+// n columns
+func (src *multiSource) matches(n int) bool {
+	if len(src.columns) != n {
+		return false
+	}
+	for c, col := range src.columns {
+		if col != c {
+			return false
+		}
+	}
+	return true
+}
+
+// newSourceReader returns a Reader of the records of an input, parsed as by
+// the Reader of the MultiReader
+func (m *multiInput) newSourceReader(in io.Reader) *Reader {
+	r := NewReader(in)
+	r.Comma, r.Comment, r.LazyQuotes, r.TrimLeadingSpace = m.r.Comma, m.r.Comment, m.r.LazyQuotes, m.r.TrimLeadingSpace
+	r.FieldsPerRecord = -1
+	return r
+}
+
+// readHeader reads the lines of in up to and including the first record,
+// which it returns, or nil if there is none
+func (m *multiInput) readHeader(in *bufio.Reader) ([]string, error) {
+	var lines []byte
+	for {
+		line, err := in.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		lines = append(lines, line...)
+		if bytes.Count(lines, []byte{'"'})%2 == 0 || err == io.EOF {
+			// the lines hold whole records, unless the quotes are off
+			records, parseErr := m.newSourceReader(bytes.NewReader(lines)).ReadAll()
+			if parseErr != nil {
+				return nil, parseErr
+			}
+			if len(records) > 0 {
+				return records[0], nil
+			}
+			lines = lines[:0]
+		}
+		if err == io.EOF {
+			return nil, nil
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMultiReader(t *testing.T) {
+	inputs := []string{
+		"id,name,city\n1,Ann,Oslo\n2,Bob,Rome\n",
+		"city,ID,name\r\n\"Lima\",3,\"Cy\nCy\"\r\n",
+		"",
+		"# exported today\nid,name,city,extra\n4,Dee,Bern,x\n5,Eve,Quito,\n",
+		"id,name,city\n",
+		"id,name,city\n6,Fay,Paris",
+		"id,name,city,extra\n7,Gus,Kyiv,y\n",
+	}
+	want := [][]string{
+		{"id", "name", "city", "extra"},
+		{"1", "Ann", "Oslo", ""},
+		{"2", "Bob", "Rome", ""},
+		{"3", "Cy\nCy", "Lima", ""},
+		{"4", "Dee", "Bern", "x"},
+		{"5", "Eve", "Quito", ""},
+		{"6", "Fay", "Paris", ""},
+		{"7", "Gus", "Kyiv", "y"},
+	}
+
+	for _, mode := range []SIMDMode{SIMDForce, SIMDDisable} {
+		for _, comma := range []rune{',', ';'} {
+			readers := make([]io.Reader, len(inputs))
+			for i, input := range inputs {
+				readers[i] = strings.NewReader(strings.ReplaceAll(input, ",", string(comma)))
+			}
+			r := MultiReader(readers...)
+			r.SIMD, r.Comma, r.Comment, r.NormalizeHeader = mode, comma, '#', LowerCase
+			got, err := r.ReadAll()
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("TestMultiReader(%d, %q): got: %q (%v) want: %q", mode, comma, got, err, want)
+			}
+		}
+	}
+
+	// inputs that match the union are passed on as they are
+	var raw []string
+	r := MultiReader(strings.NewReader("a,b\n1,\"x\"\"y\"\n"), strings.NewReader("a,b\n2,z"), strings.NewReader("a,b\n3,w\n"))
+	r.KeepRaw = true
+	for {
+		if _, err := r.Read(); err != nil {
+			break
+		}
+		raw = append(raw, string(r.RawRecord()))
+	}
+	if want := []string{"a,b", `1,"x""y"`, "2,z", "3,w"}; !reflect.DeepEqual(raw, want) {
+		t.Errorf("TestMultiReader: got: %q want: %q", raw, want)
+	}
+
+	if _, err := MultiReader(strings.NewReader("a,\"b\n")).ReadAll(); err == nil {
+		t.Errorf("TestMultiReader: expected error")
+	}
+}
@@ -0,0 +1,215 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Normalization is a set of cosmetic changes to the fields of a column.
+// The changes are applied in the order in which they are listed below.
+type Normalization uint
+
+const (
+	// TrimSpace removes leading and trailing white space.
+	TrimSpace Normalization = 1 << iota
+	// CollapseSpace replaces every run of white space by a single space.
+	CollapseSpace
+	// LowerCase maps all letters to lower case.
+	LowerCase
+	// UpperCase maps all letters to upper case.
+	UpperCase
+	// StripThousands removes the commas separating groups of digits,
+	// turning 1,234,567 into 1234567.
+	StripThousands
+	// SnakeCase turns names such as "Order Date" or "orderDate" into
+	// "order_date".
+	SnakeCase
+)
+
+// apply returns s with the normalizations of n applied
+func (n Normalization) apply(s string) string {
+	if n&TrimSpace != 0 {
+		s = strings.TrimSpace(s)
+	}
+	if n&CollapseSpace != 0 {
+		s = collapseSpace(s)
+	}
+	if n&LowerCase != 0 {
+		s = strings.ToLower(s)
+	}
+	if n&UpperCase != 0 {
+		s = strings.ToUpper(s)
+	}
+	if n&StripThousands != 0 {
+		s = stripThousands(s)
+	}
+	if n&SnakeCase != 0 {
+		s = snakeCase(s)
+	}
+	return s
+}
+
+func collapseSpace(s string) string {
+	if strings.IndexFunc(s, unicode.IsSpace) < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for i := 0; i < len(s); {
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if unicode.IsSpace(c) {
+			space = true
+		} else {
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+func stripThousands(s string) string {
+	i := strings.IndexByte(s, ',')
+	if i < 0 {
+		return s
+	}
+	buf := make([]byte, 0, len(s))
+	for ; i >= 0; i = strings.IndexByte(s, ',') {
+		if i > 0 && isDigit(s[i-1]) && i+1 < len(s) && isDigit(s[i+1]) {
+			buf = append(buf, s[:i]...)
+		} else {
+			buf = append(buf, s[:i+1]...)
+		}
+		s = s[i+1:]
+	}
+	return string(append(buf, s...))
+}
+
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s) + 4)
+	separate := false
+	for i, c := range runes {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			separate = b.Len() > 0
+			continue
+		}
+		if unicode.IsUpper(c) && i > 0 {
+			// word boundary in camelCase, or at the end of an acronym (as in HTTPServer)
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				separate = b.Len() > 0
+			}
+		}
+		if separate {
+			b.WriteByte('_')
+			separate = false
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// normalizer applies the normalizations of the columns
+type normalizer struct {
+	columns []Normalization // by column index
+	header  bool            // the first record is a header, which is left as is
+}
+
+// normalizes reports whether any columns are to be normalized
+func (r *Reader) normalizes() bool {
+	return len(r.NormalizeColumns) > 0 || len(r.NormalizeNames) > 0
+}
+
+// newNormalizer combines the normalizations by column index and by name,
+// where the names are looked up in header
+func (r *Reader) newNormalizer(header []string) *normalizer {
+	n := &normalizer{header: len(r.NormalizeNames) > 0 || r.NormalizeHeader != 0}
+	add := func(col int, norm Normalization) {
+		for len(n.columns) <= col {
+			n.columns = append(n.columns, 0)
+		}
+		n.columns[col] |= norm
+	}
+	for col, norm := range r.NormalizeColumns {
+		if col >= 0 {
+			add(col, norm)
+		}
+	}
+	if n.header {
+		names := make(map[string]Normalization, len(r.NormalizeNames))
+		for name, norm := range r.NormalizeNames {
+			names[r.NormalizeHeader.apply(name)] |= norm
+		}
+		for col, name := range header {
+			if norm, ok := names[r.NormalizeHeader.apply(name)]; ok {
+				add(col, norm)
+			}
+		}
+	}
+	return n
+}
+
+// headerOf returns the first record of buf, if it can be parsed
+func (r *Reader) headerOf(buf []byte) []string {
+	p := r.newCsvPositions(bytes.NewReader(buf), 1, 0)
+	p.rCsv.Comment = r.Comment
+	p.rCsv.LazyQuotes = r.LazyQuotes
+	p.rCsv.TrimLeadingSpace = r.TrimLeadingSpace
+	header, _, err := p.read()
+	if err != nil {
+		return nil
+	}
+	return header
+}
+
+// normalizeHeader returns a copy of header with the names normalized
+func (r *Reader) normalizeHeader(header []string) []string {
+	normalized := make([]string, len(header))
+	for i, name := range header {
+		normalized[i] = r.NormalizeHeader.apply(name)
+	}
+	return normalized
+}
+
+// normalize applies the normalizations to a block of records
+func (n *normalizer) normalize(records [][]string) {
+	for _, record := range records {
+		for col := 0; col < len(record) && col < len(n.columns); col++ {
+			if n.columns[col] != 0 {
+				record[col] = n.columns[col].apply(record[col])
+			}
+		}
+	}
+}
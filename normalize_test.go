@@ -0,0 +1,134 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalization(t *testing.T) {
+	tests := []struct {
+		norm  Normalization
+		input string
+		want  string
+	}{
+		{TrimSpace, " \tabc \n", "abc"},
+		{CollapseSpace, "a  b\t\tc d", "a b c d"},
+		{CollapseSpace, "  a  b ", " a b "},
+		{CollapseSpace, "a\xffb  c", "a\xffb c"},
+		{TrimSpace | CollapseSpace, "  New   York ", "New York"},
+		{LowerCase, "ÄBC", "äbc"},
+		{UpperCase, "äbc", "ÄBC"},
+		{StripThousands, "1,234,567", "1234567"},
+		{StripThousands, "a, b,1,", "a, b,1,"},
+		{TrimSpace | StripThousands, " 12,345.67 ", "12345.67"},
+	}
+	for _, test := range tests {
+		if got := test.norm.apply(test.input); got != test.want {
+			t.Errorf("TestNormalization: got: %q want: %q", got, test.want)
+		}
+	}
+}
+
+func TestNormalizeColumns(t *testing.T) {
+	var input, want strings.Builder
+	input.WriteString("City,Population,Country\n")
+	want.WriteString("City,Population,Country\n")
+	for i := 0; input.Len() < 1<<20; i++ {
+		fmt.Fprintf(&input, "  New   York ,\"%d,%03d\",US\n", i, i%1000)
+		fmt.Fprintf(&want, "New York,%d%03d,us\n", i, i%1000)
+	}
+	expected, err := encodingCsv([]byte(want.String()), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	small := strings.IndexByte(input.String()[100:], '\n') + 101
+	for _, size := range []int{small, input.Len()} {
+		for _, threshold := range []int{-1, 0} {
+			r := NewReader(strings.NewReader(input.String()[:size]))
+			r.FallbackThreshold = threshold
+			r.NormalizeColumns = map[int]Normalization{2: LowerCase}
+			r.NormalizeNames = map[string]Normalization{"City": TrimSpace | CollapseSpace, "Population": StripThousands}
+			records, err := r.ReadAll()
+			if err != nil {
+				t.Fatalf("TestNormalizeColumns: %v", err)
+			}
+			if !reflect.DeepEqual(records, expected[:len(records)]) {
+				t.Errorf("TestNormalizeColumns: got: %v want: %v", records[:2], expected[:2])
+			}
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Order Date":    "order_date",
+		"orderDate":     "order_date",
+		" order-date ":  "order_date",
+		"HTTPServer":    "http_server",
+		"Total (USD)":   "total_usd",
+		"address2Line":  "address2_line",
+		"already_snake": "already_snake",
+	}
+	for input, want := range tests {
+		if got := SnakeCase.apply(input); got != want {
+			t.Errorf("TestSnakeCase: got: %q want: %q", got, want)
+		}
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	var input strings.Builder
+	input.WriteString(" Order ID ,OrderDate,Customer Name\n")
+	for i := 0; input.Len() < 1<<20; i++ {
+		fmt.Fprintf(&input, "%d,2020-01-%02d,  JOHN  DOE \n", i, i%28+1)
+	}
+	small := strings.IndexByte(input.String()[100:], '\n') + 101
+
+	for _, size := range []int{small, input.Len()} {
+		for _, threshold := range []int{-1, 0} {
+			r := NewReader(strings.NewReader(input.String()[:size]))
+			r.FallbackThreshold = threshold
+			r.NormalizeHeader = TrimSpace | SnakeCase
+			r.NormalizeNames = map[string]Normalization{"Customer Name": TrimSpace | CollapseSpace | LowerCase}
+
+			if col, ok := r.ColumnIndex("order date"); !ok || col != 1 {
+				t.Errorf("TestColumnIndex: got: %d (%v) want: 1", col, ok)
+			}
+			if col, ok := r.ColumnIndex("unknown"); ok {
+				t.Errorf("TestColumnIndex: got: %d want: not found", col)
+			}
+			records, err := r.ReadAll()
+			if err != nil {
+				t.Fatalf("TestColumnIndex: %v", err)
+			}
+			if want := []string{"order_id", "order_date", "customer_name"}; !reflect.DeepEqual(records[0], want) {
+				t.Errorf("TestColumnIndex: got: %q want: %q", records[0], want)
+			}
+			if want := []string{"0", "2020-01-01", "john doe"}; !reflect.DeepEqual(records[1], want) {
+				t.Errorf("TestColumnIndex: got: %q want: %q", records[1], want)
+			}
+			if last := records[len(records)-1]; last[2] != "john doe" {
+				t.Errorf("TestColumnIndex: got: %q want: %q", last[2], "john doe")
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+// NullMask marks the fields of a record that are NULL (see NullLiterals),
+// with one bit per field.
+type NullMask []uint64
+
+// IsNull reports whether field col is NULL.
+func (m NullMask) IsNull(col int) bool {
+	return col >= 0 && col>>6 < len(m) && m[col>>6]&(1<<(col&63)) != 0
+}
+
+// ReadNullable reads one record like Read, with nil for the fields that are
+// NULL, so that NULL is told apart from empty fields.
+func (r *Reader) ReadNullable() ([]*string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+	record, nulls := r.records[r.currrecord-1], r.lastPos.nulls
+	fields := make([]*string, len(record))
+	for col := range record {
+		if !nulls.IsNull(col) {
+			fields[col] = &record[col]
+		}
+	}
+	return fields, nil
+}
+
+// markNulls sets the NULL masks of the records at positions to the fields
+// that equal one of literals, with the masks allocated in batches. Records
+// without NULL fields have no mask.
+func markNulls(literals []string, records [][]string, positions []recordPos) {
+	var masks NullMask
+	for i, record := range records {
+		var mask NullMask
+		for col, field := range record {
+			if !isNullLiteral(literals, field) {
+				continue
+			}
+			if mask == nil {
+				words := (len(record) + 63) >> 6
+				if len(masks) < words {
+					masks = make(NullMask, words+64)
+				}
+				mask, masks = masks[:words:words], masks[words:]
+			}
+			mask[col>>6] |= 1 << (col & 63)
+		}
+		positions[i].nulls = mask
+	}
+}
+
+// isNullLiteral reports whether field equals one of literals
+func isNullLiteral(literals []string, field string) bool {
+	for _, literal := range literals {
+		if field == literal {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNullLiterals(t *testing.T) {
+	input := fixture("id,a,b\n", 3000, "%d,NULL,\n", "%d,\"\",\\N\n", "%d,\"NULL\",x\n", "%d,NULLS,\"a\nb\"\n")
+
+	forModes(func(mode SIMDMode, chunkSize int) {
+		r := NewReader(strings.NewReader(input))
+		r.SIMD, r.ChunkSize, r.NullLiterals = mode, chunkSize, []string{"NULL", `\N`}
+		for n := -1; ; n++ {
+			record, err := r.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("TestNullLiterals(%d, %d): %v", mode, chunkSize, err)
+			}
+			want := [3]bool{}
+			switch {
+			case n < 0:
+			case n%4 == 0, n%4 == 2:
+				want[1] = true
+			case n%4 == 1:
+				want[2] = true
+			}
+			got := [3]bool{record.Nulls.IsNull(0), record.Nulls.IsNull(1), record.Nulls.IsNull(2)}
+			if got != want {
+				t.Errorf("TestNullLiterals(%d, %d): got: %v for %q want: %v", mode, chunkSize, got, record.Fields, want)
+				break
+			}
+			if (got == [3]bool{}) != (record.Nulls == nil) {
+				t.Errorf("TestNullLiterals(%d, %d): got: %v for %q want: no mask", mode, chunkSize, record.Nulls, record.Fields)
+			}
+		}
+	})
+
+	for _, mode := range []SIMDMode{SIMDForce, SIMDDisable} {
+		r := NewReader(strings.NewReader("NA,,x\n"))
+		r.SIMD, r.NullLiterals = mode, []string{"NA"}
+		fields, err := r.ReadNullable()
+		if err != nil || len(fields) != 3 || fields[0] != nil || fields[1] == nil || *fields[1] != "" || fields[2] == nil || *fields[2] != "x" {
+			t.Errorf("TestNullLiterals(%d): got: %v (%v) want: [nil \"\" \"x\"]", mode, fields, err)
+		}
+	}
+
+	var mask NullMask
+	if mask.IsNull(0) || (NullMask{1 << 63, 1}).IsNull(-1) || !(NullMask{1 << 63, 1}).IsNull(64) {
+		t.Errorf("TestNullLiterals: got: wrong bits want: bits 63 and 64")
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import "fmt"
+
+// A RecordError describes a problematic record, as handed to OnError.
+type RecordError struct {
+	Line   int      // line on which the record starts
+	Record []string // the record, which is partial if it could not be parsed
+	Err    error    // the error as reported by encoding/csv
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record on line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// An Action is the way to deal with a problematic record, as decided by OnError.
+type Action int
+
+const (
+	// Abort ends parsing with the error.
+	Abort Action = iota
+	// Skip drops the record.
+	Skip
+	// Replace returns the Record of the RecordError instead.
+	Replace
+)
@@ -0,0 +1,86 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestOnError(t *testing.T) {
+	var input bytes.Buffer
+	var good [][]string
+	var badLines []int
+	for i := 0; input.Len() < 1<<20; i++ {
+		switch i % 5000 {
+		case 1234:
+			input.WriteString("too,many,fields\n")
+			badLines = append(badLines, i+1)
+		case 4321:
+			input.WriteString("bare,qu\"ote\n")
+			badLines = append(badLines, i+1)
+		default:
+			fmt.Fprintf(&input, "%d,field %d\n", i, i)
+			good = append(good, []string{fmt.Sprint(i), fmt.Sprint("field ", i)})
+		}
+	}
+
+	for _, threshold := range []int{-1, input.Len() + 1} {
+		for _, action := range []Action{Skip, Replace, Abort} {
+			var mu sync.Mutex
+			var lines []int
+			r := NewReader(bytes.NewReader(input.Bytes()))
+			r.FallbackThreshold = threshold
+			r.OnError = func(err *RecordError) Action {
+				mu.Lock()
+				defer mu.Unlock()
+				lines = append(lines, err.Line)
+				err.Record = []string{"bad", fmt.Sprint(err.Line)}
+				return action
+			}
+			records, err := r.ReadAll()
+
+			switch action {
+			case Abort:
+				if !errors.Is(err, csv.ErrFieldCount) {
+					t.Errorf("TestOnError: got: %v want: %v", err, csv.ErrFieldCount)
+				}
+				continue
+			case Skip:
+				if err != nil || !reflect.DeepEqual(records, good) {
+					t.Errorf("TestOnError: got: %d records (%v) want: %d", len(records), err, len(good))
+				}
+			case Replace:
+				if err != nil || len(records) != len(good)+len(badLines) {
+					t.Errorf("TestOnError: got: %d records (%v) want: %d", len(records), err, len(good)+len(badLines))
+				} else if bad := records[badLines[0]-1]; bad[0] != "bad" || bad[1] != fmt.Sprint(badLines[0]) {
+					t.Errorf("TestOnError: got: %v want: replaced record", bad)
+				}
+			}
+			sort.Ints(lines)
+			if !reflect.DeepEqual(lines, badLines) {
+				t.Errorf("TestOnError: got: %v want: %v", lines, badLines)
+			}
+		}
+	}
+}
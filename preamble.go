@@ -0,0 +1,207 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// preambleBufferSize is the size of the buffer for skipping the preamble of
+// an io.Reader, which bounds the length of the lines passed to SkipUntil
+const preambleBufferSize = 64 << 10
+
+// skipPreamble skips the lines preceding the records (see SkipLines,
+// SkipUntil and SkipRows), including a sep= line if SepLine is set, when
+// starting at the beginning of the input. The skipped lines are discarded as
+// they are read, and only fed to InputHash.
+func (r *Reader) skipPreamble() error {
+	if r.startOffset != 0 || r.SkipLines <= 0 && r.SkipUntil == nil && r.SkipRows <= 0 && !r.SepLine {
+		return nil
+	}
+
+	var skipped int64
+	var lines preamble
+	var err error
+	if r.inMemory {
+		n := r.preambleLength(r.data, &lines)
+		if r.InputHash != nil {
+			r.InputHash.Write(r.data[:n])
+		}
+		r.data, skipped = r.data[n:], int64(n)
+		r.r = bytes.NewReader(r.data)
+	} else {
+		skipped, err = r.discardPreamble(&lines)
+	}
+
+	r.countBytes(skipped)
+	r.startOffset += skipped
+	r.lineOffset += lines.lines
+	r.dataOffset = r.startOffset
+	return err
+}
+
+// preamble keeps count of the lines and rows skipped so far
+type preamble struct {
+	lines  int
+	sep    int  // lines of the sep= line, which do not count toward SkipLines
+	rows   int  // rows skipped once past SkipLines and SkipUntil
+	inRows bool // whether past SkipLines and SkipUntil
+	quoted bool // whether within a quoted field of a row being skipped
+}
+
+// skipLine reports whether to skip line (including its terminator), given
+// the lines and rows skipped so far, which it accounts for if so
+func (r *Reader) skipLine(line []byte, p *preamble) bool {
+	if p.lines == 0 && r.SepLine && r.sepLine(line) {
+		p.lines, p.sep = 1, 1
+		return true
+	}
+	skip := r.skipPreambleLine(line, p)
+	if skip {
+		p.lines++
+	}
+	return skip
+}
+
+func (r *Reader) skipPreambleLine(line []byte, p *preamble) bool {
+	if !p.inRows {
+		if p.lines-p.sep < r.SkipLines {
+			return true
+		}
+		if r.SkipUntil != nil && !r.SkipUntil(trimTerminator(line)) {
+			return true
+		}
+		p.inRows = true
+	}
+	if p.quoted {
+		p.quoted = bytes.Count(line, []byte{'"'})&1 == 0
+	} else if p.rows >= r.SkipRows {
+		return false
+	} else if r.commentLine(line) || len(trimTerminator(line)) == 0 {
+		return true // neither counts as a row, as with encoding/csv
+	} else {
+		p.quoted = bytes.Count(line, []byte{'"'})&1 == 1
+	}
+	if !p.quoted {
+		p.rows++
+	}
+	return true
+}
+
+// sepLine reports whether line names a valid delimiter as in sep=;, in which
+// case the delimiter replaces Comma, or CommaString if it has multiple
+// characters
+func (r *Reader) sepLine(line []byte) bool {
+	sep := trimTerminator(line)
+	if !bytes.HasPrefix(sep, []byte("sep=")) || len(sep) == len("sep=") {
+		return false
+	}
+	sep = sep[len("sep="):]
+	comma, commaString := r.Comma, r.CommaString
+	if c, size := utf8.DecodeRune(sep); size == len(sep) {
+		r.Comma, r.CommaString = c, ""
+	} else {
+		r.CommaString = string(sep)
+	}
+	if r.Comma == r.Comment || !validDelim(r.Comma) || !r.validCommaString() || !r.validEscape() || !r.validCommentPrefix() {
+		r.Comma, r.CommaString = comma, commaString
+		return false
+	}
+	return true
+}
+
+// commentLine reports whether line is a comment (see Comment and CommentPrefix)
+func (r *Reader) commentLine(line []byte) bool {
+	for _, prefix := range r.commentPrefixes() {
+		if bytes.HasPrefix(line, stringBytes(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// preambleLength returns the length of the preamble of in-memory input
+func (r *Reader) preambleLength(data []byte, lines *preamble) (n int) {
+	for n < len(data) {
+		line := data[n:]
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i+1]
+		}
+		if !r.skipLine(line, lines) {
+			break
+		}
+		n += len(line)
+	}
+	return
+}
+
+// discardPreamble reads past the preamble of the input, after which r.r
+// continues with the first line that is not skipped
+func (r *Reader) discardPreamble(lines *preamble) (skipped int64, err error) {
+	br := bufio.NewReaderSize(r.r, preambleBufferSize)
+	discard := func(b []byte) {
+		if r.InputHash != nil {
+			r.InputHash.Write(b)
+		}
+		skipped += int64(len(b))
+		br.Discard(len(b))
+	}
+
+	for {
+		buf, peekErr := br.Peek(preambleBufferSize)
+		if len(buf) == 0 {
+			if peekErr != io.EOF {
+				err = peekErr
+			}
+			break
+		}
+		line := buf
+		i := bytes.IndexByte(buf, '\n')
+		if i >= 0 {
+			line = buf[:i+1]
+		}
+		if !r.skipLine(line, lines) {
+			break
+		}
+		discard(line)
+
+		// discard the remainder of a line that exceeds the buffer
+		for i < 0 {
+			b, sliceErr := br.ReadSlice('\n')
+			if r.InputHash != nil {
+				r.InputHash.Write(b)
+			}
+			skipped += int64(len(b))
+			if sliceErr != bufio.ErrBufferFull {
+				if sliceErr != nil && sliceErr != io.EOF {
+					err = sliceErr
+				}
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	buffered, _ := br.Peek(br.Buffered())
+	r.r = io.MultiReader(bytes.NewReader(append([]byte(nil), buffered...)), r.r)
+	return
+}
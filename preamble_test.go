@@ -0,0 +1,143 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSkipPreamble(t *testing.T) {
+	data := fixture("", 50000, "%[1]d,\"value \"\"%[1]d\"\"\"\n")
+	preamble := "Report of " + strings.Repeat("x", 100000) + "\r\n" + "generated,today\n\n# parameters\n"
+
+	for _, tc := range []struct {
+		name   string
+		data   string
+		config func(r *Reader)
+	}{
+		{"lines", data, func(r *Reader) { r.SkipLines = 4 }},
+		{"until", data, func(r *Reader) {
+			r.SkipUntil = func(line []byte) bool { return bytes.Equal(line, []byte("0,\"value \"\"0\"\"\"")) }
+		}},
+		{"lines-until", data, func(r *Reader) {
+			r.SkipLines, r.SkipUntil = 1, func(line []byte) bool { return !bytes.Contains(line, []byte("generated")) }
+			r.Comment = '#'
+		}},
+		{"rows", data, func(r *Reader) { r.SkipRows, r.Comment = 2, '#' }},
+		{"small", "name,value\n1,2\n", func(r *Reader) { r.SkipLines = 4 }},
+	} {
+		input := []byte(preamble + tc.data)
+		want, err := encodingCsv([]byte(tc.data), ',')
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		wantLine := 5 // for lines-until, the parser skips the blank line and comment
+
+		for _, inMemory := range []bool{false, true} {
+			r := NewReader(bytes.NewReader(input))
+			if inMemory {
+				r = newBytesReader(input)
+			}
+			tc.config(r)
+			r.InputHash = sha256.New()
+
+			first, err := r.ReadRecord()
+			if err != nil || !reflect.DeepEqual(first.Fields, want[0]) || first.Line != wantLine {
+				t.Fatalf("TestSkipPreamble(%s): got: %q on line %d, %v want: %q on line %d", tc.name, first.Fields, first.Line, err, want[0], wantLine)
+			}
+			got, err := r.ReadAll()
+			if err != nil || !reflect.DeepEqual(append([][]string{first.Fields}, got...), want) {
+				t.Fatalf("TestSkipPreamble(%s): got: %v want: records following the preamble", tc.name, err)
+			}
+			if sum := sha256.Sum256(input); !bytes.Equal(r.Digest(), sum[:]) {
+				t.Errorf("TestSkipPreamble(%s): got: digest %x want: %x", tc.name, r.Digest(), sum)
+			}
+
+			if !inMemory {
+				if err := r.Rewind(); err != nil {
+					t.Fatalf("TestSkipPreamble(%s): got: %v want: nil", tc.name, err)
+				}
+				if record, err := r.Read(); err != nil || !reflect.DeepEqual(record, want[0]) {
+					t.Errorf("TestSkipPreamble(%s): got: %q, %v after rewinding want: %q", tc.name, record, err, want[0])
+				}
+			}
+		}
+	}
+}
+
+func TestSepLine(t *testing.T) {
+	records := [][]string{{"name", "value"}}
+	for i := 0; i < 5000; i++ {
+		records = append(records, []string{fmt.Sprintf("n;%d", i), fmt.Sprintf("v,\t%d", i)})
+	}
+
+	for _, comma := range []rune{';', '\t', '€'} {
+		var b bytes.Buffer
+		w := NewWriter(&b)
+		w.Comma, w.UseCRLF, w.SepLine = comma, true, true
+		if err := w.WriteAll(records); err != nil {
+			t.Fatalf("TestSepLine(%q): %v", comma, err)
+		}
+		if want := "sep=" + string(comma) + "\r\n"; !strings.HasPrefix(b.String(), want) || strings.Count(b.String(), "sep=") != 1 {
+			t.Fatalf("TestSepLine(%q): got: %.20q want: a single %q", comma, b.String(), want)
+		}
+
+		for _, mode := range []SIMDMode{SIMDForce, SIMDDisable} {
+			for _, inMemory := range []bool{false, true} {
+				r := NewReader(bytes.NewReader(b.Bytes()))
+				if inMemory {
+					r = newBytesReader(b.Bytes())
+				}
+				r.SIMD, r.SepLine = mode, true
+				first, err := r.ReadRecord()
+				if err != nil || !reflect.DeepEqual(first.Fields, records[0]) || first.Line != 2 {
+					t.Fatalf("TestSepLine(%q, %d, %v): got: %q on line %d, %v want: %q on line 2", comma, mode, inMemory, first.Fields, first.Line, err, records[0])
+				}
+				got, err := r.ReadAll()
+				if err != nil || !reflect.DeepEqual(append([][]string{first.Fields}, got...), records) {
+					t.Errorf("TestSepLine(%q, %d, %v): got: %d records, %v want: %d", comma, mode, inMemory, len(got)+1, err, len(records))
+				}
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		input  string
+		config func(r *Reader)
+		want   [][]string
+	}{
+		{"sep=||\na||b\n", func(r *Reader) {}, [][]string{{"a", "b"}}},
+		{"sep=;\ntitle\na;b\n", func(r *Reader) { r.SkipLines = 1 }, [][]string{{"a", "b"}}},
+		{"sep=\na,b\n", func(r *Reader) {}, [][]string{{"sep="}, {"a", "b"}}},
+		{"sep=#\na,b\n", func(r *Reader) { r.Comment = '#' }, [][]string{{"sep=#"}, {"a", "b"}}},
+		{"a;b\nsep=;\n", func(r *Reader) {}, [][]string{{"a;b"}, {"sep=;"}}},
+	} {
+		for _, mode := range []SIMDMode{SIMDForce, SIMDDisable} {
+			r := NewReader(strings.NewReader(tc.input))
+			r.SIMD, r.SepLine, r.FieldsPerRecord = mode, true, -1
+			tc.config(r)
+			if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("TestSepLine(%q, %d): got: %q, %v want: %q", tc.input, mode, got, err, tc.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"hash/maphash"
+	"io"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// A ColumnProfile holds the statistics of a column (see Profile)
+type ColumnProfile struct {
+	Name      string  // of the column in the header
+	Nulls     int     // empty fields, including those of records that lack the column
+	Distinct  uint64  // estimate of the number of distinct fields other than nulls
+	Numeric   bool    // whether all fields other than nulls are numbers
+	Min, Max  string  // fields other than nulls, by value if Numeric and by byte order otherwise
+	AvgLength float64 // in bytes of the fields other than nulls
+}
+
+// Profile reads all records of r and returns the statistics of every column,
+// along with the number of records. The first record is taken to be the
+// header, which names the columns. The statistics are computed by the parsing
+// workers a block at a time, and merged in the order of the blocks. Distinct
+// counts are estimated by HyperLogLog, within about 2%.
+func Profile(r io.Reader, opts ...Option) ([]ColumnProfile, int, error) {
+	rd := NewReader(r)
+	for _, opt := range opts {
+		opt(rd)
+	}
+	rd.needHeader = true // resolved up front, so the workers can skip it
+
+	seed := maphash.MakeSeed()
+	partials, errs := streamBlocks[*profile](rd, func(records [][]string, positions []recordPos) (interface{}, error) {
+		p := &profile{seed: seed}
+		for i, record := range records {
+			if positions[i].offset != rd.dataOffset {
+				p.add(record)
+			}
+		}
+		return []*profile{p}, nil
+	})
+	total := &profile{seed: seed}
+	for p := range partials {
+		total.merge(p)
+	}
+	if err := <-errs; err != nil {
+		return nil, 0, err
+	}
+
+	rd.Lock()
+	defer rd.Unlock()
+	return total.columnProfiles(rd.header), total.records, nil
+}
+
+// profile holds the statistics of a block of records, or of several merged
+type profile struct {
+	seed    maphash.Seed
+	records int
+	columns []columnStats
+}
+
+type columnStats struct {
+	fields               int // present, including empty ones
+	values               int // non-empty fields
+	numbers              int // values that parse as numbers, while all do
+	length               int
+	min, max             string
+	minNumber, maxNumber float64
+	minText, maxText     string // of minNumber and maxNumber
+	distinct             *hyperLogLog
+}
+
+func (p *profile) add(record []string) {
+	p.records++
+	for len(p.columns) < len(record) {
+		p.columns = append(p.columns, columnStats{distinct: new(hyperLogLog)})
+	}
+	for i, field := range record {
+		c := &p.columns[i]
+		c.fields++
+		if field == "" {
+			continue
+		}
+		if c.values == 0 || field < c.min {
+			c.min = field
+		}
+		if c.values == 0 || field > c.max {
+			c.max = field
+		}
+		if c.numbers == c.values {
+			if f, err := strconv.ParseFloat(field, 64); err == nil && !math.IsNaN(f) {
+				if c.numbers == 0 || f < c.minNumber {
+					c.minNumber, c.minText = f, field
+				}
+				if c.numbers == 0 || f > c.maxNumber {
+					c.maxNumber, c.maxText = f, field
+				}
+				c.numbers++
+			}
+		}
+		c.values++
+		c.length += len(field)
+		c.distinct.add(maphash.String(p.seed, field))
+	}
+}
+
+// merge adds the statistics of o, copying the fields it keeps so the blocks
+// they point into can be released
+func (p *profile) merge(o *profile) {
+	p.records += o.records
+	for len(p.columns) < len(o.columns) {
+		p.columns = append(p.columns, columnStats{distinct: new(hyperLogLog)})
+	}
+	for i := range o.columns {
+		c, oc := &p.columns[i], &o.columns[i]
+		if oc.values > 0 {
+			if c.values == 0 || oc.min < c.min {
+				c.min = clone(oc.min)
+			}
+			if c.values == 0 || oc.max > c.max {
+				c.max = clone(oc.max)
+			}
+		}
+		if c.numbers == c.values && oc.numbers == oc.values && oc.numbers > 0 {
+			if c.numbers == 0 || oc.minNumber < c.minNumber {
+				c.minNumber, c.minText = oc.minNumber, clone(oc.minText)
+			}
+			if c.numbers == 0 || oc.maxNumber > c.maxNumber {
+				c.maxNumber, c.maxText = oc.maxNumber, clone(oc.maxText)
+			}
+		}
+		c.numbers += oc.numbers
+		c.fields += oc.fields
+		c.values += oc.values
+		c.length += oc.length
+		c.distinct.merge(oc.distinct)
+	}
+}
+
+func clone(s string) string {
+	return string([]byte(s))
+}
+
+func (p *profile) columnProfiles(header []string) []ColumnProfile {
+	n := len(p.columns)
+	if len(header) > n {
+		n = len(header)
+	}
+	profiles := make([]ColumnProfile, n)
+	for i := range profiles {
+		cp := &profiles[i]
+		if i < len(header) {
+			cp.Name = header[i]
+		}
+		cp.Nulls = p.records
+		if i >= len(p.columns) {
+			continue
+		}
+		c := &p.columns[i]
+		cp.Nulls -= c.values
+		if c.values == 0 {
+			continue
+		}
+		cp.Distinct = c.distinct.estimate()
+		cp.Numeric = c.numbers == c.values
+		if cp.Numeric {
+			cp.Min, cp.Max = c.minText, c.maxText
+		} else {
+			cp.Min, cp.Max = c.min, c.max
+		}
+		cp.AvgLength = float64(c.length) / float64(c.values)
+	}
+	return profiles
+}
+
+// hllPrecision is the number of bits of a hash that select a register of a
+// hyperLogLog, for a standard error of 1.04/sqrt(1<<hllPrecision)
+const hllPrecision = 12
+
+// hyperLogLog estimates the number of distinct hashes added
+type hyperLogLog [1 << hllPrecision]uint8
+
+func (h *hyperLogLog) add(hash uint64) {
+	register := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h[register] {
+		h[register] = rank
+	}
+}
+
+func (h *hyperLogLog) merge(o *hyperLogLog) {
+	for i, rank := range o {
+		if rank > h[i] {
+			h[i] = rank
+		}
+	}
+}
+
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h))
+	sum, zeros := 0.0, 0
+	for _, rank := range h {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros)) // linear counting is more accurate for few
+	}
+	return uint64(e + 0.5)
+}
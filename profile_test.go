@@ -0,0 +1,77 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("id,city,price,note\n")
+	cities := []string{"Paris", "Berlin", "Oslo", "Rome", "Lima", "Quito", "Bern"}
+	for i := 0; i < 20000; i++ {
+		price := fmt.Sprint(float64(i%1000) / 4)
+		if i%10 == 0 {
+			price = ""
+		}
+		switch {
+		case i%100 == 3:
+			fmt.Fprintf(&input, "%d,%s,%s\n", i, cities[i%len(cities)], price)
+		case i%3 == 0:
+			fmt.Fprintf(&input, "%d,%s,%s,\"n%d\"\n", i, cities[i%len(cities)], price, i%50)
+		default:
+			fmt.Fprintf(&input, "%d,%s,%s,\n", i, cities[i%len(cities)], price)
+		}
+	}
+
+	forModes(func(mode SIMDMode, chunkSize int) {
+		profiles, records, err := Profile(strings.NewReader(input.String()), func(r *Reader) {
+			r.SIMD, r.ChunkSize, r.FieldsPerRecord = mode, chunkSize, -1
+		})
+		if err != nil || records != 20000 || len(profiles) != 4 {
+			t.Fatalf("TestProfile(%d, %d): got: %d profiles of %d records (%v) want: 4 of 20000", mode, chunkSize, len(profiles), records, err)
+		}
+		within := func(got uint64, want float64) bool {
+			return math.Abs(float64(got)-want) <= math.Max(1, want*0.05) // hashes may collide
+		}
+
+		id, city, price, note := profiles[0], profiles[1], profiles[2], profiles[3]
+		if id.Name != "id" || id.Nulls != 0 || !id.Numeric || id.Min != "0" || id.Max != "19999" || !within(id.Distinct, 20000) || id.AvgLength != float64(10+90*2+900*3+9000*4+10000*5)/20000 {
+			t.Errorf("TestProfile(%d, %d): got: %+v", mode, chunkSize, id)
+		}
+		if city.Name != "city" || city.Nulls != 0 || city.Numeric || city.Min != "Berlin" || city.Max != "Rome" || !within(city.Distinct, 7) {
+			t.Errorf("TestProfile(%d, %d): got: %+v", mode, chunkSize, city)
+		}
+		if price.Nulls != 2000 || !price.Numeric || price.Min != "0.25" || price.Max != "249.75" || !within(price.Distinct, 900) {
+			t.Errorf("TestProfile(%d, %d): got: %+v", mode, chunkSize, price)
+		}
+		if note.Nulls != 20000-6600 || note.Numeric || note.Min != "n0" || note.Max != "n9" || !within(note.Distinct, 50) || note.AvgLength < 2 || note.AvgLength > 3 {
+			t.Errorf("TestProfile(%d, %d): got: %+v", mode, chunkSize, note)
+		}
+	})
+
+	if profiles, records, err := Profile(strings.NewReader("")); err != nil || len(profiles) != 0 || records != 0 {
+		t.Errorf("TestProfile: got: %v, %d (%v) want: none", profiles, records, err)
+	}
+	if _, _, err := Profile(strings.NewReader("a,b\n1\n")); err == nil {
+		t.Errorf("TestProfile: expected error")
+	}
+}
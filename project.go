@@ -0,0 +1,97 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+// projects reports whether the records are projected onto a subset of the
+// columns (see SelectColumns)
+func (r *Reader) projects() bool {
+	return len(r.SelectColumns) > 0 || len(r.SelectNames) > 0 || r.query != nil && len(r.query.columns) > 0
+}
+
+// projection selects the columns of the records
+type projection struct {
+	columns []int  // of the input for every selected column, or -1
+	keep    []bool // whether a column of the input is selected
+}
+
+// newProjection combines the columns selected by index and by name, where the
+// names are looked up in header
+func (r *Reader) newProjection(header []string) *projection {
+	p := &projection{columns: append([]int(nil), r.SelectColumns...)}
+	for _, name := range r.SelectNames {
+		col := -1
+		name = r.NormalizeHeader.apply(name)
+		for i, column := range header {
+			if r.NormalizeHeader.apply(column) == name {
+				col = i
+				break
+			}
+		}
+		p.columns = append(p.columns, col)
+	}
+	kept := p.columns
+	if r.query != nil {
+		for _, c := range r.query.columns {
+			p.columns = append(p.columns, r.queryColumn(c, header))
+		}
+		// the columns of the condition are unescaped for it as well
+		kept = append([]int(nil), p.columns...)
+		for _, c := range r.query.refs {
+			kept = append(kept, r.queryColumn(c, header))
+		}
+	}
+	for _, col := range kept {
+		if col >= 0 {
+			for len(p.keep) <= col {
+				p.keep = append(p.keep, false)
+			}
+			p.keep[col] = true
+		}
+	}
+	return p
+}
+
+// keeps reports whether column col of the input is selected
+func (p *projection) keeps(col int) bool {
+	return p == nil || col < len(p.keep) && p.keep[col]
+}
+
+// project replaces the records by their selected columns, which share a
+// single allocation; columns beyond the end of a record are left empty
+func (p *projection) project(records [][]string) {
+	fields := make([]string, len(records)*len(p.columns))
+	for i, record := range records {
+		projected := fields[:len(p.columns):len(p.columns)]
+		fields = fields[len(p.columns):]
+		for j, col := range p.columns {
+			if col >= 0 && col < len(record) {
+				projected[j] = record[col]
+			}
+		}
+		records[i] = projected
+	}
+}
+
+// projected returns the selected columns of record, once resolved
+func (r *Reader) projected(record []string) []string {
+	if r.proj == nil || record == nil {
+		return record
+	}
+	records := [][]string{record}
+	r.proj.project(records)
+	return records[0]
+}
@@ -0,0 +1,84 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSelectColumns(t *testing.T) {
+	var input bytes.Buffer
+	for c := 0; c < 20; c++ {
+		if c > 0 {
+			input.WriteByte(',')
+		}
+		fmt.Fprintf(&input, "C%d", c)
+	}
+	input.WriteByte('\n')
+	for i := 0; input.Len() < 1<<20; i++ {
+		for c := 0; c < 20; c++ {
+			if c > 0 {
+				input.WriteByte(',')
+			}
+			fmt.Fprintf(&input, "\"%d \"\"%d\"\"\"", i, c)
+		}
+		input.WriteByte('\n')
+	}
+	all, err := encodingCsv(input.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := make([][]string, len(all))
+	for i, record := range all {
+		expected[i] = []string{record[3], record[7], "", record[1]}
+	}
+	expected[0] = []string{"c3", "c7", "", "c1"} // see NormalizeHeader
+
+	small := bytes.IndexByte(input.Bytes()[300:], '\n') + 301
+	for _, size := range []int{small, input.Len()} {
+		for _, threshold := range []int{-1, 0} {
+			r := NewReader(bytes.NewReader(input.Bytes()[:size]))
+			r.FallbackThreshold = threshold
+			r.NormalizeHeader = LowerCase
+			r.SelectColumns = []int{3}
+			r.SelectNames = []string{"C7", "missing", "c1"}
+			records, err := r.ReadAll()
+			if err != nil {
+				t.Fatalf("TestSelectColumns: %v", err)
+			}
+			if !reflect.DeepEqual(records, expected[:len(records)]) || len(records) < 2 {
+				t.Errorf("TestSelectColumns: got: %q want: %q", records[:2], expected[:2])
+			}
+		}
+	}
+
+	// reading record by record, where FieldPos refers to the input
+	r := NewReader(bytes.NewReader(input.Bytes()))
+	r.FallbackThreshold = -1
+	r.SelectNames = []string{"C2"}
+	for i := 0; i < 3; i++ {
+		if record, err := r.Read(); err != nil || !reflect.DeepEqual(record, all[i][2:3]) {
+			t.Fatalf("TestSelectColumns: got: %q, %v want: %q", record, err, all[i][2:3])
+		}
+	}
+	if line, column := r.FieldPos(0); line != 3 || column != 2*len(`"1 ""0""",`)+1 {
+		t.Errorf("TestSelectColumns: got: line %d, column %d want: line 3, column %d", line, column, 2*len(`"1 ""0""",`)+1)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+// ReadRaw reads one record like Read, but returns its fields as byte slices.
+// The slices reference the chunk buffers holding the input (or the unescaped
+// copy of a field containing quotes), so no strings are allocated; they must
+// not be modified. Unlike RawRecord, the fields are parsed.
+func (r *Reader) ReadRaw() ([][]byte, error) {
+	record, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	return fieldBytes(record, make([][]byte, len(record))), nil
+}
+
+// ReadRawAll reads all the remaining records like ReadAll, but returns their
+// fields as byte slices, which share memory as described for ReadRaw. The
+// slices of all records are allocated at once.
+func (r *Reader) ReadRawAll() ([][][]byte, error) {
+	records, err := r.ReadAll()
+	if len(records) == 0 {
+		return nil, err
+	}
+
+	n := 0
+	for _, record := range records {
+		n += len(record)
+	}
+	all, fields := make([][][]byte, len(records)), make([][]byte, n)
+	for i, record := range records {
+		all[i] = fieldBytes(record, fields[:len(record):len(record)])
+		fields = fields[len(record):]
+	}
+	return all, err
+}
+
+// fieldBytes fills fields with the bytes of the fields of record
+func fieldBytes(record []string, fields [][]byte) [][]byte {
+	for i, field := range record {
+		fields[i] = stringBytes(field)
+	}
+	return fields
+}
@@ -0,0 +1,72 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestReadRaw(t *testing.T) {
+	for _, input := range selfTestCorpus() {
+		want, err := encodingCsv(input, ',')
+		if err != nil {
+			continue // malformed input fails the same way as for ReadAll
+		}
+		equal := func(got [][]byte, record []string) bool {
+			if len(got) != len(record) {
+				return false
+			}
+			for i := range got {
+				if string(got[i]) != record[i] {
+					return false
+				}
+			}
+			return true
+		}
+
+		for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+			all, err := newBytesReader(input, func(r *Reader) { r.kernel = kernel }).ReadRawAll()
+			if err != nil || len(all) != len(want) {
+				t.Fatalf("TestReadRaw: got: %d records, %v want: %d", len(all), err, len(want))
+			}
+			for i := range all {
+				if !equal(all[i], want[i]) {
+					t.Fatalf("TestReadRaw: record %d: got: %q want: %q", i, all[i], want[i])
+				}
+			}
+
+			r := NewReader(bytes.NewReader(input))
+			r.kernel = kernel
+			for i := 0; ; i++ {
+				fields, err := r.ReadRaw()
+				if err == io.EOF {
+					if i != len(want) {
+						t.Errorf("TestReadRaw: got: %d records want: %d", i, len(want))
+					}
+					break
+				} else if err != nil {
+					t.Fatalf("TestReadRaw: %v", err)
+				}
+				if !equal(fields, want[i]) {
+					t.Fatalf("TestReadRaw: record %d: got: %q want: %q", i, fields, want[i])
+				}
+			}
+		}
+	}
+}
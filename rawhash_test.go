@@ -0,0 +1,57 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRawHash(t *testing.T) {
+	input := fixture("id,note\r\n", 3000, "%d,plain\n", "%[1]d,\"multi\r\nline \"\"%[1]d\"\"\"\r\n", "%d,\"x\"\n")
+
+	forModes(func(mode SIMDMode, chunkSize int) {
+		r := NewReader(strings.NewReader(input))
+		r.SIMD, r.ChunkSize, r.KeepRaw, r.RawHash = mode, chunkSize, true, sha256.New
+		r.WhereColumns = map[int]Predicate{0: func(field string) bool { return field != "5" }}
+		n := 0
+		for ; ; n++ {
+			record, err := r.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("TestRawHash(%d, %d): %v", mode, chunkSize, err)
+			}
+			want := sha256.Sum256(record.Raw)
+			if !bytes.Equal(record.Hash, want[:]) || !bytes.Equal(r.RawRecordHash(), want[:]) {
+				t.Errorf("TestRawHash(%d, %d): got: %x for %q want: %x", mode, chunkSize, record.Hash, record.Raw, want)
+				break
+			}
+		}
+		if n != 3000 {
+			t.Errorf("TestRawHash(%d, %d): got: %d records want: 3000", mode, chunkSize, n)
+		}
+	})
+
+	r := NewReader(strings.NewReader(input))
+	if record, err := r.ReadRecord(); err != nil || record.Hash != nil || r.RawRecordHash() != nil {
+		t.Errorf("TestRawHash: got: %x (%v) want: none", record.Hash, err)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"unsafe"
+)
+
+// errRawQuotesEscape is returned when RawQuotes is combined with Escape,
+// whose escape sequences could not be told apart in the raw fields
+var errRawQuotesEscape = errors.New("simdcsv: RawQuotes cannot be combined with Escape")
+
+// rawQuotes replaces the quoted fields of record by their input in raw, the
+// input of the record, surrounding and doubled quotes included (see
+// Reader.RawQuotes). The fields share memory with raw.
+func rawQuotes(record []string, raw []byte, delimiter string, trimLeadingSpace bool) {
+	raw = trimTerminator(raw)
+	if bytes.IndexByte(raw, '"') < 0 {
+		return
+	}
+	s := *(*string)(unsafe.Pointer(&raw))
+	for i := range record {
+		if trimLeadingSpace {
+			s = trimLeftSpace(s)
+		}
+		end := strings.Index(s, delimiter)
+		if strings.HasPrefix(s, `"`) {
+			end = quotedEnd(s, delimiter)
+			record[i] = s[:end]
+		}
+		if end < 0 || !strings.HasPrefix(s[end:], delimiter) {
+			return // last field
+		}
+		s = s[end+len(delimiter):]
+	}
+}
+
+// quotedEnd returns the offset beyond the closing quote of the quoted field
+// at the start of s, which is the quote followed by delimiter or the end of
+// s, as other quotes can only be doubled or lazy
+func quotedEnd(s, delimiter string) int {
+	i := 1
+	for {
+		j := strings.IndexByte(s[i:], '"')
+		if j < 0 {
+			return len(s)
+		}
+		i += j + 1
+		if i < len(s) && s[i] == '"' {
+			i++ // doubled quote
+		} else if i == len(s) || strings.HasPrefix(s[i:], delimiter) {
+			return i
+		}
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRawQuotes(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		row    string
+		want   []string
+		config func(r *Reader)
+	}{
+		{"plain", "%d,a,b\n", []string{"a", "b"}, func(*Reader) {}},
+		{"quoted", "%d,\"a,b\",\"\"\n", []string{`"a,b"`, `""`}, func(*Reader) {}},
+		{"doubled", "%d,\"a \"\"b\"\"\",c\r\n", []string{`"a ""b"""`, "c"}, func(*Reader) {}},
+		{"multiline", "%d,\"a\r\nb\",\"c\nd\"\n", []string{"\"a\r\nb\"", "\"c\nd\""}, func(*Reader) {}},
+		{"trim", "%d, \"a\",  b\n", []string{`"a"`, "b"}, func(r *Reader) { r.TrimLeadingSpace = true }},
+		{"lazy", "%d,\"a\"b\",c\"\n", []string{`"a"b"`, `c"`}, func(r *Reader) { r.LazyQuotes = true }},
+		{"separator", "%d||\"a||b\"||c\n", []string{`"a||b"`, "c"}, func(r *Reader) { r.CommaString = "||" }},
+	} {
+		input := fixture("", 1000, tc.row)
+		forModes(func(mode SIMDMode, chunkSize int) {
+			r := NewReader(strings.NewReader(input))
+			r.SIMD, r.ChunkSize, r.RawQuotes = mode, chunkSize, true
+			tc.config(r)
+			records, err := r.ReadAll()
+			if err != nil || len(records) != 1000 {
+				t.Errorf("TestRawQuotes(%s, %d, %d): got: %d records (%v) want: 1000", tc.name, mode, chunkSize, len(records), err)
+				return
+			}
+			for i, record := range records {
+				if want := append([]string{fmt.Sprint(i)}, tc.want...); !reflect.DeepEqual(record, want) {
+					t.Errorf("TestRawQuotes(%s, %d, %d): got: %q want: %q", tc.name, mode, chunkSize, record, want)
+					break
+				}
+			}
+		}, 64, 4096, 1<<20)
+	}
+
+	r := NewReader(strings.NewReader("a,b\n"))
+	r.RawQuotes, r.Escape = true, '\\'
+	if _, err := r.ReadAll(); err != errRawQuotesEscape {
+		t.Errorf("TestRawQuotes: got: %v want: %v", err, errRawQuotesEscape)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"io"
+	"runtime"
+)
+
+// speculation holds the masks of a chunk as preprocessed ahead of stage 1,
+// on the assumption that the chunk does not start within quotes
+type speculation struct {
+	masks    []uint64
+	postProc []uint64
+	quoted   uint64
+}
+
+// inputAt is input that can be read at offsets
+type inputAt interface {
+	io.ReaderAt
+	io.Seeker
+}
+
+// chunkAt is a chunk as read at its offset (see readChunksAt)
+type chunkAt struct {
+	buf  []byte
+	spec *speculation
+	err  error
+}
+
+// inputAt returns the input to read chunks from at offsets, along with the
+// offset to continue at, if the input supports it and there are CPUs to spare
+// for preprocessing the chunks in parallel. The offset is taken from
+// the input itself, so that input that was positioned before it was handed
+// to the Reader is read from there.
+func (r *Reader) inputAt() (inputAt, int64, bool) {
+	if runtime.GOMAXPROCS(0) < 2 {
+		return nil, 0, false
+	}
+	if r.ra == nil || r.sep != nil || r.esc != nil || r.Stage1 != nil || r.Trace != nil || r.Replay != nil || r.Faults.reads() || len(r.commentPrefixes()) > 0 {
+		return nil, 0, false // these depend on reading the chunks in turn
+	}
+	in, ok := r.r.(inputAt)
+	if !ok {
+		return nil, 0, false
+	}
+	offset, err := in.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, false
+	}
+	return in, offset, true
+}
+
+// readChunksAt reads the input that follows chunk, the first chunk that was
+// already read, at offset onwards from multiple goroutines. Every chunk is
+// preprocessed as soon as it is read, on the assumption that it does not
+// start within quotes, which leaves stage 1 to redo the chunks that a quoted
+// field spans into.
+func (r *Reader) readChunksAt(in inputAt, offset int64, chunk []byte, chunkSize int, masksSize int, bufchan chan chunkIn, out *outputSlots) {
+
+	done := make(chan struct{})
+	pending := make(chan chan chunkAt, runtime.GOMAXPROCS(0))
+
+	defer func() {
+		close(done)
+		for result := range pending {
+			r.releaseChunk((<-result).buf)
+		}
+		// leave the input positioned after the chunks read, as reading it
+		// in turn would
+		in.Seek(offset, io.SeekStart)
+		close(bufchan)
+	}()
+
+	go func() {
+		defer close(pending)
+		for at := offset; ; at += int64(chunkSize) {
+			result := make(chan chunkAt, 1)
+			select {
+			case pending <- result:
+			case <-done:
+				return
+			}
+			go func(at int64) {
+				buf := r.acquireChunk(chunkSize)
+				n, err := in.ReadAt(buf, at)
+				if n > 0 && err == io.EOF {
+					err = nil
+				}
+				result <- chunkAt{buf[:n], r.speculate(buf[:n], masksSize), err}
+			}(at)
+		}
+	}()
+
+	var spec *speculation // of chunk, which stage 1 preprocesses for the first
+	sequence := 0
+	for result := range pending {
+		sequence++
+		out.admit(sequence)
+		next := <-result
+		if next.err != nil && next.err != io.EOF {
+			// hand on what was read as not the last chunk, so the row cut
+			// short is left out (see readChunks)
+			r.readFailed(out, next.err)
+			bufchan <- chunkIn{chunk, false, spec}
+			if len(next.buf) > 0 {
+				r.countBytes(int64(len(next.buf)))
+				if r.InputHash != nil {
+					r.InputHash.Write(next.buf)
+				}
+				offset += int64(len(next.buf))
+				bufchan <- chunkIn{next.buf, false, next.spec}
+			} else {
+				r.releaseChunk(next.buf)
+			}
+			break
+		}
+		if next.err != nil || out.isStopped() || out.failed() {
+			r.releaseChunk(next.buf)
+			bufchan <- chunkIn{chunk, true, spec}
+			break
+		}
+		r.countBytes(int64(len(next.buf)))
+		if r.InputHash != nil {
+			r.InputHash.Write(next.buf)
+		}
+		offset += int64(len(next.buf))
+		bufchan <- chunkIn{chunk, false, spec}
+		chunk, spec = next.buf, next.spec
+	}
+}
+
+// speculate preprocesses buf as if it does not start within quotes
+func (r *Reader) speculate(buf []byte, masksSize int) *speculation {
+	if len(buf) == 0 {
+		return nil
+	}
+	masks, postProc := takeMasks(masksSize, ((len(buf)>>6)+1)*2)
+	spec := &speculation{}
+	spec.masks, spec.postProc, spec.quoted = r.preprocess(buf, r.delimiter()[0], 0, masks, postProc)
+	return spec
+}
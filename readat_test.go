@@ -0,0 +1,127 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadChunksAt(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	var data bytes.Buffer
+	data.WriteString("preceding,input\n")
+	for i := 0; i < 5000; i++ {
+		// quoted fields with separators that chunks are bound to start within
+		fmt.Fprintf(&data, "%d,\"%s\",\"\"\"%d\"\"\"\n", i, strings.Repeat("a,b ", i%50), i)
+	}
+	start := int64(len("preceding,input\n"))
+
+	want, err := csv.NewReader(bytes.NewReader(data.Bytes()[start:])).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{1000, 4096, 65536} {
+		in := bytes.NewReader(data.Bytes())
+		in.Seek(start, io.SeekStart)
+		r := NewReader(in)
+		r.ChunkSize = size
+		if _, _, ok := r.inputAt(); !ok {
+			t.Fatalf("TestReadChunksAt(%d): got: sequential want: at offsets", size)
+		}
+		records, err := r.ReadAll()
+		if err != nil || !reflect.DeepEqual(records, want) {
+			t.Errorf("TestReadChunksAt(%d): got: %d records, %v want: %d records", size, len(records), err, len(want))
+		}
+		if offset, _ := in.Seek(0, io.SeekCurrent); offset != int64(data.Len()) {
+			t.Errorf("TestReadChunksAt(%d): got: input at %d want: %d", size, offset, data.Len())
+		}
+	}
+
+	// input that is not an io.ReaderAt is read in turn
+	r := NewReader(struct{ io.Reader }{bytes.NewReader(data.Bytes()[start:])})
+	r.ChunkSize = 1000
+	if _, _, ok := r.inputAt(); ok {
+		t.Errorf("TestReadChunksAt: got: at offsets want: sequential")
+	}
+	if records, err := r.ReadAll(); err != nil || !reflect.DeepEqual(records, want) {
+		t.Errorf("TestReadChunksAt: got: %d records, %v want: %d records", len(records), err, len(want))
+	}
+}
+
+// failingReaderAt fails reads at offsets from fail onwards
+type failingReaderAt struct {
+	*bytes.Reader
+	fail int64
+}
+
+var errReadAt = errors.New("read failed")
+
+func (f *failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > f.fail {
+		n := 0
+		if off < f.fail {
+			n, _ = f.Reader.ReadAt(p[:f.fail-off], off)
+		}
+		return n, errReadAt
+	}
+	return f.Reader.ReadAt(p, off)
+}
+
+func TestReadChunksAtError(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	var data bytes.Buffer
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&data, "%d,\"%s\",%d\n", i, strings.Repeat("a,b ", i%50), i)
+	}
+	want, err := csv.NewReader(bytes.NewReader(data.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReader(&failingReaderAt{bytes.NewReader(data.Bytes()), int64(data.Len() / 2)})
+	r.ChunkSize = 4096
+	if _, _, ok := r.inputAt(); !ok {
+		t.Fatalf("TestReadChunksAtError: got: sequential want: at offsets")
+	}
+	var got [][]string
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if err != errReadAt {
+				t.Fatalf("TestReadChunksAtError: got: %v want: %v", err, errReadAt)
+			}
+			break
+		}
+		got = append(got, record)
+	}
+	// the records in the input up to the failed read, which ends within a row
+	n := bytes.Count(data.Bytes()[:data.Len()/2], []byte{'\n'})
+	if !reflect.DeepEqual(got, want[:n]) {
+		t.Errorf("TestReadChunksAtError: got: %d records want: %d", len(got), n)
+	}
+}
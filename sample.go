@@ -0,0 +1,217 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync/atomic"
+)
+
+var errSampleCombined = errors.New("simdcsv: SampleEvery and SampleSize cannot be combined with Sentinel, SkipFooter or Replay")
+
+// samples reports whether only a sample of the records is read (see
+// SampleEvery and SampleSize)
+func (r *Reader) samples() bool {
+	return r.SampleEvery > 1 || r.SampleSize > 0
+}
+
+// validSample checks that sampling can be combined with the other options
+func (r *Reader) validSample() error {
+	if r.samples() && (r.Sentinel != nil || r.SkipFooter > 0 || r.Replay != nil) {
+		return errSampleCombined
+	}
+	return nil
+}
+
+// sampler selects the records of the sample by their number in the input,
+// taking the blocks of records in order
+type sampler struct {
+	every     int
+	size      int
+	sequence  int64 // of the next block, accessed atomically
+	index     int   // number of the next record in the input
+	offered   int   // number of records offered to the reservoir
+	next      int   // offer that enters the reservoir next, once it is full
+	w         float64
+	reservoir []sampled
+
+	// of encoding/csv, the records in the reservoir once the input ended
+	drained   bool
+	records   [][]string
+	positions []recordPos
+}
+
+// sampled is a record in the reservoir
+type sampled struct {
+	index  int
+	record []string
+	pos    recordPos
+}
+
+func (r *Reader) newSampler() *sampler {
+	s := &sampler{every: r.SampleEvery, size: r.SampleSize}
+	if s.every < 1 {
+		s.every = 1
+	}
+	return s
+}
+
+// keep reports whether the next record of the input is sampled, or else
+// offers it to the reservoir, if any. The first record of the input is
+// always kept.
+func (s *sampler) keep(record []string, pos recordPos, first bool) bool {
+	index := s.index
+	s.index++
+	if first && index == 0 {
+		return true
+	}
+	if index%s.every != 0 {
+		return false
+	}
+	if s.size <= 0 {
+		return true
+	}
+	s.offer(sampled{index, record, pos})
+	return false
+}
+
+// offer offers a record to the reservoir, which holds a uniform sample of the
+// records offered so far. Rather than drawing for every record, the number of
+// records to pass over is drawn once a record enters (Algorithm L).
+func (s *sampler) offer(x sampled) {
+	offered := s.offered
+	s.offered++
+	if offered < s.size {
+		s.reservoir = append(s.reservoir, x)
+		if offered == s.size-1 {
+			s.next, s.w = offered, math.Exp(math.Log(1-rand.Float64())/float64(s.size))
+			s.skip()
+		}
+		return
+	}
+	if offered == s.next {
+		s.reservoir[rand.Intn(s.size)] = x
+		s.w *= math.Exp(math.Log(1-rand.Float64()) / float64(s.size))
+		s.skip()
+	}
+}
+
+// skip draws the next offer that enters the reservoir
+func (s *sampler) skip() {
+	skip := math.Floor(math.Log(1-rand.Float64())/math.Log(1-s.w)) + 1
+	if skip > math.MaxInt32 || math.IsNaN(skip) {
+		s.next = math.MaxInt
+		return
+	}
+	s.next += int(skip)
+}
+
+// drain returns the records in the reservoir in the order of the input
+func (s *sampler) drain() ([][]string, []recordPos) {
+	sort.Slice(s.reservoir, func(i, j int) bool { return s.reservoir[i].index < s.reservoir[j].index })
+	records, positions := make([][]string, len(s.reservoir)), make([]recordPos, len(s.reservoir))
+	for i, x := range s.reservoir {
+		records[i], positions[i] = x.record, x.pos
+	}
+	s.reservoir = nil
+	return records, positions
+}
+
+// sample drops the records of output that are not sampled, which the workers
+// do in the order of the blocks
+func (r *Reader) sample(out *outputSlots, output *recordsOutput) {
+	s := r.sampling
+	out.waitUntil(func() bool {
+		return atomic.LoadInt64(&s.sequence) == int64(output.sequence) || out.isStopped()
+	})
+	if out.isStopped() {
+		return
+	}
+	first := output.sequence == 0 && r.startOffset == r.dataOffset
+	n := 0
+	for i, record := range output.records {
+		var pos recordPos
+		if i < len(output.positions) {
+			pos = output.positions[i]
+		}
+		if s.keep(record, pos, first) {
+			output.records[n] = record
+			if i < len(output.positions) {
+				output.positions[n] = pos
+			}
+			n++
+		}
+	}
+	output.records = output.records[:n]
+	if n < len(output.positions) {
+		output.positions = output.positions[:n]
+	}
+	atomic.StoreInt64(&s.sequence, int64(output.sequence)+1)
+	out.wake()
+}
+
+// flushSample hands the records in the reservoir to the consumer as the last
+// block, once all others have been emitted
+func (r *Reader) flushSample(out *outputSlots) {
+	if s := r.sampling; s != nil && s.size > 0 && !out.isStopped() {
+		records, positions := s.drain()
+		sequence := int(atomic.LoadInt64(&s.sequence))
+		r.sampling = nil // the block is emitted as is
+		r.emit(out, recordsOutput{sequence, records, positions, nil, nil, checkpoint{}, nil, nil})
+	}
+}
+
+// csvReadSampled reads the next record of the sample from encoding/csv,
+// reading all of the input first to sample a reservoir
+func (r *Reader) csvReadSampled() ([]string, recordPos, error) {
+	if !r.samples() {
+		return r.csvReadFooter()
+	}
+	if r.sampling == nil {
+		r.sampling = r.newSampler()
+	}
+	s := r.sampling
+	if s.size <= 0 {
+		for {
+			record, pos, err := r.csvReadFooter()
+			if err != nil || s.keep(record, pos, r.startOffset == r.dataOffset) {
+				return record, pos, err
+			}
+		}
+	}
+	for !s.drained {
+		record, pos, err := r.csvReadFooter()
+		if err == io.EOF {
+			s.records, s.positions = s.drain()
+			s.drained = true
+		} else if err != nil {
+			return nil, recordPos{}, err
+		} else if s.keep(record, pos, r.startOffset == r.dataOffset) {
+			return record, pos, nil
+		}
+	}
+	if len(s.records) == 0 {
+		return nil, recordPos{}, io.EOF
+	}
+	record, pos := s.records[0], s.positions[0]
+	s.records, s.positions = s.records[1:], s.positions[1:]
+	return record, pos, nil
+}
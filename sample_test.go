@@ -0,0 +1,115 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("id,name\n")
+	var rows [][]string
+	for i := 0; i < 5000; i++ {
+		rows = append(rows, []string{fmt.Sprint(i), fmt.Sprintf("name \"%d\"", i)})
+		fmt.Fprintf(&input, "%d,\"name \"\"%d\"\"\"\n", i, i)
+	}
+	header := []string{"id", "name"}
+
+	forModes(func(mode SIMDMode, chunkSize int) {
+		for _, readAll := range []bool{false, true} {
+			// every Nth record, where the header counts as the first
+			want := [][]string{header}
+			for i := 9; i < len(rows); i += 10 {
+				want = append(want, rows[i])
+			}
+			r := NewReader(strings.NewReader(input.String()))
+			r.SIMD, r.ChunkSize, r.SampleEvery = mode, chunkSize, 10
+			if got, err := readRecords(r, readAll); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("TestSample(%d, %d, %v): got: %d records %.3q (%v) want: %d %.3q", mode, chunkSize, readAll, len(got), got, err, len(want), want)
+			}
+
+			for _, tc := range []struct {
+				every, size, want int
+			}{
+				{0, 50, 50},
+				{7, 20, 20},
+				{0, 6000, 5000},
+			} {
+				r := NewReader(strings.NewReader(input.String()))
+				r.SIMD, r.ChunkSize, r.SampleEvery, r.SampleSize = mode, chunkSize, tc.every, tc.size
+				got, err := readRecords(r, readAll)
+				if err != nil || len(got) != tc.want+1 || !reflect.DeepEqual(got[0], header) {
+					t.Errorf("TestSample(%d, %d, %v, %d, %d): got: %d records (%v) want: %d", mode, chunkSize, readAll, tc.every, tc.size, len(got), err, tc.want+1)
+					continue
+				}
+				last := -1
+				for _, record := range got[1:] {
+					id, _ := strconv.Atoi(record[0])
+					if id <= last || tc.every > 1 && (id+1)%tc.every != 0 || !reflect.DeepEqual(record, rows[id]) {
+						t.Errorf("TestSample(%d, %d, %v, %d, %d): got: %q after %d", mode, chunkSize, readAll, tc.every, tc.size, record, last)
+						break
+					}
+					last = id
+				}
+			}
+		}
+	})
+
+	// the records of the reservoir are drawn uniformly
+	sum, n := 0, 0
+	for i := 0; i < 100; i++ {
+		r := NewReader(strings.NewReader(input.String()))
+		r.ChunkSize, r.SampleSize = 4096, 50
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("TestSample: %v", err)
+		}
+		for _, record := range records[1:] {
+			id, _ := strconv.Atoi(record[0])
+			sum, n = sum+id, n+1
+		}
+	}
+	if mean := sum / n; mean < 2400 || mean > 2600 {
+		t.Errorf("TestSample: got: mean %d want: 2500", mean)
+	}
+
+	values, errs := Stream(func() *Reader {
+		r := NewReader(strings.NewReader(input.String()))
+		r.ChunkSize, r.SampleSize = 4096, 10
+		return r
+	}(), func(record []string) (string, error) { return record[0], nil })
+	got := 0
+	for range values {
+		got++
+	}
+	if err := <-errs; err != nil || got != 11 {
+		t.Errorf("TestSample(Stream): got: %d values (%v) want: 11", got, err)
+	}
+
+	for _, mode := range []SIMDMode{SIMDForce, SIMDDisable} {
+		r := NewReader(strings.NewReader(input.String()))
+		r.SIMD, r.SampleEvery, r.SkipFooter = mode, 2, 1
+		if _, err := r.ReadAll(); err != errSampleCombined {
+			t.Errorf("TestSample(%d): got: %v want: %v", mode, err, errSampleCombined)
+		}
+	}
+}
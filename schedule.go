@@ -0,0 +1,145 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Schedule describes how a parse was distributed over the stage 2 workers
+// (see Reader.Trace and Reader.Replay)
+type Schedule struct {
+	Chunks []ChunkTrace // indexed by sequence
+}
+
+// ChunkTrace records the processing of a single chunk of input. Start and
+// Done are positions in the order of all events of the parse, and are -1
+// for chunks that were never processed (when parsing ended early).
+type ChunkTrace struct {
+	Offset int64 // offset of the chunk in the input
+	Size   int   // size of the chunk in bytes
+	Worker int   // stage 2 worker that processed the chunk
+	Start  int   // event at which the worker started on the chunk
+	Done   int   // event at which the worker handed over the records
+}
+
+// scheduler records the schedule of a parse, or enforces the schedule of
+// a previous parse. A nil scheduler leaves the schedule to the runtime.
+type scheduler struct {
+	trace  *Schedule
+	replay *Schedule
+	out    *outputSlots
+
+	mu     sync.Mutex // guards trace
+	events int64
+}
+
+func newScheduler(trace, replay *Schedule, out *outputSlots) *scheduler {
+	if trace == nil && replay == nil {
+		return nil
+	}
+	if trace != nil {
+		trace.Chunks = trace.Chunks[:0]
+	}
+	return &scheduler{trace: trace, replay: replay, out: out}
+}
+
+// replayed returns the recorded trace of a chunk, if any
+func (s *scheduler) replayed(sequence int) (ChunkTrace, bool) {
+	if s == nil || s.replay == nil || sequence >= len(s.replay.Chunks) {
+		return ChunkTrace{}, false
+	}
+	return s.replay.Chunks[sequence], true
+}
+
+// size returns the size of the chunk for sequence
+func (s *scheduler) size(sequence int, chunkSize int) int {
+	if c, ok := s.replayed(sequence); ok && c.Size <= chunkSize {
+		return c.Size
+	}
+	return chunkSize
+}
+
+// workers returns the number of workers to replay with, or 0 when the
+// workers are left to the stage 2 scaler
+func (s *scheduler) workers() int {
+	if s == nil || s.replay == nil {
+		return 0
+	}
+	workers := 1
+	for _, c := range s.replay.Chunks {
+		if c.Worker >= workers {
+			workers = c.Worker + 1
+		}
+	}
+	return workers
+}
+
+// worker returns the worker to hand the chunk for sequence to; chunks
+// beyond the recorded schedule go to the first worker
+func (s *scheduler) worker(sequence int) int {
+	if c, ok := s.replayed(sequence); ok && c.Worker >= 0 {
+		return c.Worker
+	}
+	return 0
+}
+
+// chunk records the boundaries of the chunk for sequence
+func (s *scheduler) chunk(sequence int, offset int64, size int) {
+	if s == nil || s.trace == nil {
+		return
+	}
+	s.mu.Lock()
+	s.trace.Chunks = append(s.trace.Chunks, ChunkTrace{offset, size, -1, -1, -1})
+	s.mu.Unlock()
+}
+
+// event performs action as the start (or completion, if done) of the chunk
+// for sequence by worker, after waiting for its turn when replaying
+func (s *scheduler) event(sequence int, worker int, done bool, action func()) {
+	if s == nil {
+		action()
+		return
+	}
+	if c, ok := s.replayed(sequence); ok {
+		turn := c.Start
+		if done {
+			turn = c.Done
+		}
+		s.out.waitUntil(func() bool {
+			return atomic.LoadInt64(&s.events) >= int64(turn) || s.out.isStopped()
+		})
+	}
+
+	action()
+
+	s.mu.Lock()
+	event := int(s.events)
+	atomic.StoreInt64(&s.events, s.events+1)
+	if s.trace != nil && sequence < len(s.trace.Chunks) {
+		c := &s.trace.Chunks[sequence]
+		c.Worker = worker
+		if done {
+			c.Done = event
+		} else {
+			c.Start = event
+		}
+	}
+	s.mu.Unlock()
+	s.out.wake()
+}
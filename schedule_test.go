@@ -0,0 +1,93 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestScheduleReplay(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/worldcitiespop-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	want, err := encodingCsv(buf, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	trace := &Schedule{}
+	r := NewReader(bytes.NewReader(buf))
+	r.Trace = trace
+	if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("TestScheduleReplay: got: %v want: records of encoding/csv", err)
+	}
+
+	offset := int64(0)
+	for i, c := range trace.Chunks {
+		if c.Offset != offset || c.Start < 0 || c.Start >= c.Done {
+			t.Fatalf("TestScheduleReplay: got: %+v for chunk %d", c, i)
+		}
+		offset += int64(c.Size)
+	}
+	if offset != int64(len(buf)) || len(trace.Chunks) < 4 {
+		t.Fatalf("TestScheduleReplay: got: %d bytes in %d chunks want: %d bytes", offset, len(trace.Chunks), len(buf))
+	}
+
+	// three workers that complete every three chunks in reverse order,
+	// with chunks smaller than the default
+	replay := &Schedule{}
+	event := 0
+	for offset, i := int64(0), 0; offset < int64(len(buf)); i++ {
+		c := ChunkTrace{Offset: offset, Size: 99968 - i%3*64, Worker: i % 3}
+		if rem := int64(len(buf)) - offset; rem < int64(c.Size) {
+			c.Size = int(rem)
+		}
+		replay.Chunks = append(replay.Chunks, c)
+		offset += int64(c.Size)
+	}
+	for i := 0; i < len(replay.Chunks); i += 3 {
+		group := replay.Chunks[i:]
+		if len(group) > 3 {
+			group = group[:3]
+		}
+		for j := range group {
+			group[j].Start, event = event, event+1
+		}
+		for j := len(group) - 1; j >= 0; j-- {
+			group[j].Done, event = event, event+1
+		}
+	}
+
+	for _, inMemory := range []bool{false, true} {
+		retrace := &Schedule{}
+		r = NewReader(bytes.NewReader(buf))
+		if inMemory {
+			r = newBytesReader(buf)
+		}
+		r.Replay, r.Trace = replay, retrace
+		if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("TestScheduleReplay: got: %v want: records of encoding/csv", err)
+		}
+		if !reflect.DeepEqual(retrace, replay) {
+			t.Errorf("TestScheduleReplay: got: %+v want: %+v", retrace.Chunks[:3], replay.Chunks[:3])
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+var errNotReaderAt = errors.New("simdcsv: input does not implement io.ReaderAt")
+
+// A checkpoint is a position in the input at which parsing can resume
+type checkpoint struct {
+	records int   // number of records preceding the checkpoint
+	offset  int64 // input offset
+	line    int   // line at offset
+}
+
+// received accounts for a block of records received from the workers,
+// remembering where it starts so SeekRecord can resume from there. It
+// returns the block without the records from the trailer on (see Sentinel).
+func (r *Reader) received(output recordsOutput) recordsOutput {
+	r.Memory.releaseRecords(output)
+	if len(output.records) == 0 {
+		return output
+	}
+	if r.ra != nil && !r.samples() && (len(r.index) == 0 || r.consumed > r.index[len(r.index)-1].records) {
+		start := output.start
+		start.records = r.consumed + len(r.footer)
+		r.index = append(r.index, start)
+	}
+	for i, record := range output.records {
+		if r.atSentinel(record, r.consumed+i) {
+			output.records = output.records[:i]
+			if len(output.positions) > i {
+				output.positions = output.positions[:i]
+			}
+			break
+		}
+	}
+	if err := r.dropDuplicates(&output); err != nil {
+		r.slots.stop()
+		output.records, output.positions, output.decoded, r.readErr = nil, nil, nil, err
+		return output
+	}
+	output.records, output.positions = r.holdFooter(output.records, output.positions)
+	r.atQueryLimit(&output.records, &output.positions)
+	r.consumed += len(output.records)
+	r.Manifest.add(output.records)
+	return output
+}
+
+// Rewind restarts reading at the beginning of the input, which requires the
+// input to implement io.ReaderAt (as do *os.File and *bytes.Reader). The
+// input is assumed to start at offset 0.
+func (r *Reader) Rewind() error {
+	return r.SeekRecord(0)
+}
+
+// SeekRecord positions the reader such that the next call to Read returns
+// the record following the first n records, which requires the input to
+// implement io.ReaderAt. Parsing resumes from the closest block start seen
+// so far, so seeking within input that was read before avoids reparsing
+// everything that precedes it. It returns io.EOF if the input holds fewer
+// than n records.
+func (r *Reader) SeekRecord(n int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.ra == nil {
+		return errNotReaderAt
+	}
+	if n < 0 {
+		return errors.New("simdcsv: negative record number")
+	}
+
+	from := checkpoint{line: 1}
+	for _, cp := range r.index {
+		if cp.records > n {
+			break
+		}
+		from = cp
+	}
+	pending := len(r.records) - r.currrecord
+	if r.recordNumber <= n && from.records <= r.recordNumber &&
+		r.consumed-pending == r.recordNumber && r.rCsv == nil && r.readErr == nil {
+		// continuing from the current record is closer
+		return r.skip(n - r.recordNumber)
+	}
+
+	r.restart(from)
+	return r.skip(n - from.records)
+}
+
+// restart stops parsing and resumes reading the input from checkpoint from
+func (r *Reader) restart(from checkpoint) {
+	if r.slots != nil {
+		// the stages read the state reset below until they are done
+		r.slots.stop()
+		r.slots.wait()
+	}
+	r.slots, r.rCsv, r.IsStreaming = nil, nil, false
+	r.records, r.positions, r.currrecord, r.readErr = nil, nil, 0, nil
+	r.data, r.inMemory = nil, false
+
+	r.r = io.NewSectionReader(r.ra, from.offset, math.MaxInt64-from.offset)
+	r.startOffset, r.lineOffset = from.offset, from.line-1
+	r.recordNumber, r.consumed, r.lastPos, r.trailer = from.records, from.records, recordPos{}, nil
+	r.footer, r.footerPositions, r.sampling = nil, nil, nil
+	if r.dedup != nil {
+		r.dedup.seen.reset()
+	}
+	if from.offset == 0 {
+		r.header, r.norm, r.proj, r.where, r.dedup = nil, nil, nil, nil, nil
+		if r.InputHash != nil {
+			r.InputHash.Reset()
+		}
+	}
+}
+
+// skip advances past the next n records
+func (r *Reader) skip(n int) error {
+	for n > 0 {
+		if r.currrecord >= len(r.records) {
+			if r.readErr != nil {
+				return r.readErr
+			}
+			var err error
+			if !r.simd() {
+				var record []string
+				var pos recordPos
+				if record, pos, err = r.csvRead(); err == nil {
+					r.records, r.positions, r.currrecord = [][]string{record}, []recordPos{pos}, 0
+				}
+			} else {
+				err = r.nextblock()
+			}
+			if err != nil {
+				r.readErr = err
+				return err
+			}
+		}
+		skipped := len(r.records) - r.currrecord
+		if skipped > n {
+			skipped = n
+		}
+		r.currrecord += skipped
+		r.recordNumber += skipped
+		r.lastPos = r.positions[r.currrecord-1]
+		n -= skipped
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSeekRecord(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; input.Len() < 1<<21; i++ {
+		fmt.Fprintf(&input, "%d,\"field \"\"%d\"\"\",%s\n", i, i, strings.Repeat("x", i%50))
+	}
+	want, err := encodingCsv(input.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	r := NewReader(bytes.NewReader(input.Bytes()))
+	r.FallbackThreshold = -1
+
+	// seek forward before anything was read, then read everything
+	if err := r.SeekRecord(10); err != nil {
+		t.Fatalf("TestSeekRecord: %v", err)
+	}
+	for i := 10; i < len(want); i++ {
+		if record, err := r.Read(); err != nil || !reflect.DeepEqual(record, want[i]) {
+			t.Fatalf("TestSeekRecord: got: %v (%v) want: %v", record, err, want[i])
+		}
+	}
+
+	for _, n := range []int{len(want) / 2, 3, len(want) - 1, len(want) / 3, len(want)/3 + 5, 0} {
+		if err := r.SeekRecord(n); err != nil {
+			t.Fatalf("TestSeekRecord: %v", err)
+		}
+		record, err := r.Read()
+		if err != nil || !reflect.DeepEqual(record, want[n]) {
+			t.Fatalf("TestSeekRecord: got: %v (%v) want: %v", record, err, want[n])
+		}
+		if r.RecordNumber() != n+1 || r.LineNumber() != n+1 {
+			t.Errorf("TestSeekRecord: got: %d:%d want: %d:%d", r.RecordNumber(), r.LineNumber(), n+1, n+1)
+		}
+	}
+
+	if err := r.Rewind(); err != nil {
+		t.Fatalf("TestSeekRecord: %v", err)
+	}
+	if records, err := r.ReadAll(); err != nil || !reflect.DeepEqual(records, want) {
+		t.Errorf("TestSeekRecord: got: %d records (%v) want: %d", len(records), err, len(want))
+	}
+
+	if err := r.SeekRecord(len(want) + 1); err != io.EOF {
+		t.Errorf("TestSeekRecord: got: %v want: %v", err, io.EOF)
+	}
+
+	if err := NewReader(io.MultiReader(&input)).Rewind(); err != errNotReaderAt {
+		t.Errorf("TestSeekRecord: got: %v want: %v", err, errNotReaderAt)
+	}
+}
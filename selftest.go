@@ -0,0 +1,134 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A kernel is an implementation of the parsing stages
+type kernel struct {
+	name       string
+	supported  func() bool
+	simd       bool       // whether the kernel runs the SIMD stages, as opposed to encoding/csv
+	stage1     stage1Func // implementation of stage1_preprocess_buffer, if not the default
+	stage2     stage2Func // implementation of _stage2_parse_masks, if not the default
+	downclocks bool       // whether the kernel may downclock the CPU (see DisableAVX512)
+}
+
+// kernels lists all kernels, in order of preference
+var kernels = append(simdKernels, &kernel{"stdlib", func() bool { return true }, false, nil, nil, false})
+
+// simdKernel returns the kernel that runs the SIMD stages for r, if any
+func (r *Reader) simdKernel() *kernel {
+	if r.kernel != nil {
+		return r.kernel
+	}
+	mode := r.simdMode()
+	if mode == SIMDDisable {
+		return nil
+	}
+	for _, k := range simdKernels {
+		if k.supported() && !(k.downclocks && r.DisableAVX512) {
+			return k
+		}
+	}
+	if mode == SIMDForce {
+		return swarKernel
+	}
+	return nil
+}
+
+// stage2 returns the implementation of _stage2_parse_masks for r
+func (r *Reader) stage2() stage2Func {
+	if k := r.simdKernel(); k != nil && k.stage2 != nil {
+		return k.stage2
+	}
+	return _stage2_parse_masks
+}
+
+// simd reports whether r parses with the SIMD stages
+func (r *Reader) simd() bool {
+	if r.kernel != nil {
+		return r.kernel.simd && r.kernel.supported()
+	}
+	return r.simdKernel() != nil
+}
+
+// Kernels returns the names of the kernels that the CPU supports.
+func Kernels() (names []string) {
+	for _, k := range kernels {
+		if k.supported() {
+			names = append(names, k.name)
+		}
+	}
+	return
+}
+
+// SelfTest parses a reference corpus with each of the kernels that the CPU
+// supports forcibly selected, and returns an error naming the first kernel
+// whose records (or failure to parse) differ from those of encoding/csv.
+// It allows validating correctness on new hardware or toolchains.
+func SelfTest() error {
+	for i, input := range selfTestCorpus() {
+		want, wantErr := encodingCsv(input, ',')
+		for _, k := range kernels {
+			if !k.supported() {
+				continue
+			}
+			r := NewReader(bytes.NewReader(input))
+			r.kernel, r.FallbackThreshold = k, -1
+			got, err := r.ReadAll()
+			if (err != nil) != (wantErr != nil) {
+				return fmt.Errorf("simdcsv: self-test of kernel %s on input %d: got error %v, want %v", k.name, i, err, wantErr)
+			}
+			if wantErr == nil && !reflect.DeepEqual(got, want) {
+				return fmt.Errorf("simdcsv: self-test of kernel %s on input %d: records differ", k.name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// selfTestCorpus returns the reference inputs of SelfTest, which cover the
+// peculiarities of the format both within and across chunks
+func selfTestCorpus() [][]byte {
+	corpus := [][]byte{
+		[]byte("a,b,c\n1,2,3\n"),
+		[]byte("a,b,c\r\n1,2,3\r\n"),
+		[]byte("a,\"b,c\",d\n\"e\"\"f\",g,\"\"\n"),
+		[]byte("a,\"b\nc\",d\ne,\"f\r\ng\",h"),
+		[]byte("\n\na,b\n\n\r\nc,d\n\n"),
+		[]byte("a,b\nc,d,e\n"),
+		[]byte("a,b\"c\n"),
+		[]byte("a,\"b\"c\n"),
+	}
+
+	// fields of all shapes, spanning multiple chunks and both ends of 64-byte blocks
+	var big bytes.Buffer
+	for i := 0; big.Len() < 1<<20; i++ {
+		eol := "\n"
+		if i%7 == 0 {
+			eol = "\r\n"
+		}
+		fmt.Fprintf(&big, "%d,\"quoted, \"\"%d\"\"\",%s,,\"%s\"%s", i, i*i, strings.Repeat("x", i%67), strings.Repeat("y", i%61), eol)
+	}
+	return append(corpus, big.Bytes())
+}
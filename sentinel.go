@@ -0,0 +1,55 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrTrailerCount is returned when the number of records differs from the
+// count declared by the trailer (see Reader.Sentinel).
+var ErrTrailerCount = errors.New("simdcsv: number of records differs from trailer")
+
+// atSentinel checks whether record is the trailer that ends the data, given
+// the number of records preceding it, in which case reading ends with
+// io.EOF or, if the declared count differs, ErrTrailerCount
+func (r *Reader) atSentinel(record []string, preceding int) bool {
+	if r.Sentinel == nil || r.trailer != nil {
+		return false
+	}
+	count, ok := r.Sentinel(record)
+	if !ok {
+		return false
+	}
+	r.trailer, r.readErr = record, io.EOF
+	if count >= 0 && count != preceding {
+		r.readErr = fmt.Errorf("%w: trailer declares %d records, read %d", ErrTrailerCount, count, preceding)
+	}
+	if r.slots != nil {
+		r.slots.stop()
+	}
+	return true
+}
+
+// Trailer returns the record that ended the data, if any (see Sentinel).
+func (r *Reader) Trailer() []string {
+	r.Lock()
+	defer r.Unlock()
+	return r.trailer
+}
@@ -0,0 +1,90 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSentinel(t *testing.T) {
+	var data bytes.Buffer
+	for i := 0; data.Len() < 1<<20; i++ {
+		fmt.Fprintf(&data, "%d,\"value \"\"%d\"\"\"\n", i, i)
+	}
+	want, err := encodingCsv(data.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	sentinel := func(record []string) (int, bool) {
+		if record[0] != "END" {
+			return 0, false
+		}
+		count, _ := strconv.Atoi(record[1])
+		return count, true
+	}
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		for _, count := range []int{len(want), len(want) + 1} {
+			input := append(append([]byte(nil), data.Bytes()...), fmt.Sprintf("END,%d\nafter,end\n", count)...)
+
+			r := NewReader(bytes.NewReader(input))
+			r.kernel, r.Sentinel = kernel, sentinel
+			got, err := r.ReadAll()
+			if count == len(want) {
+				if err != nil || !reflect.DeepEqual(got, want) {
+					t.Fatalf("TestSentinel: got: %v want: records preceding the trailer", err)
+				}
+				if trailer := r.Trailer(); !reflect.DeepEqual(trailer, []string{"END", strconv.Itoa(count)}) {
+					t.Errorf("TestSentinel: got: %q want: trailer", trailer)
+				}
+			} else if !errors.Is(err, ErrTrailerCount) {
+				t.Fatalf("TestSentinel: got: %v want: %v", err, ErrTrailerCount)
+			}
+
+			r = NewReader(bytes.NewReader(input))
+			r.kernel, r.Sentinel = kernel, sentinel
+			n := 0
+			for ; ; n++ {
+				if _, err = r.Read(); err != nil {
+					break
+				}
+			}
+			if n != len(want) || (count == len(want)) != (err == io.EOF) {
+				t.Errorf("TestSentinel: got: %d records, %v want: %d records", n, err, len(want))
+			}
+		}
+	}
+
+	input := append(append([]byte(nil), data.Bytes()...), fmt.Sprintf("END,%d\nafter,end\n", len(want))...)
+	r := NewReader(bytes.NewReader(input))
+	r.Sentinel = sentinel
+	values, errs := Stream(r, func(record []string) (string, error) { return record[0], nil })
+	n := 0
+	for range values {
+		n++
+	}
+	if err := <-errs; err != nil || n != len(want) {
+		t.Errorf("TestSentinel: got: %d values, %v want: %d values", n, err, len(want))
+	}
+}
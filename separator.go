@@ -0,0 +1,202 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// separatorPlaceholder stands in for the bytes of a multi-byte separator in
+// stage 1, as it does not occur in valid UTF-8
+const separatorPlaceholder = 0xff
+
+// separator keeps a multi-byte separator (a non-ASCII Comma, or CommaString)
+// on the SIMD path: stage 1 processes a copy of every chunk in which each
+// occurrence of the separator is replaced by as many placeholder bytes, each
+// of which counts as a separator. The masks then apply to the chunk itself,
+// from which stage 2 yields records with empty fields between the
+// placeholders, which are dropped.
+type separator struct {
+	seq     []byte // bytes of the separator
+	subst   []byte // as many placeholder bytes
+	scratch []byte // copy of the chunk processed by stage 1
+}
+
+// newSeparator returns the separator for seq, or nil if seq is a single byte
+func newSeparator(seq string) *separator {
+	if len(seq) < 2 {
+		return nil
+	}
+	return &separator{seq: []byte(seq), subst: bytes.Repeat([]byte{separatorPlaceholder}, len(seq))}
+}
+
+// substitute returns a copy of buf with its separators replaced, or buf
+// itself if it contains placeholder bytes already, in which case the chunk
+// is to be parsed by encoding/csv. The copy is only valid until the next call.
+func (s *separator) substitute(buf []byte) ([]byte, bool) {
+	if bytes.IndexByte(buf, separatorPlaceholder) >= 0 {
+		return buf, false
+	}
+	if cap(s.scratch) < len(buf)+chunkAlign {
+		s.scratch = allocChunk(len(buf))
+	}
+	scratch := s.scratch[:len(buf)]
+	copy(scratch, buf)
+	for i := 0; ; {
+		j := bytes.Index(scratch[i:], s.seq)
+		if j < 0 {
+			break
+		}
+		i += j + copy(scratch[i+j:], s.subst)
+	}
+	return scratch, true
+}
+
+// fields drops the empty fields between the placeholders of a separator,
+// which leaves every len(seq)th field of record
+func (s *separator) fields(record []string) []string {
+	n := 0
+	for i := 0; i < len(record); i += len(s.seq) {
+		record[n] = record[i]
+		n++
+	}
+	return record[:n:n]
+}
+
+// csvPlaceholders are the runes that stand in for a CommaString of as many
+// bytes for encoding/csv, which only supports a single rune for Comma.
+// Being of the same length keeps the input offsets intact.
+var csvPlaceholders = [...]rune{2: '\u0080', 3: '\uffff', 4: '\U0010ffff'}
+
+// delimiter returns the field delimiter, which is CommaString if set
+func (r *Reader) delimiter() string {
+	if r.CommaString != "" {
+		return r.CommaString
+	}
+	return string(r.Comma)
+}
+
+// csvPlaceholder returns the rune that stands in for CommaString for
+// encoding/csv, or 0 if CommaString is a single rune (if any)
+func (r *Reader) csvPlaceholder() rune {
+	if _, size := utf8.DecodeRuneInString(r.CommaString); size == len(r.CommaString) {
+		return 0
+	}
+	return csvPlaceholders[len(r.CommaString)]
+}
+
+// csvComma returns the Comma that encoding/csv parses with
+func (r *Reader) csvComma() rune {
+	if placeholder := r.csvPlaceholder(); placeholder != 0 {
+		return placeholder
+	}
+	comma, _ := utf8.DecodeRuneInString(r.delimiter())
+	return comma
+}
+
+// validCommaString reports whether CommaString is empty or a valid delimiter
+func (r *Reader) validCommaString() bool {
+	s := r.CommaString
+	if s == "" {
+		return true
+	}
+	if len(s) > 4 || !utf8.ValidString(s) || strings.ContainsAny(s, "\"\r\n") || strings.ContainsRune(s, utf8.RuneError) {
+		return false
+	}
+	return r.Comment == 0 || !strings.HasPrefix(s, string(r.Comment)) && r.csvPlaceholder() != r.Comment
+}
+
+// separatorFilter returns in with every occurrence of CommaString replaced
+// by its placeholder, if it has one
+func (r *Reader) separatorFilter(in io.Reader) io.Reader {
+	placeholder := r.csvPlaceholder()
+	if placeholder == 0 {
+		return in
+	}
+	return &separatorFilter{in: in, seq: []byte(r.CommaString), placeholder: []byte(string(placeholder))}
+}
+
+// separatorFilter replaces a CommaString by its placeholder, holding back
+// the bytes that may start a CommaString until the bytes that follow are read
+type separatorFilter struct {
+	in               io.Reader
+	seq, placeholder []byte
+	buf              []byte // bytes read but not yet returned
+	err              error
+}
+
+func (f *separatorFilter) Read(p []byte) (int, error) {
+	for {
+		ready := len(f.buf)
+		if f.err == nil {
+			ready -= len(f.seq) - 1
+		}
+		if ready > 0 {
+			n := copy(p, f.buf[:ready])
+			f.buf = f.buf[:copy(f.buf, f.buf[n:])]
+			return n, nil
+		}
+		if f.err != nil {
+			return 0, f.err
+		}
+
+		held := len(f.buf)
+		if free := cap(f.buf) - held; free < len(p) {
+			f.buf = append(make([]byte, 0, held+len(p)), f.buf...)
+		}
+		n, err := f.in.Read(f.buf[held : held+len(p)])
+		f.buf, f.err = f.buf[:held+n], err
+		for i := 0; ; {
+			j := bytes.Index(f.buf[i:], f.seq)
+			if j < 0 {
+				break
+			}
+			i += j + copy(f.buf[i+j:], f.placeholder)
+		}
+	}
+}
+
+// newCsvPositions returns the encoding/csv Reader of in, with its comments
+// filtered and CommaString and escapes substituted
+func (r *Reader) newCsvPositions(in io.Reader, line int, offset int64) *csvPositions {
+	in = r.escapeFilter(in)
+	p := newCsvPositions(r.commentFilter(r.separatorFilter(in)), line, offset)
+	p.escapes, _ = in.(*escapeFilter)
+	p.raw.limit = r.MaxRecordBytes
+	p.preserveCRLF = r.PreserveCRLF
+	if r.RawQuotes {
+		p.rawQuotes = r.delimiter()
+	}
+	p.rCsv.Comma = r.csvComma()
+	if placeholder := r.csvPlaceholder(); placeholder != 0 {
+		p.placeholder, p.commaString = string(placeholder), r.CommaString
+	}
+	return p
+}
+
+// restoreSeparators replaces the placeholders that encoding/csv returns
+// within the fields of record (which stem from quoted fields) by commaString
+func restoreSeparators(record []string, placeholder, commaString string) {
+	for i, field := range record {
+		if strings.Contains(field, placeholder) {
+			record[i] = strings.ReplaceAll(field, placeholder, commaString)
+		}
+	}
+}
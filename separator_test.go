@@ -0,0 +1,102 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRuneSeparator(t *testing.T) {
+	for _, comma := range []rune{'é', '、', '；', '🙂'} {
+		sep := string(comma)
+		var data bytes.Buffer
+		for i := 0; i < 3000; i++ {
+			fmt.Fprintf(&data, "%d%s\"quoted%s\"\"%d\"\"\"%s%s%s\r\n", i, sep, sep, i, sep, strings.Repeat("x", i%100), sep)
+		}
+		rCsv := csv.NewReader(bytes.NewReader(data.Bytes()))
+		rCsv.Comma = comma
+		want, err := rCsv.ReadAll()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		for _, size := range []int{0, 64, 1000} {
+			for _, lazy := range []bool{false, true} {
+				r := NewReader(bytes.NewReader(data.Bytes()))
+				r.Comma, r.ChunkSize, r.LazyQuotes = comma, size, lazy
+				if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("TestRuneSeparator(%q, %d, %v): got: %d records, %v want: %d records", comma, size, lazy, len(got), err, len(want))
+				}
+			}
+		}
+	}
+
+	// input that is not valid UTF-8 is handed to encoding/csv
+	data := []byte(strings.Repeat("a\xff；b；\"c\"\n", 100000))
+	want, _ := encodingCsv(data, '；')
+	r := NewReader(bytes.NewReader(data))
+	r.Comma = '；'
+	if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("TestRuneSeparator(invalid UTF-8): got: %d records, %v want: %d records", len(got), err, len(want))
+	}
+}
+
+func TestCommaString(t *testing.T) {
+	for _, comma := range []string{"||", "\t|\t", "::::", "→|"} {
+		var data bytes.Buffer
+		var want [][]string
+		for i := 0; i < 3000; i++ {
+			record := []string{fmt.Sprint(i), "quoted" + comma + `"` + fmt.Sprint(i) + `"`, strings.Repeat("x", i%100), "|:"}
+			want = append(want, record)
+			fmt.Fprintf(&data, "%s%s\"%s\"%s%s%s%s\n", record[0], comma, strings.ReplaceAll(record[1], `"`, `""`), comma, record[2], comma, record[3])
+		}
+
+		for _, size := range []int{0, 64, 1000} {
+			for _, lazy := range []bool{false, true} {
+				r := NewReader(bytes.NewReader(data.Bytes()))
+				r.CommaString, r.ChunkSize, r.LazyQuotes = comma, size, lazy
+				if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("TestCommaString(%q, %d, %v): got: %d records, %v want: %d records", comma, size, lazy, len(got), err, len(want))
+				}
+			}
+		}
+
+		// small input is parsed by encoding/csv, using a placeholder
+		r := NewReader(bytes.NewReader(data.Bytes()[:bytes.IndexByte(data.Bytes(), '\n')+1]))
+		r.CommaString, r.KeepRaw = comma, true
+		if record, err := r.Read(); err != nil || !reflect.DeepEqual(record, want[0]) {
+			t.Errorf("TestCommaString(%q): got: %q, %v want: %q", comma, record, err, want[0])
+		}
+		if line, column := r.FieldPos(2); line != 1 || column != len(fmt.Sprintf(`0%s"quoted%s""0"""%s`, comma, comma, comma))+1 {
+			t.Errorf("TestCommaString(%q): got: field at %d:%d", comma, line, column)
+		}
+		if !bytes.Equal(r.RawRecord(), data.Bytes()[:bytes.IndexByte(data.Bytes(), '\n')]) {
+			t.Errorf("TestCommaString(%q): got: raw record %q", comma, r.RawRecord())
+		}
+	}
+
+	r := NewReader(strings.NewReader("a||b\n"))
+	r.CommaString = `"|`
+	if _, err := r.Read(); err != errInvalidDelim {
+		t.Errorf("TestCommaString: got: %v want: %v", err, errInvalidDelim)
+	}
+}
@@ -19,17 +19,20 @@ package simdcsv
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"log"
 	"math/bits"
+	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"unicode"
 	"unicode/utf8"
+	"unsafe"
 )
 
 // Below is the same interface definition from encoding/csv
@@ -42,7 +45,9 @@ import (
 //
 // The Reader converts all \r\n sequences in its input to plain \n,
 // including in multiline field values, so that the returned data does
-// not depend on which line-ending convention an input file uses.
+// not depend on which line-ending convention an input file uses
+// (unless PreserveCRLF is set). Bare \r line endings are only supported
+// when BareCR is set.
 type Reader struct {
 	sync.Mutex
 	// Comma is the field delimiter.
@@ -51,6 +56,29 @@ type Reader struct {
 	// or the Unicode replacement character (0xFFFD).
 	Comma rune
 
+	// CommaString, if not empty, is the field delimiter instead of Comma,
+	// which may consist of multiple characters (such as "||" or "\t|\t") of
+	// up to 4 bytes in total. Input containing the character that stands in
+	// for it in encoding/csv (U+0080, U+FFFF or U+10FFFF, for 2, 3 or 4
+	// bytes respectively) is not supported.
+	CommaString string
+
+	// Escape, if not 0, is the escape character (such as '\\') of
+	// MySQL-style CSV, which makes the character that follows it literal,
+	// be it a quote, Comma, newline or Escape itself. As in MySQL, \0, \b,
+	// \n, \r, \t and \Z stand for NUL, backspace, newline, carriage return,
+	// tab and Ctrl-Z, whereas \N (NULL) is returned as is. Escape must be an
+	// ASCII character other than a quote, \r or \n, nor be part of the
+	// other delimiters. Input containing U+0081, which stands in for escaped
+	// characters in encoding/csv, is not supported.
+	Escape rune
+
+	// Encoding is the character encoding of the input, which is transcoded
+	// to UTF-8 as it is read, by the goroutine that reads the chunks. A byte
+	// order mark at the start of UTF-16 input is dropped. Offsets, such as
+	// InputOffset, refer to the transcoded input, which cannot be seeked.
+	Encoding Encoding
+
 	// Comment, if not 0, is the comment character. Lines beginning with the
 	// Comment character without preceding whitespace are ignored.
 	// With leading whitespace the Comment character becomes part of the
@@ -60,6 +88,12 @@ type Reader struct {
 	// It must also not be equal to Comma.
 	Comment rune
 
+	// CommentPrefix, if not empty, starts comment lines that are ignored,
+	// like Comment does, but may consist of multiple characters (such as
+	// "//" or "--"). It must not contain \r or \n, nor start with a quote
+	// or Comma.
+	CommentPrefix string
+
 	// FieldsPerRecord is the number of expected fields per record.
 	// If FieldsPerRecord is positive, Read requires each record to
 	// have the given number of fields. If FieldsPerRecord is 0, Read sets it to
@@ -76,172 +110,750 @@ type Reader struct {
 	// This is done even if the field delimiter, Comma, is white space.
 	TrimLeadingSpace bool
 
-	ReuseRecord   bool // Deprecated: Unused by simdcsv.
+	// ReuseRecord controls whether calls to Read may return a slice sharing
+	// the backing array of the previous call's returned slice for performance.
+	// By default, each call to Read returns memory owned by the caller. Since
+	// the reused slice is shared, ReuseRecord rules out concurrent calls to Read.
+	ReuseRecord bool
+
+	// If PreserveCRLF is true, \r\n sequences within quoted fields are
+	// returned as is rather than converted to \n, for workflows that need
+	// the fields byte for byte (such as checksumming or diffing them).
+	PreserveCRLF bool
+
+	// If BareCR is true, carriage returns that are not followed by a newline
+	// end records as well, as in files from classic Mac OS. They are read as
+	// newlines, including by RawRecord, so that the offsets of the input are
+	// unaffected. Within quoted fields they are kept as they are.
+	BareCR bool
+
+	// If RawQuotes is true, quoted fields are returned as they appear in the
+	// input, with their surrounding quotes, doubled quotes and line endings,
+	// which saves unescaping them for tools that write the fields out as is.
+	// RawQuotes cannot be combined with Escape.
+	RawQuotes bool
+
 	TrailingComma bool // Deprecated: No longer used.
 
-	r    *bufio.Reader
-	rCsv *csv.Reader // Used as fallback when simd isn't supported
+	// MaxRecordBytes, MaxFieldBytes and MaxFieldsPerRecord, if positive,
+	// limit the size of a record in the input, the size of a field and the
+	// number of fields of a record. Reading ends with a RecordError
+	// wrapping ErrRecordTooLarge, ErrFieldTooLarge or ErrTooManyFields
+	// respectively once a record exceeds them, which bounds the memory
+	// that hostile or corrupted input (such as an unterminated quote) takes.
+	MaxRecordBytes     int
+	MaxFieldBytes      int
+	MaxFieldsPerRecord int
+
+	// FallbackThreshold is the input size in bytes below which parsing is
+	// handed to encoding/csv, which beats setting up the SIMD stages for
+	// tiny inputs. Zero selects a default of 16 KB, a negative value
+	// forces the SIMD path for every input size.
+	FallbackThreshold int
+
+	// ChunkSize is the size in bytes of the chunks of input handed to the
+	// parsing stages, rounded up to a multiple of 64 bytes. Zero selects a
+	// default of 320000 bytes. Smaller chunks limit the memory in use,
+	// whereas rows spanning chunks are more costly to parse.
+	ChunkSize int
+
+	// MaxPendingBlocks and MaxPendingBytes, if positive, bound how far the
+	// parsing stages run ahead of a slow consumer, in blocks of records not
+	// yet received and in the size of their input respectively: reading the
+	// input waits until the consumer catches up. The chunks being read come
+	// on top. By default only the depth of the pipeline bounds it.
+	MaxPendingBlocks int
+	MaxPendingBytes  int64
+
+	// DisableAVX512, if true, keeps stage 1 on AVX2 on CPUs that support
+	// AVX-512 as well, for those that downclock heavily when running AVX-512
+	// instructions.
+	DisableAVX512 bool
+
+	// SIMD forces the SIMD stages for every input, even on CPUs that
+	// SupportedCPU rejects, or forbids them so encoding/csv parses
+	// everything, e.g. to compare the two. It defaults to the SIMDCSV
+	// environment variable: "force" or "off".
+	SIMD SIMDMode
+
+	// InputHash, if non-nil, is fed the exact bytes read from the source
+	// while parsing (e.g. sha256.New() or crc32.New(crc32.MakeTable(crc32.Castagnoli))).
+	// Hashing is done by the goroutine reading the input, so it overlaps
+	// with both parsing stages. Use Digest once all records have been read.
+	InputHash hash.Hash
+
+	// Manifest, if non-nil, accumulates the number of bytes read and the
+	// number and checksum of the records returned, so the manifests of
+	// shards parsed separately can be verified against one another (see
+	// MergeManifests). Set its Offset to the offset of the shard in the
+	// complete input prior to reading.
+	Manifest *Manifest
+
+	// KeepRaw, if true, retains the unmodified bytes of every record (prior
+	// to unescaping quotes and normalizing line endings, but without the
+	// terminating newline), as returned by RawRecord.
+	KeepRaw bool
+
+	// RawHash, if set, returns a new hash (e.g. sha256.New), with which the
+	// parsing workers hash the unmodified bytes of every record (as returned
+	// by RawRecord, whether or not KeepRaw is set), for deduplication and
+	// integrity checks without serializing the records again. The sums are
+	// returned by RawRecordHash and ReadRecord.
+	RawHash func() hash.Hash
+
+	// FieldTransform, if set, is applied to every field, with col the index
+	// of the field in its record and raw its contents, which must neither be
+	// modified nor retained. The returned string replaces the field, whereas
+	// an error ends parsing. FieldTransform is invoked concurrently by the
+	// parsing workers, so it must be safe for concurrent use.
+	FieldTransform func(col int, raw []byte) (string, error)
+
+	// NullLiterals, if set, are the fields that stand for NULL, such as
+	// "NULL", `\N` or "NA", whether quoted or not. The parsing workers mark
+	// the fields that equal one of them, after FieldTransform and Map, and
+	// the fields are returned as is by Read, whereas ReadNullable returns
+	// nil for them and ReadRecord marks them in Nulls.
+	NullLiterals []string
+
+	// NormalizeColumns and NormalizeNames select the normalizations of
+	// columns by index and by name, respectively. Names refer to the header
+	// in the first record, which is left as is once NormalizeNames is set.
+	// Normalizations are applied prior to FieldTransform.
+	NormalizeColumns map[int]Normalization
+	NormalizeNames   map[string]Normalization
+
+	// NormalizeHeader, if non-zero, marks the first record as a header and
+	// selects the normalizations of its names, such as TrimSpace|SnakeCase.
+	// Names in NormalizeNames and passed to ColumnIndex are normalized
+	// likewise, so lookups are robust to cosmetic changes to the header.
+	NormalizeHeader Normalization
+
+	// SelectColumns and SelectNames select the columns that make up the
+	// records, by index and by name, respectively, with the columns by index
+	// first. Names refer to the header in the first record. The parsing
+	// workers skip the other fields, and options that refer to columns by
+	// index, such as NormalizeColumns and FieldTransform, refer to the
+	// selected columns, unlike FieldsPerRecord. Columns that a record lacks
+	// and names missing from the header yield empty fields.
+	SelectColumns []int
+	SelectNames   []string
+
+	// WhereColumns and WhereNames drop the records that do not match all of
+	// the predicates by column index and by name, respectively. Predicates
+	// are evaluated by the parsing workers, prior to the normalizations and
+	// FieldTransform, and are invoked concurrently, so they must be safe for
+	// concurrent use. Names refer to the header in the first record, which
+	// is kept whenever columns are referred to by name. Columns that a record
+	// lacks match as empty fields.
+	WhereColumns map[int]Predicate
+	WhereNames   map[string]Predicate
+
+	// DedupColumns and DedupNames drop every record whose key, made up of
+	// the fields of these columns by index and by name, repeats the key of
+	// an earlier record, so the first of duplicates is kept. The parsing
+	// workers hash the keys after FieldTransform, which the reader looks up
+	// in the order of the records. As for NormalizeColumns, indexes refer to
+	// the selected columns, and names to the header in the first record,
+	// which is kept. Keys are remembered from the start of reading, or from
+	// where SeekRecord or Rewind resumed.
+	DedupColumns []int
+	DedupNames   []string
+
+	// DedupSpill, if positive, is the number of keys of DedupColumns and
+	// DedupNames held in memory at most, beyond which they are written out
+	// to temporary files, to dedup inputs with more keys than fit in memory.
+	// The files are searched through Bloom filters held in memory.
+	DedupSpill int
+
+	// SampleEvery, if greater than 1, reads every SampleEvery-th record only,
+	// and SampleSize, if positive, reads a uniform random sample of that many
+	// records, which are returned in the order of the input once all of it
+	// has been parsed. If both are set, the sample is drawn from every
+	// SampleEvery-th record. The first record, typically a header, is always
+	// kept. The parsing workers drop the other records, in the order of the
+	// blocks, before the predicates, the projection and the normalizations.
+	// Sampling cannot be combined with Sentinel, SkipFooter or Replay.
+	SampleEvery int
+	SampleSize  int
+
+	// OnError, if set, is consulted about every record that cannot be
+	// parsed or has the wrong number of fields, and decides whether to
+	// Abort parsing (the default), Skip the record or Replace it by the
+	// Record of the RecordError. OnError may be invoked concurrently by the
+	// parsing workers and in any order, so it must be safe for concurrent use.
+	OnError func(err *RecordError) Action
+
+	// Trace, if non-nil, records the schedule of the parse: the chunk
+	// boundaries, the stage 2 worker that processed every chunk and the
+	// order in which the chunks were started and completed. Replay, if
+	// non-nil, parses with the schedule recorded for a previous parse of the
+	// same input, so that reordering bugs can be reproduced from a trace.
+	// Both are meant for debugging; replaying may hold all chunks in memory.
+	Trace  *Schedule
+	Replay *Schedule
+
+	// Logger, if non-nil, receives diagnostics: errors reading the input
+	// (which are returned as well) and chunks handed to encoding/csv. A
+	// *slog.Logger will do.
+	Logger Logger
+
+	// Memory, if non-nil, accumulates the memory used by each stage of the
+	// pipeline while parsing, to help with tuning for the data at hand.
+	Memory *MemoryProfile
+
+	// OnFallback, if non-nil, is called with the counters so far whenever
+	// encoding/csv takes over from the SIMD stages: for inputs below
+	// FallbackThreshold, on CPUs without SIMD support and for chunks that
+	// the SIMD stages cannot handle. It may be called concurrently from
+	// several goroutines. See Stats.
+	OnFallback func(Stats)
+
+	// Faults, if non-nil, injects failures for testing (see Faults).
+	Faults *Faults
+
+	// SkipLines and SkipUntil skip the preamble that precedes the records,
+	// such as a report title or parameters. SkipLines is the number of lines
+	// to skip, after which SkipUntil, if set, is passed every line (without
+	// its terminator) until it reports the start of the records.
+	SkipLines int
+	SkipUntil func(line []byte) bool
+
+	// SkipRows is the number of rows to skip once past SkipLines and
+	// SkipUntil. Unlike lines, rows are counted as encoding/csv counts
+	// records: quoted fields may span lines, and neither empty lines nor
+	// comments count.
+	SkipRows int
+
+	// If SepLine is true, a first line such as sep=; (as written by Excel,
+	// see Writer.SepLine) selects the delimiter that follows the equals sign,
+	// as Comma, or as CommaString if it has multiple characters, and is
+	// skipped. A first line that names no valid delimiter is read as is.
+	SepLine bool
+
+	// SkipFooter is the number of records at the end of the input to drop,
+	// such as summary lines. Records are held back until SkipFooter more
+	// follow them. The footer is parsed like any record, so set
+	// FieldsPerRecord to -1 if its number of fields differs.
+	SkipFooter int
+
+	// Sentinel, if set, reports whether a record is the trailer that ends
+	// the data (such as "END,12345"), along with the number of records that
+	// the trailer declares to precede it, or -1 if it does not. Neither the
+	// trailer nor anything after it is returned, and reading ends with
+	// ErrTrailerCount if the declared count is off. Sentinel is passed the
+	// records as returned, so any header counts as a preceding record.
+	Sentinel func(record []string) (count int, ok bool)
+
+	// Stage1, if non-nil, replaces the built-in implementation of stage 1
+	// (experimental, see Stage1Backend).
+	Stage1 Stage1Backend
+
+	r    io.Reader
+	rCsv *csvPositions // Used as fallback when simd isn't supported
+
+	// source that supports rewinding (see SeekRecord), along with the input
+	// offset and number of lines preceding r
+	ra          io.ReaderAt
+	startOffset int64
+	lineOffset  int
+	dataOffset  int64 // input offset of the first record, after the preamble
+
+	// in-memory input that is sliced into chunks without copying (see ReadBytes)
+	data     []byte
+	inMemory bool
+
+	transcoding bool // whether the input is transcoded (see Encoding)
+
+	sep *separator // multi-byte separator, if any, as substituted for stage 1
+	esc *escaper   // escapes, if any, as neutralized for stage 1
 
 	//* state: IsStreaming when true, the readallstreaming process is active
 	IsStreaming bool
-	records     [][]string            //Current block of records
-	hash        map[int]recordsOutput // seqences of blocks waiting
-	currrecord  int                   //current record in block
-	sequence    int                   // current block sequence number
-	readchan    chan recordsOutput
+	records     [][]string   //Current block of records
+	lastRecord  []string     // record returned by Read, if ReuseRecord is set
+	positions   []recordPos  //position of each record in block
+	currrecord  int          //current record in block
+	slots       *outputSlots // blocks of records in sequence
+	norm        *normalizer  // normalizations by column, once resolved
+	proj        *projection  // selected columns, once resolved
+	where       *predicates  // predicates by column, once resolved
+	header      []string     // (normalized) first record, once read
+	decode      blockDecoder // decodes blocks within the workers (see Stream)
+	needHeader  bool         // whether to resolve the header up front (see Decoder)
+	sched       *scheduler   // records or replays the schedule, if any
+	kernel      *kernel      // kernel forcibly selected, if any (see SelfTest)
+	stats       readerStats  // counters of the work done (see Stats)
+	readErr     error        // error that ended the input while peeking
+	trailer     []string     // record that ended the data (see Sentinel)
+	query       *sqlQuery    // see Select
+	dedup       *dedup       // key columns and keys seen, once resolved
+	sampling    *sampler     // see SampleEvery and SampleSize
+	maps        []mapper     // see Map
+
+	footer          [][]string  // records held back as possibly the footer (see SkipFooter)
+	footerPositions []recordPos // positions of the footer records
+	chunk           []byte      // chunk buffer of the current block, if releasable
+
+	recordNumber int       // ordinal of the record last returned by Read
+	lastPos      recordPos // position of the record last returned by Read
+
+	consumed int          // number of records received from the workers
+	index    []checkpoint // blocks received so far, to seek records
 }
 
+// defaultFallbackThreshold is the crossover point below which encoding/csv
+// outperforms the SIMD stages (see FallbackThreshold)
+const defaultFallbackThreshold = 16384
+
+// defaultChunkSize is the size of the chunks of input (see ChunkSize)
+const defaultChunkSize = 320000
+
+// queueDepth is the capacity of the channels between the pipeline stages
+const queueDepth = 128
+
 var errInvalidDelim = errors.New("csv: invalid field or comment delimiter")
 
+var errInvalidChunkSize = errors.New("simdcsv: negative ChunkSize")
+
 func validDelim(r rune) bool {
 	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
 }
 
 // NewReader returns a new Reader that reads from r.
+//
+// Input is read directly into the chunk buffers handed to the parsing
+// stages, so r is not wrapped in an intermediate buffer.
 func NewReader(r io.Reader) *Reader {
+	ra, _ := r.(io.ReaderAt)
 	return &Reader{
 		Comma: ',',
-		r:     bufio.NewReader(r),
+		r:     r,
+		ra:    ra,
+	}
+}
+
+// NewReaderSize returns a new Reader that reads from r through a buffer of
+// at least size bytes. This only pays off for sources that are expensive to
+// call with small reads; a size <= 0 is equivalent to NewReader.
+func NewReaderSize(r io.Reader, size int) *Reader {
+	if size <= 0 {
+		return NewReader(r)
 	}
+	rd := NewReader(bufio.NewReaderSize(r, size))
+	rd.ra, _ = r.(io.ReaderAt)
+	return rd
 }
 
 type chunkInfo struct {
-	sequence int
-	chunk    []byte
-	masks    []uint64
-	postProc []uint64
-	header   uint64
-	trailer  uint64
-	splitRow []byte
+	sequence  int
+	chunk     []byte
+	masks     []uint64
+	postProc  []uint64
+	header    uint64
+	trailer   uint64
+	splitRow  []byte
+	line      int   // line on which the chunk continues after header
+	rowLine   int   // line on which splitRow starts
+	rowOffset int64 // input offset at which splitRow starts
+	offset    int64 // input offset of chunk
+	fallback  bool  // whether the chunk is to be parsed by encoding/csv
 }
 
 type recordsOutput struct {
-	sequence int
-	records  [][]string
-	err      error
+	sequence  int
+	records   [][]string
+	positions []recordPos
+	err       error
+	decoded   interface{} // records as decoded by the workers (see Stream)
+	start     checkpoint  // where the records of the block start
+	chunk     []byte      // chunk buffer the fields point into, if releasable
+	keys      []dedupKey  // keys of the records (see DedupColumns)
 }
 
 type chunkIn struct {
 	buf  []byte
 	last bool
+	spec *speculation // masks of buf as preprocessed ahead, if any
 }
 
 // readAllStreaming reads all the remaining records from r.
-func (r *Reader) readAllStreaming() (out chan recordsOutput) {
+func (r *Reader) readAllStreaming() (out *outputSlots) {
+	if out = r.startStreaming(); out != nil {
+		r.streamRecords(out)
+	}
+	return
+}
 
+// startStreaming returns the slots to stream the records into, or nil if
+// streaming is in progress
+func (r *Reader) startStreaming() (out *outputSlots) {
 	if r.IsStreaming {
 		return nil // We don't want 2 active readers
 	}
 	r.IsStreaming = true
-	out = make(chan recordsOutput, 128)
+	out = newOutputSlots(reorderWindowSize)
+	r.sched = newScheduler(r.Trace, r.Replay, out)
+	return
+}
 
-	fallback := func(ioReader io.Reader) recordsOutput {
-		rCsv := csv.NewReader(ioReader)
+// streamRecords reads all the remaining records from r into out, of which
+// the first chunk is obtained on the calling goroutine
+func (r *Reader) streamRecords(out *outputSlots) {
+
+	fallback := func(ioReader io.Reader, line int, offset int64, fieldsPerRecord int) recordsOutput {
+		r.fellBack()
+		p := r.newCsvPositions(ioReader, line, offset)
+		p.onError = r.OnError
+		rCsv := p.rCsv
 		rCsv.LazyQuotes = r.LazyQuotes
 		rCsv.TrimLeadingSpace = r.TrimLeadingSpace
 		rCsv.Comment = r.Comment
-		rCsv.Comma = r.Comma
-		rCsv.FieldsPerRecord = r.FieldsPerRecord
-		rCsv.ReuseRecord = r.ReuseRecord
-		rcds, err := rCsv.ReadAll()
-		return recordsOutput{-1, rcds, err}
+		rCsv.FieldsPerRecord = fieldsPerRecord
+		rcds, positions, err := p.readAll()
+		return recordsOutput{0, rcds, positions, err, nil, checkpoint{offset: r.startOffset, line: line}, nil, nil}
 	}
 
-	if r.Comma == r.Comment || !validDelim(r.Comma) || (r.Comment != 0 && !validDelim(r.Comment)) {
-		go func() {
-			out <- recordsOutput{-1, nil, errInvalidDelim}
-			close(out)
-		}()
+	if r.Comma == r.Comment || !validDelim(r.Comma) || (r.Comment != 0 && !validDelim(r.Comment)) || !r.validCommaString() || !r.validEscape() || !r.validCommentPrefix() {
+		r.emit(out, recordsOutput{0, nil, nil, errInvalidDelim, nil, checkpoint{}, nil, nil})
+		r.IsStreaming = false
+		out.close()
+		return
+	}
+
+	if r.ChunkSize < 0 {
+		r.emit(out, recordsOutput{0, nil, nil, errInvalidChunkSize, nil, checkpoint{}, nil, nil})
 		r.IsStreaming = false
+		out.close()
 		return
 	}
 
-	if r.LazyQuotes ||
-		r.Comma != 0 && r.Comma > unicode.MaxLatin1 ||
-		r.Comment != 0 && r.Comment > unicode.MaxLatin1 {
+	if r.SkipFooter > 0 && r.filters() {
+		r.emit(out, recordsOutput{0, nil, nil, errFooterWhere, nil, checkpoint{}, nil, nil})
+		r.IsStreaming = false
+		out.close()
+		return
+	}
+
+	if r.RawQuotes && r.Escape != 0 {
+		r.emit(out, recordsOutput{0, nil, nil, errRawQuotesEscape, nil, checkpoint{}, nil, nil})
+		r.IsStreaming = false
+		out.close()
+		return
+	}
+
+	if err := r.validSample(); err != nil {
+		r.emit(out, recordsOutput{0, nil, nil, err, nil, checkpoint{}, nil, nil})
+		r.IsStreaming = false
+		out.close()
+		return
+	}
+	if r.samples() {
+		r.sampling = r.newSampler()
+	}
+
+	r.transcode()
+	r.bareCRs()
+	if err := r.skipPreamble(); err != nil {
+		r.emit(out, recordsOutput{0, nil, nil, err, nil, checkpoint{}, nil, nil})
+		r.IsStreaming = false
+		out.close()
+		return
+	}
+
+	if r.Comment != 0 && r.Comment > unicode.MaxLatin1 {
+		r.IsStreaming = false
 		go func() {
-			out <- fallback(r.r)
-			close(out)
+			r.emit(out, fallback(r.input(), r.lineOffset+1, r.startOffset, r.FieldsPerRecord))
+			r.flushSample(out)
+			out.close()
 		}()
-		r.IsStreaming = false
 		return
 	}
 
-	chunkSize := 320000
+	r.sep, r.esc = newSeparator(r.delimiter()), newEscaper(r.Escape)
+
+	chunkSize := r.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = defaultChunkSize
+	}
 
 	// chunkSize must be a multiple of 64 bytes
 	chunkSize = (chunkSize + 63) &^ 63
+	out.limit = r.maxPending(chunkSize)
 	masksSize := ((chunkSize >> 6) + 2) * 3 // add 2 extra slots as safety for masks
 
 	// channel with slices of input
-	bufchan := make(chan chunkIn, cap(out))
-
-	go func() {
-
-		defer func() {
-			close(bufchan)
+	bufchan := make(chan chunkIn, queueDepth)
+
+	// the first chunk is obtained on the caller's goroutine: when the input
+	// fits within a single chunk, the pipeline costs more than it saves
+	var single, first []byte
+	failed := false // whether reading single failed
+	if r.inMemory {
+		data := r.data
+		r.data = nil
+		r.countBytes(int64(len(data)))
+		if len(data) == 0 {
 			r.IsStreaming = false
-		}()
-
-		br := bufio.NewReader(r.r)
-		chunk := make([]byte, chunkSize)
-
-		n, err := br.Read(chunk)
-		if err == io.EOF {
-			return
-		} else if err != nil {
-			log.Printf("bufio.Read() encounterend error: %v", err)
+			out.close()
 			return
+		} else if len(data) < chunkSize {
+			if r.InputHash != nil {
+				r.InputHash.Write(data)
+			}
+			single = data
 		} else {
-			chunk = chunk[:n]
+			first = data
+			go r.sliceChunks(data, chunkSize, bufchan, out)
 		}
-
-		for {
-			chunkNext := make([]byte, chunkSize)
-
-			n, err := br.Read(chunkNext)
-			if err == io.EOF {
-				if n > 0 {
-					panic("last buffer should be empty")
-				}
-				bufchan <- chunkIn{chunk, true}
-				break
-			} else if err != nil {
-				log.Printf("bufio.Read() encounterend error: %v", err)
-				bufchan <- chunkIn{chunk, true}
-				break
+	} else {
+		in := r.input()
+		chunk := r.acquireChunk(chunkSize)[:r.sched.size(0, chunkSize)]
+		n, err := io.ReadFull(in, chunk)
+		switch {
+		case err == nil:
+			first = chunk
+			if at, offset, ok := r.inputAt(); ok {
+				go r.readChunksAt(at, offset, chunk, chunkSize, masksSize, bufchan, out)
 			} else {
-				bufchan <- chunkIn{chunk, false}
-				chunk = chunkNext[:n]
+				go r.readChunks(in, chunk, chunkSize, bufchan, out)
 			}
+		case err == io.ErrUnexpectedEOF && n > 0:
+			single = chunk[:n]
+		case err == io.EOF:
+			r.releaseChunk(chunk)
+			r.IsStreaming = false
+			out.close()
+			return
+		default:
+			if r.readFailed(out, err); n == 0 {
+				r.releaseChunk(chunk)
+				r.IsStreaming = false
+				out.close()
+				return
+			}
+			single, failed = chunk[:n], true
 		}
-	}()
+	}
+
+	if r.projects() && r.proj == nil || r.filters() && r.where == nil {
+		// resolve the selected columns and predicates up front, so the
+		// workers can skip the others
+		var header []string
+		if buf := first; r.startOffset == r.dataOffset {
+			if single != nil {
+				buf = single
+			}
+			header = r.headerOf(buf)
+		}
+		if r.projects() && r.proj == nil {
+			r.proj = r.newProjection(header)
+		}
+		if r.filters() && r.where == nil {
+			r.where = r.newPredicates(header)
+		}
+	}
+
+	if single != nil {
+		if len(single) < r.fallbackThreshold() && !failed {
+			r.emit(out, fallback(bytes.NewReader(single), r.lineOffset+1, r.startOffset, r.FieldsPerRecord))
+		} else {
+			// input that failed is not the last, so the row cut short is left out
+			r.fusedStreaming(single, !failed, chunkSize, masksSize, fallback, out)
+		}
+		r.flushSample(out)
+		r.IsStreaming = false
+		out.close()
+		return
+	}
+
+	if r.normalizes() && r.norm == nil {
+		// resolve the header up front, so the workers need not wait for it
+		r.norm = r.newNormalizer(r.projected(r.headerOf(first)))
+	}
+	if r.dedups() && r.dedup == nil {
+		r.dedup = r.newDedup(r.normalizeHeader(r.projected(r.headerOf(first))))
+	}
+	if r.needHeader && r.header == nil && r.startOffset == r.dataOffset {
+		r.header = r.normalizeHeader(r.projected(r.headerOf(first)))
+	}
 
 	// channel with preprocessed chunks
-	chunks := make(chan chunkInfo, cap(out))
+	chunks := make(chan chunkInfo, queueDepth)
 
-	go r.stage1Streaming(bufchan, chunkSize, masksSize, chunks)
+	go r.stage1Streaming(bufchan, chunkSize, masksSize, chunks, out)
 
 	go func() {
 		var wg sync.WaitGroup
-
-		// Determine how many second stages to run in parallel
-		const cores = 2
-		wg.Add(cores)
 		fieldsPerRecord := int64(r.FieldsPerRecord)
 
-		for parallel := 0; parallel < cores; parallel++ {
-			go r.stage2Streaming(chunks, &wg, &fieldsPerRecord, fallback, out)
+		if workers := r.sched.workers(); workers > 0 {
+			// hand every chunk to the worker of the replayed schedule
+			queues := make([]chan chunkInfo, workers)
+			for w := range queues {
+				queues[w] = make(chan chunkInfo, len(r.Replay.Chunks)+queueDepth)
+				wg.Add(1)
+				go r.stage2Streaming(queues[w], w, &wg, &fieldsPerRecord, fallback, out, nil)
+			}
+			for chunkInfo := range chunks {
+				queues[r.sched.worker(chunkInfo.sequence)] <- chunkInfo
+			}
+			for _, queue := range queues {
+				close(queue)
+			}
+			wg.Wait()
+			r.flushSample(out)
+			r.IsStreaming = false
+			out.close()
+			return
+		}
+
+		// Start with a single second stage, more are added while chunks are
+		// queueing up (see stage2Scaler)
+		workers := int32(0)
+		scaler := &stage2Scaler{active: 1, max: int32(runtime.GOMAXPROCS(0))}
+		scaler.spawn = func() {
+			wg.Add(1)
+			go r.stage2Streaming(chunks, int(atomic.AddInt32(&workers, 1)), &wg, &fieldsPerRecord, fallback, out, scaler)
 		}
+		wg.Add(1)
+		go r.stage2Streaming(chunks, 0, &wg, &fieldsPerRecord, fallback, out, scaler)
 
 		wg.Wait()
-		close(out)
+		r.flushSample(out)
+		r.IsStreaming = false
+		out.close()
 	}()
 
 	return
 }
 
-func (r *Reader) stage1Streaming(bufchan chan chunkIn, chunkSize int, masksSize int, chunks chan chunkInfo) {
+// readChunks reads the input into chunk buffers, starting with chunk as
+// the first chunk that was already read
+func (r *Reader) readChunks(in io.Reader, chunk []byte, chunkSize int, bufchan chan chunkIn, out *outputSlots) {
+
+	defer close(bufchan)
+
+	sequence := 0
+	for {
+		sequence++
+		if out.admit(sequence); out.isStopped() || out.failed() {
+			bufchan <- chunkIn{chunk, true, nil}
+			break
+		}
+		chunkNext := r.acquireChunk(chunkSize)
+
+		// fill the chunk regardless of short reads, as pipes and network
+		// streams return, so only the final chunk can be short (and is
+		// followed by one without data)
+		n, err := io.ReadFull(in, chunkNext[:r.sched.size(sequence, chunkSize)])
+		if err == io.ErrUnexpectedEOF && n > 0 {
+			err = nil // unless the input failed with it, without data
+		}
+		if err == io.EOF {
+			r.releaseChunk(chunkNext)
+			bufchan <- chunkIn{chunk, true, nil}
+			break
+		} else if err != nil {
+			// hand on what was read as not the last chunk, so the row cut
+			// short is left out
+			r.readFailed(out, err)
+			bufchan <- chunkIn{chunk, false, nil}
+			if n > 0 {
+				bufchan <- chunkIn{chunkNext[:n], false, nil}
+			} else {
+				r.releaseChunk(chunkNext)
+			}
+			break
+		} else {
+			bufchan <- chunkIn{chunk, false, nil}
+			chunk = chunkNext[:n]
+		}
+	}
+}
+
+// sliceChunks hands out chunks of in-memory input without copying
+func (r *Reader) sliceChunks(data []byte, chunkSize int, bufchan chan chunkIn, out *outputSlots) {
+
+	defer close(bufchan)
+
+	for sequence := 0; ; sequence++ {
+		out.admit(sequence)
+		size := r.sched.size(sequence, chunkSize)
+		chunk, last := data, len(data) <= size || out.isStopped() || out.failed()
+		if len(data) > size {
+			chunk, data = data[:size:size], data[size:]
+		}
+		if r.InputHash != nil {
+			r.InputHash.Write(chunk)
+		}
+		bufchan <- chunkIn{chunk, last, nil}
+		if last {
+			break
+		}
+	}
+}
+
+// acquireChunk returns a chunk buffer of length size
+func (r *Reader) acquireChunk(size int) []byte {
+	buf, pooled := takeChunk(size)
+	r.Memory.acquireChunk(buf, pooled)
+	return buf
+}
+
+// releaseChunk hands a chunk buffer that is no longer referenced back to
+// the pool, unless it belongs to in-memory input
+func (r *Reader) releaseChunk(buf []byte) {
+	if !r.inMemory {
+		r.Memory.releaseChunk(buf)
+		putChunk(buf)
+	}
+}
+
+// maxPending returns the number of chunks the input may run ahead of the
+// consumer, or 0 if not bounded
+func (r *Reader) maxPending(chunkSize int) int {
+	limit := r.MaxPendingBlocks
+	if r.MaxPendingBytes > 0 {
+		n := int(r.MaxPendingBytes / int64(chunkSize))
+		if n < 1 {
+			n = 1
+		}
+		if limit <= 0 || n < limit {
+			limit = n
+		}
+	}
+	return limit
+}
+
+func (r *Reader) fallbackThreshold() int {
+	if r.kernel == nil && r.simdMode() == SIMDForce {
+		return -1
+	}
+	if r.FallbackThreshold == 0 {
+		return defaultFallbackThreshold
+	}
+	return r.FallbackThreshold
+}
+
+// fusedStreaming runs both stages inline on the caller's goroutine for an
+// input that consists of a single (last) chunk.
+func (r *Reader) fusedStreaming(buf []byte, last bool, chunkSize int, masksSize int, fallback func(ioReader io.Reader, line int, offset int64, fieldsPerRecord int) recordsOutput, out *outputSlots) {
+
+	bufchan := make(chan chunkIn, 1)
+	bufchan <- chunkIn{buf, last, nil}
+	close(bufchan)
+
+	chunks := make(chan chunkInfo, 1)
+	r.stage1Streaming(bufchan, chunkSize, masksSize, chunks, out)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	fieldsPerRecord := int64(r.FieldsPerRecord)
+	r.stage2Streaming(chunks, 0, &wg, &fieldsPerRecord, fallback, out, nil)
+}
+
+func (r *Reader) stage1Streaming(bufchan chan chunkIn, chunkSize int, masksSize int, chunks chan chunkInfo, out *outputSlots) {
 
 	defer close(chunks)
 
@@ -249,19 +861,70 @@ func (r *Reader) stage1Streaming(bufchan chan chunkIn, chunkSize int, masksSize
 	quoted := uint64(0) // initialized quoted state to unquoted
 
 	splitRow := make([]byte, 0, 256)
+	line, rowLine := r.lineOffset+1, r.lineOffset+1   // line at the start of the chunk and of splitRow
+	offset, rowOffset := r.startOffset, r.startOffset // likewise for the input offset
+
+	// stray quotes throw off the quoted state that the rows are split by,
+	// so chunks with any are rescanned for their rows (see lazyQuotes)
+	lazy := newLazyQuotes(r.delimiter(), byte(r.Escape))
+	lazy.comment = r.commentPrefixes()
+
+	// the quotes of comment lines do not toggle the quoted state either
+	comments := newCommentQuotes(r.commentPrefixes())
+
+	tooLarge := false // whether a row exceeded MaxRecordBytes, after which the input is drained
 
 	for chunk := range bufchan {
 
-		postProcStream := make([]uint64, 0, ((chunkSize>>6)+1)*2)
-		masksStream := make([]uint64, masksSize)
+		if tooLarge {
+			if chunk.spec != nil {
+				putMasks(chunk.spec.masks, chunk.spec.postProc)
+			}
+			r.releaseChunk(chunk.buf)
+			continue
+		}
+
+		r.sched.chunk(sequence, offset, len(chunk.buf))
+		r.stats.chunks.Add(1)
+
+		buf, comma, substituted := chunk.buf, r.delimiter()[0], true
+		if r.esc != nil {
+			buf = r.esc.neutralize(buf)
+		}
+		if r.sep != nil {
+			buf, substituted = r.sep.substitute(buf)
+			comma = separatorPlaceholder
+		}
+		if comments != nil {
+			buf = comments.blank(buf, quoted != 0)
+		}
 
-		masksStream, postProcStream, quoted = stage1PreprocessBufferEx(chunk.buf, uint64(r.Comma), quoted, &masksStream, &postProcStream)
+		quotedIn := quoted
+		var masksStream, postProcStream []uint64
+		if chunk.spec != nil && quoted == 0 {
+			// preprocessed as read, and rightly so (see readChunksAt)
+			masksStream, postProcStream, quoted = chunk.spec.masks, chunk.spec.postProc, chunk.spec.quoted
+		} else {
+			if chunk.spec != nil {
+				putMasks(chunk.spec.masks, chunk.spec.postProc) // speculated wrongly
+			}
+			masks, postProc := takeMasks(masksSize, ((chunkSize>>6)+1)*2)
+			masksStream, postProcStream, quoted = r.preprocess(buf, comma, quoted, masks, postProc)
+		}
 
 		header, trailer := uint64(0), uint64(0)
 
+		// newlines within quoted fields do not end rows, so the row split
+		// from the previous chunk ends at the first delimiter outside quotes
+		// as of the quoted state at the start of the chunk, and the row that
+		// is split into the next chunk starts after the last one, as of the
+		// quoted state at the end
 		if sequence > 0 {
-			for index := 0; index < len(masksStream); index += 3 {
-				hr := bits.TrailingZeros64(masksStream[index])
+			q := quotedIn
+			for index := 0; index+2 < len(masksStream); index += 3 {
+				inQuotes := prefixXor(masksStream[index+2]) ^ q
+				q = uint64(int64(inQuotes) >> 63)
+				hr := bits.TrailingZeros64(masksStream[index] &^ inQuotes)
 				header += uint64(hr)
 				if hr < 64 {
 					// upon finding the first delimiter bit, we can break out
@@ -278,51 +941,254 @@ func (r *Reader) stage1Streaming(bufchan chan chunkIn, chunkSize int, masksSize
 		}
 
 		if !chunk.last && header < uint64(len(chunk.buf)) {
-			for index := 3; index < len(masksStream); index += 3 {
-				tr := bits.LeadingZeros64(masksStream[len(masksStream)-index])
+			q := quoted
+			for index := 3; index <= len(masksStream); index += 3 {
+				quotes := masksStream[len(masksStream)-index+2]
+				if bits.OnesCount64(quotes)&1 == 1 {
+					q = ^q // the quoted state at the start of the block
+				}
+				inQuotes := prefixXor(quotes) ^ q
+				delimiters := masksStream[len(masksStream)-index]
+				if index == 3 && chunk.buf[len(chunk.buf)-1] == '\r' {
+					// the newline of a CRLF that ends the chunk follows in
+					// the next one, so the row is split along with it
+					delimiters &^= 1 << ((len(chunk.buf) - 1) & 63)
+				}
+				tr := bits.LeadingZeros64(delimiters &^ inQuotes)
 				trailer += uint64(tr)
 				if tr < 64 {
 					break
 				}
 			}
+			trailer -= uint64(-len(chunk.buf) & 63) // the masks are padded to 64 bytes
+		}
+
+		fallback := !substituted
+		if !lazy.wellFormed(chunk.buf, masksStream, quotedIn, chunk.last) {
+			header, trailer, quoted = lazy.resolve(splitRow, chunk.buf, sequence == 0, chunk.last)
+			if comments != nil {
+				comments.resync(lazy.line, lazy.within)
+			}
+			fallback = true
+		}
+		if trailer >= uint64(len(chunk.buf)) {
+			// no row ends within the chunk (such as the first one), which
+			// therefore continues the split row as a whole
+			header, trailer = uint64(len(chunk.buf)), 0
 		}
 
 		splitRow = append(splitRow, chunk.buf[:header]...)
+		if r.MaxRecordBytes > 0 && len(splitRow) > r.MaxRecordBytes {
+			// such as for an unterminated quote, which would swallow the
+			// remainder of the input
+			out.fail(recordTooLarge(rowLine, r.MaxRecordBytes))
+			putMasks(masksStream, postProcStream)
+			r.releaseChunk(chunk.buf)
+			tooLarge = true
+			continue
+		}
 
+		headerLine := line + bytes.Count(chunk.buf[:header], []byte{'\n'})
+		trailerLine := headerLine
+		var nextRow []byte
 		if header < uint64(len(chunk.buf)) {
-			chunks <- chunkInfo{sequence, chunk.buf, masksStream, postProcStream, header, trailer, splitRow}
+			trailerLine += bytes.Count(chunk.buf[header:len(chunk.buf)-int(trailer)], []byte{'\n'})
+			// copy the trailer before stage 2 gets hold of the chunk, which
+			// it may release
+			nextRow = append(make([]byte, 0, len(splitRow)*3/2), chunk.buf[len(chunk.buf)-int(trailer):]...)
+			info := chunkInfo{sequence, chunk.buf, masksStream, postProcStream, header, trailer, splitRow, headerLine, rowLine, rowOffset, offset, fallback}
+			r.Memory.acquireMasks(info)
+			chunks <- info
 		} else {
-			chunks <- chunkInfo{sequence, nil, nil, nil, 0, 0, splitRow}
+			discarded := chunkInfo{masks: masksStream, postProc: postProcStream}
+			r.Memory.acquireMasks(discarded)
+			r.Memory.releaseMasks(discarded)
+			putMasks(masksStream, postProcStream)
+			if !chunk.last {
+				// the row continues into the next chunk, so keep accumulating it
+				chunks <- chunkInfo{sequence: sequence, line: headerLine, rowLine: rowLine, rowOffset: rowOffset}
+				line = headerLine
+				offset += int64(len(chunk.buf))
+				r.releaseChunk(chunk.buf) // contents have been copied into splitRow
+				sequence++
+				continue
+			}
+			info := chunkInfo{sequence, nil, nil, nil, 0, 0, splitRow, headerLine, rowLine, rowOffset, offset, false}
+			r.Memory.acquireMasks(info)
+			chunks <- info
 		}
 
-		splitRow = make([]byte, 0, len(splitRow)*3/2)
-		splitRow = append(splitRow, chunk.buf[len(chunk.buf)-int(trailer):]...)
+		splitRow = nextRow
+		line = trailerLine + bytes.Count(splitRow, []byte{'\n'})
+		rowLine = trailerLine
+		offset += int64(len(chunk.buf))
+		rowOffset = offset - int64(trailer)
+
+		if header >= uint64(len(chunk.buf)) {
+			r.releaseChunk(chunk.buf) // contents have been copied into splitRow
+		}
 
 		sequence++
 	}
 }
 
-func (r *Reader) stage2Streaming(chunks chan chunkInfo, wg *sync.WaitGroup, fieldsPerRecord *int64, fallback func(ioReader io.Reader) recordsOutput, out chan recordsOutput) {
+// stage2Fallback parses a chunk that the SIMD stages could not handle with
+// encoding/csv, preceded by the records of the row split from the previous chunk.
+// encoding/csv is given the number of fields established by the previous
+// chunks, as it would otherwise take it from the first record of the chunk
+func (r *Reader) stage2Fallback(chunkInfo chunkInfo, splitRecords [][]string, splitPositions []recordPos, fieldsPerRecord *int64, fallback func(ioReader io.Reader, line int, offset int64, fieldsPerRecord int) recordsOutput) recordsOutput {
+	r.logDebug("simdcsv: chunk parsed by encoding/csv", "sequence", chunkInfo.sequence, "offset", chunkInfo.offset)
+	fpr := r.FieldsPerRecord
+	if established := int(atomic.LoadInt64(fieldsPerRecord)); established > 0 {
+		fpr = established
+	}
+	rcrds := fallback(bytes.NewReader(chunkInfo.chunk[chunkInfo.header:len(chunkInfo.chunk)-int(chunkInfo.trailer)]), chunkInfo.line, chunkInfo.offset+int64(chunkInfo.header), fpr)
+	r.releaseChunk(chunkInfo.chunk) // fallback copies all fields
+	rcrds.sequence = chunkInfo.sequence
+	rcrds.start = checkpoint{offset: chunkInfo.rowOffset, line: chunkInfo.rowLine}
+	if rcrds.err == nil && len(splitRecords) > 0 {
+		rcrds.records = append(splitRecords[:len(splitRecords):len(splitRecords)], rcrds.records...)
+		rcrds.positions = append(splitPositions[:len(splitPositions):len(splitPositions)], rcrds.positions...)
+	}
+	if rcrds.err == nil {
+		rcrds.records, rcrds.positions, rcrds.err = r.checkFieldsPerRecord(rcrds.records, rcrds.positions, fieldsPerRecord)
+	}
+	return rcrds
+}
+
+// checkFieldsPerRecord enforces the number of fields across chunks on the
+// records of a chunk parsed by encoding/csv, which only sees the chunk,
+// consulting OnError about the records that are off
+func (r *Reader) checkFieldsPerRecord(records [][]string, positions []recordPos, fieldsPerRecord *int64) ([][]string, []recordPos, error) {
+	if len(records) > 0 {
+		atomic.CompareAndSwapInt64(fieldsPerRecord, 0, int64(len(records[0])))
+	}
+	fpr := atomic.LoadInt64(fieldsPerRecord)
+	if fpr <= 0 {
+		return records, positions, nil
+	}
+
+	n := 0
+	for i, record := range records {
+		if int64(len(record)) != fpr {
+			err := fieldCountError(positions, i)
+			if r.OnError == nil {
+				return nil, nil, err
+			}
+			recordErr := &RecordError{Line: positions[i].line, Record: record, Err: err}
+			switch r.OnError(recordErr) {
+			case Skip:
+				continue
+			case Replace:
+				record = recordErr.Record
+			default:
+				return nil, nil, err
+			}
+		}
+		records[n], positions[n] = record, positions[i]
+		n++
+	}
+	return records[:n], positions[:n], nil
+}
+
+// stage2Scaler adapts the number of stage 2 workers to the backlog of
+// preprocessed chunks: a worker that finds more chunks waiting starts an
+// additional worker (up to max), whereas a worker that finds the queue
+// empty retires (as long as another worker remains). So when the input
+// source is the bottleneck, a single worker is left running.
+type stage2Scaler struct {
+	active int32
+	max    int32
+	spawn  func()
+}
+
+func (s *stage2Scaler) scaleUp(backlog int) {
+	for backlog > 0 {
+		active := atomic.LoadInt32(&s.active)
+		if active >= s.max {
+			return
+		}
+		if atomic.CompareAndSwapInt32(&s.active, active, active+1) {
+			s.spawn()
+			return
+		}
+	}
+}
+
+func (s *stage2Scaler) retire(backlog int) bool {
+	for backlog == 0 {
+		active := atomic.LoadInt32(&s.active)
+		if active <= 1 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&s.active, active, active-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reader) stage2Streaming(chunks chan chunkInfo, worker int, wg *sync.WaitGroup, fieldsPerRecord *int64, fallback func(ioReader io.Reader, line int, offset int64, fieldsPerRecord int) recordsOutput, out *outputSlots, scaler *stage2Scaler) {
 	defer wg.Done()
 
-	simdlines, rowsSize, columnsSize := 1024, 500, 50000
+	retired := false
+	if scaler != nil {
+		defer func() {
+			if !retired {
+				atomic.AddInt32(&scaler.active, -1)
+			}
+		}()
+	}
+
+	simdlines := 1024
+	comments := r.commentPrefixes()
+
+	// rows and columns are scratch space that is reused for every chunk;
+	// the fields of each chunk are copied out before building the records
+	rows, columns := make([]uint64, 500), make([]string, 50000)
+	var inputStage2 inputStage2
+	var outputStage2 outputAsm
+
+	emit := func(chunkInfo chunkInfo, output recordsOutput) {
+		r.Memory.releaseMasks(chunkInfo)
+		putMasks(chunkInfo.masks, chunkInfo.postProc)
+		r.sched.event(output.sequence, worker, true, func() { r.emit(out, output) })
+	}
 
 	for chunkInfo := range chunks {
 
+		if out.isStopped() {
+			r.Memory.releaseMasks(chunkInfo)
+			putMasks(chunkInfo.masks, chunkInfo.postProc)
+			continue // just drain remaining chunks
+		}
+		if scaler != nil {
+			scaler.scaleUp(len(chunks))
+		}
+		r.sched.event(chunkInfo.sequence, worker, false, func() {})
+
 		simdrecords := make([][]string, 0, simdlines)
+		positions := make([]recordPos, 0, simdlines)
 
-		rows := make([]uint64, rowsSize, rowsSize)
-		columns := make([]string, columnsSize, columnsSize)
-		inputStage2, outputStage2 := newInputStage2(), outputAsm{}
+		inputStage2, outputStage2 = newInputStage2(), outputAsm{}
 
 		skipRowsForPostProcessing := 0
 		if len(chunkInfo.splitRow) > 0 { // first append the row split between chunks
-			records, err := encodingCsv(chunkInfo.splitRow, r.Comma)
+			p := r.newCsvPositions(bytes.NewReader(chunkInfo.splitRow), chunkInfo.rowLine, chunkInfo.rowOffset)
+			p.rCsv.Comment, p.rCsv.LazyQuotes, p.rCsv.TrimLeadingSpace = r.Comment, r.LazyQuotes, r.TrimLeadingSpace
+			p.rCsv.FieldsPerRecord = -1 // enforced across chunks (see ensureFieldsPerRecord)
+			p.onError = r.OnError
+			records, rowPositions, err := p.readAll()
 			if err != nil {
-				out <- recordsOutput{-1, nil, err}
-				break
+				emit(chunkInfo, recordsOutput{chunkInfo.sequence, nil, nil, err, nil, checkpoint{}, nil, nil})
+				continue
+			}
+			if n := len(rowPositions); n > 0 {
+				// the terminator of the row follows in the chunk
+				rowPositions[n-1].raw = append(rowPositions[n-1].raw, terminatorAt(chunkInfo.chunk, int(chunkInfo.header))...)
 			}
 			simdrecords = append(simdrecords, records...)
+			positions = append(positions, rowPositions...)
 			skipRowsForPostProcessing = len(simdrecords)
 		}
 
@@ -337,8 +1203,9 @@ func (r *Reader) stage2Streaming(chunks chan chunkInfo, wg *sync.WaitGroup, fiel
 			chunkInfo.masks[skip*3+1] &= ^uint64((1 << shift) - 1)
 			chunkInfo.masks[skip*3+2] &= ^uint64((1 << shift) - 1)
 
-			skipTz := (chunkInfo.trailer >> 6) + 1
-			shiftTz := chunkInfo.trailer & 0x3f
+			padded := chunkInfo.trailer + uint64(-len(chunkInfo.chunk)&63) // from the end of the masks
+			skipTz := (padded >> 6) + 1
+			shiftTz := padded & 0x3f
 
 			chunkInfo.masks[len(chunkInfo.masks)-int(skipTz)*3+0] <<= shiftTz
 			chunkInfo.masks[len(chunkInfo.masks)-int(skipTz)*3+1] <<= shiftTz
@@ -347,56 +1214,100 @@ func (r *Reader) stage2Streaming(chunks chan chunkInfo, wg *sync.WaitGroup, fiel
 			chunkInfo.masks[len(chunkInfo.masks)-int(skipTz)*3+1] >>= shiftTz
 			chunkInfo.masks[len(chunkInfo.masks)-int(skipTz)*3+2] >>= shiftTz
 
-			var parsingError bool
-			rows, columns, parsingError = stage2ParseBufferExStreaming(chunkInfo.chunk[skip*0x40:len(chunkInfo.chunk)-int(chunkInfo.trailer)], chunkInfo.masks[skip*3:], '\n', &inputStage2, &outputStage2, &rows, &columns)
+			buf, masks := chunkInfo.chunk[skip*0x40:len(chunkInfo.chunk)-int(chunkInfo.trailer)], chunkInfo.masks[skip*3:]
+
+			parsingError := chunkInfo.fallback || r.Faults.fallback(chunkInfo.sequence)
+			if !parsingError {
+				rows, columns, parsingError = stage2ParseBufferExStreamingWith(r.stage2(), buf, masks, '\n', &inputStage2, &outputStage2, &rows, &columns)
+			}
 			if parsingError {
-				out <- fallback(bytes.NewReader(chunkInfo.chunk[skip*0x40 : len(chunkInfo.chunk)-int(chunkInfo.trailer)]))
-				break
+				emit(chunkInfo, r.stage2Fallback(chunkInfo, simdrecords[:skipRowsForPostProcessing], positions, fieldsPerRecord, fallback))
+				continue
 			}
 
+			fields := columns[:outputStage2.index/2]
+			if r.TrimLeadingSpace {
+				trimLeadingSpace(fields, buf)
+			}
+			if r.proj == nil {
+				fields = make([]string, outputStage2.index/2)
+				copy(fields, columns)
+			} // else the selected fields are copied out when emitted
 			for line := 0; line < outputStage2.line; line += 2 {
-				simdrecords = append(simdrecords, columns[rows[line]:rows[line]+rows[line+1]])
+				record := fields[rows[line] : rows[line]+rows[line+1] : rows[line]+rows[line+1]]
+				if r.sep != nil {
+					record = r.sep.fields(record)
+				}
+				simdrecords = append(simdrecords, record)
+			}
+			positions = recordPositions(buf, masks, int(shift), chunkInfo.line, chunkInfo.offset+int64(skip*0x40), comments, positions)
+			if r.esc != nil && bytes.IndexByte(buf, r.esc.escape) >= 0 {
+				recountLines(buf, int(shift), chunkInfo.line, chunkInfo.offset+int64(skip*0x40), positions[skipRowsForPostProcessing:])
+			}
+			if len(positions) != len(simdrecords) {
+				// cannot happen as long as recordPositions mirrors stage 2
+				positions = make([]recordPos, len(simdrecords))
 			}
 
-			columns = columns[:(outputStage2.index)/2]
-			rows = rows[:outputStage2.line]
-
-			if len(chunkInfo.postProc) > 0 {
+			proj, preserveCRLF := r.proj, r.PreserveCRLF
+			if r.RawQuotes {
+				delimiter := r.delimiter()
+				for i, record := range simdrecords[skipRowsForPostProcessing:] {
+					rawQuotes(record, positions[skipRowsForPostProcessing+i].raw, delimiter, r.TrimLeadingSpace)
+				}
+			} else if len(chunkInfo.postProc) > 0 {
 				pprs := getPostProcRows(chunkInfo.chunk, chunkInfo.postProc, simdrecords[skipRowsForPostProcessing:])
 				for _, ppr := range pprs {
+					r.stats.unescapedRows.Add(int64(ppr.end - ppr.start))
 					for r := ppr.start + skipRowsForPostProcessing; r < ppr.end+skipRowsForPostProcessing; r++ {
 						for c := range simdrecords[r] {
-							simdrecords[r][c] = strings.ReplaceAll(simdrecords[r][c], "\"\"", "\"")
-							simdrecords[r][c] = strings.ReplaceAll(simdrecords[r][c], "\r\n", "\n")
+							if !proj.keeps(c) {
+								continue
+							}
+							simdrecords[r][c] = unescapeQuoted(simdrecords[r][c], !preserveCRLF)
 						}
 					}
 				}
 			}
 
-			if errSimd := ensureFieldsPerRecord(&simdrecords, fieldsPerRecord); errSimd != nil {
-				out <- fallback(bytes.NewReader(chunkInfo.chunk[skip*0x40 : len(chunkInfo.chunk)-int(chunkInfo.trailer)]))
-				break
+			if r.esc != nil {
+				for _, record := range simdrecords[skipRowsForPostProcessing:] {
+					for c := range record {
+						if !proj.keeps(c) {
+							continue
+						}
+						record[c] = unescapeField(record[c], r.esc.escape)
+					}
+				}
 			}
-		}
 
-		if r.Comment != 0 {
-			filterOutComments(&simdrecords, byte(r.Comment))
-		}
-		if r.TrimLeadingSpace {
-			trimLeadingSpace(&simdrecords)
+			// comments must not count towards the number of fields (the row
+			// split from the previous chunk is never a comment)
+			if len(comments) > 0 {
+				filterOutComments(&simdrecords, &positions)
+			}
+
+			splitRecords := simdrecords[:skipRowsForPostProcessing] // ensureFieldsPerRecord clears simdrecords
+			if errSimd := ensureFieldsPerRecord(&simdrecords, positions, fieldsPerRecord); errSimd != nil {
+				emit(chunkInfo, r.stage2Fallback(chunkInfo, splitRecords, positions[:skipRowsForPostProcessing], fieldsPerRecord, fallback))
+				continue
+			}
 		}
 
 		if simdlines < len(simdrecords) {
 			simdlines = len(simdrecords) * 9 >> 3
 		}
-		if rowsSize < cap(rows) {
-			rowsSize = cap(rows) * 3 / 4
-		}
-		if columnsSize < cap(columns) {
-			columnsSize = cap(columns) * 3 / 4
+
+		var chunk []byte
+		if chunkInfo.sequence > 0 {
+			chunk = chunkInfo.chunk // the header may point into the first
 		}
+		emit(chunkInfo, recordsOutput{chunkInfo.sequence, simdrecords, positions, nil, nil, checkpoint{offset: chunkInfo.rowOffset, line: chunkInfo.rowLine}, chunk, nil})
 
-		out <- recordsOutput{chunkInfo.sequence, simdrecords, nil}
+		if scaler != nil && scaler.retire(len(chunks)) {
+			retired = true
+			return
+		}
 	}
 }
 
@@ -408,56 +1319,52 @@ func (r *Reader) stage2Streaming(chunks chan chunkInfo, wg *sync.WaitGroup, fiel
 func (r *Reader) ReadAll() ([][]string, error) {
 	r.Lock()
 	defer r.Unlock()
-	if !SupportedCPU() {
-		if r.rCsv == nil {
-			r.rCsv = csv.NewReader(r.r)
-			defer func() {
-				r.rCsv = nil
-			}()
-			r.rCsv.LazyQuotes = r.LazyQuotes
-			r.rCsv.TrimLeadingSpace = r.TrimLeadingSpace
-			r.rCsv.Comment = r.Comment
-			r.rCsv.Comma = r.Comma
-			r.rCsv.FieldsPerRecord = r.FieldsPerRecord
-			r.rCsv.ReuseRecord = r.ReuseRecord
-		}
-
-		return r.rCsv.ReadAll()
-	}
-
-	out := r.readAllStreaming()
+	return r.readAll(context.Background())
+}
 
+// readAll implements ReadAll, giving up on the fallback path once ctx is
+// done (see ReadAllContext)
+func (r *Reader) readAll(ctx context.Context) ([][]string, error) {
+	// start with any records that were peeked at or not yet returned by Read
 	records := make([][]string, 0)
-	hash := make(map[int][][]string)
-	sequence := 0
+	records = append(records, r.records[r.currrecord:]...)
+	r.records, r.positions, r.currrecord = nil, nil, 0
+	if r.readErr != nil && r.readErr != io.EOF {
+		return nil, r.readErr
+	}
 
-	for rcrds := range out {
-		if rcrds.err != nil {
-			// upon encountering an error ...
-			for range out {
-				// ... drain channel
+	// nothing remains once the input ended while peeking, or at the trailer
+	if r.readErr == nil && !r.simd() {
+		for ctx.Err() == nil {
+			record, _, err := r.csvRead()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
 			}
-			return nil, rcrds.err
+			records = append(records, record)
 		}
-
-		// check whether number is in sequence
-		if rcrds.sequence > sequence {
-			hash[rcrds.sequence] = rcrds.records
-			continue
+	} else if r.readErr == nil {
+		if r.slots == nil {
+			r.slots = r.readAllStreaming()
 		}
 
-		records = append(records, rcrds.records...)
-		sequence++
-
-		// check if we already received higher sequence numbers
 		for {
-			if val, ok := hash[sequence]; ok {
-				records = append(records, val...)
-				delete(hash, sequence)
-				sequence++
-			} else {
+			rcrds, ok := r.slots.get()
+			if !ok {
 				break
 			}
+			if rcrds.err != nil {
+				r.slots.stop()
+				return nil, rcrds.err
+			}
+			records = append(records, r.received(rcrds).records...)
+			if r.readErr != nil {
+				break
+			}
+		}
+		if r.readErr != nil && r.readErr != io.EOF {
+			return nil, r.readErr
 		}
 	}
 
@@ -468,103 +1375,533 @@ func (r *Reader) ReadAll() ([][]string, error) {
 	}
 }
 
+// Read reads one record (a slice of fields) from r.
+//
+// Read may be called from multiple goroutines concurrently, in which case
+// every record is returned to exactly one of the callers, in no particular
+// order among them. Since RecordNumber, LineNumber and RawRecord then refer to
+// the record most recently returned to any caller, concurrent callers
+// should use ReadRecord instead.
 func (r *Reader) Read() ([]string, error) {
 	r.Lock()
 	defer r.Unlock()
-	if !SupportedCPU() {
-		if r.rCsv == nil {
-			r.rCsv = csv.NewReader(r.r)
-			r.rCsv.LazyQuotes = r.LazyQuotes
-			r.rCsv.TrimLeadingSpace = r.TrimLeadingSpace
-			r.rCsv.Comment = r.Comment
-			r.rCsv.Comma = r.Comma
-			r.rCsv.FieldsPerRecord = r.FieldsPerRecord
-			r.rCsv.ReuseRecord = r.ReuseRecord
-		}
 
-		return r.rCsv.Read()
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+	if r.ReuseRecord {
+		r.lastRecord = append(r.lastRecord[:0], r.records[r.currrecord-1]...)
+		return r.lastRecord, nil
 	}
+	return r.records[r.currrecord-1], nil
+}
 
-	if !r.IsStreaming {
-		r.records = make([][]string, 0)
-		r.hash = make(map[int]recordsOutput)
-		r.currrecord = 0
-		r.sequence = 0
-		r.readchan = r.readAllStreaming()
+// A Record is a record along with its position in the input, as returned by
+// ReadRecord.
+type Record struct {
+	Fields []string
+	Number int      // ordinal of the record, starting at 1 (see RecordNumber)
+	Line   int      // line on which the record starts (see LineNumber)
+	Raw    []byte   // unmodified bytes of the record, if KeepRaw is set
+	Hash   []byte   // sum of the unmodified bytes, if RawHash is set
+	Nulls  NullMask // fields that are NULL, if NullLiterals is set
+}
+
+// ReadRecord reads one record along with its position. Like Read, it may be
+// called from multiple goroutines concurrently.
+func (r *Reader) ReadRecord() (Record, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.next(); err != nil {
+		return Record{}, err
 	}
+	return Record{r.records[r.currrecord-1], r.recordNumber, r.lastPos.line, r.rawRecord(), r.lastPos.sum, r.lastPos.nulls}, nil
+}
 
+// next advances to the next record, which becomes the last one of the
+// records read so far
+func (r *Reader) next() error {
 	if r.currrecord >= len(r.records) {
-		err := r.nextblock()
+		if r.readErr != nil {
+			return r.readErr
+		}
+		var err error
+		if !r.simd() {
+			var record []string
+			var pos recordPos
+			if record, pos, err = r.csvRead(); err == nil {
+				r.records, r.positions, r.currrecord = [][]string{record}, []recordPos{pos}, 0
+			}
+		} else {
+			err = r.nextblock()
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
-	ret := r.records[r.currrecord]
+	r.recordNumber++
+	r.lastPos = r.positions[r.currrecord]
 	r.currrecord++
-	return ret, nil
+	return nil
+}
+
+// Peek returns the next n records without advancing the reader: subsequent
+// calls to Read (or ReadAll) return the same records again. If fewer than n
+// records remain, Peek returns these along with the error that ended the
+// input, which is io.EOF at the end of the input.
+func (r *Reader) Peek(n int) ([][]string, error) {
+	r.Lock()
+	defer r.Unlock()
 
+	r.fill(n)
+	peeked := r.records[r.currrecord:]
+	if len(peeked) >= n {
+		return append([][]string(nil), peeked[:n]...), nil
+	}
+	return append([][]string(nil), peeked...), r.readErr
 }
 
-func (r *Reader) clearchan() {
-	for _ = range r.readchan {
-		//...
+// fill reads ahead until at least n records are pending or the input ends
+func (r *Reader) fill(n int) {
+	owned := false // whether the records were copied already
+	for len(r.records)-r.currrecord < n && r.readErr == nil {
+		pending := r.records[r.currrecord:]
+		pendingPositions := r.positions[r.currrecord:]
+		if !owned {
+			pending = pending[:len(pending):len(pending)] // make sure to copy on append
+			pendingPositions = pendingPositions[:len(pendingPositions):len(pendingPositions)]
+		}
+
+		var err error
+		if !r.simd() {
+			var record []string
+			var pos recordPos
+			if record, pos, err = r.csvRead(); err == nil {
+				r.records = append(pending, record)
+				r.positions = append(pendingPositions, pos)
+			}
+		} else if err = r.nextblock(); err == nil {
+			r.records = append(pending, r.records...)
+			r.positions = append(pendingPositions, r.positions...)
+		}
+		if err != nil {
+			r.readErr = err
+			r.records, r.positions = pending, pendingPositions
+		}
+		r.currrecord, owned = 0, err == nil
 	}
 }
 
-func (r *Reader) nextblock() error {
+// ColumnIndex returns the index of the column with the given name in the
+// header, which is the first record of the input, reading ahead if it has
+// not been read yet. The name is normalized by NormalizeHeader likewise.
+func (r *Reader) ColumnIndex(name string) (int, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.header == nil {
+		r.fill(1)
+	}
+	name = r.NormalizeHeader.apply(name)
+	for i, column := range r.header {
+		if column == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// RecordNumber returns the ordinal of the record most recently returned by
+// Read, starting at 1, or 0 if no record has been read yet.
+func (r *Reader) RecordNumber() int {
+	r.Lock()
+	defer r.Unlock()
+	return r.recordNumber
+}
+
+// LineNumber returns the line of the input on which the record most recently
+// returned by Read starts, starting at 1. Newlines within quoted fields count
+// towards the line number, as do empty lines and comments.
+func (r *Reader) LineNumber() int {
+	r.Lock()
+	defer r.Unlock()
+	return r.lastPos.line
+}
 
+// RawRecord returns the unmodified bytes of the record most recently returned
+// by Read, without its terminating newline. It returns nil unless KeepRaw is
+// set. The returned slice must not be modified.
+func (r *Reader) RawRecord() []byte {
+	r.Lock()
+	defer r.Unlock()
+	return r.rawRecord()
+}
+
+// InputOffset returns the input stream byte offset of the current reader
+// position, like encoding/csv.Reader.InputOffset. The offset gives the
+// location of the end of the record most recently returned by Read (including
+// its terminator) and the beginning of the next one, from where parsing can
+// resume later on.
+func (r *Reader) InputOffset() int64 {
+	r.Lock()
+	defer r.Unlock()
+	if r.lastPos.raw == nil {
+		return r.startOffset
+	}
+	return r.lastPos.offset + int64(len(r.lastPos.raw))
+}
+
+// RawRecordHash returns the sum of the unmodified bytes of the record most
+// recently returned by Read, as hashed by RawHash. It returns nil unless
+// RawHash is set. The returned slice must not be modified.
+func (r *Reader) RawRecordHash() []byte {
+	r.Lock()
+	defer r.Unlock()
+	return r.lastPos.sum
+}
+
+// rawRecord returns the raw bytes of the last record, if KeepRaw is set
+func (r *Reader) rawRecord() []byte {
+	if !r.KeepRaw {
+		return nil
+	}
+	return trimTerminator(r.lastPos.raw)
+}
+
+// csvReader returns the encoding/csv Reader (along with the positions of its
+// records) that is used when the CPU is not supported
+func (r *Reader) csvReader() *csvPositions {
+	if r.rCsv == nil {
+		r.fellBack()
+		r.rCsv = r.newCsvPositions(r.input(), r.lineOffset+1, r.startOffset)
+		r.rCsv.onError = r.OnError
+		r.rCsv.rCsv.LazyQuotes = r.LazyQuotes
+		r.rCsv.rCsv.TrimLeadingSpace = r.TrimLeadingSpace
+		r.rCsv.rCsv.Comment = r.Comment
+		r.rCsv.rCsv.FieldsPerRecord = r.FieldsPerRecord
+	}
+	return r.rCsv
+}
+
+// csvRead reads the next record from encoding/csv along with its position
+func (r *Reader) csvRead() ([]string, recordPos, error) {
+	if r.rCsv == nil {
+		if !r.validCommaString() || !r.validEscape() || !r.validCommentPrefix() {
+			return nil, recordPos{}, errInvalidDelim
+		}
+		if r.SkipFooter > 0 && r.filters() {
+			return nil, recordPos{}, errFooterWhere
+		}
+		if r.RawQuotes && r.Escape != 0 {
+			return nil, recordPos{}, errRawQuotesEscape
+		}
+		if err := r.validSample(); err != nil {
+			return nil, recordPos{}, err
+		}
+		r.transcode()
+		r.bareCRs()
+		if err := r.skipPreamble(); err != nil {
+			return nil, recordPos{}, err
+		}
+	}
 	for {
-		rcrds, ok := r.hash[r.sequence]
-		if ok {
-			r.sequence++
-			if len(rcrds.records) == 0 {
+		record, pos, err := r.csvReadSampled()
+		if err != nil {
+			return nil, recordPos{}, err
+		}
+		if err = r.checkLimits([][]string{record}, []recordPos{pos}); err != nil {
+			return nil, recordPos{}, err
+		}
+		if r.projects() {
+			if r.proj == nil {
+				var header []string
+				if r.header == nil && r.startOffset == r.dataOffset {
+					header = record
+				}
+				r.proj = r.newProjection(header)
+			}
+			record = r.projected(record)
+		}
+		if r.header == nil && r.startOffset == r.dataOffset {
+			r.header = r.normalizeHeader(record)
+			if r.NormalizeHeader != 0 {
+				record = append([]string(nil), r.header...)
+			}
+		}
+		if r.normalizes() {
+			if r.norm == nil {
+				if r.norm = r.newNormalizer(record); r.norm.header {
+					return record, pos, nil
+				}
+			}
+			r.norm.normalize([][]string{record})
+		}
+		if r.FieldTransform != nil {
+			if err = r.transformFields([][]string{record}, []recordPos{pos}); err != nil {
+				return nil, recordPos{}, err
+			}
+		}
+		if r.maps != nil {
+			records, positions := [][]string{record}, []recordPos{pos}
+			if err = r.mapRecords(&records, &positions); err != nil {
+				return nil, recordPos{}, err
+			} else if len(records) == 0 {
 				continue
 			}
-			if rcrds.err != nil {
-				r.clearchan()
-				return rcrds.err
+			record = records[0]
+		}
+		if r.NullLiterals != nil {
+			positions := []recordPos{pos}
+			markNulls(r.NullLiterals, [][]string{record}, positions)
+			pos = positions[0]
+		}
+		if r.atSentinel(record, r.consumed) {
+			return nil, recordPos{}, r.readErr
+		}
+		if max := r.queryLimit(); max >= 0 && r.consumed >= max {
+			return nil, recordPos{}, io.EOF
+		}
+		if dup, err := r.duplicate(record, pos); err != nil {
+			return nil, recordPos{}, err
+		} else if dup {
+			continue
+		}
+		if r.RawHash != nil {
+			positions := []recordPos{pos}
+			hashRaw(r.RawHash(), positions)
+			pos = positions[0]
+		}
+		r.consumed++
+		r.Manifest.add([][]string{record})
+		r.stats.records.Add(1)
+		return record, pos, nil
+	}
+}
+
+// emit hands a block of records to the consumer, after applying the
+// predicates, the projection, the normalizations, FieldTransform and Map
+func (r *Reader) emit(out *outputSlots, output recordsOutput) {
+	if r.sampling != nil {
+		r.sample(out, &output)
+	}
+	if output.err == nil {
+		if err := r.checkLimits(output.records, output.positions); err != nil {
+			output.records, output.positions, output.err = nil, nil, err
+		}
+	}
+	first := output.sequence == 0 && r.startOffset == r.dataOffset
+	if (r.projects() || r.filters()) && output.err == nil {
+		// only a single block is emitted when the columns and predicates
+		// were not resolved up front
+		var header []string
+		if first && len(output.records) > 0 {
+			header = output.records[0]
+		}
+		if r.projects() && r.proj == nil {
+			r.proj = r.newProjection(header)
+		}
+		if r.filters() && r.where == nil {
+			r.where = r.newPredicates(header)
+		}
+	}
+	if r.filters() && output.err == nil {
+		r.where.filter(&output.records, &output.positions, first)
+	}
+	if r.projects() && output.err == nil {
+		r.proj.project(output.records)
+	}
+	if output.sequence == 0 && r.startOffset == r.dataOffset && output.err == nil && len(output.records) > 0 {
+		// only a single block comes with sequence 0, unless the header was
+		// resolved up front
+		if r.header == nil {
+			r.header = r.normalizeHeader(output.records[0])
+		}
+		if r.NormalizeHeader != 0 {
+			output.records[0] = append([]string(nil), r.header...)
+		}
+	}
+	if r.normalizes() && output.err == nil {
+		records := output.records
+		if r.norm == nil {
+			// only a single block is emitted when the header was not resolved up front
+			var header []string
+			if len(records) > 0 {
+				header = records[0]
 			}
-			r.currrecord = 0
-			r.records = rcrds.records
-			return nil
+			r.norm = r.newNormalizer(header)
+		}
+		if output.sequence == 0 && r.startOffset == r.dataOffset && r.norm.header && len(records) > 0 {
+			records = records[1:]
 		}
-		break
+		r.norm.normalize(records)
 	}
-	for rcrds := range r.readchan {
-		if rcrds.sequence > r.sequence {
-			r.hash[rcrds.sequence] = rcrds
-			continue
+	if r.FieldTransform != nil && output.err == nil {
+		if err := r.transformFields(output.records, output.positions); err != nil {
+			output.records, output.positions, output.err = nil, nil, err
 		}
-		if rcrds.sequence == r.sequence {
-			delete(r.hash, rcrds.sequence)
-			r.sequence++
-			if rcrds.err != nil {
-				r.clearchan()
-				return rcrds.err
+	}
+	if r.maps != nil && output.err == nil {
+		if err := r.mapRecords(&output.records, &output.positions); err != nil {
+			output.records, output.positions, output.err = nil, nil, err
+		}
+	}
+	if r.NullLiterals != nil && output.err == nil {
+		markNulls(r.NullLiterals, output.records, output.positions)
+	}
+	if r.RawHash != nil && output.err == nil {
+		hashRaw(r.RawHash(), output.positions)
+	}
+	if r.dedups() && output.err == nil {
+		if r.dedup == nil {
+			// only a single block is emitted when the keys were not resolved up front
+			var header []string
+			if first && len(output.records) > 0 {
+				header = output.records[0]
 			}
-			if len(rcrds.records) == 0 {
-				continue
+			r.dedup = r.newDedup(header)
+		}
+		output.keys = r.dedup.keys(output.records)
+	}
+	if r.decode != nil && output.err == nil {
+		if decoded, err := r.decode(output.records, output.positions); err != nil {
+			output.records, output.positions, output.err = nil, nil, err
+		} else {
+			output.decoded = decoded
+		}
+	}
+	r.Memory.acquireRecords(output)
+	r.stats.records.Add(int64(len(output.records)))
+	out.put(output)
+}
+
+// transformFields replaces all fields of records by the result of FieldTransform
+func (r *Reader) transformFields(records [][]string, positions []recordPos) error {
+	for i, record := range records {
+		for col, field := range record {
+			s, err := r.FieldTransform(col, stringBytes(field))
+			if err != nil {
+				return fmt.Errorf("record on line %d: field %d: %w", positions[i].line, col+1, err)
 			}
-			r.records = rcrds.records
-			r.currrecord = 0
-			return nil
+			record[col] = s
 		}
 	}
-	return io.EOF
+	return nil
+}
+
+// stringBytes returns the bytes of s without copying
+func stringBytes(s string) []byte {
+	return *(*[]byte)(unsafe.Pointer(&struct {
+		string
+		int
+	}{s, len(s)}))
 }
 
-func filterOutComments(records *[][]string, comment byte) {
+// input returns the source to read from, teeing into InputHash when set.
+func (r *Reader) input() io.Reader {
+	in := r.r
+	if r.Faults.reads() {
+		in = &faultReader{in: in, faults: r.Faults, offset: r.startOffset}
+	}
+	in = &countedReader{in: in, r: r}
+	if r.InputHash != nil {
+		return io.TeeReader(in, r.InputHash)
+	}
+	return in
+}
+
+// Digest returns the checksum of all input consumed so far as computed by
+// InputHash, or nil if InputHash is not set. The digest covers the complete
+// input once ReadAll has returned or Read has returned io.EOF.
+func (r *Reader) Digest() []byte {
+	if r.InputHash == nil {
+		return nil
+	}
+	return r.InputHash.Sum(nil)
+}
 
-	// iterate in reverse so as to prevent starting over when removing element
-	for i := len(*records) - 1; i >= 0; i-- {
-		record := (*records)[i]
-		if len(record) > 0 && len(record[0]) > 0 && record[0][0] == comment {
-			*records = append((*records)[:i], (*records)[i+1:len(*records)]...)
+func (r *Reader) nextblock() error {
+
+	if r.slots == nil {
+		r.slots = r.readAllStreaming()
+	}
+
+	for {
+		rcrds, ok := r.slots.get()
+		if !ok {
+			return io.EOF
+		}
+		if rcrds.err != nil {
+			r.slots.stop()
+			return rcrds.err
+		}
+		if rcrds = r.received(rcrds); len(rcrds.records) == 0 {
+			if r.readErr != nil {
+				return r.readErr
+			}
+			continue
+		}
+		r.records, r.positions, r.chunk = rcrds.records, rcrds.positions, rcrds.chunk
+		r.currrecord = 0
+		return nil
+	}
+}
+
+// stop abandons parsing the remainder of the input
+func (r *Reader) stop() {
+	r.Lock()
+	defer r.Unlock()
+	if r.slots != nil {
+		r.slots.stop()
+	}
+}
+
+// csvBlockSize is the number of records per block when reading blocks from
+// encoding/csv
+const csvBlockSize = 1024
+
+// readBlock returns the records not returned yet a block at a time, or the
+// error that ended the input (io.EOF at the end of the input)
+func (r *Reader) readBlock() ([][]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.currrecord >= len(r.records) {
+		if r.readErr != nil {
+			return nil, r.readErr
+		}
+		if !r.simd() {
+			block, _, err := r.readCsvBlock()
+			return block, err
+		}
+		if err := r.nextblock(); err != nil {
+			return nil, err
+		}
+	}
+	block := r.records[r.currrecord:]
+	r.currrecord = len(r.records)
+	return block, nil
+}
+
+// readCsvBlock reads a block of records from encoding/csv along with their
+// positions, keeping any error that ends the block for the next call
+func (r *Reader) readCsvBlock() ([][]string, []recordPos, error) {
+	block, positions := make([][]string, 0, csvBlockSize), make([]recordPos, 0, csvBlockSize)
+	for len(block) < csvBlockSize {
+		record, pos, err := r.csvRead()
+		if err != nil {
+			if len(block) == 0 {
+				return nil, nil, err
+			}
+			r.readErr = err
+			break
 		}
+		block, positions = append(block, record), append(positions, pos)
 	}
+	return block, positions, nil
 }
 
-func ensureFieldsPerRecord(records *[][]string, fieldsPerRecord *int64) error {
+func ensureFieldsPerRecord(records *[][]string, positions []recordPos, fieldsPerRecord *int64) error {
 
 	if atomic.LoadInt64(fieldsPerRecord) == 0 {
 		if len(*records) > 0 {
@@ -577,22 +1914,87 @@ func ensureFieldsPerRecord(records *[][]string, fieldsPerRecord *int64) error {
 		for i, record := range *records {
 			if int64(len(record)) != fpr {
 				*records = nil
-				return errors.New(fmt.Sprintf("record on line %d: wrong number of fields", i+1))
+				return fieldCountError(positions, i)
 			}
 		}
 	}
 	return nil
 }
 
-func trimLeadingSpace(records *[][]string) {
+// fieldCountError returns the error for the i-th record having the wrong
+// number of fields, as encoding/csv reports it
+func fieldCountError(positions []recordPos, i int) error {
+	line := i + 1
+	if i < len(positions) {
+		line = positions[i].line
+	}
+	return &csv.ParseError{StartLine: line, Line: line, Column: 1, Err: csv.ErrFieldCount}
+}
 
-	for i := 0; i < len(*records); i++ {
-		for j := range (*records)[i] {
-			(*records)[i][j] = strings.TrimLeftFunc((*records)[i][j], func(r rune) bool {
-				return unicode.IsSpace(r)
-			})
+// asciiSpace holds the ASCII characters for which unicode.IsSpace is true
+var asciiSpace = [256]bool{'\t': true, '\n': true, '\v': true, '\f': true, '\r': true, ' ': true}
+
+// trimLeadingSpace strips leading white space off fields, as parsed by
+// stage 2 from buf prior to building the records, by advancing their pointer
+// words (see columnWords). Quoted fields, which follow their opening quote in
+// buf, are kept as they are, like encoding/csv does.
+func trimLeadingSpace(fields []string, buf []byte) {
+	if len(buf) == 0 {
+		return
+	}
+	base := uintptr(unsafe.Pointer(&buf[0]))
+	words := columnWords(fields)
+	for i, field := range fields {
+		if at := words[2*i] - base; words[2*i] > base && at <= uintptr(len(buf)) && buf[at-1] == '"' {
+			continue
+		}
+		n := 0
+		for n < len(field) && n < shortSpaceRun && asciiSpace[field[n]] {
+			n++
 		}
+		if n == shortSpaceRun || n < len(field) && field[n] >= utf8.RuneSelf {
+			n = len(field) - len(trimLeftSpace(field))
+		}
+		if n == 0 {
+			continue
+		}
+		if words[2*i+1] -= uintptr(n); words[2*i+1] == 0 {
+			// keep pointing within the row, which post-processing finds by
+			// its first field (see getPostProcRows), yet not beyond the chunk
+			n--
+		}
+		words[2*i] += uintptr(n)
+	}
+}
+
+// shortSpaceRun is the length of leading white space beyond which the
+// vectorized skipSpace kernel pays off its call
+const shortSpaceRun = 16
+
+// trimLeftSpace strips leading white space, resorting to the vectorized
+// skipSpace kernel for long runs and to unicode.IsSpace for non-ASCII runes
+func trimLeftSpace(s string) string {
+	i := 0
+	for i < len(s) && i < shortSpaceRun && asciiSpace[s[i]] {
+		i++
+	}
+	if i == shortSpaceRun {
+		i += skipSpace(s[i:])
+	}
+	s = s[i:]
+	if len(s) > 0 && s[0] >= utf8.RuneSelf {
+		return strings.TrimLeftFunc(s, unicode.IsSpace)
+	}
+	return s
+}
+
+// skipSpaceGeneric is skipSpace for CPUs without a vectorized kernel
+func skipSpaceGeneric(s string) int {
+	i := 0
+	for i < len(s) && asciiSpace[s[i]] {
+		i++
 	}
+	return i
 }
 
 func allocMasks(buf []byte) []uint64 {
@@ -1,5 +1,5 @@
-//go:build !amd64 || appengine || !gc || noasm
-// +build !amd64 appengine !gc noasm
+//go:build (!amd64 && !arm64) || appengine || !gc || noasm
+// +build !amd64,!arm64 appengine !gc noasm
 
 /*
  * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
@@ -19,15 +19,18 @@
 
 package simdcsv
 
+// simdKernels lists the kernels running the SIMD stages, in order of preference
+var simdKernels = []*kernel{swarKernel}
+
 // SupportedCPU will return whether the CPU is supported.
 func SupportedCPU() bool {
 	return false
 }
 
-func stage1PreprocessBufferEx(buf []byte, separatorChar, quoted uint64, masks *[]uint64, postProc *[]uint64) ([]uint64, []uint64, uint64) {
-	return nil, nil, 0
+func stage1_preprocess_buffer(buf []byte, separatorChar uint64, input1 *stage1Input, output1 *stage1Output, postProc *[]uint64, offset uint64, masks []uint64, masksOffset uint64) (processed, masksWritten uint64) {
+	return stage1PreprocessBufferSWAR(buf, separatorChar, input1, output1, postProc, offset, masks, masksOffset)
 }
 
-func stage2ParseBufferExStreaming(buf []byte, masks []uint64, delimiterChar uint64, inputStage2 *inputStage2, outputStage2 *outputAsm, rows *[]uint64, columns *[]string) ([]uint64, []string, bool) {
-	return nil, nil, false
+func _stage2_parse_masks(buf []byte, masks []uint64, lastCharIsDelimiter uint64, rows []uint64, columns []string, input2 *inputStage2, offset uint64, output2 *outputAsm) (processed, masksRead uint64) {
+	return stage2ParseMasksGeneric(buf, masks, lastCharIsDelimiter, rows, columns, input2, offset, output2)
 }
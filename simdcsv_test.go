@@ -18,8 +18,10 @@ package simdcsv
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/csv"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -28,6 +30,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"unicode/utf8"
 )
@@ -414,6 +417,7 @@ x,,,
 	for _, tt := range tests {
 		t.Run(tt.Name, func(t *testing.T) {
 			r := NewReader(strings.NewReader(tt.Input))
+			r.FallbackThreshold = -1
 
 			if tt.Comma != 0 {
 				r.Comma = tt.Comma
@@ -557,6 +561,7 @@ func compareAgainstEncodingCsv(t *testing.T, test []byte, sep rune) {
 	}
 	r := NewReader(bytes.NewReader(test))
 	r.Comma = sep
+	r.FallbackThreshold = -1
 	simdrecords, err := r.ReadAll()
 	if err != nil {
 		log.Fatalf("%v", err)
@@ -568,17 +573,16 @@ func compareAgainstEncodingCsv(t *testing.T, test []byte, sep rune) {
 }
 
 // filter out commented rows before returning to client
-func testIgnoreCommentedLines(t *testing.T, csvData []byte) {
-
-	const comment = '#'
+func testIgnoreCommentedLines(t *testing.T, csvData []byte, comment rune) {
 
 	simdr := NewReader(bytes.NewReader(csvData))
+	simdr.Comment = comment
 	simdr.FieldsPerRecord = -1
+	simdr.FallbackThreshold = -1
 	simdrecords, err := simdr.ReadAll()
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
-	filterOutComments(&simdrecords, comment)
 
 	r := csv.NewReader(bytes.NewReader(csvData))
 	r.Comment = comment
@@ -594,16 +598,28 @@ func testIgnoreCommentedLines(t *testing.T, csvData []byte) {
 
 func TestIgnoreCommentedLines(t *testing.T) {
 	t.Run("simple", func(t *testing.T) {
-		testIgnoreCommentedLines(t, []byte("a,b,c\n#hello,good,bye\nd,e,f\n\n"))
+		testIgnoreCommentedLines(t, []byte("a,b,c\n#hello,good,bye\nd,e,f\n\n"), '#')
 	})
 	t.Run("first", func(t *testing.T) {
-		testIgnoreCommentedLines(t, []byte("#a,b,c\nhello,good,bye\nd,e,f\n\n"))
+		testIgnoreCommentedLines(t, []byte("#a,b,c\nhello,good,bye\nd,e,f\n\n"), '#')
 	})
 	t.Run("last", func(t *testing.T) {
-		testIgnoreCommentedLines(t, []byte("a,b,c\nd,e,f\n#IGNORED\n"))
+		testIgnoreCommentedLines(t, []byte("a,b,c\nd,e,f\n#IGNORED\n"), '#')
 	})
 	t.Run("multiple", func(t *testing.T) {
-		testIgnoreCommentedLines(t, []byte("a,b,c\n#A,B,C\nd,e,f\n#g,h,i\n"))
+		testIgnoreCommentedLines(t, []byte("a,b,c\n#A,B,C\nd,e,f\n#g,h,i\n"), '#')
+	})
+	t.Run("quoted", func(t *testing.T) {
+		testIgnoreCommentedLines(t, []byte("a,b\n\"#not a comment\",x\n#a comment\n\"y\n#still not\",z\n"), '#')
+	})
+	t.Run("latin1", func(t *testing.T) {
+		testIgnoreCommentedLines(t, []byte("a,b\n\u00a7ignored\nc,d\n"), '\u00a7')
+	})
+	t.Run("quotes", func(t *testing.T) {
+		testIgnoreCommentedLines(t, []byte("x,y\n#,\"\na,b\n#\"\nc,d\n"), '#')
+	})
+	t.Run("unbalanced", func(t *testing.T) {
+		testIgnoreCommentedLines(t, []byte("#,\"\n,\n#\""), '#')
 	})
 }
 
@@ -611,14 +627,18 @@ func testFieldsPerRecord(t *testing.T, csvData []byte, fieldsPerRecord int64) {
 
 	simdr := NewReader(bytes.NewReader(csvData))
 	simdr.FieldsPerRecord = int(fieldsPerRecord)
+	simdr.FallbackThreshold = -1
 	simdrecords, errSimd := simdr.ReadAll()
 
 	r := csv.NewReader(bytes.NewReader(csvData))
 	r.FieldsPerRecord = int(fieldsPerRecord)
 	records, err := r.ReadAll()
 
-	// are both returning errors, then this test is a pass
+	// are both returning errors, then these must match
 	if errSimd != nil && err != nil {
+		if !reflect.DeepEqual(errSimd, err) {
+			t.Errorf("TestFieldsPerRecord: got: %v want: %v", errSimd, err)
+		}
 		return
 	}
 
@@ -644,16 +664,48 @@ func TestEnsureFieldsPerRecord(t *testing.T) {
 	t.Run("auto-fail", func(t *testing.T) {
 		testFieldsPerRecord(t, []byte("a,b,c\nd,e\ng,h\n"), 0)
 	})
+
+	// a later chunk of which all records have a different number of fields,
+	// starting with the first row that starts in the second chunk
+	var buf bytes.Buffer
+	line := 1
+	for ; buf.Len() < defaultChunkSize; line++ {
+		fmt.Fprintf(&buf, "%d,b,c\n", line)
+	}
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&buf, "%d,e,f,g\n", line+i)
+	}
+	for _, fieldsPerRecord := range []int{0, 3} {
+		r := NewReader(bytes.NewReader(buf.Bytes()))
+		r.FieldsPerRecord = fieldsPerRecord
+		_, err := r.ReadAll()
+		var parseErr *csv.ParseError
+		if !errors.As(err, &parseErr) || !errors.Is(err, csv.ErrFieldCount) {
+			t.Errorf("TestEnsureFieldsPerRecord(%d): got: %v want: %v", fieldsPerRecord, err, csv.ErrFieldCount)
+		}
+	}
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	_, err := r.ReadAll()
+	if want := (&csv.ParseError{StartLine: line, Line: line, Column: 1, Err: csv.ErrFieldCount}); !reflect.DeepEqual(err, want) {
+		t.Errorf("TestEnsureFieldsPerRecord: got: %v want: %v", err, want)
+	}
+	r = NewReader(bytes.NewReader(buf.Bytes()))
+	r.OnError = func(*RecordError) Action { return Skip }
+	records, err := r.ReadAll()
+	if err != nil || len(records) != line-1 {
+		t.Errorf("TestEnsureFieldsPerRecord(Skip): got: %d records, %v want: %d", len(records), err, line-1)
+	}
 }
 
 func testTrimLeadingSpace(t *testing.T, csvData []byte) {
 
 	simdr := NewReader(bytes.NewReader(csvData))
+	simdr.FallbackThreshold = -1
+	simdr.TrimLeadingSpace = true
 	simdrecords, err := simdr.ReadAll()
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
-	trimLeadingSpace(&simdrecords)
 
 	r := csv.NewReader(bytes.NewReader(csvData))
 	r.TrimLeadingSpace = true
@@ -677,6 +729,71 @@ func TestTrimLeadingSpace(t *testing.T) {
 	t.Run("unicode", func(t *testing.T) {
 		testTrimLeadingSpace(t, []byte("j,"+string('\u00A0')+"k,l\n"))
 	})
+	t.Run("long", func(t *testing.T) {
+		testTrimLeadingSpace(t, []byte("m,"+strings.Repeat(" ", 15)+"n,"+strings.Repeat(" ", 16)+"o\n"+strings.Repeat("\t", 40)+"p,"+strings.Repeat(" ", 17)+",q\n"))
+	})
+	t.Run("quoted", func(t *testing.T) {
+		testTrimLeadingSpace(t, []byte("\" x\", y\n\"\nb\",\"\tc\"\n\" d\"\"e\", g\r\n"))
+	})
+	t.Run("blank", func(t *testing.T) {
+		testTrimLeadingSpace(t, []byte("a,b\n ,\"\"\"\"\n  ,\"c\r\nd\"\n"))
+	})
+}
+
+func TestSkipSpace(t *testing.T) {
+	for _, spaces := range []string{"", " ", "\t", " \t\n\v\f\r", strings.Repeat(" ", 31), strings.Repeat("\t ", 16), strings.Repeat(" ", 100)} {
+		for _, rest := range []string{"", "a", "\x08", "\x0e", "\u00a0x", "!" + strings.Repeat(" ", 40)} {
+			s := spaces + rest
+			if got := skipSpace(s); got != len(spaces) {
+				t.Errorf("TestSkipSpace(%q): got: %d want: %d", s, got, len(spaces))
+			}
+		}
+	}
+}
+
+func TestUnescapeQuotes(t *testing.T) {
+	for _, s := range []string{"", "a", `""`, `""""`, `""""""`, `a""b`, `""a""`, `a""b""c""`, strings.Repeat(`x""`, 100), "no quotes at all"} {
+		if got, want := unescapeQuoted(s, false), strings.ReplaceAll(s, `""`, `"`); got != want {
+			t.Errorf("TestUnescapeQuotes(%q): got: %q want: %q", s, got, want)
+		}
+	}
+}
+
+func TestNormalizeCRLF(t *testing.T) {
+	for _, s := range []string{"", "a", "\r", "\n", "\r\n", "\r\r\n", "a\r\nb", "a\rb\r\n", "\r\n\r\n\r\n", strings.Repeat("x\r\n", 100), "no returns at all"} {
+		if got, want := unescapeQuoted(s, true), strings.ReplaceAll(s, "\r\n", "\n"); got != want {
+			t.Errorf("TestNormalizeCRLF(%q): got: %q want: %q", s, got, want)
+		}
+	}
+}
+
+func TestUnescapeQuoted(t *testing.T) {
+	for _, s := range []string{"\"\"\r\n", "\r\"\"\n", "a\"\"\r\r\n\"\"\"", "\r\n\"\"" + strings.Repeat("x", 40) + "\"\"\r\n", strings.Repeat("\"\"x\r\n", 50), strings.Repeat("y", 33) + "\r"} {
+		for _, crlf := range []bool{false, true} {
+			want := strings.ReplaceAll(s, `""`, `"`)
+			if crlf {
+				want = strings.ReplaceAll(want, "\r\n", "\n")
+			}
+			if got := unescapeQuoted(s, crlf); got != want {
+				t.Errorf("TestUnescapeQuoted(%q, %v): got: %q want: %q", s, crlf, got, want)
+			}
+		}
+	}
+}
+
+func TestIndexQuoteCR(t *testing.T) {
+	for _, prefix := range []int{0, 1, 31, 32, 33, 64, 100} {
+		for _, rest := range []string{"", "\"", "\r", "a\"\r", "\rb\"", "\n,"} {
+			s := strings.Repeat("x", prefix) + rest
+			want := strings.IndexAny(s, "\"\r")
+			if got := indexQuoteCR(s); got != want {
+				t.Errorf("TestIndexQuoteCR(%q): got: %d want: %d", s, got, want)
+			}
+			if got := indexQuoteCRGeneric(s); got != want {
+				t.Errorf("TestIndexQuoteCR(%q): got: %d want: %d (generic)", s, got, want)
+			}
+		}
+	}
 }
 
 func TestExample(t *testing.T) {
@@ -831,6 +948,502 @@ Ken,Thompson,ken
 	fmt.Println(out.String())
 }
 
+func TestInputHash(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/parking-citations-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	want := sha256.Sum256(buf)
+
+	for _, lazy := range []bool{false, true} {
+		r := NewReader(bytes.NewReader(buf))
+		r.LazyQuotes = lazy
+		r.InputHash = sha256.New()
+		if _, err := r.ReadAll(); err != nil {
+			t.Fatalf("%v", err)
+		}
+		if got := r.Digest(); !bytes.Equal(got, want[:]) {
+			t.Errorf("TestInputHash(lazy=%v): got: %x want: %x", lazy, got, want)
+		}
+	}
+
+	if NewReader(bytes.NewReader(buf)).Digest() != nil {
+		t.Errorf("TestInputHash: expected nil digest without InputHash")
+	}
+}
+
+func TestNewReaderSize(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/worldcitiespop-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	records, err := encodingCsv(buf, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, size := range []int{0, 4096, 1 << 20} {
+		simdrecords, err := NewReaderSize(bytes.NewReader(buf), size).ReadAll()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if !reflect.DeepEqual(simdrecords, records) {
+			t.Errorf("TestNewReaderSize(%d): got: %v want: %v", size, len(simdrecords), len(records))
+		}
+	}
+}
+
+func TestChunkSize(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/worldcitiespop-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	records, err := encodingCsv(buf, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, size := range []int{0, 1000, 4096, 1 << 20} {
+		for _, inMemory := range []bool{false, true} {
+			r := NewReader(bytes.NewReader(buf))
+			if inMemory {
+				r = newBytesReader(buf)
+			}
+			r.ChunkSize = size
+			simdrecords, err := r.ReadAll()
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if !reflect.DeepEqual(simdrecords, records) {
+				t.Errorf("TestChunkSize(%d, %v): got: %v want: %v", size, inMemory, len(simdrecords), len(records))
+			}
+		}
+	}
+
+	// rows spanning several chunks
+	var long bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&long, "%d,\"%s,%s\",%s\n", i, strings.Repeat("x", i%300), strings.Repeat("y", i%150), strings.Repeat("z", i%200))
+	}
+	want, err := encodingCsv(long.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, size := range []int{64, 128, 192} {
+		r := NewReader(bytes.NewReader(long.Bytes()))
+		r.ChunkSize = size
+		if got, err := r.ReadAll(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("TestChunkSize(%d): got: %v, %v want: %v", size, len(got), err, len(want))
+		}
+	}
+
+	// rows with quoted newlines, which chunks are bound to start within
+	var multi bytes.Buffer
+	var lines []int
+	for i, line := 0, 1; i < 3000; i++ {
+		fmt.Fprintf(&multi, "%d,\"multi\nline %s\",\"\"\"%d\"\"\"\n", i, strings.Repeat("a,\n", i%20), i)
+		lines = append(lines, line)
+		line += 2 + i%20
+	}
+	if want, err = encodingCsv(multi.Bytes(), ','); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, size := range []int{64, 1000, 4096} {
+		r := NewReader(struct{ io.Reader }{bytes.NewReader(multi.Bytes())})
+		r.ChunkSize = size
+		for i := 0; ; i++ {
+			record, err := r.ReadRecord()
+			if err == io.EOF {
+				if i != len(want) {
+					t.Errorf("TestChunkSize(%d): got: %d records want: %d", size, i, len(want))
+				}
+				break
+			}
+			if err != nil || !reflect.DeepEqual(record.Fields, want[i]) || record.Line != lines[i] {
+				t.Fatalf("TestChunkSize(%d): got: %q on line %d, %v want: %q on line %d", size, record.Fields, record.Line, err, want[i], lines[i])
+			}
+		}
+	}
+
+	// a stray quote within the first chunk, of which no row ends within it
+	stray := "\"\"\",,\n\nabab,,\"a\"ba\"ba,\"\"b,abbab,,,a\n\"b\"\"\n\n\nabbab\n\"aaa,a,b\"ab\"b,,\n"
+	for _, size := range []int{64, 4096} {
+		r := NewReader(strings.NewReader(stray))
+		r.FallbackThreshold, r.FieldsPerRecord, r.ChunkSize = -1, -1, size
+		var parseErr *csv.ParseError
+		if _, err := r.ReadAll(); !errors.As(err, &parseErr) || !errors.Is(err, csv.ErrQuote) {
+			t.Errorf("TestChunkSize(%d): got: %v want: %v", size, err, csv.ErrQuote)
+		}
+	}
+
+	r := NewReader(bytes.NewReader(buf))
+	r.ChunkSize = -1
+	if _, err := r.ReadAll(); err != errInvalidChunkSize {
+		t.Errorf("TestChunkSize(-1): got: %v want: %v", err, errInvalidChunkSize)
+	}
+}
+
+func TestReadFused(t *testing.T) {
+	const input = "a,b\nc,d\ne,f\n"
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e", "f"}}
+
+	r := NewReader(strings.NewReader(input))
+	r.FallbackThreshold = -1
+	for _, w := range want {
+		record, err := r.Read()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		runtime.Gosched()
+		if !reflect.DeepEqual(record, w) {
+			t.Errorf("TestReadFused: got: %v want: %v", record, w)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("TestReadFused: got: %v want: %v", err, io.EOF)
+	}
+}
+
+func TestStage2Scaler(t *testing.T) {
+	spawned := 0
+	s := &stage2Scaler{active: 1, max: 3, spawn: func() { spawned++ }}
+
+	s.scaleUp(0)
+	if spawned != 0 {
+		t.Errorf("TestStage2Scaler: scaled up without backlog")
+	}
+	for i := 0; i < 5; i++ {
+		s.scaleUp(10)
+	}
+	if spawned != 2 || s.active != 3 {
+		t.Errorf("TestStage2Scaler: got: %d (%d active) want: 2 (3 active)", spawned, s.active)
+	}
+	if s.retire(1) {
+		t.Errorf("TestStage2Scaler: retired with backlog")
+	}
+	if !s.retire(0) || !s.retire(0) || s.retire(0) {
+		t.Errorf("TestStage2Scaler: last worker must not retire")
+	}
+}
+
+func TestReuseRecord(t *testing.T) {
+	input := selfTestCorpus()
+	big := input[len(input)-1]
+	want, err := encodingCsv(big, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, kernel := range []*kernel{nil, kernels[len(kernels)-1]} {
+		for _, reuse := range []bool{false, true} {
+			r := NewReader(bytes.NewReader(big))
+			r.kernel, r.ReuseRecord = kernel, reuse
+			var previous []string
+			for i := 0; ; i++ {
+				record, err := r.Read()
+				if err == io.EOF {
+					break
+				} else if err != nil || !reflect.DeepEqual(record, want[i]) {
+					t.Fatalf("TestReuseRecord(%v): record %d: got: %q, %v want: %q", reuse, i, record, err, want[i])
+				}
+				if shared := previous != nil && &previous[:1][0] == &record[:1][0]; previous != nil && shared != reuse {
+					t.Fatalf("TestReuseRecord(%v): record %d: got: shared %v", reuse, i, shared)
+				}
+				previous = record
+				if !reuse {
+					// the record is owned by the caller, so appending leaves the next one be
+					_ = append(record, "appended")
+				}
+			}
+		}
+	}
+}
+
+func TestFallbackThreshold(t *testing.T) {
+	const input = "a,b\nc,\"d\"\"\"\ne,f\n"
+	want := [][]string{{"a", "b"}, {"c", `d"`}, {"e", "f"}}
+
+	for _, threshold := range []int{-1, 0, 1 << 20} {
+		r := NewReader(strings.NewReader(input))
+		r.FallbackThreshold = threshold
+		var records [][]string
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%v", err)
+			}
+			records = append(records, record)
+		}
+		if !reflect.DeepEqual(records, want) {
+			t.Errorf("TestFallbackThreshold(%d): got: %v want: %v", threshold, records, want)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/worldcitiespop-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	records, err := encodingCsv(buf, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	r := NewReader(bytes.NewReader(buf))
+	peeked, err := r.Peek(5)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !reflect.DeepEqual(peeked, records[:5]) {
+		t.Errorf("TestPeek: got: %v want: %v", peeked, records[:5])
+	}
+
+	if record, _ := r.Read(); !reflect.DeepEqual(record, records[0]) {
+		t.Errorf("TestPeek: got: %v want: %v", record, records[0])
+	}
+
+	// peek across several blocks
+	peeked, err = r.Peek(50000)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !reflect.DeepEqual(peeked, records[1:50001]) {
+		t.Errorf("TestPeek: got: %v want: %v", len(peeked), 50000)
+	}
+
+	// peek beyond the end of the input
+	peeked, err = r.Peek(len(records))
+	if err != io.EOF || !reflect.DeepEqual(peeked, records[1:]) {
+		t.Errorf("TestPeek: got: %v (%v) want: %v (%v)", len(peeked), err, len(records)-1, io.EOF)
+	}
+
+	rest, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !reflect.DeepEqual(rest, records[1:]) {
+		t.Errorf("TestPeek: got: %v want: %v", len(rest), len(records)-1)
+	}
+}
+
+func TestPeekError(t *testing.T) {
+	r := NewReader(strings.NewReader("a,b\nc,\"d\n"))
+	r.FallbackThreshold = -1
+	peeked, err := r.Peek(3)
+	if err == nil || err == io.EOF {
+		t.Fatalf("TestPeekError: expected parse error, got: %v (%v)", err, peeked)
+	}
+	if _, err2 := r.Read(); err2 != err {
+		t.Errorf("TestPeekError: got: %v want: %v", err2, err)
+	}
+}
+
+func testRecordLineNumber(t *testing.T, name string, input []byte, sep, comment rune, threshold int) {
+	want := csv.NewReader(bytes.NewReader(input))
+	want.Comma, want.Comment = sep, comment
+
+	r := NewReader(bytes.NewReader(input))
+	r.Comma, r.Comment = sep, comment
+	r.FallbackThreshold = threshold
+	r.KeepRaw = true
+	for n := 1; ; n++ {
+		record, err := r.Read()
+		expected, errWant := want.Read()
+		if err != errWant {
+			t.Fatalf("TestRecordLineNumber(%s): got: %v want: %v", name, err, errWant)
+		} else if err != nil {
+			break
+		}
+		if !reflect.DeepEqual(record, expected) {
+			t.Fatalf("TestRecordLineNumber(%s): got: %v want: %v", name, record, expected)
+		}
+		line, _ := want.FieldPos(0)
+		if r.RecordNumber() != n || r.LineNumber() != line {
+			t.Fatalf("TestRecordLineNumber(%s): got: %d:%d want: %d:%d", name, r.RecordNumber(), r.LineNumber(), n, line)
+		}
+		// the raw record must parse into the same record again
+		reparsed := csv.NewReader(bytes.NewReader(r.RawRecord()))
+		reparsed.Comma = sep
+		if again, err := reparsed.Read(); err != nil || !reflect.DeepEqual(again, record) {
+			t.Fatalf("TestRecordLineNumber(%s): got: %q want: %v", name, r.RawRecord(), record)
+		}
+	}
+}
+
+func TestRecordLineNumber(t *testing.T) {
+	tricky := "a,b\n\n\"multi\nline\",x\r\n\"\"\"q\"\"\",y\n#c\n\"\",\n\"\r\n\r\n\",z\nlast,w"
+
+	// records straddling chunk boundaries
+	var big bytes.Buffer
+	for i := 0; big.Len() < 1<<20; i++ {
+		fmt.Fprintf(&big, "%d,\"%s\",%s\n", i, strings.Repeat("x\"\"", i%7), strings.Repeat("y", i%97))
+		if i%13 == 0 {
+			big.WriteString("\r\n")
+		}
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		sep   rune
+	}{
+		{"tricky", []byte(tricky), ','},
+		{"big", big.Bytes(), ','},
+	}
+	for _, file := range []string{"parking-citations-100K.csv", "worldcitiespop-100K.csv", "part.tbl"} {
+		buf, err := ioutil.ReadFile("testdata/" + file)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		sep := ','
+		if strings.HasSuffix(file, ".tbl") {
+			sep = '|'
+		}
+		tests = append(tests, struct {
+			name  string
+			input []byte
+			sep   rune
+		}{file, buf, sep})
+	}
+
+	for _, test := range tests {
+		for _, threshold := range []int{-1, 0} {
+			testRecordLineNumber(t, test.name, test.input, test.sep, '#', threshold)
+		}
+	}
+}
+
+func TestRawRecord(t *testing.T) {
+	input := "a,\"b\"\"c\"\r\n\n# comment\n\"multi\r\nline\",d\nlast,e"
+	want := []string{"a,\"b\"\"c\"", "\"multi\r\nline\",d", "last,e"}
+
+	for _, threshold := range []int{-1, 0} {
+		r := NewReader(strings.NewReader(input))
+		r.Comment = '#'
+		r.FallbackThreshold = threshold
+		r.KeepRaw = true
+		for i := range want {
+			if _, err := r.Read(); err != nil {
+				t.Fatalf("TestRawRecord: %v", err)
+			}
+			if string(r.RawRecord()) != want[i] {
+				t.Errorf("TestRawRecord: got: %q want: %q", r.RawRecord(), want[i])
+			}
+		}
+	}
+
+	r := NewReader(strings.NewReader(input))
+	r.Comment = '#'
+	if _, err := r.Read(); err != nil || r.RawRecord() != nil {
+		t.Errorf("TestRawRecord: got: %q want: nil", r.RawRecord())
+	}
+}
+
+func TestFieldTransform(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/worldcitiespop-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	want, err := encodingCsv(buf, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, record := range want {
+		for c := range record {
+			record[c] = strings.ToUpper(record[c])
+		}
+	}
+
+	upper := func(col int, raw []byte) (string, error) {
+		return string(bytes.ToUpper(raw)), nil
+	}
+	for _, threshold := range []int{-1, len(buf) + 1} {
+		r := NewReader(bytes.NewReader(buf))
+		r.FallbackThreshold = threshold
+		r.FieldTransform = upper
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("TestFieldTransform: %v", err)
+		}
+		if !reflect.DeepEqual(records, want) {
+			t.Errorf("TestFieldTransform: mismatch for threshold %d", threshold)
+		}
+	}
+
+	errNumber := errors.New("not a number")
+	number := func(col int, raw []byte) (string, error) {
+		if col == 1 && len(raw) > 0 && (raw[0] < '0' || raw[0] > '9') {
+			return "", errNumber
+		}
+		return string(raw), nil
+	}
+	for _, threshold := range []int{-1, 0} {
+		r := NewReader(strings.NewReader("a,1\nb,2\n\"c\nc\",3\nd,x\n"))
+		r.FallbackThreshold = threshold
+		r.FieldTransform = number
+		_, err := r.ReadAll()
+		if !errors.Is(err, errNumber) || !strings.Contains(err.Error(), "line 5: field 2") {
+			t.Errorf("TestFieldTransform: got: %v want: %v on line 5", err, errNumber)
+		}
+	}
+}
+
+func TestConcurrentRead(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/worldcitiespop-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	want, err := encodingCsv(buf, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	r := NewReader(bytes.NewReader(buf))
+
+	const readers = 8
+	results := make([][]Record, readers)
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				record, err := r.ReadRecord()
+				if err == io.EOF {
+					return
+				} else if err != nil {
+					t.Errorf("TestConcurrentRead: %v", err)
+					return
+				}
+				results[i] = append(results[i], record)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make([]bool, len(want))
+	for _, records := range results {
+		for _, record := range records {
+			n := record.Number - 1
+			if n < 0 || n >= len(want) || seen[n] {
+				t.Fatalf("TestConcurrentRead: record %d returned twice or out of range", record.Number)
+			}
+			seen[n] = true
+			if !reflect.DeepEqual(record.Fields, want[n]) || record.Line != record.Number {
+				t.Fatalf("TestConcurrentRead: got: %v (line %d) want: %v (line %d)", record.Fields, record.Line, want[n], n+1)
+			}
+		}
+	}
+	for n := range seen {
+		if !seen[n] {
+			t.Fatalf("TestConcurrentRead: record %d not returned", n+1)
+		}
+	}
+}
+
 func BenchmarkSimdCsv(b *testing.B) {
 	b.Run("parking-citations-100K", func(b *testing.B) {
 		benchmarkSimdCsv(b, "testdata/parking-citations-100K.csv")
@@ -863,6 +1476,48 @@ func benchmarkSimdCsv(b *testing.B, file string) {
 	}
 }
 
+// BenchmarkTrimLeadingSpace parses a file whose fields all start with a
+// space, with and without trimming
+func BenchmarkTrimLeadingSpace(b *testing.B) {
+	buf, err := ioutil.ReadFile("testdata/nyc-taxi-data-100K.csv")
+	if err != nil {
+		panic(err)
+	}
+	buf = bytes.ReplaceAll(buf, []byte(","), []byte(", "))
+
+	for _, trim := range []bool{false, true} {
+		b.Run(fmt.Sprintf("trim=%v", trim), func(b *testing.B) {
+			b.SetBytes(int64(len(buf)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r := NewReader(bytes.NewReader(buf))
+				r.TrimLeadingSpace = trim
+				if _, err := r.ReadAll(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkUnescape parses a file with quoted fields containing escaped
+// quotes, which are unescaped in post-processing
+func BenchmarkUnescape(b *testing.B) {
+	buf, err := ioutil.ReadFile("testdata/nyc-taxi-data-100K.csv")
+	if err != nil {
+		panic(err)
+	}
+	buf = bytes.ReplaceAll(buf, []byte(","), []byte(`,"quoted ""field"" here",`))
+
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewReader(bytes.NewReader(buf)).ReadAll(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkEncodingCsv(b *testing.B) {
 	b.Run("parking-citations-100K", func(b *testing.B) {
 		benchmarkEncodingCsv(b, "testdata/parking-citations-100K.csv")
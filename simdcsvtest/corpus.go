@@ -0,0 +1,122 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package simdcsvtest provides helpers for verifying that simdcsv parses
+// a corpus of CSV files exactly like encoding/csv does.
+package simdcsvtest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/minio/simdcsv"
+)
+
+// Config is the configuration applied to both parsers
+type Config struct {
+	Comma            rune // defaults to ','
+	Comment          rune
+	FieldsPerRecord  int
+	LazyQuotes       bool
+	TrimLeadingSpace bool
+
+	// Pattern selects the files of a corpus by name (see filepath.Match).
+	// It defaults to "*.csv".
+	Pattern string
+}
+
+// Compare parses data with both simdcsv and encoding/csv under cfg, and
+// returns an error describing the first difference in the records, or
+// in whether and why parsing fails, if any.
+func Compare(data []byte, cfg Config) error {
+
+	rCsv := csv.NewReader(bytes.NewReader(data))
+	rSimd := simdcsv.NewReader(bytes.NewReader(data))
+	if cfg.Comma != 0 {
+		rCsv.Comma, rSimd.Comma = cfg.Comma, cfg.Comma
+	}
+	rCsv.Comment, rSimd.Comment = cfg.Comment, cfg.Comment
+	rCsv.FieldsPerRecord, rSimd.FieldsPerRecord = cfg.FieldsPerRecord, cfg.FieldsPerRecord
+	rCsv.LazyQuotes, rSimd.LazyQuotes = cfg.LazyQuotes, cfg.LazyQuotes
+	rCsv.TrimLeadingSpace, rSimd.TrimLeadingSpace = cfg.TrimLeadingSpace, cfg.TrimLeadingSpace
+
+	want, wantErr := rCsv.ReadAll()
+	got, gotErr := rSimd.ReadAll()
+
+	switch {
+	case wantErr != nil && gotErr == nil:
+		return fmt.Errorf("simdcsv: no error, encoding/csv: %v", wantErr)
+	case wantErr == nil && gotErr != nil:
+		return fmt.Errorf("simdcsv: %v, encoding/csv: no error", gotErr)
+	case wantErr != nil:
+		var gotParse, wantParse *csv.ParseError
+		if errors.As(wantErr, &wantParse) && (!errors.As(gotErr, &gotParse) || gotParse.Err != wantParse.Err) {
+			return fmt.Errorf("simdcsv: %v, encoding/csv: %v", gotErr, wantErr)
+		}
+		return nil
+	}
+
+	if len(got) != len(want) {
+		return fmt.Errorf("simdcsv: %d records, encoding/csv: %d records", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			return fmt.Errorf("record %d: simdcsv: %q, encoding/csv: %q", i, got[i], want[i])
+		}
+	}
+	return nil
+}
+
+// VerifyCorpus walks dir and, in a subtest per file matching the pattern
+// of cfg, asserts that simdcsv parses the file like encoding/csv does.
+func VerifyCorpus(t *testing.T, dir string, cfg Config) {
+	t.Helper()
+
+	pattern := cfg.Pattern
+	if pattern == "" {
+		pattern = "*.csv"
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if match, err := filepath.Match(pattern, d.Name()); err != nil || !match {
+			return err
+		}
+		name, _ := filepath.Rel(dir, path)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Compare(data, cfg); err != nil {
+				t.Error(err)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("VerifyCorpus: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsvtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyCorpus(t *testing.T) {
+	VerifyCorpus(t, "../testdata", Config{})
+	VerifyCorpus(t, "../testdata", Config{Comma: '|', Pattern: "*.tbl"})
+}
+
+func TestCompare(t *testing.T) {
+
+	testCases := []struct {
+		name  string
+		input string
+		cfg   Config
+	}{
+		{"simple", "a,b,c\n1,2,3\n", Config{}},
+		{"quoted", "a,\"b\"\"c\",\"d\ne\"\n", Config{}},
+		{"bare-quote", "a,b\"c\n", Config{}},
+		{"lazy-quotes", "a,b\"c\n", Config{LazyQuotes: true}},
+		{"field-count", "a,b\n1,2,3\n", Config{}},
+		{"comment", "#a,b\n1,2\n", Config{Comment: '#'}},
+	}
+
+	dir := t.TempDir()
+	for _, tc := range testCases {
+		if err := Compare([]byte(tc.input), tc.cfg); err != nil {
+			t.Errorf("TestCompare(%s): got: %v want: nil", tc.name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, tc.name+".csv"), []byte(tc.input), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	VerifyCorpus(t, dir, Config{})
+}
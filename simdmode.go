@@ -0,0 +1,57 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import "os"
+
+// A SIMDMode decides between the SIMD stages and encoding/csv.
+type SIMDMode int
+
+const (
+	// SIMDAuto runs the SIMD stages on CPUs that support them, and hands
+	// inputs below FallbackThreshold to encoding/csv.
+	SIMDAuto SIMDMode = iota
+	// SIMDForce runs the SIMD stages for inputs of any size, with the
+	// portable SWAR kernel on CPUs that support no other.
+	SIMDForce
+	// SIMDDisable hands all parsing to encoding/csv.
+	SIMDDisable
+)
+
+// defaultSIMDMode is the mode of readers that leave SIMD at SIMDAuto, as
+// set with the SIMDCSV environment variable: "force" or "off"
+var defaultSIMDMode = parseSIMDMode(os.Getenv("SIMDCSV"))
+
+// parseSIMDMode parses the value of the SIMDCSV environment variable,
+// where anything unknown means SIMDAuto
+func parseSIMDMode(s string) SIMDMode {
+	switch s {
+	case "force":
+		return SIMDForce
+	case "off":
+		return SIMDDisable
+	}
+	return SIMDAuto
+}
+
+// simdMode returns the mode in effect for r
+func (r *Reader) simdMode() SIMDMode {
+	if r.SIMD != SIMDAuto {
+		return r.SIMD
+	}
+	return defaultSIMDMode
+}
@@ -0,0 +1,63 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSIMDMode(t *testing.T) {
+	const input = "a,\"b\nc\",d\ne,f,\"g\"\"h\"\n"
+	want, _ := encodingCsv([]byte(input), ',')
+
+	defer func(mode SIMDMode) { defaultSIMDMode = mode }(defaultSIMDMode)
+	defaultSIMDMode = SIMDAuto
+
+	for _, tc := range []struct {
+		mode SIMDMode
+		simd bool
+	}{
+		{SIMDAuto, SupportedCPU() || swarKernel.supported()}, // the portable kernel stands in for vector ones
+		{SIMDForce, true},
+		{SIMDDisable, false},
+	} {
+		r := NewReader(strings.NewReader(input))
+		r.SIMD = tc.mode
+		if r.simdMode() != tc.mode || r.simd() != tc.simd {
+			t.Errorf("TestSIMDMode(%d): got: %v want: %v", tc.mode, r.simd(), tc.simd)
+		}
+		got, err := r.ReadAll()
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("TestSIMDMode(%d): got: %q, %v want: %q", tc.mode, got, err, want)
+		}
+	}
+
+	r := NewReader(nil)
+	if r.SIMD = SIMDForce; r.fallbackThreshold() >= 0 {
+		t.Errorf("TestSIMDMode: got: %d want: no fallback to encoding/csv when forced", r.fallbackThreshold())
+	}
+}
+
+func TestParseSIMDMode(t *testing.T) {
+	for s, want := range map[string]SIMDMode{"": SIMDAuto, "force": SIMDForce, "off": SIMDDisable, "bogus": SIMDAuto} {
+		if got := parseSIMDMode(s); got != want {
+			t.Errorf("TestParseSIMDMode(%q): got: %d want: %d", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// reorderWindowSize is the maximum number of chunks that the stage 2
+// workers may run ahead of the next chunk expected by the consumer
+const reorderWindowSize = 64
+
+// outputSlots is a ring of pre-sequenced slots that the stage 2 workers
+// write their results into directly, and that the consumer reads from in
+// sequence order. The result for sequence s always goes into slot s%size,
+// so no reordering is needed. A worker that gets more than size chunks
+// ahead of the consumer waits for its slot to come around, which bounds
+// the number of completed chunks held in memory.
+//
+// Handing over a slot only takes atomic operations; the mutex and
+// condition variable are used just when either side actually has to wait.
+type outputSlots struct {
+	slots []outputSlot
+	next  int   // next sequence to be returned, owned by the consumer
+	taken int64 // next sequence to be returned, for the input to read
+	limit int   // chunks the input may run ahead of the consumer, if bounded
+	err   error // error that ended the input, if any (see fail)
+
+	closed  int32 // set once no more results will be put
+	stopped int32 // set once the consumer is no longer interested
+
+	waiters int32
+	mu      sync.Mutex
+	cond    *sync.Cond
+}
+
+type outputSlot struct {
+	sequence int64  // sequence the slot is currently reserved for
+	full     uint32 // whether output holds the result for sequence
+	output   recordsOutput
+}
+
+func newOutputSlots(size int) *outputSlots {
+	q := &outputSlots{slots: make([]outputSlot, size)}
+	for i := range q.slots {
+		q.slots[i].sequence = int64(i)
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// waitUntil blocks until cond holds, only resorting to the mutex on the slow path
+func (q *outputSlots) waitUntil(cond func() bool) {
+	if cond() {
+		return
+	}
+	q.mu.Lock()
+	atomic.AddInt32(&q.waiters, 1) // announce before re-checking so no wake-up gets lost
+	for !cond() {
+		q.cond.Wait()
+	}
+	atomic.AddInt32(&q.waiters, -1)
+	q.mu.Unlock()
+}
+
+func (q *outputSlots) wake() {
+	if atomic.LoadInt32(&q.waiters) > 0 {
+		q.mu.Lock()
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	}
+}
+
+// put stores the result for its sequence, waiting for the slot to become
+// available. Results are discarded once the consumer has stopped.
+func (q *outputSlots) put(output recordsOutput) {
+	s := &q.slots[output.sequence%len(q.slots)]
+	q.waitUntil(func() bool {
+		return atomic.LoadInt64(&s.sequence) == int64(output.sequence) || q.isStopped()
+	})
+	if q.isStopped() {
+		return
+	}
+	s.output = output
+	atomic.StoreUint32(&s.full, 1)
+	q.wake()
+}
+
+// get returns the result for the next sequence, or false when no more
+// results are forthcoming
+func (q *outputSlots) get() (recordsOutput, bool) {
+	s := &q.slots[q.next%len(q.slots)]
+	q.waitUntil(func() bool {
+		return atomic.LoadUint32(&s.full) == 1 || atomic.LoadInt32(&q.closed) == 1
+	})
+	if atomic.LoadUint32(&s.full) == 0 {
+		q.mu.Lock()
+		err := q.err
+		q.mu.Unlock()
+		if err != nil {
+			return recordsOutput{sequence: q.next, err: err}, true
+		}
+		return recordsOutput{}, false
+	}
+	output := s.output
+	s.output = recordsOutput{}
+	atomic.StoreUint32(&s.full, 0)
+	atomic.StoreInt64(&s.sequence, int64(q.next+len(q.slots)))
+	q.next++
+	atomic.StoreInt64(&q.taken, int64(q.next))
+	q.wake()
+	return output, true
+}
+
+// admit waits until the input may go on with the chunk of sequence, which
+// is at most limit chunks ahead of the next result to be returned (see
+// Reader.MaxPendingBlocks)
+func (q *outputSlots) admit(sequence int) {
+	if q.limit > 0 {
+		q.waitUntil(func() bool {
+			return int64(sequence)-atomic.LoadInt64(&q.taken) <= int64(q.limit) || q.isStopped()
+		})
+	}
+}
+
+// fail records the error that ended the input, which get returns once all
+// results have been returned
+func (q *outputSlots) fail(err error) {
+	q.mu.Lock()
+	q.err = err
+	q.mu.Unlock()
+}
+
+// close marks that all results have been put
+func (q *outputSlots) close() {
+	atomic.StoreInt32(&q.closed, 1)
+	q.wake()
+}
+
+// wait blocks until all results have been put, that is until the stages
+// that produce them are done
+func (q *outputSlots) wait() {
+	q.waitUntil(func() bool { return atomic.LoadInt32(&q.closed) == 1 })
+}
+
+// stop releases all producers when the consumer bails out early, after
+// which any further results are discarded
+func (q *outputSlots) stop() {
+	atomic.StoreInt32(&q.stopped, 1)
+	q.wake()
+}
+
+// failed reports whether the input ended with an error (see fail)
+func (q *outputSlots) failed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.err != nil
+}
+
+func (q *outputSlots) isStopped() bool {
+	return atomic.LoadInt32(&q.stopped) == 1
+}
@@ -0,0 +1,157 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOutputSlotsOrdering(t *testing.T) {
+	const total, producers = 1000, 4
+
+	q := newOutputSlots(4 * producers) // a producer never waits on itself
+
+	var wg sync.WaitGroup
+	wg.Add(producers)
+	for p := 0; p < producers; p++ {
+		go func(p int) {
+			defer wg.Done()
+			// every producer puts its sequences in reverse order within each stride
+			for base := 0; base < total; base += 4 * producers {
+				for s := base + 4*producers - 1; s >= base; s-- {
+					if s%producers == p && s < total {
+						q.put(recordsOutput{sequence: s})
+					}
+				}
+			}
+		}(p)
+	}
+	go func() {
+		wg.Wait()
+		q.close()
+	}()
+
+	for expected := 0; ; expected++ {
+		output, ok := q.get()
+		if !ok {
+			if expected != total {
+				t.Errorf("TestOutputSlotsOrdering: got: %d want: %d", expected, total)
+			}
+			break
+		}
+		if output.sequence != expected {
+			t.Fatalf("TestOutputSlotsOrdering: got: %d want: %d", output.sequence, expected)
+		}
+	}
+}
+
+func TestOutputSlotsWindow(t *testing.T) {
+	q := newOutputSlots(2)
+	q.put(recordsOutput{sequence: 0})
+	q.put(recordsOutput{sequence: 1})
+
+	done := make(chan struct{})
+	go func() {
+		q.put(recordsOutput{sequence: 2})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("TestOutputSlotsWindow: sequence 2 admitted beyond window")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if output, _ := q.get(); output.sequence != 0 {
+		t.Errorf("TestOutputSlotsWindow: got: %d want: 0", output.sequence)
+	}
+	<-done
+
+	// stopping the consumer must release all waiting producers
+	done = make(chan struct{})
+	go func() {
+		q.put(recordsOutput{sequence: 5})
+		close(done)
+	}()
+	q.stop()
+	<-done
+}
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(&c.n, int64(n))
+	return n, err
+}
+
+func TestMaxPending(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	var data bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&data, "%d,field,%d\n", i, i*i)
+	}
+	want, err := NewReader(bytes.NewReader(data.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	const chunkSize = 1 << 12
+	for _, tc := range []struct {
+		blocks int
+		bytes  int64
+		limit  int
+	}{
+		{blocks: 2, limit: 2},
+		{bytes: 3 * chunkSize, limit: 3},
+		{blocks: 5, bytes: 1, limit: 1},
+	} {
+		in := &countingReader{r: bytes.NewReader(data.Bytes())}
+		r := NewReader(in)
+		r.ChunkSize, r.MaxPendingBlocks, r.MaxPendingBytes = chunkSize, tc.blocks, tc.bytes
+		got := make([][]string, 1)
+		if got[0], err = r.Read(); err != nil {
+			t.Fatalf("%v", err)
+		}
+		time.Sleep(50 * time.Millisecond) // for the input to run ahead
+
+		// the first block was received, along with the chunk being read
+		if n, max := atomic.LoadInt64(&in.n), int64((tc.limit+2)*chunkSize); n > max {
+			t.Errorf("TestMaxPending(%d, %d): got: %d bytes read want: at most %d", tc.blocks, tc.bytes, n, max)
+		}
+		rest, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if got = append(got, rest...); !reflect.DeepEqual(got, want) {
+			t.Errorf("TestMaxPending(%d, %d): got: %d records want: %d", tc.blocks, tc.bytes, len(got), len(want))
+		}
+	}
+}
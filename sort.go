@@ -0,0 +1,303 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"container/heap"
+	"errors"
+	"io"
+	"os"
+	"sort"
+)
+
+// SortOptions configures SortByColumn
+type SortOptions struct {
+	// Header keeps the first record in front of the sorted records.
+	Header bool
+
+	// Less orders the fields of the column, in byte order if nil. Records
+	// with equal fields keep their order of the input, and records that lack
+	// the column sort as if the field were empty.
+	Less func(a, b string) bool
+
+	// MaxBytes is the size of the fields held in memory and sorted at a time
+	// (256 MiB if not positive). Larger inputs are sorted in runs of this
+	// size, which are written to temporary files in TempDir (os.TempDir() if
+	// empty) and then merged.
+	MaxBytes int
+	TempDir  string
+
+	// ReaderOptions configure the Reader of the input. The output is written
+	// with its Comma.
+	ReaderOptions []Option
+}
+
+const (
+	defaultSortBytes = 256 << 20
+	mergeWays        = 16       // runs merged at a time
+	mergeChunkSize   = 64 << 10 // ChunkSize of the readers of runs, as many are read at once
+)
+
+var errSortColumn = errors.New("simdcsv: negative sort column")
+
+// SortByColumn writes the records of r to w, sorted by the fields of column
+// col. The input is parsed and sorted in runs of at most opts.MaxBytes,
+// which are merged from temporary files if there is more than one, so the
+// input may be much larger than memory. The runs are written and parsed
+// back by Writer and Reader.
+func SortByColumn(r io.Reader, w io.Writer, col int, opts SortOptions) error {
+	if col < 0 {
+		return errSortColumn
+	}
+	in := NewReader(r)
+	for _, opt := range opts.ReaderOptions {
+		opt(in)
+	}
+	in.ReuseRecord = false
+	out := NewWriter(w)
+	out.Comma = in.Comma
+
+	s := &sorter{col: col, less: opts.Less, dir: opts.TempDir}
+	if s.less == nil {
+		s.less = func(a, b string) bool { return a < b }
+	}
+	defer s.remove()
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultSortBytes
+	}
+
+	var records [][]string
+	size := 0
+	for first := true; ; first = false {
+		record, err := in.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if first && opts.Header {
+			if err := out.Write(record); err != nil {
+				return err
+			}
+			continue
+		}
+		records = append(records, record)
+		for _, field := range record {
+			size += len(field)
+		}
+		if size >= maxBytes {
+			if err := s.spill(records); err != nil {
+				return err
+			}
+			records, size = nil, 0
+		}
+	}
+
+	if len(s.runs) == 0 {
+		s.sort(records)
+		for _, record := range records {
+			if err := out.Write(record); err != nil {
+				return err
+			}
+		}
+	} else {
+		if len(records) > 0 {
+			if err := s.spill(records); err != nil {
+				return err
+			}
+		}
+		if err := s.merge(out); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// sorter sorts records by a column in runs written to temporary files
+type sorter struct {
+	col   int
+	less  func(a, b string) bool
+	dir   string
+	runs  []*os.File // in the order of the input
+	files []*os.File // all files created, removed once done
+}
+
+func (s *sorter) key(record []string) string {
+	if s.col < len(record) {
+		return record[s.col]
+	}
+	return ""
+}
+
+func (s *sorter) sort(records [][]string) {
+	sort.SliceStable(records, func(i, j int) bool { return s.less(s.key(records[i]), s.key(records[j])) })
+}
+
+// create returns a new temporary file, to be written with a Writer
+func (s *sorter) create() (*os.File, *Writer, error) {
+	f, err := os.CreateTemp(s.dir, "simdcsv-sort-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	s.files = append(s.files, f)
+	return f, NewWriter(f), nil
+}
+
+// writeRun writes a record to a run, preceded by an empty field, so records
+// of a single empty field do not turn into empty lines
+func writeRun(w *Writer, record []string) error {
+	return w.Write(append([]string{""}, record...))
+}
+
+// spill sorts records and writes them to a new run
+func (s *sorter) spill(records [][]string) error {
+	s.sort(records)
+	f, w, err := s.create()
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := writeRun(w, record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	s.runs = append(s.runs, f)
+	return nil
+}
+
+// merge merges the runs mergeWays at a time into new runs until few enough
+// are left to be merged into out
+func (s *sorter) merge(out *Writer) error {
+	for len(s.runs) > mergeWays {
+		var merged []*os.File
+		for i := 0; i < len(s.runs); i += mergeWays {
+			end := i + mergeWays
+			if end > len(s.runs) {
+				end = len(s.runs)
+			}
+			f, w, err := s.create()
+			if err != nil {
+				return err
+			}
+			if err := s.mergeRuns(s.runs[i:end], w, writeRun); err != nil {
+				return err
+			}
+			merged = append(merged, f)
+		}
+		s.runs = merged
+	}
+	return s.mergeRuns(s.runs, out, (*Writer).Write)
+}
+
+// mergeRuns writes the records of runs to w in order, where records with
+// equal keys are taken from the earlier run first, so the sort is stable
+func (s *sorter) mergeRuns(runs []*os.File, w *Writer, write func(w *Writer, record []string) error) error {
+	m := &sortMerge{sorter: s}
+	var readers []*Reader
+	defer func() {
+		for _, r := range readers {
+			r.stop()
+		}
+	}()
+	for i, f := range runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r := NewReader(f)
+		r.FieldsPerRecord, r.PreserveCRLF, r.ChunkSize = -1, true, mergeChunkSize
+		readers = append(readers, r)
+		run := &sortRun{r: r, index: i}
+		if err := run.next(); err == nil {
+			m.runs = append(m.runs, run)
+		} else if err != io.EOF {
+			return err
+		}
+	}
+	heap.Init(m)
+	for len(m.runs) > 0 {
+		run := m.runs[0]
+		if err := write(w, run.record); err != nil {
+			return err
+		}
+		if err := run.next(); err == io.EOF {
+			heap.Pop(m)
+		} else if err != nil {
+			return err
+		} else {
+			heap.Fix(m, 0)
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// remove closes and removes the temporary files
+func (s *sorter) remove() {
+	for _, f := range s.files {
+		f.Close()
+		os.Remove(f.Name())
+	}
+}
+
+// sortRun is a run being merged
+type sortRun struct {
+	r      *Reader
+	record []string // next record of the run
+	index  int      // order of the run
+}
+
+// next reads the next record of the run, without its leading empty field
+func (run *sortRun) next() error {
+	record, err := run.r.Read()
+	if err != nil {
+		return err
+	}
+	run.record = record[1:]
+	return nil
+}
+
+// sortMerge is the heap of the runs being merged by their next records
+type sortMerge struct {
+	*sorter
+	runs []*sortRun
+}
+
+func (m *sortMerge) Len() int { return len(m.runs) }
+
+func (m *sortMerge) Less(i, j int) bool {
+	a, b := m.key(m.runs[i].record), m.key(m.runs[j].record)
+	if m.less(a, b) {
+		return true
+	}
+	return !m.less(b, a) && m.runs[i].index < m.runs[j].index
+}
+
+func (m *sortMerge) Swap(i, j int) { m.runs[i], m.runs[j] = m.runs[j], m.runs[i] }
+
+func (m *sortMerge) Push(x interface{}) { m.runs = append(m.runs, x.(*sortRun)) }
+
+func (m *sortMerge) Pop() interface{} {
+	run := m.runs[len(m.runs)-1]
+	m.runs = m.runs[:len(m.runs)-1]
+	return run
+}
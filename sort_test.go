@@ -0,0 +1,106 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSortByColumn(t *testing.T) {
+	var input bytes.Buffer
+	w := csv.NewWriter(&input)
+	w.Write([]string{"id", "key", "note"})
+	var rows [][]string
+	for i := 0; i < 5000; i++ {
+		row := []string{fmt.Sprint(i), fmt.Sprint((i * 7919) % 613), "plain"}
+		switch i % 5 {
+		case 1:
+			row[2] = "multi\nline, \"quoted\""
+		case 2:
+			row = row[:2]
+		case 3:
+			row = []string{"", ""}
+		}
+		rows = append(rows, row)
+	}
+	w.WriteAll(rows)
+
+	key := func(row []string) string {
+		if len(row) > 1 {
+			return row[1]
+		}
+		return ""
+	}
+	number := func(s string) float64 {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+	for _, tc := range []struct {
+		less     func(a, b string) bool
+		maxBytes int
+	}{
+		{nil, 0},
+		{nil, 20000},
+		{func(a, b string) bool { return number(a) < number(b) }, 4000},
+		{func(a, b string) bool { return a > b }, 1 << 20},
+	} {
+		less := tc.less
+		if less == nil {
+			less = func(a, b string) bool { return a < b }
+		}
+		want := append([][]string{{"id", "key", "note"}}, rows...)
+		sort.SliceStable(want[1:], func(i, j int) bool { return less(key(want[1+i]), key(want[1+j])) })
+
+		dir := t.TempDir()
+		var output bytes.Buffer
+		err := SortByColumn(bytes.NewReader(input.Bytes()), &output, 1, SortOptions{Header: true, Less: tc.less, MaxBytes: tc.maxBytes, TempDir: dir,
+			ReaderOptions: []Option{func(r *Reader) { r.FieldsPerRecord = -1 }}})
+		if err != nil {
+			t.Fatalf("TestSortByColumn(%d): %v", tc.maxBytes, err)
+		}
+		var expected bytes.Buffer
+		NewWriter(&expected).WriteAll(want)
+		if !bytes.Equal(output.Bytes(), expected.Bytes()) {
+			t.Errorf("TestSortByColumn(%d): got: %.200q want: %.200q", tc.maxBytes, output.String(), expected.String())
+		}
+		if files, _ := os.ReadDir(dir); len(files) > 0 {
+			t.Errorf("TestSortByColumn(%d): got: %d temporary files left want: 0", tc.maxBytes, len(files))
+		}
+	}
+
+	// records of a single empty field survive the runs
+	var output strings.Builder
+	if err := SortByColumn(strings.NewReader("\"\"\nb\n\"\"\na\n"), &output, 0, SortOptions{MaxBytes: 1, ReaderOptions: []Option{func(r *Reader) { r.SIMD = SIMDDisable }}}); err != nil || output.String() != "\n\na\nb\n" {
+		t.Errorf("TestSortByColumn: got: %q (%v) want: %q", output.String(), err, "\n\na\nb\n")
+	}
+
+	semicolons := "b;2\na;1\nc;3\n"
+	output.Reset()
+	if err := SortByColumn(strings.NewReader(semicolons), &output, 0, SortOptions{ReaderOptions: []Option{func(r *Reader) { r.Comma = ';' }}}); err != nil || output.String() != "a;1\nb;2\nc;3\n" {
+		t.Errorf("TestSortByColumn: got: %q (%v) want: %q", output.String(), err, "a;1\nb;2\nc;3\n")
+	}
+	if err := SortByColumn(strings.NewReader(semicolons), &output, -1, SortOptions{}); err != errSortColumn {
+		t.Errorf("TestSortByColumn: got: %v want: %v", err, errSortColumn)
+	}
+}
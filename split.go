@@ -0,0 +1,157 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+var errSplitShards = errors.New("simdcsv: number of shards must be positive")
+
+// splitBlockSize is the size of the blocks in which Split scans its input
+const splitBlockSize = 1 << 20
+
+// Split divides the records of r into n shards of about equal size, which
+// it writes to the writers that create returns for the shards in turn, and
+// closes them. Shards end at record boundaries, which are found as by
+// SplitBlob, with the same assumption about quotes. If repeatHeader is set,
+// the first record is taken to be the header, which starts every shard,
+// while the records that follow are divided. Shards are left empty (but
+// for the header) if there are too few records to go around.
+//
+// If r implements io.ReaderAt and io.Seeker, as *os.File does, the pieces
+// of the input are scanned in parallel and copied in place from the current
+// offset of r onwards. Otherwise r is read into memory first.
+func Split(r io.Reader, n int, repeatHeader bool, create func(shard int) (io.WriteCloser, error)) error {
+	if n <= 0 {
+		return errSplitShards
+	}
+	in, ok := r.(inputAt)
+	if !ok {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		in = bytes.NewReader(b)
+	}
+	start, err := in.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	size, err := in.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	var header []byte
+	if repeatHeader {
+		piece, err := scanAt(in, start, size, true)
+		if err != nil {
+			return err
+		}
+		end := size
+		if piece.newline[0] >= 0 {
+			end = start + int64(piece.newline[0]) + 1
+		}
+		header = make([]byte, end-start)
+		if _, err := in.ReadAt(header, start); err != nil {
+			return err
+		}
+		start = end
+	}
+
+	// cut the records into pieces of about equal size
+	var boundaries []ChunkBoundary
+	if length := uint64(size - start); length > 0 {
+		chunkSize := (length + uint64(n) - 1) / uint64(n)
+		var mu sync.Mutex
+		var scanErr error
+		pieces := scanPieces(length, chunkSize, func(from, to uint64) blobPiece {
+			piece, err := scanAt(in, start+int64(from), start+int64(to), false)
+			if err != nil {
+				mu.Lock()
+				scanErr = err
+				mu.Unlock()
+			}
+			return piece
+		})
+		if scanErr != nil {
+			return scanErr
+		}
+		if boundaries, err = pieceBoundaries(pieces, chunkSize, length); err != nil {
+			return err
+		}
+	}
+
+	for shard := 0; shard < n; shard++ {
+		w, err := create(shard)
+		if err != nil {
+			return err
+		}
+		if err = writeShard(w, in, start, header, boundaries, shard); err != nil {
+			w.Close()
+			return err
+		}
+		if err = w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeShard writes the header, if any, and the range of the shard, if any,
+// of the input that follows the header at start
+func writeShard(w io.Writer, in io.ReaderAt, start int64, header []byte, boundaries []ChunkBoundary, shard int) error {
+	if len(header) > 0 {
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+	}
+	if shard >= len(boundaries) {
+		return nil
+	}
+	b := boundaries[shard]
+	_, err := io.Copy(w, io.NewSectionReader(in, start+int64(b.Start), int64(b.End-b.Start)))
+	return err
+}
+
+// scanAt scans the input from start to end in blocks, as scanPiece scans a
+// piece, stopping at the first newline outside quotes if first is set
+func scanAt(in io.ReaderAt, start, end int64, first bool) (blobPiece, error) {
+	piece := blobPiece{newline: [2]int{-1, -1}}
+	blockSize := int64(splitBlockSize)
+	if end-start < blockSize {
+		blockSize = end - start
+	}
+	buf := make([]byte, blockSize)
+	for offset := start; offset < end; offset += int64(len(buf)) {
+		if end-offset < int64(len(buf)) {
+			buf = buf[:end-offset]
+		}
+		if _, err := in.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return piece, err
+		}
+		piece = piece.then(scanPiece(buf), int(offset-start))
+		if first && piece.newline[0] >= 0 {
+			break
+		}
+	}
+	return piece, nil
+}
@@ -0,0 +1,108 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type shardBuffer struct{ bytes.Buffer }
+
+func (b *shardBuffer) Close() error { return nil }
+
+func TestSplit(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("id,note\n")
+	for i := 0; i < 2000; i++ {
+		if i%7 == 0 {
+			fmt.Fprintf(&input, "%d,\"spans\n%d\nlines, \"\"quoted\"\"\"\n", i, i)
+		} else {
+			fmt.Fprintf(&input, "%d,plain %d\n", i, i)
+		}
+	}
+	whole, err := ReadBytes([]byte(input.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "input.csv")
+	if err := os.WriteFile(path, []byte(input.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{1, 2, 3, 7, 16, 5000} {
+		for _, repeatHeader := range []bool{false, true} {
+			for _, source := range []string{"file", "reader"} {
+				var r io.Reader = struct{ io.Reader }{strings.NewReader(input.String())}
+				if source == "file" {
+					f, err := os.Open(path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					defer f.Close()
+					r = f
+				}
+				shards := make([]*shardBuffer, n)
+				err := Split(r, n, repeatHeader, func(shard int) (io.WriteCloser, error) {
+					shards[shard] = &shardBuffer{}
+					return shards[shard], nil
+				})
+				if err != nil {
+					t.Fatalf("TestSplit(%d, %v, %s): %v", n, repeatHeader, source, err)
+				}
+
+				got := [][]string{whole[0]}
+				nonEmpty := 0
+				for i, shard := range shards {
+					records, err := ReadBytes(shard.Bytes())
+					if err != nil {
+						t.Fatalf("TestSplit(%d, %v, %s): shard %d: %v", n, repeatHeader, source, i, err)
+					}
+					if repeatHeader {
+						if len(records) == 0 || !reflect.DeepEqual(records[0], whole[0]) {
+							t.Fatalf("TestSplit(%d, %v, %s): shard %d: got: %.2q want: header first", n, repeatHeader, source, i, records)
+						}
+						records = records[1:]
+					} else if i == 0 {
+						records = records[1:]
+					}
+					if len(records) > 0 {
+						nonEmpty++
+					}
+					got = append(got, records...)
+				}
+				if !reflect.DeepEqual(got, whole) {
+					t.Errorf("TestSplit(%d, %v, %s): got: %d records want: %d", n, repeatHeader, source, len(got), len(whole))
+				}
+				if want := n; n <= 16 && nonEmpty != want {
+					t.Errorf("TestSplit(%d, %v, %s): got: %d shards with records want: %d", n, repeatHeader, source, nonEmpty, want)
+				}
+			}
+		}
+	}
+
+	if err := Split(strings.NewReader(input.String()), 0, false, nil); err != errSplitShards {
+		t.Errorf("TestSplit: got: %v want: %v", err, errSplitShards)
+	}
+}
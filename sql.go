@@ -0,0 +1,546 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// errSelectCombined is returned by Select if columns or predicates have been
+// set otherwise
+var errSelectCombined = errors.New("simdcsv: Select cannot be combined with SelectColumns, SelectNames, WhereColumns or WhereNames")
+
+// Select sets up r to return the result of query, a statement in the style of
+// S3 Select:
+//
+//	SELECT * | column, ... [FROM S3Object [[AS] alias]] [WHERE condition] [LIMIT n]
+//
+// Columns are named by the header in the first record, or numbered from 1 as
+// _1, _2 and so on, optionally qualified by the alias (s._1), and quoted
+// with double quotes if need be. Conditions compare columns to one another
+// or to 'strings' and numbers with =, !=, <>, <, <=, > and >=, match them
+// against patterns with [NOT] LIKE, and combine these with AND, OR, NOT and
+// parentheses. Fields are compared as numbers to numbers, and to columns
+// whenever both parse as numbers, and as strings otherwise; fields that are
+// not numbers do not match comparisons to numbers.
+//
+// The condition is evaluated by the parsing workers, on the same terms as
+// WhereNames, and the records are projected onto the selected columns as
+// for SelectNames, with the header returned first if columns are named.
+// Reading ends once LIMIT records follow the header.
+func (r *Reader) Select(query string) error {
+	if r.projects() || r.filters() {
+		return errSelectCombined
+	}
+	q, err := parseQuery(query)
+	if err != nil {
+		return err
+	}
+	r.query = q
+	return nil
+}
+
+// sqlQuery is a parsed SELECT statement
+type sqlQuery struct {
+	columns []sqlColumn // selected, none for *
+	where   *sqlCond
+	refs    []sqlColumn // referred to by where
+	limit   int         // or -1
+	header  bool        // whether columns are named, so the header is kept
+}
+
+// sqlColumn refers to a column by name or else by index
+type sqlColumn struct {
+	name  string
+	index int
+}
+
+// sqlOperand is a column or a literal
+type sqlOperand struct {
+	column   *sqlColumn
+	literal  string
+	number   float64
+	isNumber bool
+}
+
+// sqlCond is a node of a condition: AND, OR and NOT combine their operands,
+// whereas comparisons and LIKE apply to a and b
+type sqlCond struct {
+	op          string
+	left, right *sqlCond
+	a, b        sqlOperand
+}
+
+// queryColumn returns the index of column c in header, or -1
+func (r *Reader) queryColumn(c sqlColumn, header []string) int {
+	if c.name == "" {
+		return c.index
+	}
+	name := r.NormalizeHeader.apply(c.name)
+	for i, column := range header {
+		if r.NormalizeHeader.apply(column) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// queryLimit returns the number of records that the query returns at most,
+// the header included, or -1 if there is no limit
+func (r *Reader) queryLimit() int {
+	if r.query == nil || r.query.limit < 0 {
+		return -1
+	}
+	if r.query.header {
+		return r.query.limit + 1
+	}
+	return r.query.limit
+}
+
+// atQueryLimit truncates records to the limit of the query given the records
+// consumed before, ending reading once the limit is reached
+func (r *Reader) atQueryLimit(records *[][]string, positions *[]recordPos) {
+	max := r.queryLimit()
+	if max < 0 || r.consumed+len(*records) < max {
+		return
+	}
+	n := max - r.consumed
+	if n < 0 {
+		n = 0
+	}
+	*records = (*records)[:n]
+	if len(*positions) > n {
+		*positions = (*positions)[:n]
+	}
+	if r.readErr == nil {
+		r.readErr = io.EOF
+	}
+	if r.slots != nil {
+		r.slots.stop()
+	}
+}
+
+// bind returns the function that evaluates c on a record of the input with
+// header
+func (r *Reader) bind(c *sqlCond, header []string) func(record []string) bool {
+	switch c.op {
+	case "AND":
+		left, right := r.bind(c.left, header), r.bind(c.right, header)
+		return func(record []string) bool { return left(record) && right(record) }
+	case "OR":
+		left, right := r.bind(c.left, header), r.bind(c.right, header)
+		return func(record []string) bool { return left(record) || right(record) }
+	case "NOT":
+		left := r.bind(c.left, header)
+		return func(record []string) bool { return !left(record) }
+	case "LIKE", "NOT LIKE":
+		a, pattern, not := r.operand(c.a, header), c.b.literal, c.op == "NOT LIKE"
+		return func(record []string) bool { return like(a(record), pattern) != not }
+	}
+
+	a, b := r.operand(c.a, header), r.operand(c.b, header)
+	cmp := comparison(c.op)
+	switch {
+	case c.a.isNumber || c.b.isNumber:
+		return func(record []string) bool {
+			x, errA := strconv.ParseFloat(a(record), 64)
+			y, errB := strconv.ParseFloat(b(record), 64)
+			return errA == nil && errB == nil && cmp(compareFloats(x, y))
+		}
+	case c.a.column != nil && c.b.column != nil:
+		return func(record []string) bool {
+			s, t := a(record), b(record)
+			x, errA := strconv.ParseFloat(s, 64)
+			y, errB := strconv.ParseFloat(t, 64)
+			if errA == nil && errB == nil {
+				return cmp(compareFloats(x, y))
+			}
+			return cmp(strings.Compare(s, t))
+		}
+	}
+	return func(record []string) bool { return cmp(strings.Compare(a(record), b(record))) }
+}
+
+// operand returns the function that returns the value of o for a record,
+// which is empty for columns that a record lacks
+func (r *Reader) operand(o sqlOperand, header []string) func(record []string) string {
+	if o.column == nil {
+		return func([]string) string { return o.literal }
+	}
+	col := r.queryColumn(*o.column, header)
+	return func(record []string) string {
+		if col >= 0 && col < len(record) {
+			return record[col]
+		}
+		return ""
+	}
+}
+
+// comparison returns the function that applies a comparison operator to the
+// result of a three-way comparison
+func comparison(op string) func(c int) bool {
+	switch op {
+	case "=":
+		return func(c int) bool { return c == 0 }
+	case "!=", "<>":
+		return func(c int) bool { return c != 0 }
+	case "<":
+		return func(c int) bool { return c < 0 }
+	case "<=":
+		return func(c int) bool { return c <= 0 }
+	case ">":
+		return func(c int) bool { return c > 0 }
+	}
+	return func(c int) bool { return c >= 0 }
+}
+
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// like reports whether s matches pattern, in which % matches any number of
+// characters and _ a single character
+func like(s, pattern string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '%':
+			pattern = strings.TrimLeft(pattern, "%")
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if like(s[i:], pattern) {
+					return true
+				}
+			}
+			return false
+		case '_':
+			if s == "" {
+				return false
+			}
+			_, n := utf8.DecodeRuneInString(s)
+			s, pattern = s[n:], pattern[1:]
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+			s, pattern = s[1:], pattern[1:]
+		}
+	}
+	return s == ""
+}
+
+// sqlParser parses a query from its tokens
+type sqlParser struct {
+	tokens []sqlToken
+	alias  string
+	query  *sqlQuery
+}
+
+// sqlToken is an identifier ('i'), quoted identifier ('q'), string ('s'),
+// number ('n') or operator ('o')
+type sqlToken struct {
+	kind byte
+	text string
+}
+
+// parseQuery parses a SELECT statement (see Reader.Select)
+func parseQuery(query string) (*sqlQuery, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{tokens: tokens, query: &sqlQuery{limit: -1}}
+	if err := p.parse(); err != nil {
+		return nil, fmt.Errorf("simdcsv: %w in query %q", err, query)
+	}
+	return p.query, nil
+}
+
+func (p *sqlParser) parse() error {
+	if !p.keyword("SELECT") {
+		return errors.New("expected SELECT")
+	}
+	if !p.operator("*") {
+		for {
+			column, err := p.column()
+			if err != nil {
+				return err
+			}
+			p.query.columns = append(p.query.columns, column)
+			if !p.operator(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("FROM") {
+		if len(p.tokens) == 0 || p.tokens[0].kind != 'i' {
+			return errors.New("expected table after FROM")
+		}
+		p.tokens = p.tokens[1:]
+		p.keyword("AS")
+		if len(p.tokens) > 0 && p.tokens[0].kind == 'i' && !isKeyword(p.tokens[0].text) {
+			p.alias, p.tokens = p.tokens[0].text, p.tokens[1:]
+		}
+		// columns in the SELECT list qualified by the alias
+		for i, c := range p.query.columns {
+			if prefix := p.alias + "."; p.alias != "" && strings.HasPrefix(c.name, prefix) {
+				p.query.columns[i] = sqlColumnNamed(c.name[len(prefix):])
+			}
+		}
+	}
+	if p.keyword("WHERE") {
+		where, err := p.or()
+		if err != nil {
+			return err
+		}
+		p.query.where = where
+	}
+	if p.keyword("LIMIT") {
+		if len(p.tokens) == 0 || p.tokens[0].kind != 'n' {
+			return errors.New("expected number after LIMIT")
+		}
+		limit, err := strconv.Atoi(p.tokens[0].text)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid LIMIT %s", p.tokens[0].text)
+		}
+		p.query.limit, p.tokens = limit, p.tokens[1:]
+	}
+	if len(p.tokens) > 0 {
+		return fmt.Errorf("unexpected %q", p.tokens[0].text)
+	}
+	for _, c := range append(p.query.columns, p.query.refs...) {
+		p.query.header = p.query.header || c.name != ""
+	}
+	return nil
+}
+
+// keyword consumes the next token if it is keyword k
+func (p *sqlParser) keyword(k string) bool {
+	if len(p.tokens) > 0 && p.tokens[0].kind == 'i' && strings.EqualFold(p.tokens[0].text, k) {
+		p.tokens = p.tokens[1:]
+		return true
+	}
+	return false
+}
+
+// operator consumes the next token if it is operator o
+func (p *sqlParser) operator(o string) bool {
+	if len(p.tokens) > 0 && p.tokens[0].kind == 'o' && p.tokens[0].text == o {
+		p.tokens = p.tokens[1:]
+		return true
+	}
+	return false
+}
+
+// column parses a column name, possibly qualified by the alias of the table,
+// which is resolved once the FROM clause is parsed
+func (p *sqlParser) column() (sqlColumn, error) {
+	if len(p.tokens) == 0 || p.tokens[0].kind != 'i' && p.tokens[0].kind != 'q' || p.tokens[0].kind == 'i' && isKeyword(p.tokens[0].text) {
+		return sqlColumn{}, errors.New("expected column")
+	}
+	t := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	if t.kind == 'q' {
+		return sqlColumn{name: t.text, index: -1}, nil
+	}
+	if i := strings.LastIndexByte(t.text, '.'); i >= 0 && p.alias != "" && t.text[:i] == p.alias {
+		t.text = t.text[i+1:]
+	}
+	return sqlColumnNamed(t.text), nil
+}
+
+// sqlColumnNamed returns the column of an unquoted name, which refers to a
+// column by index if it is _1, _2 and so on
+func sqlColumnNamed(name string) sqlColumn {
+	if strings.HasPrefix(name, "_") {
+		if n, err := strconv.Atoi(name[1:]); err == nil && n > 0 {
+			return sqlColumn{index: n - 1}
+		}
+	}
+	return sqlColumn{name: name, index: -1}
+}
+
+func (p *sqlParser) or() (*sqlCond, error) {
+	left, err := p.and()
+	for err == nil && p.keyword("OR") {
+		var right *sqlCond
+		right, err = p.and()
+		left = &sqlCond{op: "OR", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *sqlParser) and() (*sqlCond, error) {
+	left, err := p.not()
+	for err == nil && p.keyword("AND") {
+		var right *sqlCond
+		right, err = p.not()
+		left = &sqlCond{op: "AND", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *sqlParser) not() (*sqlCond, error) {
+	if p.keyword("NOT") {
+		c, err := p.not()
+		return &sqlCond{op: "NOT", left: c}, err
+	}
+	if p.operator("(") {
+		c, err := p.or()
+		if err == nil && !p.operator(")") {
+			err = errors.New("expected )")
+		}
+		return c, err
+	}
+	return p.predicate()
+}
+
+// predicate parses a comparison or a LIKE
+func (p *sqlParser) predicate() (*sqlCond, error) {
+	a, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if not := p.keyword("NOT"); not || p.keyword("LIKE") {
+		if not && !p.keyword("LIKE") {
+			return nil, errors.New("expected LIKE after NOT")
+		}
+		if len(p.tokens) == 0 || p.tokens[0].kind != 's' {
+			return nil, errors.New("expected pattern after LIKE")
+		}
+		c := &sqlCond{op: "LIKE", a: a, b: sqlOperand{literal: p.tokens[0].text}}
+		if not {
+			c.op = "NOT LIKE"
+		}
+		p.tokens = p.tokens[1:]
+		return c, nil
+	}
+	for _, op := range []string{"=", "!=", "<>", "<=", ">=", "<", ">"} {
+		if p.operator(op) {
+			b, err := p.operand()
+			return &sqlCond{op: op, a: a, b: b}, err
+		}
+	}
+	return nil, errors.New("expected comparison")
+}
+
+func (p *sqlParser) operand() (sqlOperand, error) {
+	if len(p.tokens) > 0 {
+		switch t := p.tokens[0]; t.kind {
+		case 's':
+			p.tokens = p.tokens[1:]
+			return sqlOperand{literal: t.text}, nil
+		case 'n':
+			p.tokens = p.tokens[1:]
+			f, err := strconv.ParseFloat(t.text, 64)
+			return sqlOperand{literal: t.text, number: f, isNumber: true}, err
+		}
+	}
+	column, err := p.column()
+	p.query.refs = append(p.query.refs, column)
+	return sqlOperand{column: &column}, err
+}
+
+// sqlKeywords are the reserved words, which cannot name columns unquoted
+var sqlKeywords = []string{"SELECT", "FROM", "AS", "WHERE", "AND", "OR", "NOT", "LIKE", "LIMIT"}
+
+func isKeyword(s string) bool {
+	for _, k := range sqlKeywords {
+		if strings.EqualFold(s, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// tokenize splits query into tokens
+func tokenize(query string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	for s := query; ; {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			return tokens, nil
+		}
+		switch c := s[0]; {
+		case c == '\'' || c == '"':
+			// quotes are escaped by doubling them
+			var text strings.Builder
+			i := 1
+			for {
+				j := strings.IndexByte(s[i:], c)
+				if j < 0 {
+					return nil, fmt.Errorf("simdcsv: unterminated %c in query %q", c, query)
+				}
+				text.WriteString(s[i : i+j])
+				i += j + 1
+				if i < len(s) && s[i] == c {
+					text.WriteByte(c)
+					i++
+					continue
+				}
+				break
+			}
+			kind := byte('s')
+			if c == '"' {
+				kind = 'q'
+			}
+			tokens, s = append(tokens, sqlToken{kind, text.String()}), s[i:]
+		case c >= '0' && c <= '9' || c == '-' || c == '.':
+			i := 1
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == 'e' || s[i] == 'E' ||
+				(s[i] == '-' || s[i] == '+') && (s[i-1] == 'e' || s[i-1] == 'E')) {
+				i++
+			}
+			tokens, s = append(tokens, sqlToken{'n', s[:i]}), s[i:]
+		case c == '_' || unicode.IsLetter(firstRune(s)):
+			i := strings.IndexFunc(s, func(r rune) bool {
+				return r != '_' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			if i < 0 {
+				i = len(s)
+			}
+			tokens, s = append(tokens, sqlToken{'i', s[:i]}), s[i:]
+		default:
+			n := 1
+			if len(s) > 1 && (s[:2] == "<=" || s[:2] == ">=" || s[:2] == "<>" || s[:2] == "!=") {
+				n = 2
+			}
+			if !strings.ContainsAny(s[:n], "*,()=<>!") || s[:n] == "!" {
+				return nil, fmt.Errorf("simdcsv: unexpected %q in query %q", s[:n], query)
+			}
+			tokens, s = append(tokens, sqlToken{'o', s[:n]}), s[n:]
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	cities := []string{"Paris", "Berlin", "Boston", "Oslo"}
+	var input strings.Builder
+	input.WriteString("id,name,price,city\n")
+	var rows [][]string
+	for i := 0; i < 5000; i++ {
+		row := []string{fmt.Sprint(i), fmt.Sprintf("name %d", i%100), fmt.Sprint(i % 97), cities[i%len(cities)]}
+		if i%10 == 0 {
+			row[1] = fmt.Sprintf("Jo\"e %d", i%100)
+		}
+		rows = append(rows, row)
+		fmt.Fprintf(&input, "%s,\"%s\",%s,%s\n", row[0], strings.ReplaceAll(row[1], `"`, `""`), row[2], row[3])
+	}
+	number := func(s string) float64 {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+
+	for _, tc := range []struct {
+		query   string
+		header  []string
+		match   func(row []string) bool
+		project func(row []string) []string
+		limit   int
+	}{
+		{"SELECT name, id FROM S3Object WHERE price > 10 AND city = 'Paris' LIMIT 5", []string{"name", "id"},
+			func(row []string) bool { return number(row[2]) > 10 && row[3] == "Paris" },
+			func(row []string) []string { return []string{row[1], row[0]} }, 5},
+		{"select * where not (city like 'B%') or id <= 3", []string{"id", "name", "price", "city"},
+			func(row []string) bool { return !strings.HasPrefix(row[3], "B") || number(row[0]) <= 3 },
+			func(row []string) []string { return row }, -1},
+		{"SELECT s._1, s._3 FROM S3Object AS s WHERE s._3 >= 50 AND _4 <> 'Oslo'", nil,
+			func(row []string) bool { return number(row[2]) >= 50 && row[3] != "Oslo" },
+			func(row []string) []string { return []string{row[0], row[2]} }, -1},
+		{`SELECT id WHERE "name" = 'Jo"e 20' OR name LIKE '%9_' AND price = id`, []string{"id"},
+			func(row []string) bool {
+				return row[1] == `Jo"e 20` || strings.HasSuffix(row[1][:len(row[1])-1], "9") && row[2] == row[0]
+			},
+			func(row []string) []string { return row[:1] }, -1},
+		{"SELECT city LIMIT 0", []string{"city"}, nil, nil, 0},
+	} {
+		var want [][]string
+		if tc.header != nil {
+			want = append(want, tc.header)
+		}
+		for _, row := range rows {
+			if tc.limit >= 0 && len(want) == tc.limit+boolIndex(tc.header != nil) {
+				break
+			}
+			if tc.match(row) {
+				want = append(want, tc.project(row))
+			}
+		}
+
+		forModes(func(mode SIMDMode, chunkSize int) {
+			for _, readAll := range []bool{false, true} {
+				r := NewReader(strings.NewReader(input.String()))
+				r.SIMD, r.ChunkSize = mode, chunkSize
+				if err := r.Select(tc.query); err != nil {
+					t.Fatalf("TestSelect(%q): %v", tc.query, err)
+				}
+				got, err := readRecords(r, readAll)
+				if err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("TestSelect(%q, %d, %d, %v): got: %d records %.3q (%v) want: %d %.3q", tc.query, mode, chunkSize, readAll, len(got), got, err, len(want), want)
+				}
+			}
+		})
+	}
+
+	for _, query := range []string{"", "DELETE FROM t", "SELECT", "SELECT a,", "SELECT a FROM", "SELECT a WHERE", "SELECT a WHERE b",
+		"SELECT a WHERE b = 'x", "SELECT a WHERE b ~ 1", "SELECT a WHERE (b = 1", "SELECT a LIMIT x", "SELECT a LIMIT -1", "SELECT a b",
+		"SELECT a WHERE b NOT = 1", "SELECT a WHERE b LIKE 1", "SELECT € FROM t"} {
+		if err := NewReader(strings.NewReader("")).Select(query); err == nil {
+			t.Errorf("TestSelect(%q): expected error", query)
+		}
+	}
+
+	r := NewReader(strings.NewReader(""))
+	r.SelectNames = []string{"a"}
+	if err := r.Select("SELECT b"); err != errSelectCombined {
+		t.Errorf("TestSelect: got: %v want: %v", err, errSelectCombined)
+	}
+}
+
+func TestLike(t *testing.T) {
+	for _, tc := range []struct {
+		s, pattern string
+		want       bool
+	}{
+		{"", "", true}, {"a", "", false}, {"abc", "abc", true}, {"abc", "a%", true}, {"abc", "%c", true},
+		{"abc", "%b%", true}, {"abc", "a_c", true}, {"abc", "a_", false}, {"aé", "a_", true}, {"abc", "%%", true},
+		{"abc", "%d%", false}, {"abcbc", "a%bc", true}, {"ab", "a%b%", true},
+	} {
+		if got := like(tc.s, tc.pattern); got != tc.want {
+			t.Errorf("TestLike(%q, %q): got: %v want: %v", tc.s, tc.pattern, got, tc.want)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"log"
 	"math/bits"
 	"reflect"
+	"strings"
 	"unsafe"
 )
 
@@ -187,6 +188,59 @@ func getPostProcRows(buf []byte, postProc []uint64, simdrecords [][]string) []po
 	return ppRowsMerged
 }
 
+// unescapeQuoted collapses every pair of double quotes in a quoted field into
+// a single quote and, if crlf, every \r\n pair into a single \n, leaving bare
+// carriage returns untouched. Both are located in a single pass by the
+// (vectorized) indexQuoteCR and the runs in between are copied in bulk into
+// a single allocation, which is only made if the field changes.
+func unescapeQuoted(s string, crlf bool) string {
+
+	var buf []byte
+	start := 0 // start of the run yet to be copied
+	for i := indexQuoteCR(s); i >= 0 && i+1 < len(s); {
+		skip := -1
+		switch {
+		case s[i] == '"' && s[i+1] == '"':
+			skip = i + 1 // escaped quote
+		case s[i] == '\r' && s[i+1] == '\n' && crlf:
+			skip = i // carriage return
+		}
+		next := i + 1
+		if skip >= 0 {
+			if buf == nil {
+				buf = make([]byte, 0, len(s)-1)
+			}
+			buf = append(buf, s[start:skip]...)
+			start, next = skip+1, i+2
+		}
+		j := indexQuoteCR(s[next:])
+		if j < 0 {
+			break
+		}
+		i = next + j
+	}
+	if buf == nil {
+		return s
+	}
+	buf = append(buf, s[start:]...)
+
+	return *(*string)(unsafe.Pointer(&buf))
+}
+
+// indexQuoteCRGeneric is indexQuoteCR for CPUs without a vectorized kernel,
+// looking for the carriage returns before the first quote only
+func indexQuoteCRGeneric(s string) int {
+	i := strings.IndexByte(s, '"')
+	before := s
+	if i >= 0 {
+		before = s[:i]
+	}
+	if j := strings.IndexByte(before, '\r'); j >= 0 {
+		return j
+	}
+	return i
+}
+
 func diffBitmask(diff1, diff2 string) (diff string) {
 	if len(diff1) != len(diff2) {
 		log.Fatalf("sizes don't match")
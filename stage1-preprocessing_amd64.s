@@ -1,4 +1,5 @@
-//+build !noasm !appengine
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
 
 // func stage1_preprocess_test(input *stage1Input, output *stage1Output)
 TEXT ·stage1_preprocess_test(SB), 7, $0
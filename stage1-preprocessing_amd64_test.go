@@ -1,3 +1,6 @@
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
+
 /*
  * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
  *
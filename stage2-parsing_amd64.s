@@ -1,4 +1,5 @@
-//+build !noasm !appengine
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
 
 // See Input struct
 #define INPUT_BASE   0x38
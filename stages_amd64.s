@@ -1,4 +1,5 @@
-//+build !noasm !appengine
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
 
 #define CREATE_MASK(_Y1, _Y2, _R1, _R2) \
 	VPMOVMSKB _Y1, _R1 \
@@ -0,0 +1,51 @@
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
+
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+// simdKernels lists the kernels running the SIMD stages, in order of preference
+var simdKernels = []*kernel{
+	{"neon", SupportedCPU, true, nil, nil, false},
+	swarKernel,
+}
+
+// SupportedCPU will return whether the CPU is supported.
+// NEON is a mandatory part of ARMv8, so every arm64 CPU is.
+func SupportedCPU() bool {
+	return true
+}
+
+// scanBlockNEON classifies the bytes of a single block using NEON instructions
+//
+//go:noescape
+func scanBlockNEON(block *[64]byte, separatorChar byte, masks *[4]uint64)
+
+// scanBufferNEON is the blockScanner using NEON instructions
+func scanBufferNEON(buf []byte, offset uint64, separatorChar byte, masks *[4]uint64) {
+	var block [64]byte
+	scanBlockNEON(loadBlock(buf, offset, &block), separatorChar, masks)
+}
+
+func stage1_preprocess_buffer(buf []byte, separatorChar uint64, input1 *stage1Input, output1 *stage1Output, postProc *[]uint64, offset uint64, masks []uint64, masksOffset uint64) (processed, masksWritten uint64) {
+	return stage1PreprocessBufferGeneric(buf, separatorChar, input1, output1, postProc, offset, masks, masksOffset, scanBufferNEON)
+}
+
+func _stage2_parse_masks(buf []byte, masks []uint64, lastCharIsDelimiter uint64, rows []uint64, columns []string, input2 *inputStage2, offset uint64, output2 *outputAsm) (processed, masksRead uint64) {
+	return stage2ParseMasksGeneric(buf, masks, lastCharIsDelimiter, rows, columns, input2, offset, output2)
+}
@@ -0,0 +1,57 @@
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
+
+#include "textflag.h"
+
+// MOVEMASK sets a bit in R for every byte of V0-V3 that equals the bytes of V30,
+// by weighing the comparison results with V31 and adding them up pairwise
+#define MOVEMASK(R) \
+	VCMEQ V30.B16, V0.B16, V4.B16  \
+	VCMEQ V30.B16, V1.B16, V5.B16  \
+	VCMEQ V30.B16, V2.B16, V6.B16  \
+	VCMEQ V30.B16, V3.B16, V7.B16  \
+	VAND  V31.B16, V4.B16, V4.B16  \
+	VAND  V31.B16, V5.B16, V5.B16  \
+	VAND  V31.B16, V6.B16, V6.B16  \
+	VAND  V31.B16, V7.B16, V7.B16  \
+	VADDP V5.B16, V4.B16, V4.B16   \
+	VADDP V7.B16, V6.B16, V6.B16   \
+	VADDP V6.B16, V4.B16, V4.B16   \
+	VADDP V4.B16, V4.B16, V4.B16   \
+	VMOV  V4.D[0], R
+
+// func scanBlockNEON(block *[64]byte, separatorChar byte, masks *[4]uint64)
+TEXT ·scanBlockNEON(SB), NOSPLIT, $0-24
+	MOVD  block+0(FP), R0
+	MOVBU separatorChar+8(FP), R1
+	MOVD  masks+16(FP), R2
+
+	VLD1 (R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+
+	MOVD $0x8040201008040201, R3 // weight of each byte within a group of 8
+	VMOV R3, V31.D[0]
+	VMOV R3, V31.D[1]
+
+	// quote mask
+	MOVD $0x22, R3
+	VDUP R3, V30.B16
+	MOVEMASK(R4)
+	MOVD R4, 0(R2)
+
+	// separator mask
+	VDUP R1, V30.B16
+	MOVEMASK(R4)
+	MOVD R4, 8(R2)
+
+	// carriage return
+	MOVD $0x0d, R3
+	VDUP R3, V30.B16
+	MOVEMASK(R4)
+	MOVD R4, 16(R2)
+
+	// newline
+	MOVD $0x0a, R3
+	VDUP R3, V30.B16
+	MOVEMASK(R4)
+	MOVD R4, 24(R2)
+	RET
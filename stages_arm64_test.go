@@ -0,0 +1,43 @@
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
+
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestScanBlockNEON(t *testing.T) {
+	const alphabet = "ab,;\"\r\n\x00\xff"
+	rng := rand.New(rand.NewSource(292))
+	for i := 0; i < 1000; i++ {
+		var block [64]byte
+		for j := range block {
+			block[j] = alphabet[rng.Intn(len(alphabet))]
+		}
+		separatorChar := alphabet[rng.Intn(4)]
+		var got, want [4]uint64
+		scanBlockNEON(&block, separatorChar, &got)
+		scanBlock(&block, separatorChar, &want)
+		if got != want {
+			t.Fatalf("TestScanBlockNEON: %q: got: %x want: %x", block, got, want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
+
+// See stage1Input struct
+#define QUOTE_MASK_IN           0
+#define SEPARATOR_MASK_IN       8
+#define CARRIAGE_RETURN_MASK_IN 16
+#define QUOTE_MASK_IN_NEXT      24
+#define NEWLINE_MASK_IN         40
+#define NEWLINE_MASK_IN_NEXT    48
+
+// See stage1Output struct
+#define QUOTE_MASK_OUT            0
+#define SEPARATOR_MASK_OUT        8
+#define CARRIAGE_RETURN_MASK_OUT  16
+#define NEEDS_POST_PROCESSING_OUT 24
+
+// Offsets for  masks slice
+#define MASKS_NEWLINE_OFFSET   0
+#define MASKS_SEPARATOR_OFFSET 8
+#define MASKS_QUOTE_OFFSET     16
+#define MASKS_ELEM_SIZE        24
+
+#define Z_QUOTE_CHAR Z5
+#define Z_SEPARATOR  Z4
+#define Z_CARRIAGE_R Z3
+#define Z_NEWLINE    Z2
+
+#define ADD_TRAILING_NEWLINE \
+	MOVQ $1, AX \
+	SHLQ CX, AX \ // only lower 6 bits are taken into account, which is good for current and next ZMM words
+	ORQ  AX, BX
+
+// LOAD_BLOCK loads the 64 bytes at OFF(DI)(DX*1) into ZMM, with the bytes
+// beyond the end of the buffer zeroed (and not accessed)
+#define LOAD_BLOCK(OFF, ZMM) \
+	MOVQ       buf_len+8(FP), CX                \
+	SUBQ       DX, CX                           \
+	SUBQ       $OFF, CX                         \ // bytes remaining from the block on
+	XORQ       R8, R8                           \
+	CMPQ       CX, R8                           \
+	CMOVQLT    R8, CX                           \
+	MOVQ       $64, R8                          \
+	CMPQ       CX, R8                           \
+	CMOVQGT    R8, CX                           \
+	MOVQ       $-1, AX                          \
+	BZHIQ      CX, AX, AX                       \
+	KMOVQ      AX, K2                           \
+	VMOVDQU8.Z OFF(DI)(DX*1), K2, ZMM
+
+// func stage1_preprocess_buffer_avx512(buf []byte, separatorChar uint64, input1 *stage1Input, output1 *stage1Output, postProc *[]uint64, offset uint64, masks []uint64, masksOffset uint64) (processed, masksWritten uint64)
+TEXT ·stage1_preprocess_buffer_avx512(SB), 7, $0
+
+	MOVL         $0x0a, AX                // character for newline
+	VPBROADCASTB AX, Z_NEWLINE
+	MOVL         $0x0d, AX                // character for carriage return
+	VPBROADCASTB AX, Z_CARRIAGE_R
+	MOVQ         separatorChar+24(FP), AX // get character for separator
+	VPBROADCASTB AX, Z_SEPARATOR
+	MOVL         $0x22, AX                // character for quote
+	VPBROADCASTB AX, Z_QUOTE_CHAR
+
+	MOVQ buf+0(FP), DI
+	MOVQ offset+56(FP), DX
+	MOVQ masks_base+64(FP), R11
+	MOVQ masksOffset+88(FP), R12
+	ADDQ $6, R12                 // advance indexing register by 6, for easy comparisons to max length of slice
+
+	// blocks are loaded in pairs of 128 bytes, the second of which is kept in Z7
+	LOAD_BLOCK(0, Z6)
+	LOAD_BLOCK(0x40, Z7)
+
+	MOVQ input1+32(FP), SI
+
+	// quote mask
+	VPCMPEQB Z_QUOTE_CHAR, Z6, K1
+	KMOVQ    K1, CX
+	MOVQ     CX, QUOTE_MASK_IN_NEXT(SI) // store in next slot, so that it gets copied back
+
+	// newline
+	VPCMPEQB Z_NEWLINE, Z6, K1
+	KMOVQ    K1, BX
+
+	MOVQ buf_len+8(FP), CX
+	CMPQ CX, $64
+	JGE  skipAddTrailingNewlinePrologue
+	ADD_TRAILING_NEWLINE
+
+skipAddTrailingNewlinePrologue:
+	MOVQ BX, NEWLINE_MASK_IN_NEXT(SI)                           // store in next slot, so that it gets copied back
+	MOVQ BX, MASKS_NEWLINE_OFFSET-MASKS_ELEM_SIZE*2(R11)(R12*8)
+
+loop:
+	MOVQ input1+32(FP), SI
+
+	// copy next masks to current slot (for quote mask and newline mask)
+	MOVQ QUOTE_MASK_IN_NEXT(SI), CX
+	MOVQ CX, QUOTE_MASK_IN(SI)
+	MOVQ NEWLINE_MASK_IN_NEXT(SI), CX
+	MOVQ CX, NEWLINE_MASK_IN(SI)
+
+	// separator mask
+	VPCMPEQB Z_SEPARATOR, Z6, K1
+	KMOVQ    K1, CX
+	MOVQ     CX, SEPARATOR_MASK_IN(SI)
+
+	// carriage return
+	VPCMPEQB Z_CARRIAGE_R, Z6, K1
+	KMOVQ    K1, CX
+	MOVQ     CX, CARRIAGE_RETURN_MASK_IN(SI)
+
+	// is the next block the second of the current pair?
+	MOVQ  DX, AX
+	SUBQ  offset+56(FP), AX
+	TESTQ $0x40, AX
+	JNZ   loadPair
+	VMOVDQA64 Z7, Z6
+	JMP   skipLoadPair
+
+loadPair:
+	LOAD_BLOCK(0x40, Z6)
+	LOAD_BLOCK(0x80, Z7)
+
+skipLoadPair:
+	VPCMPEQB Z_QUOTE_CHAR, Z6, K1
+	KMOVQ    K1, CX
+	MOVQ     CX, QUOTE_MASK_IN_NEXT(SI)
+
+	// newline mask for next ZMM word
+	VPCMPEQB Z_NEWLINE, Z6, K1
+	KMOVQ    K1, BX
+
+	// Write unaltered newline mask into next slot already
+	MOVQ BX, MASKS_NEWLINE_OFFSET-MASKS_ELEM_SIZE(R11)(R12*8)
+
+	MOVQ buf_len+8(FP), CX
+	SUBQ DX, CX
+	JLT  skipAddTrailingNewline
+	ADD_TRAILING_NEWLINE
+
+skipAddTrailingNewline:
+	MOVQ BX, NEWLINE_MASK_IN_NEXT(SI)
+
+	PUSHQ R12
+	PUSHQ R11
+	PUSHQ DI
+	PUSHQ DX
+	MOVQ  input1+32(FP), AX
+	MOVQ  output1+40(FP), R10
+	CALL  ·stage1_preprocess(SB)
+	POPQ  DX
+	POPQ  DI
+	POPQ  R11
+	POPQ  R12
+
+	MOVQ output1+40(FP), R10
+
+	// write out masks to slice
+	MOVQ QUOTE_MASK_OUT(R10), AX
+	MOVQ AX, MASKS_QUOTE_OFFSET-MASKS_ELEM_SIZE*2(R11)(R12*8)
+	MOVQ SEPARATOR_MASK_OUT(R10), AX
+	MOVQ AX, MASKS_SEPARATOR_OFFSET-MASKS_ELEM_SIZE*2(R11)(R12*8)
+	MOVQ CARRIAGE_RETURN_MASK_OUT(R10), AX
+	ORQ  AX, MASKS_NEWLINE_OFFSET-MASKS_ELEM_SIZE*2(R11)(R12*8)
+	ADDQ $3, R12
+
+	MOVQ output1+40(FP), R10
+	CMPQ NEEDS_POST_PROCESSING_OUT(R10), $1
+	JNZ  unmodified
+
+	MOVQ postProc+48(FP), AX
+	MOVQ 0(AX), BX
+	MOVQ 8(AX), CX
+	MOVQ DX, (BX)(CX*8)
+	INCQ 8(AX)
+	INCQ CX
+	ADDQ $0x40, DX
+	CMPQ CX, 16(AX)          // slice is full?
+	JGE  exit
+	SUBQ $0x40, DX
+
+unmodified:
+	ADDQ $0x40, DX
+
+	CMPQ R12, masks_len+72(FP) // still space in masks slice?
+	JGE  exit
+
+	CMPQ DX, buf_len+8(FP)
+	JLT  loop
+
+exit:
+	VZEROUPPER
+	MOVQ DX, processed+96(FP)
+	SUBQ $6, R12
+	MOVQ R12, masksWritten+104(FP)
+	RET
@@ -0,0 +1,111 @@
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
+
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestStage1AVX512(t *testing.T) {
+	if !supportedAVX512() || !SupportedCPU() {
+		t.Skip("requires AVX-512")
+	}
+
+	rng := rand.New(rand.NewSource(293))
+	for i := 0; i < 500; i++ {
+		buf := randomCsv(rng, 1+rng.Intn(2000))
+		if i%2 == 1 {
+			const alphabet = "ab;,\"\"\r\n\n"
+			for j := range buf {
+				buf[j] = alphabet[rng.Intn(len(alphabet))]
+			}
+		}
+		wantPostProc := make([]uint64, 0, 4)
+		want, wantPostProc, wantQuoted := stage1PreprocessBufferEx(buf, ',', 0, nil, &wantPostProc)
+		gotPostProc := make([]uint64, 0, 4)
+		got, gotPostProc, gotQuoted := stage1PreprocessBufferWith(stage1_preprocess_buffer_avx512, buf, ',', 0, nil, &gotPostProc)
+		if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(gotPostProc, wantPostProc) || gotQuoted != wantQuoted {
+			t.Fatalf("TestStage1AVX512: %q: got: %v %v %d want: %v %v %d", buf, got, gotPostProc, gotQuoted, want, wantPostProc, wantQuoted)
+		}
+	}
+}
+
+func TestDisableAVX512(t *testing.T) {
+	if !supportedAVX512() {
+		t.Skip("requires AVX-512")
+	}
+
+	r := NewReader(nil)
+	if k := r.simdKernel(); k == nil || k.name != "avx512" {
+		t.Errorf("TestDisableAVX512: got: %v want: avx512", k)
+	}
+	r.DisableAVX512 = true
+	if k := r.simdKernel(); k == nil || k.name != "avx2" {
+		t.Errorf("TestDisableAVX512: got: %v want: avx2", k)
+	}
+}
+
+// BenchmarkStage1AVX512 compares stage 1 with AVX-512 to stage 1 with AVX2,
+// which the AVX-512 kernel replaces
+func BenchmarkStage1AVX512(b *testing.B) {
+	if !supportedAVX512() {
+		b.Skip("requires AVX-512")
+	}
+	for _, kernel := range []struct {
+		name   string
+		stage1 stage1Func
+	}{
+		{"avx512", stage1_preprocess_buffer_avx512},
+		{"avx2", stage1_preprocess_buffer},
+	} {
+		b.Run(kernel.name+"/parking-citations-100K", func(b *testing.B) {
+			benchmarkStage1AVX512(b, kernel.stage1, "testdata/parking-citations-100K.csv")
+		})
+		b.Run(kernel.name+"/worldcitiespop-100K", func(b *testing.B) {
+			benchmarkStage1AVX512(b, kernel.stage1, "testdata/worldcitiespop-100K.csv")
+		})
+		b.Run(kernel.name+"/nyc-taxi-data-100K", func(b *testing.B) {
+			benchmarkStage1AVX512(b, kernel.stage1, "testdata/nyc-taxi-data-100K.csv")
+		})
+	}
+}
+
+func benchmarkStage1AVX512(b *testing.B, stage1 stage1Func, filename string) {
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+
+	postProc := make([]uint64, 0, len(buf)>>6)
+	masks := allocMasks(buf)
+
+	for i := 0; i < b.N; i++ {
+		postProc = postProc[:0]
+		stage1PreprocessBufferWith(stage1, buf, uint64(','), 0, &masks, &postProc)
+	}
+}
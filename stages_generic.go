@@ -0,0 +1,318 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"math/bits"
+	"reflect"
+	"unsafe"
+)
+
+// A blockScanner sets the bits of the quotes, separators, carriage returns
+// and newlines (in that order) of the 64-byte block of buf at offset in masks,
+// with the block zero padded beyond the end of buf
+type blockScanner func(buf []byte, offset uint64, separatorChar byte, masks *[4]uint64)
+
+// scanBuffer is the blockScanner written in plain Go
+func scanBuffer(buf []byte, offset uint64, separatorChar byte, masks *[4]uint64) {
+	var block [64]byte
+	scanBlock(loadBlock(buf, offset, &block), separatorChar, masks)
+}
+
+// scanBlock classifies the bytes of a single block for scanBuffer
+func scanBlock(block *[64]byte, separatorChar byte, masks *[4]uint64) {
+	*masks = [4]uint64{}
+	for i, c := range block {
+		if c == '"' {
+			masks[0] |= 1 << i
+		}
+		if c == separatorChar {
+			masks[1] |= 1 << i
+		}
+		if c == '\r' {
+			masks[2] |= 1 << i
+		}
+		if c == '\n' {
+			masks[3] |= 1 << i
+		}
+	}
+}
+
+// loadBlock returns the 64-byte block of buf at offset, zero padded beyond the end of buf
+func loadBlock(buf []byte, offset uint64, block *[64]byte) *[64]byte {
+	if offset+64 <= uint64(len(buf)) {
+		return (*[64]byte)(buf[offset : offset+64])
+	}
+	*block = [64]byte{}
+	if offset < uint64(len(buf)) {
+		copy(block[:], buf[offset:])
+	}
+	return block
+}
+
+// A stage1Func preprocesses buf from offset on, as stage1_preprocess_buffer does
+type stage1Func func(buf []byte, separatorChar uint64, input1 *stage1Input, output1 *stage1Output, postProc *[]uint64, offset uint64, masks []uint64, masksOffset uint64) (processed, masksWritten uint64)
+
+// stage1PreprocessBufferWith is stage1PreprocessBufferEx running preprocess
+// in place of stage1_preprocess_buffer
+func stage1PreprocessBufferWith(preprocess stage1Func, buf []byte, separatorChar, quoted uint64, masks *[]uint64, postProc *[]uint64) ([]uint64, []uint64, uint64) {
+
+	if postProc == nil {
+		_postProc := make([]uint64, 0, 128)
+		postProc = &_postProc
+	}
+
+	if masks == nil {
+		_masks := allocMasks(buf)
+		masks = &_masks
+	}
+
+	processed, masksOffset := uint64(0), uint64(0)
+	inputStage1, outputStage1 := stage1Input{}, stage1Output{}
+	inputStage1.quoted = quoted
+	for {
+		processed, masksOffset = preprocess(buf, separatorChar, &inputStage1, &outputStage1, postProc, processed, *masks, masksOffset)
+
+		if processed >= uint64(len(buf)) {
+			break
+		}
+		if masksOffset >= uint64(len(*masks)) {
+			break
+		}
+
+		// Check if we need to grow the slice for keeping track of the lines to post process
+		if len(*postProc) >= cap(*postProc)/2 {
+			_postProc := make([]uint64, len(*postProc), cap(*postProc)*2)
+			copy(_postProc, (*postProc)[:])
+			postProc = &_postProc
+		}
+	}
+
+	return (*masks)[:masksOffset], *postProc, inputStage1.quoted
+}
+
+// stage1PreprocessBufferGeneric is stage1_preprocess_buffer for architectures
+// that only need to provide a blockScanner, it matches the assembly bit for bit
+func stage1PreprocessBufferGeneric(buf []byte, separatorChar uint64, input1 *stage1Input, output1 *stage1Output, postProc *[]uint64, offset uint64, masks []uint64, masksOffset uint64, scan blockScanner) (processed, masksWritten uint64) {
+
+	var in [4]uint64
+
+	scan(buf, offset, byte(separatorChar), &in)
+	input1.quoteMaskInNext = in[0]
+	newlines := in[3]
+	if len(buf) < 64 {
+		newlines |= 1 << (uint64(len(buf)) & 63)
+	}
+	input1.newlineMaskInNext = newlines
+	masks[masksOffset] = newlines
+
+	for {
+		// copy next masks to current slot (for quote mask and newline mask)
+		input1.quoteMaskIn = input1.quoteMaskInNext
+		input1.newlineMaskIn = input1.newlineMaskInNext
+		input1.separatorMaskIn = in[1]
+		input1.carriageReturnMaskIn = in[2]
+
+		scan(buf, offset+64, byte(separatorChar), &in)
+		input1.quoteMaskInNext = in[0]
+		masks[masksOffset+3] = in[3] // write unaltered newline mask into next slot already
+		input1.newlineMaskInNext = in[3] | 1<<(uint64(len(buf))&63)
+
+		preprocessMasks(input1, output1)
+
+		masks[masksOffset+2] = output1.quoteMaskOut
+		masks[masksOffset+1] = output1.separatorMaskOut
+		masks[masksOffset] |= output1.carriageReturnMaskOut
+		masksOffset += 3
+
+		offset += 0x40
+		if output1.needsPostProcessing == 1 {
+			*postProc = append(*postProc, offset-0x40)
+			if len(*postProc) >= cap(*postProc) { // slice is full?
+				break
+			}
+		}
+
+		if masksOffset+6 >= uint64(len(masks)) || offset >= uint64(len(buf)) {
+			break
+		}
+	}
+
+	return offset, masksOffset
+}
+
+// columnWords returns the pointer and size words of columns: as the
+// assembly does, they are written free of write barriers, which is fine
+// since they point into the chunk being parsed
+func columnWords(columns []string) (words []uintptr) {
+	hdr := (*reflect.SliceHeader)(unsafe.Pointer(&words))
+	hdr.Data = (*reflect.SliceHeader)(unsafe.Pointer(&columns)).Data
+	hdr.Len, hdr.Cap = 2*len(columns), 2*len(columns)
+	return
+}
+
+// stage2ParseMasksGeneric is _stage2_parse_masks in plain Go
+func stage2ParseMasksGeneric(buf []byte, masks []uint64, lastCharIsDelimiter uint64, rows []uint64, columns []string, input2 *inputStage2, offset uint64, output2 *outputAsm) (processed, masksRead uint64) {
+
+	base := (*reflect.SliceHeader)(unsafe.Pointer(&buf)).Data
+
+	words := columnWords(columns)
+
+	for {
+		// check whether there is still enough reserved space in the rows and columns destination buffer
+		if output2.index/2+64 >= len(columns) || output2.line+64 >= len(rows) {
+			break
+		}
+
+		input2.delimiterMask = masks[masksRead]
+		if offset+0x40 > uint64(len(buf)) && lastCharIsDelimiter != 1 {
+			// OR in closing delimiter into last delimiter mask
+			input2.delimiterMask |= 1 << (uint64(len(buf)) & 63)
+		}
+		input2.separatorMask = masks[masksRead+1]
+		input2.quoteMask = masks[masksRead+2]
+
+		stage2ParseBlock(base, input2, offset, output2, rows, words)
+
+		offset += 0x40
+		masksRead += 3
+		if masksRead > uint64(len(masks)) {
+			break
+		}
+		if offset < uint64(len(buf)) {
+			continue
+		}
+
+		// in case we end exactly on a 64-byte boundary, simulate a last "trailing"
+		// delimiter, but only if the buffer is not already terminated by a delimiter
+		if offset == uint64(len(buf)) && lastCharIsDelimiter != 1 {
+			input2.delimiterMask, input2.separatorMask, input2.quoteMask = 1, 0, 0
+			stage2ParseBlock(base, input2, offset, output2, rows, words)
+		}
+		break
+	}
+
+	return offset, masksRead
+}
+
+// stage2ParseBlock is stage2ParseMasks writing to the rows and the words
+// of the columns as the assembly does, with base the address of the buffer
+func stage2ParseBlock(base uintptr, input *inputStage2, offset uint64, output *outputAsm, rows []uint64, columns []uintptr) {
+
+	const clearMask = 0xfffffffffffffffe
+
+	separatorPos := bits.TrailingZeros64(input.separatorMask)
+	delimiterPos := bits.TrailingZeros64(input.delimiterMask)
+	quotePos := bits.TrailingZeros64(input.quoteMask)
+
+	for {
+		if separatorPos < delimiterPos && separatorPos < quotePos {
+
+			if input.quoted == 0 {
+				// verify that last closing quote is immediately followed by either a separator or delimiter
+				if input.lastClosingQuote > 0 &&
+					input.lastClosingQuote+1 != uint64(separatorPos)+offset {
+					if input.errorOffset == 0 {
+						input.errorOffset = uint64(separatorPos) + offset // mark first error position
+					}
+				}
+				input.lastClosingQuote = 0
+
+				columns[output.index] = base + uintptr(output.strData) // pointer to start of element
+				output.index++
+				columns[output.index] = uintptr((-output.strLen + uint64(separatorPos) + offset) - output.strData) // size of element
+				output.index++
+				output.strData = uint64(separatorPos) + offset + 1 // start of next element
+				output.strLen = 0
+
+				input.lastSeparatorOrDelimiter = uint64(separatorPos) + offset
+			}
+
+			input.separatorMask &= clearMask << separatorPos
+			separatorPos = bits.TrailingZeros64(input.separatorMask)
+
+		} else if delimiterPos < separatorPos && delimiterPos < quotePos {
+
+			if input.quoted == 0 {
+				// verify that last closing quote is immediately followed by either a separator or delimiter
+				if input.lastClosingQuote > 0 &&
+					input.lastClosingQuote+1 != uint64(delimiterPos)+offset {
+					if input.errorOffset == 0 {
+						input.errorOffset = uint64(delimiterPos) + offset // mark first error position
+					}
+				}
+				input.lastClosingQuote = 0
+
+				// for delimiters, we may end exactly on a separator (without a delimiter following),
+				// this leads to a length of zero for the string, so we nil out the pointer value
+				// (to avoid potentially pointing exactly at the first available byte after the buffer)
+				size := (-output.strLen + uint64(delimiterPos) + offset) - output.strData
+				if size == 0 {
+					columns[output.index] = 0 // pointer to start of element
+				} else {
+					columns[output.index] = base + uintptr(output.strData) // pointer to start of element
+				}
+				output.index++
+				columns[output.index] = uintptr(size) // size of element
+				output.index++
+				output.strData = uint64(delimiterPos) + offset + 1 // start of next element
+				output.strLen = 0
+
+				if uint64(output.index)/2-output.indexPrev == 1 && size == 0 {
+					// prevent empty lines from being written
+				} else {
+					// write out start and length for a new row
+					rows[output.line] = output.indexPrev
+					output.line++
+					rows[output.line] = uint64(output.index)/2 - output.indexPrev
+					output.line++
+				}
+
+				output.indexPrev = uint64(output.index) / 2 // keep current index for next round
+
+				input.lastSeparatorOrDelimiter = uint64(delimiterPos) + offset
+			}
+
+			input.delimiterMask &= clearMask << delimiterPos
+			delimiterPos = bits.TrailingZeros64(input.delimiterMask)
+
+		} else if quotePos < separatorPos && quotePos < delimiterPos {
+
+			if input.quoted == 0 {
+				// check that this opening quote is preceded by either a separator or delimiter
+				if input.lastSeparatorOrDelimiter+1 != uint64(quotePos)+offset {
+					if input.errorOffset == 0 {
+						input.errorOffset = uint64(quotePos) + offset
+					}
+				}
+				output.strData += 1 // skip over starting quote
+			} else {
+				output.strLen += 1 // exclude closing quote
+				input.lastClosingQuote = uint64(quotePos) + offset
+			}
+
+			input.quoted = ^input.quoted
+
+			input.quoteMask &= clearMask << quotePos
+			quotePos = bits.TrailingZeros64(input.quoteMask)
+
+		} else {
+			// we must be done
+			break
+		}
+	}
+}
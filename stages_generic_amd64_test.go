@@ -0,0 +1,150 @@
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
+
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// stage1Generic runs stage1PreprocessBufferEx with stage1PreprocessBufferGeneric in place of the assembly
+func stage1Generic(buf []byte, separatorChar uint64, masks []uint64, postProc []uint64) ([]uint64, []uint64, uint64) {
+	processed, masksOffset := uint64(0), uint64(0)
+	input, output := stage1Input{}, stage1Output{}
+	for {
+		processed, masksOffset = stage1PreprocessBufferGeneric(buf, separatorChar, &input, &output, &postProc, processed, masks, masksOffset, scanBuffer)
+		if processed >= uint64(len(buf)) || masksOffset >= uint64(len(masks)) {
+			break
+		}
+		if len(postProc) >= cap(postProc)/2 {
+			postProc = append(make([]uint64, 0, cap(postProc)*2), postProc...)
+		}
+	}
+	return masks[:masksOffset], postProc, input.quoted
+}
+
+// stage2Generic parses buf the way stage2ParseBufferEx does with stage2ParseMasksGeneric in place of the assembly
+func stage2Generic(buf []byte, masks []uint64, rows []uint64, columns []string, lastCharIsDelimiter uint64) ([]uint64, []string, uint64) {
+	input, output := newInputStage2(), outputAsm{}
+	offset, masksOffset := uint64(0), uint64(0)
+	for {
+		processed, masksRead := stage2ParseMasksGeneric(buf, masks[masksOffset:], lastCharIsDelimiter, rows, columns, &input, offset, &output)
+		if input.errorOffset != 0 || int(processed) >= len(buf) {
+			break
+		}
+		offset, masksOffset = processed, masksOffset+masksRead
+		rows = append(rows[:output.line], make([]uint64, len(rows))...)
+		columns = append(columns[:output.index/2], make([]string, len(columns))...)
+	}
+	return rows[:output.line], columns[:output.index/2], input.errorOffset
+}
+
+// randomCsv returns well-formed CSV of about n bytes, with quoted fields
+// holding separators, newlines, carriage returns and escaped quotes
+func randomCsv(rng *rand.Rand, n int) []byte {
+	const unquoted, quoted = "abc", "ab,\r\n\"\""
+	var buf []byte
+	for len(buf) < n {
+		for f := rng.Intn(4); ; f-- {
+			if rng.Intn(3) == 0 {
+				buf = append(buf, '"')
+				for j := rng.Intn(12); j > 0; j-- {
+					if c := quoted[rng.Intn(len(quoted))]; c == '"' {
+						buf = append(buf, '"', '"')
+					} else {
+						buf = append(buf, c)
+					}
+				}
+				buf = append(buf, '"')
+			} else {
+				for j := rng.Intn(12); j > 0; j-- {
+					buf = append(buf, unquoted[rng.Intn(len(unquoted))])
+				}
+			}
+			if f <= 0 {
+				break
+			}
+			buf = append(buf, ',')
+		}
+		if rng.Intn(2) == 0 {
+			buf = append(buf, '\r')
+		}
+		buf = append(buf, '\n')
+	}
+	if len(buf) > 0 {
+		buf = buf[:len(buf)-rng.Intn(2)]
+	}
+	return buf
+}
+
+func TestStagesGeneric(t *testing.T) {
+	if !SupportedCPU() {
+		t.Skip("requires AVX2")
+	}
+
+	const alphabet = "ab,,\"\"\r\n\n"
+	rng := rand.New(rand.NewSource(292))
+	for i := 0; i < 2000; i++ {
+		buf := make([]byte, rng.Intn(400))
+		for j := range buf {
+			buf[j] = alphabet[rng.Intn(len(alphabet))]
+		}
+		if i%2 == 0 {
+			buf = randomCsv(rng, len(buf))
+		}
+		if len(buf) == 0 {
+			continue
+		}
+
+		masks := make([]uint64, len(allocMasks(buf))+3*rng.Intn(3))
+		postProc := make([]uint64, 0, 4)
+		want, wantPostProc, wantQuoted := stage1PreprocessBufferEx(buf, ',', 0, &masks, &postProc)
+		want = append([]uint64{}, want...)
+		got, gotPostProc, gotQuoted := stage1Generic(buf, ',', make([]uint64, len(masks)), make([]uint64, 0, 4))
+		if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(gotPostProc, wantPostProc) || gotQuoted != wantQuoted {
+			t.Fatalf("TestStagesGeneric: %q: got: %v %v %d want: %v %v %d", buf, got, gotPostProc, gotQuoted, want, wantPostProc, wantQuoted)
+		}
+		lastCharIsDelimiter := uint64(0)
+		if len(buf) > 0 && (buf[len(buf)-1] == '\n' || buf[len(buf)-1] == '\r') {
+			lastCharIsDelimiter = 1
+		}
+		wantInput, wantOutput := newInputStage2(), outputAsm{}
+		wantRows, wantColumns := make([]uint64, 1024), make([]string, 1024)
+		_, _ = _stage2_parse_masks(buf, want, lastCharIsDelimiter, wantRows, wantColumns, &wantInput, 0, &wantOutput)
+		gotRows, gotColumns, gotError := stage2Generic(buf, want, make([]uint64, 65+rng.Intn(8)), make([]string, 65+rng.Intn(8)), lastCharIsDelimiter)
+		if gotError != wantInput.errorOffset {
+			t.Fatalf("TestStagesGeneric: %q: got: error at %d want: error at %d", buf, gotError, wantInput.errorOffset)
+		}
+		if gotError != 0 {
+			continue
+		}
+		wantRows, wantColumns = wantRows[:wantOutput.line], wantColumns[:wantOutput.index/2]
+		if !reflect.DeepEqual(gotRows, wantRows) || !reflect.DeepEqual(gotColumns, wantColumns) {
+			t.Fatalf("TestStagesGeneric: %q: got: %v %q want: %v %q", buf, gotRows, gotColumns, wantRows, wantColumns)
+		}
+		for j := range gotColumns {
+			if g, w := (*reflect.StringHeader)(unsafe.Pointer(&gotColumns[j])).Data, (*reflect.StringHeader)(unsafe.Pointer(&wantColumns[j])).Data; g != w {
+				t.Fatalf("TestStagesGeneric: %q: column %d: got: %x want: %x", buf, j, g, w)
+			}
+		}
+	}
+}
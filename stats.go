@@ -0,0 +1,78 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// Stats holds counters of the work done by a Reader (see Reader.Stats).
+type Stats struct {
+	BytesRead     int64 // bytes read from the input, including any preamble
+	Chunks        int64 // chunks preprocessed by stage 1
+	Records       int64 // records handed to the consumer
+	Fallbacks     int64 // times encoding/csv parsed (part of) the input instead of the SIMD stages
+	UnescapedRows int64 // rows passed over to unescape quotes or normalize line endings (all rows of blocks needing it)
+}
+
+// readerStats holds the counters of Stats, which the stages update concurrently
+type readerStats struct {
+	bytesRead     atomic.Int64
+	chunks        atomic.Int64
+	records       atomic.Int64
+	fallbacks     atomic.Int64
+	unescapedRows atomic.Int64
+}
+
+// Stats returns the counters of the work done so far. It is safe to call
+// while parsing, from any goroutine.
+func (r *Reader) Stats() Stats {
+	return Stats{
+		BytesRead:     r.stats.bytesRead.Load(),
+		Chunks:        r.stats.chunks.Load(),
+		Records:       r.stats.records.Load(),
+		Fallbacks:     r.stats.fallbacks.Load(),
+		UnescapedRows: r.stats.unescapedRows.Load(),
+	}
+}
+
+// countBytes accounts for n bytes read from the input
+func (r *Reader) countBytes(n int64) {
+	r.stats.bytesRead.Add(n)
+	r.Manifest.countBytes(n)
+}
+
+// fellBack accounts for encoding/csv taking over from the SIMD stages
+func (r *Reader) fellBack() {
+	r.stats.fallbacks.Add(1)
+	if r.OnFallback != nil {
+		r.OnFallback(r.Stats())
+	}
+}
+
+// countedReader counts the bytes read from the input
+type countedReader struct {
+	in io.Reader
+	r  *Reader
+}
+
+func (cr *countedReader) Read(p []byte) (n int, err error) {
+	n, err = cr.in.Read(p)
+	cr.r.countBytes(int64(n))
+	return
+}
@@ -0,0 +1,64 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	defer func(mode SIMDMode) { defaultSIMDMode = mode }(defaultSIMDMode)
+	defaultSIMDMode = SIMDAuto
+
+	var b strings.Builder
+	for i := 0; i < 1000; i++ {
+		if i%10 == 0 {
+			fmt.Fprintf(&b, "%d,\"a \"\"quoted\"\" field\"\n", i)
+		} else {
+			fmt.Fprintf(&b, "%d,field\n", i)
+		}
+	}
+	input := b.String()
+
+	r := NewReader(strings.NewReader(input))
+	r.SIMD, r.ChunkSize = SIMDForce, 1024
+	r.OnFallback = func(Stats) { t.Errorf("TestStats: got: fallback want: none") }
+	if _, err := r.ReadAll(); err != nil {
+		t.Fatalf("TestStats: got: %v want: nil", err)
+	}
+	s := r.Stats()
+	if s.BytesRead != int64(len(input)) || s.Records != 1000 || s.Chunks < int64(len(input)/1024) || s.Fallbacks != 0 || s.UnescapedRows < 100 || s.UnescapedRows > 1000 {
+		t.Errorf("TestStats: got: %+v want: %d bytes, 1000 records, %d chunks, 100 to 1000 rows unescaped", s, len(input), len(input)/1024+1)
+	}
+
+	for _, mode := range []SIMDMode{SIMDAuto, SIMDDisable} {
+		var calls []Stats
+		r := NewReader(strings.NewReader("a,b\nc,d\n"))
+		r.SIMD = mode
+		r.OnFallback = func(s Stats) { calls = append(calls, s) }
+		for {
+			if _, err := r.Read(); err != nil {
+				break
+			}
+		}
+		if s := r.Stats(); len(calls) != 1 || calls[0].Fallbacks != 1 || s.Records != 2 || s.BytesRead != 8 {
+			t.Errorf("TestStats(%d): got: %+v after %d callbacks want: 2 records read by encoding/csv", mode, s, len(calls))
+		}
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"fmt"
+	"io"
+)
+
+// blockDecoder decodes a block of records into a slice of values
+type blockDecoder func(records [][]string, positions []recordPos) (interface{}, error)
+
+// Stream decodes all remaining records of r with decode and delivers the
+// values in the order of the records. Decoding is done by the parsing
+// workers, so decode is invoked concurrently and must be safe for concurrent
+// use. An error that ends parsing or decoding is delivered on the error
+// channel, after which both channels are closed; at the end of the input
+// they are closed without an error. The values must be received until the
+// channel is closed. Once Stream has been called, r must no longer be read
+// from directly.
+func Stream[T any](r *Reader, decode func([]string) (T, error)) (<-chan T, <-chan error) {
+
+	decodeBlock := func(records [][]string, positions []recordPos) (interface{}, error) {
+		values := make([]T, len(records))
+		for i, record := range records {
+			v, err := decode(record)
+			if err != nil {
+				return nil, fmt.Errorf("record on line %d: %w", positions[i].line, err)
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+	return streamBlocks[T](r, decodeBlock)
+}
+
+// StreamHeader is like Stream, except that the first record is taken as the
+// header (see ReadHeader), which is passed to decode along with every other
+// record rather than decoded itself. If the header was read already, all
+// remaining records are decoded.
+func StreamHeader[T any](r *Reader, decode func(header, record []string) (T, error)) (<-chan T, <-chan error) {
+	r.Lock()
+	skipHeader := r.recordNumber == 0
+	if skipHeader {
+		// the workers need the header, so it is resolved up front
+		r.needHeader = true
+	}
+	r.Unlock()
+
+	decodeBlock := func(records [][]string, positions []recordPos) (interface{}, error) {
+		values := make([]T, 0, len(records))
+		for i, record := range records {
+			if skipHeader && positions[i].offset == r.dataOffset {
+				continue
+			}
+			v, err := decode(r.header, record)
+			if err != nil {
+				return nil, fmt.Errorf("record on line %d: %w", positions[i].line, err)
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+	return streamBlocks[T](r, decodeBlock)
+}
+
+// streamBlocks delivers the values of type T that decodeBlock decodes the
+// remaining records of r into (see Stream)
+func streamBlocks[T any](r *Reader, decodeBlock blockDecoder) (<-chan T, <-chan error) {
+	r.Lock()
+	if r.slots == nil && r.Sentinel == nil && r.SkipFooter <= 0 && !r.dedups() {
+		// only hand the decoder to workers that have yet to be started, and
+		// that need not stop at a trailer, hold back the footer nor drop
+		// duplicates
+		r.decode = decodeBlock
+	}
+	r.Unlock()
+
+	values, errs := make(chan T, queueDepth), make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(values)
+		for {
+			decoded, err := r.readDecoded(decodeBlock)
+			if err != nil {
+				if err != io.EOF {
+					errs <- err
+				}
+				return
+			}
+			for _, v := range decoded.([]T) {
+				values <- v
+			}
+		}
+	}()
+	return values, errs
+}
+
+// readDecoded returns the records not returned yet a block at a time, as
+// decoded by the workers or else by decodeBlock
+func (r *Reader) readDecoded(decodeBlock blockDecoder) (interface{}, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.currrecord >= len(r.records) {
+		if r.readErr != nil {
+			return nil, r.readErr
+		}
+		if !r.simd() {
+			records, positions, err := r.readCsvBlock()
+			if err != nil {
+				return nil, err
+			}
+			r.records, r.positions, r.currrecord = records, positions, 0
+		} else {
+			if r.slots == nil {
+				r.slots = r.readAllStreaming()
+			}
+			for {
+				output, ok := r.slots.get()
+				if !ok {
+					return nil, io.EOF
+				}
+				if output.err != nil {
+					r.slots.stop()
+					return nil, output.err
+				}
+				output = r.received(output)
+				if output.decoded != nil {
+					return output.decoded, nil
+				}
+				if len(output.records) > 0 {
+					r.records, r.positions, r.currrecord = output.records, output.positions, 0
+					break
+				}
+				if r.readErr != nil {
+					return nil, r.readErr
+				}
+			}
+		}
+	}
+
+	records, positions := r.records[r.currrecord:], r.positions[r.currrecord:]
+	r.currrecord = len(r.records)
+	return decodeBlock(records, positions)
+}
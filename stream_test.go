@@ -0,0 +1,100 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	var input bytes.Buffer
+	input.WriteString("id,name\n")
+	const total = 100000
+	for i := 0; i < total; i++ {
+		fmt.Fprintf(&input, "%d,name %d\n", i, i)
+	}
+
+	type row struct {
+		id   int
+		name string
+	}
+	decode := func(record []string) (row, error) {
+		id, err := strconv.Atoi(record[0])
+		return row{id, record[1]}, err
+	}
+
+	// decoding is done by the workers, unless records were read already
+	for _, header := range []bool{false, true} {
+		buf := input.Bytes()
+		if !header {
+			buf = buf[bytes.IndexByte(buf, '\n')+1:]
+		}
+		r := NewReader(bytes.NewReader(buf))
+		if header {
+			if _, err := r.Read(); err != nil {
+				t.Fatalf("TestStream: %v", err)
+			}
+		}
+		values, errs := Stream(r, decode)
+
+		expected := 0
+		for v := range values {
+			if v.id != expected || v.name != fmt.Sprintf("name %d", expected) {
+				t.Fatalf("TestStream: got: %v want: %d", v, expected)
+			}
+			expected++
+		}
+		if err := <-errs; err != nil {
+			t.Errorf("TestStream: %v", err)
+		}
+		if expected != total {
+			t.Errorf("TestStream: got: %d want: %d", expected, total)
+		}
+	}
+
+	// the header is passed along with the records
+	values, errs := StreamHeader(NewReader(bytes.NewReader(input.Bytes())), func(header, record []string) (string, error) {
+		return header[1] + "=" + record[1], nil
+	})
+	expected := 0
+	for v := range values {
+		if want := fmt.Sprintf("name=name %d", expected); v != want {
+			t.Fatalf("TestStream: got: %q want: %q", v, want)
+		}
+		expected++
+	}
+	if err := <-errs; err != nil || expected != total {
+		t.Errorf("TestStream: got: %d values, %v want: %d", expected, err, total)
+	}
+
+	// the header fails to decode
+	for _, threshold := range []int{-1, 0} {
+		r := NewReader(strings.NewReader("id,name\n1,a\n"))
+		r.FallbackThreshold = threshold
+		values, errs := Stream(r, decode)
+		for range values {
+			t.Errorf("TestStream: unexpected value")
+		}
+		if err := <-errs; err == nil || !strings.HasPrefix(err.Error(), "record on line 1:") {
+			t.Errorf("TestStream: got: %v want: error on line 1", err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// swarKernel runs the SIMD stages in plain Go, treating 64-bit words as
+// vectors of eight bytes (SWAR), so that CPUs without a vectorized kernel
+// need not hand the input to encoding/csv wholesale. 32-bit platforms,
+// which lack native 64-bit words, stick to encoding/csv.
+var swarKernel = &kernel{"swar", func() bool { return bits.UintSize == 64 }, true, stage1PreprocessBufferSWAR, stage2ParseMasksGeneric, false}
+
+const (
+	lowBits  = 0x0101010101010101 // lowest bit of every byte
+	highBits = 0x8080808080808080 // highest bit of every byte
+)
+
+// stage1PreprocessBufferSWAR is stage1_preprocess_buffer using scanBufferSWAR
+func stage1PreprocessBufferSWAR(buf []byte, separatorChar uint64, input1 *stage1Input, output1 *stage1Output, postProc *[]uint64, offset uint64, masks []uint64, masksOffset uint64) (processed, masksWritten uint64) {
+	return stage1PreprocessBufferGeneric(buf, separatorChar, input1, output1, postProc, offset, masks, masksOffset, scanBufferSWAR)
+}
+
+// scanBufferSWAR is the blockScanner classifying eight bytes at a time
+func scanBufferSWAR(buf []byte, offset uint64, separatorChar byte, masks *[4]uint64) {
+	var block [64]byte
+	b := loadBlock(buf, offset, &block)
+
+	separators := uint64(separatorChar) * lowBits
+	var quote, separator, carriageReturn, newline uint64
+	for i := 0; i < 64; i += 8 {
+		w := binary.LittleEndian.Uint64(b[i:])
+		quote |= zeroBytes(w^'"'*lowBits) << i
+		separator |= zeroBytes(w^separators) << i
+		carriageReturn |= zeroBytes(w^'\r'*lowBits) << i
+		newline |= zeroBytes(w^'\n'*lowBits) << i
+	}
+	*masks = [4]uint64{quote, separator, carriageReturn, newline}
+}
+
+// zeroBytes returns a mask in which bit i is set if byte i of w is zero
+func zeroBytes(w uint64) uint64 {
+	nonzero := (w&^highBits + ^uint64(highBits)) | w // highest bit of the non-zero bytes
+	zero := ^nonzero & highBits
+	// gather the highest bits of the bytes into the top byte
+	return (zero >> 7) * 0x0102040810204080 >> 56
+}
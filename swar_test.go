@@ -0,0 +1,103 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestScanBufferSWAR(t *testing.T) {
+	rng := rand.New(rand.NewSource(294))
+	for i := 0; i < 1000; i++ {
+		buf := make([]byte, rng.Intn(130))
+		for j := range buf {
+			switch rng.Intn(3) {
+			case 0:
+				buf[j] = "\",;\r\n"[rng.Intn(5)]
+			default:
+				buf[j] = byte(rng.Intn(256)) // all byte values, including those with the highest bit set
+			}
+		}
+		separatorChar := byte(rng.Intn(256))
+		for offset := uint64(0); offset < uint64(len(buf))+64; offset += 64 {
+			var got, want [4]uint64
+			scanBufferSWAR(buf, offset, separatorChar, &got)
+			scanBuffer(buf, offset, separatorChar, &want)
+			if got != want {
+				t.Fatalf("TestScanBufferSWAR: %q at %d: got: %x want: %x", buf, offset, got, want)
+			}
+		}
+	}
+}
+
+func TestSWARKernel(t *testing.T) {
+	for _, file := range []string{"testdata/parking-citations-100K.csv", "testdata/worldcitiespop-100K.csv", "testdata/nyc-taxi-data-100K.csv"} {
+		buf, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := encodingCsv(buf, ',')
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := NewReader(bytes.NewReader(buf))
+		r.kernel = swarKernel
+		got, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("TestSWARKernel: %s: got: %v want: nil", file, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("TestSWARKernel: %s: records differ", file)
+		}
+	}
+}
+
+func BenchmarkSWAR(b *testing.B) {
+	b.Run("parking-citations-100K", func(b *testing.B) {
+		benchmarkSWAR(b, "testdata/parking-citations-100K.csv")
+	})
+	b.Run("worldcitiespop-100K", func(b *testing.B) {
+		benchmarkSWAR(b, "testdata/worldcitiespop-100K.csv")
+	})
+	b.Run("nyc-taxi-data-100K", func(b *testing.B) {
+		benchmarkSWAR(b, "testdata/nyc-taxi-data-100K.csv")
+	})
+}
+
+func benchmarkSWAR(b *testing.B, file string) {
+
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(err)
+	}
+
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r := NewReader(bytes.NewReader(buf))
+		r.kernel = swarKernel
+		if _, err := r.ReadAll(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"io"
+	"sync"
+)
+
+// A TeeReader is one of the consumers of a Reader that is shared by means of
+// Tee. A TeeReader is meant to be used from a single goroutine.
+type TeeReader struct {
+	blocks    chan [][]string
+	done      chan struct{}
+	closeOnce sync.Once
+	records   [][]string // remainder of the current block
+	err       error      // set before blocks is closed
+}
+
+// Tee parses the input of r once on behalf of n consumers, each of which
+// reads all records at its own pace. A consumer may fall behind by up to size
+// blocks of records, after which parsing waits until it catches up (or is
+// closed). The records are shared between the consumers and must not be
+// modified. Once Tee has been called, r must no longer be read from directly.
+func (r *Reader) Tee(n, size int) []*TeeReader {
+
+	readers := make([]*TeeReader, n)
+	for i := range readers {
+		readers[i] = &TeeReader{blocks: make(chan [][]string, size), done: make(chan struct{})}
+	}
+
+	go func() {
+		var err error
+		for {
+			var block [][]string
+			if block, err = r.readBlock(); err != nil {
+				break
+			}
+			active := 0
+			for _, t := range readers {
+				select {
+				case <-t.done:
+					continue
+				default:
+				}
+				select {
+				case t.blocks <- block:
+					active++
+				case <-t.done:
+				}
+			}
+			if active == 0 {
+				r.stop() // all consumers are gone
+				err = io.EOF
+				break
+			}
+		}
+		for _, t := range readers {
+			t.err = err
+			close(t.blocks)
+		}
+	}()
+
+	return readers
+}
+
+// Read reads the next record. At the end of the input it returns io.EOF,
+// otherwise the error that ended parsing.
+func (t *TeeReader) Read() ([]string, error) {
+	for len(t.records) == 0 {
+		block, ok := <-t.blocks
+		if !ok {
+			return nil, t.err
+		}
+		t.records = block
+	}
+	record := t.records[0]
+	t.records = t.records[1:]
+	return record, nil
+}
+
+// ReadAll reads all the remaining records.
+func (t *TeeReader) ReadAll() ([][]string, error) {
+	records := t.records
+	t.records = nil
+	for block := range t.blocks {
+		records = append(records, block...)
+	}
+	if t.err != io.EOF {
+		return nil, t.err
+	}
+	return records, nil
+}
+
+// Close detaches the consumer, so that parsing no longer waits for it.
+func (t *TeeReader) Close() {
+	t.closeOnce.Do(func() { close(t.done) })
+}
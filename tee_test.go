@@ -0,0 +1,86 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/parking-citations-100K.csv")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	want, err := encodingCsv(buf, ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	readers := NewReader(bytes.NewReader(buf)).Tee(3, 1)
+
+	// the first consumer leaves early, which must not hold up the others
+	readers[0].Close()
+
+	var wg sync.WaitGroup
+	results := make([][][]string, len(readers))
+	for i := 1; i < len(readers); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i == 1 {
+				var err error
+				if results[i], err = readers[i].ReadAll(); err != nil {
+					t.Errorf("TestTee: %v", err)
+				}
+				return
+			}
+			for {
+				record, err := readers[i].Read()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Errorf("TestTee: %v", err)
+					return
+				}
+				results[i] = append(results[i], record)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 1; i < len(readers); i++ {
+		if !reflect.DeepEqual(results[i], want) {
+			t.Errorf("TestTee: consumer %d: got: %d records want: %d", i, len(results[i]), len(want))
+		}
+	}
+}
+
+func TestTeeError(t *testing.T) {
+	r := NewReader(strings.NewReader("a,b\nc,\"d\n"))
+	r.FallbackThreshold = -1
+	for _, tr := range r.Tee(2, 4) {
+		if _, err := tr.ReadAll(); err == nil {
+			t.Errorf("TestTeeError: expected parse error")
+		}
+	}
+}
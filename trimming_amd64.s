@@ -0,0 +1,61 @@
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
+
+// func skipSpace(s string) int
+TEXT ·skipSpaceAVX2(SB), 7, $0
+	MOVQ s_base+0(FP), SI
+	MOVQ s_len+8(FP), CX
+	XORQ AX, AX
+
+	MOVQ         $0x20, DX // space
+	MOVQ         DX, X1
+	VPBROADCASTB X1, Y1
+	MOVQ         $0x09, DX // \t, start of \t \n \v \f \r range
+	MOVQ         DX, X2
+	VPBROADCASTB X2, Y2
+	MOVQ         $0x04, DX // width of \t \n \v \f \r range minus one
+	MOVQ         DX, X3
+	VPBROADCASTB X3, Y3
+
+loop:
+	MOVQ CX, DX
+	SUBQ AX, DX
+	CMPQ DX, $32
+	JL   tail
+
+	VMOVDQU   (SI)(AX*1), Y0
+	VPCMPEQB  Y0, Y1, Y4 // byte == ' '
+	VPSUBB    Y2, Y0, Y5 // byte - '\t'
+	VPMINUB   Y3, Y5, Y6
+	VPCMPEQB  Y5, Y6, Y6 // (byte - '\t') <= 4 (unsigned)
+	VPOR      Y4, Y6, Y4
+	VPMOVMSKB Y4, DX
+	NOTL      DX
+	TESTL     DX, DX
+	JNZ       found
+	ADDQ      $32, AX
+	JMP       loop
+
+found:
+	BSFL DX, DX
+	ADDQ DX, AX
+	JMP  done
+
+tail:
+	CMPQ    AX, CX
+	JGE     done
+	MOVBLZX (SI)(AX*1), DX
+	CMPB    DL, $0x20
+	JEQ     next
+	SUBB    $0x09, DL
+	CMPB    DL, $0x04
+	JHI     done
+
+next:
+	INCQ AX
+	JMP  tail
+
+done:
+	VZEROUPPER
+	MOVQ AX, ret+16(FP)
+	RET
@@ -0,0 +1,55 @@
+//go:build !appengine && !noasm && gc
+// +build !appengine,!noasm,gc
+
+// func indexQuoteCRAVX2(s string) int
+TEXT ·indexQuoteCRAVX2(SB), 7, $0
+	MOVQ s_base+0(FP), SI
+	MOVQ s_len+8(FP), CX
+	XORQ AX, AX
+
+	MOVQ         $0x22, DX // quote
+	VMOVQ        DX, X1   // VEX encoded, avoiding an SSE to AVX transition
+	VPBROADCASTB X1, Y1
+	MOVQ         $0x0d, DX // carriage return
+	VMOVQ        DX, X2
+	VPBROADCASTB X2, Y2
+
+loop:
+	MOVQ CX, DX
+	SUBQ AX, DX
+	CMPQ DX, $32
+	JL   tail
+
+	VMOVDQU   (SI)(AX*1), Y0
+	VPCMPEQB  Y0, Y1, Y3 // byte == '"'
+	VPCMPEQB  Y0, Y2, Y4 // byte == '\r'
+	VPOR      Y3, Y4, Y3
+	VPMOVMSKB Y3, DX
+	TESTL     DX, DX
+	JNZ       found
+	ADDQ      $32, AX
+	JMP       loop
+
+found:
+	BSFL DX, DX
+	ADDQ DX, AX
+	JMP  done
+
+tail:
+	CMPQ    AX, CX
+	JGE     notfound
+	MOVBLZX (SI)(AX*1), DX
+	CMPB    DL, $0x22
+	JEQ     done
+	CMPB    DL, $0x0d
+	JEQ     done
+	INCQ    AX
+	JMP     tail
+
+notfound:
+	MOVQ $-1, AX
+
+done:
+	VZEROUPPER
+	MOVQ AX, ret+16(FP)
+	RET
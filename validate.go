@@ -0,0 +1,193 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF8 is reported by Validate for input that is not valid UTF-8.
+var ErrInvalidUTF8 = errors.New("invalid UTF-8")
+
+// maxValidateErrors is the number of problems after which Validate gives up
+const maxValidateErrors = 1000
+
+// Validate checks that the input is well-formed CSV as per RFC 4180, with
+// fields delimited by commas: quotes must enclose fields as a whole and be
+// balanced, all records must have as many fields as the first, and the input
+// must be valid UTF-8. Unlike ReadAll, it carries on past every problem,
+// without materializing any records. It returns a *csv.ParseError for every
+// problem, giving the line and column, up to the first 1000, or nil if the
+// input is valid. An error reading the input ends the list.
+func Validate(r io.Reader) []error {
+	v := validator{in: bufio.NewReaderSize(r, 64<<10), first: -1}
+	for len(v.errs) < maxValidateErrors {
+		line, err := v.readLine()
+		if len(line) > 0 {
+			v.validate(line)
+		}
+		if err != nil {
+			if v.state == stateQuoted {
+				v.fail(v.line, v.width+1, csv.ErrQuote) // the quoted field lacks its closing quote
+			}
+			if err != io.EOF {
+				v.errs = append(v.errs, err)
+			}
+			break
+		}
+	}
+	if len(v.errs) > maxValidateErrors {
+		v.errs = v.errs[:maxValidateErrors]
+	}
+	return v.errs
+}
+
+// States of a validator within a record
+const (
+	stateFieldStart = iota
+	stateUnquoted
+	stateQuoted
+	stateQuotedQuote // a quote within a quoted field, which may close it
+	stateInvalid     // the rest of the line is skipped after a problem
+)
+
+// validator tracks the state of Validate from line to line
+type validator struct {
+	in      *bufio.Reader
+	buf     []byte // line that exceeds the buffer of in
+	errs    []error
+	line    int // current line
+	start   int // line on which the record started
+	state   int
+	fields  int // number of fields of the current record so far
+	width   int // length of the current line
+	invalid bool
+	first   int // number of fields of the first record, or -1
+}
+
+// readLine returns the next line, including its terminator
+func (v *validator) readLine() ([]byte, error) {
+	line, err := v.in.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		v.buf = append(v.buf[:0], line...)
+		for err == bufio.ErrBufferFull {
+			line, err = v.in.ReadSlice('\n')
+			v.buf = append(v.buf, line...)
+		}
+		line = v.buf
+	}
+	if len(line) > 0 {
+		v.line++
+	}
+	return line, err
+}
+
+// fail records a problem at column (a 1-based byte index) of line
+func (v *validator) fail(line, column int, err error) {
+	v.errs = append(v.errs, &csv.ParseError{StartLine: v.start, Line: line, Column: column, Err: err})
+}
+
+// validate checks the next line of the input
+func (v *validator) validate(line []byte) {
+	v.width = len(line)
+	if !utf8.Valid(line) {
+		column := 1
+		for rest := line; ; {
+			r, size := utf8.DecodeRune(rest)
+			if r == utf8.RuneError && size <= 1 {
+				break
+			}
+			rest, column = rest[size:], column+size
+		}
+		v.errs = append(v.errs, &csv.ParseError{StartLine: v.line, Line: v.line, Column: column, Err: ErrInvalidUTF8})
+	}
+
+	text := bytes.TrimSuffix(bytes.TrimSuffix(line, []byte{'\n'}), []byte{'\r'})
+	if v.state != stateQuoted {
+		if len(text) == 0 {
+			return // empty lines hold no record
+		}
+		v.start, v.state, v.fields, v.invalid = v.line, stateFieldStart, 1, false
+		if bytes.IndexByte(text, '"') < 0 {
+			// all but the quoted fields are plain
+			v.fields = bytes.Count(text, []byte{','}) + 1
+			v.endRecord()
+			return
+		}
+	}
+
+	for i, c := range text {
+		switch v.state {
+		case stateFieldStart, stateUnquoted:
+			switch c {
+			case ',':
+				v.state = stateFieldStart
+				v.fields++
+			case '"':
+				if v.state == stateUnquoted {
+					v.fail(v.line, i+1, csv.ErrBareQuote)
+					v.state, v.invalid = stateInvalid, true
+				} else {
+					v.state = stateQuoted
+				}
+			default:
+				v.state = stateUnquoted
+			}
+		case stateQuoted:
+			if c == '"' {
+				v.state = stateQuotedQuote
+			}
+		case stateQuotedQuote:
+			switch c {
+			case '"':
+				v.state = stateQuoted
+			case ',':
+				v.state = stateFieldStart
+				v.fields++
+			default:
+				v.fail(v.line, i, csv.ErrQuote) // at the quote
+				v.state, v.invalid = stateInvalid, true
+			}
+		}
+		if v.state == stateInvalid {
+			break
+		}
+	}
+
+	if v.state == stateQuoted {
+		return // the quoted field continues on the next line
+	}
+	v.endRecord()
+}
+
+// endRecord checks the number of fields of the record that ends
+func (v *validator) endRecord() {
+	v.state = stateFieldStart
+	if v.invalid {
+		return
+	}
+	if v.first < 0 {
+		v.first = v.fields
+	} else if v.fields != v.first {
+		v.errs = append(v.errs, &csv.ParseError{StartLine: v.start, Line: v.start, Column: 1, Err: csv.ErrFieldCount})
+	}
+}
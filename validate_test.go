@@ -0,0 +1,69 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var valid bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&valid, "%d,\"quoted, \"\"multi\"\"\r\nline\",ünïcode %s\r\n\n", i, strings.Repeat("x", i%100))
+	}
+	if errs := Validate(bytes.NewReader(valid.Bytes())); len(errs) != 0 {
+		t.Errorf("TestValidate: got: %v want: none", errs)
+	}
+
+	const input = "a,b,c\n" +
+		"1,2\n" + // line 2: too few fields
+		"1,b\"are,3\n" + // line 3: bare quote
+		"1,\"x\"y,3\n" + // line 4: extraneous quote
+		"1,\xff,3\n" + // line 5: invalid UTF-8
+		"1,\"multi\nline\",3,4\n" + // lines 6-7: too many fields
+		"1,2,\"open\n"
+	want := []*csv.ParseError{
+		{StartLine: 2, Line: 2, Column: 1, Err: csv.ErrFieldCount},
+		{StartLine: 3, Line: 3, Column: 4, Err: csv.ErrBareQuote},
+		{StartLine: 4, Line: 4, Column: 5, Err: csv.ErrQuote},
+		{StartLine: 5, Line: 5, Column: 3, Err: ErrInvalidUTF8},
+		{StartLine: 6, Line: 6, Column: 1, Err: csv.ErrFieldCount},
+		{StartLine: 8, Line: 8, Column: 11, Err: csv.ErrQuote},
+	}
+	errs := Validate(strings.NewReader(input))
+	var got []*csv.ParseError
+	for _, err := range errs {
+		var pe *csv.ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("TestValidate: got: %v want: *csv.ParseError", err)
+		}
+		got = append(got, pe)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TestValidate: got: %v want: %v", errs, want)
+	}
+
+	if errs := Validate(strings.NewReader(strings.Repeat("a\"b\n", 2*maxValidateErrors))); len(errs) != maxValidateErrors {
+		t.Errorf("TestValidate: got: %d errors want: %d", len(errs), maxValidateErrors)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A Predicate reports whether a field matches (see WhereColumns)
+type Predicate func(field string) bool
+
+// Equal returns the Predicate that matches fields equal to s
+func Equal(s string) Predicate {
+	return func(field string) bool { return field == s }
+}
+
+// HasPrefix returns the Predicate that matches fields starting with prefix
+func HasPrefix(prefix string) Predicate {
+	return func(field string) bool { return strings.HasPrefix(field, prefix) }
+}
+
+// InRange returns the Predicate that matches numeric fields between min and
+// max inclusive. Fields that are not numbers do not match.
+func InRange(min, max float64) Predicate {
+	return func(field string) bool {
+		f, err := strconv.ParseFloat(field, 64)
+		return err == nil && min <= f && f <= max
+	}
+}
+
+// filters reports whether records are dropped unless they match predicates
+// (see WhereColumns)
+func (r *Reader) filters() bool {
+	return len(r.WhereColumns) > 0 || len(r.WhereNames) > 0 || r.query != nil && r.query.where != nil
+}
+
+// predicates holds the predicates by column of the input (see WhereColumns)
+type predicates struct {
+	header  bool  // whether the first record is a header, which is kept
+	columns []int // of the input for every predicate, or -1
+	preds   []Predicate
+	cond    func(record []string) bool // of the query, if any (see Select)
+}
+
+// newPredicates combines the predicates by column index and by name, where
+// the names are looked up in header (of the input). Column indexes refer to
+// the selected columns, so the projection is resolved first.
+func (r *Reader) newPredicates(header []string) *predicates {
+	f := &predicates{header: len(r.WhereNames) > 0 || len(r.SelectNames) > 0 || len(r.NormalizeNames) > 0 || r.NormalizeHeader != 0 || r.query != nil && r.query.header}
+	if r.query != nil && r.query.where != nil {
+		f.cond = r.bind(r.query.where, header)
+	}
+	for c, pred := range r.WhereColumns {
+		col := c
+		if r.proj != nil {
+			col = -1
+			if c < len(r.proj.columns) {
+				col = r.proj.columns[c]
+			}
+		}
+		f.columns, f.preds = append(f.columns, col), append(f.preds, pred)
+	}
+	for name, pred := range r.WhereNames {
+		col := -1
+		name = r.NormalizeHeader.apply(name)
+		for i, column := range header {
+			if r.NormalizeHeader.apply(column) == name {
+				col = i
+				break
+			}
+		}
+		f.columns, f.preds = append(f.columns, col), append(f.preds, pred)
+	}
+	return f
+}
+
+// match reports whether all predicates match record, where columns missing
+// from the record are empty
+func (f *predicates) match(record []string) bool {
+	for i, col := range f.columns {
+		field := ""
+		if col >= 0 && col < len(record) {
+			field = record[col]
+		}
+		if !f.preds[i](field) {
+			return false
+		}
+	}
+	return f.cond == nil || f.cond(record)
+}
+
+// filter removes the records that do not match, except for the header if
+// the records start with the first record
+func (f *predicates) filter(records *[][]string, positions *[]recordPos, first bool) {
+	n := 0
+	for i, record := range *records {
+		if i == 0 && first && f.header || f.match(record) {
+			(*records)[n] = record
+			if i < len(*positions) {
+				(*positions)[n] = (*positions)[i]
+			}
+			n++
+		}
+	}
+	*records = (*records)[:n]
+	if n < len(*positions) {
+		*positions = (*positions)[:n]
+	}
+}
+
+// csvReadWhere reads the next record that matches the predicates, if any
+func (r *Reader) csvReadWhere() ([]string, recordPos, error) {
+	for {
+		record, pos, err := r.csvReader().read()
+		if err != nil || !r.filters() {
+			return record, pos, err
+		}
+		first := r.header == nil && r.startOffset == r.dataOffset
+		if r.where == nil {
+			var header []string
+			if first {
+				header = record
+			}
+			if r.projects() && r.proj == nil {
+				r.proj = r.newProjection(header)
+			}
+			r.where = r.newPredicates(header)
+		}
+		if first && r.where.header || r.where.match(record) {
+			return record, pos, nil
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWhere(t *testing.T) {
+	cities := []string{"Paris", "Berlin", "Madrid", "Parma"}
+	var input bytes.Buffer
+	input.WriteString("id,city,population\n")
+	for i := 0; input.Len() < 1<<20; i++ {
+		fmt.Fprintf(&input, "%d,\"%s\",%d\n", i, cities[i%len(cities)], i%7000)
+	}
+	all, err := encodingCsv(input.Bytes(), ',')
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	small := bytes.IndexByte(input.Bytes()[200:], '\n') + 201
+	for _, size := range []int{small, input.Len()} {
+		for _, threshold := range []int{-1, 0} {
+			for _, project := range []bool{false, true} {
+				var expected [][]string
+				for i, record := range all {
+					if i > 0 && (record[1] != "Paris" && record[1] != "Parma" || len(record[2]) != 4 || record[2] > "5000") {
+						continue
+					}
+					if project {
+						record = []string{record[2], record[0]}
+					}
+					expected = append(expected, record)
+				}
+
+				r := NewReader(bytes.NewReader(input.Bytes()[:size]))
+				r.FallbackThreshold = threshold
+				r.WhereNames = map[string]Predicate{"city": HasPrefix("Par")}
+				r.WhereColumns = map[int]Predicate{2: InRange(1000, 5000)}
+				if project {
+					r.SelectNames = []string{"population", "id"}
+					r.WhereColumns = map[int]Predicate{0: InRange(1000, 5000)}
+				}
+				records, err := r.ReadAll()
+				if err != nil {
+					t.Fatalf("TestWhere: %v", err)
+				}
+				if len(records) < 1 || !reflect.DeepEqual(records, expected[:len(records)]) {
+					t.Fatalf("TestWhere: got: %d records want: %d", len(records), len(expected))
+				}
+				if size == input.Len() && len(records) != len(expected) {
+					t.Errorf("TestWhere: got: %d records want: %d", len(records), len(expected))
+				}
+			}
+		}
+	}
+
+	// a record on its own matching line
+	r := NewReader(bytes.NewReader(input.Bytes()))
+	r.FallbackThreshold = -1
+	r.WhereColumns = map[int]Predicate{0: Equal("1234")}
+	if record, err := r.ReadRecord(); err != nil || record.Fields[0] != "1234" || record.Line != 1236 {
+		t.Errorf("TestWhere: got: %q on line %d, %v want: record 1234 on line 1236", record.Fields, record.Line, err)
+	}
+}
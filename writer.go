@@ -0,0 +1,305 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Writer writes records using CSV encoding, producing the same output as
+// encoding/csv.Writer. Fields are scanned eight bytes at a time, as 64-bit
+// words (SWAR, no vector instructions), to decide whether they need quoting,
+// and fields that do not are copied to the output buffer as a whole.
+//
+// Writes are buffered, so Flush must eventually be called to ensure that
+// the record is written to the underlying io.Writer.
+//
+// If Parallel is set, WriteAll encodes ranges of the records concurrently
+// on all CPUs, writing the encoded ranges in order, for exporting many
+// records to fast storage.
+type Writer struct {
+	Comma    rune // Field delimiter (set to ',' by NewWriter)
+	UseCRLF  bool // True to use \r\n as the line terminator
+	Parallel bool // True to encode the records of WriteAll concurrently
+	SepLine  bool // True to precede the records by a sep= line naming Comma, as Excel reads
+	w        *bufio.Writer
+	sepDone  bool // whether the sep= line was written
+}
+
+// NewWriter returns a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		Comma: ',',
+		w:     bufio.NewWriterSize(w, 64<<10),
+	}
+}
+
+// Write writes a single CSV record to w along with any necessary quoting.
+// A record is a slice of strings with each string being one field.
+// Writes are buffered, so Flush must eventually be called to ensure
+// that the record is written to the underlying io.Writer.
+func (w *Writer) Write(record []string) error {
+	if !validDelim(w.Comma) {
+		return errInvalidDelim
+	}
+	if err := w.writeSepLine(); err != nil {
+		return err
+	}
+
+	for n, field := range record {
+		if n > 0 {
+			if _, err := w.w.WriteRune(w.Comma); err != nil {
+				return err
+			}
+		}
+
+		// If we don't have to have a quoted field then just
+		// write out the field and continue to the next field.
+		if !w.fieldNeedsQuotes(field) {
+			if _, err := w.w.WriteString(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := w.w.WriteByte('"'); err != nil {
+			return err
+		}
+		for len(field) > 0 {
+			// Copy everything up to the next special character in one go
+			i := strings.IndexAny(field, "\"\r\n")
+			if i < 0 {
+				i = len(field)
+			}
+			if _, err := w.w.WriteString(field[:i]); err != nil {
+				return err
+			}
+			field = field[i:]
+
+			// Encode the special character.
+			if len(field) > 0 {
+				var err error
+				switch field[0] {
+				case '"':
+					_, err = w.w.WriteString(`""`)
+				case '\r':
+					if !w.UseCRLF {
+						err = w.w.WriteByte('\r')
+					}
+				case '\n':
+					if w.UseCRLF {
+						_, err = w.w.WriteString("\r\n")
+					} else {
+						err = w.w.WriteByte('\n')
+					}
+				}
+				field = field[1:]
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if err := w.w.WriteByte('"'); err != nil {
+			return err
+		}
+	}
+	var err error
+	if w.UseCRLF {
+		_, err = w.w.WriteString("\r\n")
+	} else {
+		err = w.w.WriteByte('\n')
+	}
+	return err
+}
+
+// writeSepLine writes the sep= line ahead of the first record if SepLine is set
+func (w *Writer) writeSepLine() error {
+	if !w.SepLine || w.sepDone {
+		return nil
+	}
+	w.sepDone = true
+	terminator := "\n"
+	if w.UseCRLF {
+		terminator = "\r\n"
+	}
+	_, err := w.w.WriteString("sep=" + string(w.Comma) + terminator)
+	return err
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+// To check if an error occurred during the Flush, call Error.
+func (w *Writer) Flush() {
+	w.w.Flush()
+}
+
+// Error reports any error that has occurred during a previous Write or Flush.
+func (w *Writer) Error() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// WriteAll writes multiple CSV records to w using Write and then calls Flush,
+// returning any error from the Flush.
+func (w *Writer) WriteAll(records [][]string) error {
+	if w.Parallel && len(records) > minParallelRows {
+		return w.writeAllParallel(records)
+	}
+	for _, record := range records {
+		err := w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+// minParallelRows is the least number of records in a range that
+// writeAllParallel encodes, as smaller ranges do not pay off
+const minParallelRows = 256
+
+// encodedRange holds the encoding of a range of records
+type encodedRange struct {
+	buf bytes.Buffer
+	w   *bufio.Writer // writes to buf
+}
+
+var encodedRanges = sync.Pool{New: func() interface{} {
+	e := &encodedRange{}
+	e.w = bufio.NewWriterSize(&e.buf, 64<<10)
+	return e
+}}
+
+// writeAllParallel is WriteAll encoding ranges of records from multiple
+// goroutines, with as many ranges encoded ahead of the one being written
+// as there are CPUs
+func (w *Writer) writeAllParallel(records [][]string) error {
+	if !validDelim(w.Comma) {
+		return errInvalidDelim
+	}
+	if err := w.writeSepLine(); err != nil {
+		return err
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	rows := len(records) / (4 * workers)
+	if rows < minParallelRows {
+		rows = minParallelRows
+	}
+
+	ranges := make(chan chan *encodedRange, workers)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(ranges)
+		for len(records) > 0 {
+			n := rows
+			if n > len(records) {
+				n = len(records)
+			}
+			result := make(chan *encodedRange, 1)
+			select {
+			case ranges <- result:
+			case <-done:
+				return
+			}
+			go func(records [][]string) {
+				e := encodedRanges.Get().(*encodedRange)
+				e.buf.Reset()
+				enc := &Writer{Comma: w.Comma, UseCRLF: w.UseCRLF, w: e.w}
+				enc.WriteAll(records) // cannot fail writing to a bytes.Buffer
+				result <- e
+			}(records[:n])
+			records = records[n:]
+		}
+	}()
+
+	for result := range ranges {
+		e := <-result
+		_, err := w.w.Write(e.buf.Bytes())
+		encodedRanges.Put(e)
+		if err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+// fieldNeedsQuotes reports whether our field must be enclosed in quotes.
+// Fields with a Comma, fields with a quote or newline, and
+// fields which start with a space must be enclosed in quotes.
+// We used to quote empty strings, but we do not anymore (as of Go 1.4).
+// The field `\.` is always quoted, as it marks the end of data for
+// PostgreSQL.
+func (w *Writer) fieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` {
+		return true
+	}
+
+	if w.Comma < utf8.RuneSelf {
+		if hasSpecial(field, byte(w.Comma)) {
+			return true
+		}
+	} else if hasSpecial(field, '"') || strings.ContainsRune(field, w.Comma) {
+		return true
+	}
+
+	r1, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r1)
+}
+
+const (
+	swarOnes  = 0x0101010101010101
+	swarHighs = 0x8080808080808080
+)
+
+// hasSpecial reports whether s contains comma, a quote, or a newline,
+// testing eight bytes at a time within a 64-bit word (SWAR)
+func hasSpecial(s string, comma byte) bool {
+	commas, quotes := swarOnes*uint64(comma), swarOnes*uint64('"')
+	crs, lfs := swarOnes*uint64('\r'), swarOnes*uint64('\n')
+
+	var word [8]byte
+	for ; len(s) >= 8; s = s[8:] {
+		copy(word[:], s)
+		v := binary.LittleEndian.Uint64(word[:])
+		if hasZeroByte(v^commas)|hasZeroByte(v^quotes)|hasZeroByte(v^crs)|hasZeroByte(v^lfs) != 0 {
+			return true
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == comma || c == '"' || c == '\r' || c == '\n' {
+			return true
+		}
+	}
+	return false
+}
+
+// hasZeroByte returns a non-zero value if any byte of v is zero
+func hasZeroByte(v uint64) uint64 {
+	return (v - swarOnes) &^ v & swarHighs
+}
@@ -0,0 +1,136 @@
+/*
+ * MinIO Cloud Storage, (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simdcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	const alphabet = "ab ,;\"\r\n\t\\.é|"
+
+	rnd := rand.New(rand.NewSource(0))
+	records := [][]string{{""}, {`\.`}, {" a", "\tb"}, {"a\r\nb", "c\"d"}, {" x", "€"}}
+	for i := 0; i < 1000; i++ {
+		record := make([]string, 1+rnd.Intn(4))
+		for j := range record {
+			field := make([]byte, rnd.Intn(24))
+			for k := range field {
+				field[k] = alphabet[rnd.Intn(len(alphabet))]
+			}
+			record[j] = string(field)
+		}
+		records = append(records, record)
+	}
+
+	for _, comma := range []rune{',', ';', '|', '€'} {
+		for _, crlf := range []bool{false, true} {
+			for _, parallel := range []bool{false, true} {
+				var got, want bytes.Buffer
+				w := NewWriter(&got)
+				w.Comma, w.UseCRLF, w.Parallel = comma, crlf, parallel
+				w.Write(records[len(records)-1]) // buffered ahead of WriteAll
+				if err := w.WriteAll(records); err != nil {
+					t.Fatalf("TestWriter: %v", err)
+				}
+				cw := csv.NewWriter(&want)
+				cw.Comma, cw.UseCRLF = comma, crlf
+				cw.Write(records[len(records)-1])
+				cw.WriteAll(records)
+				if got.String() != want.String() {
+					t.Errorf("TestWriter(%q, %v, %v): got: %q want: %q", comma, crlf, parallel, got.String(), want.String())
+				}
+			}
+		}
+	}
+
+	w := NewWriter(&bytes.Buffer{})
+	w.Comma = '"'
+	if err := w.Write([]string{"a"}); err != errInvalidDelim {
+		t.Errorf("TestWriter: got: %v want: %v", err, errInvalidDelim)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("failing writer") }
+
+func TestWriterError(t *testing.T) {
+	w := NewWriter(failingWriter{})
+	w.Write([]string{"abc"})
+	w.Flush()
+	if err := w.Error(); err == nil {
+		t.Errorf("TestWriterError: expected error")
+	}
+
+	w = NewWriter(failingWriter{})
+	w.Parallel = true
+	records := make([][]string, 100000)
+	for i := range records {
+		records[i] = []string{"abc", "def"}
+	}
+	if err := w.WriteAll(records); err == nil {
+		t.Errorf("TestWriterError: expected error (parallel)")
+	}
+}
+
+func TestHasSpecial(t *testing.T) {
+	for _, s := range []string{"", "abcdefgh", "abcdefghijklmnop", "x", "¬¢ª"} {
+		if hasSpecial(s, ',') {
+			t.Errorf("TestHasSpecial(%q): got: true", s)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		for _, c := range ",\"\r\n" {
+			s := []byte("abcdefghijklmnopqrst")
+			s[i] = byte(c)
+			if !hasSpecial(string(s), ',') {
+				t.Errorf("TestHasSpecial(%q): got: false", s)
+			}
+		}
+	}
+}
+
+// BenchmarkHasSpecial compares hasSpecial to strings.ContainsAny on fields
+// that do not need quoting, which are scanned to the end
+func BenchmarkHasSpecial(b *testing.B) {
+	for _, size := range []int{8, 32, 256} {
+		field := strings.Repeat("abcdefgh", size/8)
+		b.Run("swar/"+strconv.Itoa(size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				if hasSpecial(field, ',') {
+					b.Fatal("BenchmarkHasSpecial: got: true")
+				}
+			}
+		})
+		b.Run("ContainsAny/"+strconv.Itoa(size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				if strings.ContainsAny(field, ",\"\r\n") {
+					b.Fatal("BenchmarkHasSpecial: got: true")
+				}
+			}
+		})
+	}
+}